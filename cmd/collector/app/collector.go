@@ -4,11 +4,12 @@ import (
 	"context"
 	"fmt"
 	"io"
-	"net/http"
 	"time"
 
 	// "go.opentelemetry.io/collector/receiver"
+	"github.com/savsgio/atreugo/v11"
 	"go.uber.org/zap"
+	"google.golang.org/grpc"
 
 	"logger/cmd/collector/app/flags"
 	"logger/cmd/collector/app/processor"
@@ -41,12 +42,18 @@ type Collector struct {
 	tenancyMgr     *tenancy.Manager
 
 	// state, read only
-	hServer                    *http.Server
-	// grpcServer                 *grpc.Server
+	hServer                    *atreugo.Atreugo
+	grpcServer                 *grpc.Server
+	syslogServer               *server.SyslogServer
+	fluentServer               *server.FluentServer
+	gelfServer                 *server.GELFServer
 	// otlpReceiver               receiver.Traces
 	// zipkinReceiver             receiver.Traces
-	// tlsGRPCCertWatcherCloser   io.Closer
+	tlsGRPCCertWatcherCloser   io.Closer
 	tlsHTTPCertWatcherCloser   io.Closer
+	tlsSyslogCertWatcherCloser io.Closer
+	tlsFluentCertWatcherCloser io.Closer
+	tlsGELFCertWatcherCloser   io.Closer
 	// tlsZipkinCertWatcherCloser io.Closer
 }
 
@@ -94,46 +101,76 @@ func (c *Collector) Start(options *flags.CollectorOptions) error {
 	c.logProcessor = handlerBuilder.BuildLogProcessor(additionalProcessors...)
 	c.logHandlers = handlerBuilder.BuildHandlers(c.logProcessor)
 
-	err := server.StartHTTPServer(&server.HttpServerParams{
-		Handler: c.logHandlers.BatchesHandler,
-		Logger: c.logger,
-		HostPort: options.HTTP.HostPort,
+	grpcServer, err := server.StartGRPCServer(&server.GRPCServerParams{
+		GRPCOptions: options.GRPC,
+		Handler:     c.logHandlers.GRPCHandler,
+		TenancyMgr:  c.tenancyMgr,
+		HealthCheck: c.hCheck,
+		Logger:      c.logger,
+	})
+	if err != nil {
+		return fmt.Errorf("could not start gRPC server: %w", err)
+	}
+	c.grpcServer = grpcServer
+	c.tlsGRPCCertWatcherCloser = &options.GRPC.TLS
+
+	httpServer, err := server.StartHTTPServer(&server.HttpServerParams{
+		HTTPOptions:          options.HTTP,
+		Handler:              c.logHandlers.BatchesHandler,
+		LokiHandler:          c.logHandlers.LokiHandler,
+		ElasticsearchHandler: c.logHandlers.ElasticsearchHandler,
+		HECHandler:           c.logHandlers.HECHandler,
+		HealthCheck:          c.hCheck,
+		Logger:               c.logger,
 	})
 	if err != nil {
 		return fmt.Errorf("could not start HTTP server: %w", err)
 	}
+	c.hServer = httpServer
+	c.tlsHTTPCertWatcherCloser = &options.HTTP.TLS
+
+	if options.Syslog.UDPHostPort != "" || options.Syslog.TCPHostPort != "" {
+		syslogServer, err := server.StartSyslogServer(&server.SyslogServerParams{
+			SyslogOptions: options.Syslog,
+			Handler:       c.logHandlers.SyslogHandler,
+			HealthCheck:   c.hCheck,
+			Logger:        c.logger,
+		})
+		if err != nil {
+			return fmt.Errorf("could not start syslog receiver: %w", err)
+		}
+		c.syslogServer = syslogServer
+		c.tlsSyslogCertWatcherCloser = &options.Syslog.TLS
+	}
 
-	// grpcServer, err := server.StartGRPCServer(&server.GRPCServerParams{
-	// 	HostPort:                options.GRPC.HostPort,
-	// 	Handler:                 c.logHandlers.GRPCHandler,
-	// 	TLSConfig:               options.GRPC.TLS,
-	// 	SamplingStore:           c.strategyStore,
-	// 	Logger:                  c.logger,
-	// 	MaxReceiveMessageLength: options.GRPC.MaxReceiveMessageLength,
-	// 	MaxConnectionAge:        options.GRPC.MaxConnectionAge,
-	// 	MaxConnectionAgeGrace:   options.GRPC.MaxConnectionAgeGrace,
-	// })
-	// if err != nil {
-	// 	return fmt.Errorf("could not start gRPC server: %w", err)
-	// }
-	// c.grpcServer = grpcServer
-
-	// httpServer, err := server.StartHTTPServer(&server.HTTPServerParams{
-	// 	HostPort:       options.HTTP.HostPort,
-	// 	Handler:        c.logHandlers.JaegerBatchesHandler,
-	// 	TLSConfig:      options.HTTP.TLS,
-	// 	HealthCheck:    c.hCheck,
-	// 	MetricsFactory: c.metricsFactory,
-	// 	SamplingStore:  c.strategyStore,
-	// 	Logger:         c.logger,
-	// })
-	// if err != nil {
-	// 	return fmt.Errorf("could not start HTTP server: %w", err)
-	// }
-	// c.hServer = httpServer
+	if options.Fluent.HostPort != "" {
+		fluentServer, err := server.StartFluentServer(&server.FluentServerParams{
+			FluentOptions: options.Fluent,
+			Handler:       c.logHandlers.FluentHandler,
+			HealthCheck:   c.hCheck,
+			Logger:        c.logger,
+		})
+		if err != nil {
+			return fmt.Errorf("could not start Fluent Forward receiver: %w", err)
+		}
+		c.fluentServer = fluentServer
+		c.tlsFluentCertWatcherCloser = &options.Fluent.TLS
+	}
+
+	if options.GELF.UDPHostPort != "" || options.GELF.TCPHostPort != "" {
+		gelfServer, err := server.StartGELFServer(&server.GELFServerParams{
+			GELFOptions: options.GELF,
+			Handler:     c.logHandlers.GELFHandler,
+			HealthCheck: c.hCheck,
+			Logger:      c.logger,
+		})
+		if err != nil {
+			return fmt.Errorf("could not start GELF receiver: %w", err)
+		}
+		c.gelfServer = gelfServer
+		c.tlsGELFCertWatcherCloser = &options.GELF.TLS
+	}
 
-	// c.tlsGRPCCertWatcherCloser = &options.GRPC.TLS
-	// c.tlsHTTPCertWatcherCloser = &options.HTTP.TLS
 	// c.tlsZipkinCertWatcherCloser = &options.Zipkin.TLS
 
 	
@@ -159,11 +196,14 @@ func (c *Collector) publishOpts(cOpts *flags.CollectorOptions) {
 // Close the component and all its underlying dependencies
 func (c *Collector) Close() error {
 	// Stop gRPC server
+	if c.grpcServer != nil {
+		c.grpcServer.GracefulStop()
+	}
 
 	// Stop HTTP server
 	if c.hServer != nil {
 		timeout, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		if err := c.hServer.Shutdown(timeout); err != nil {
+		if err := c.hServer.ShutdownWithContext(timeout); err != nil {
 			c.logger.Fatal("failed to stop the main HTTP server", zap.Error(err))
 		}
 		defer cancel()
@@ -171,6 +211,27 @@ func (c *Collector) Close() error {
 
 	
 
+	// Stop syslog receiver
+	if c.syslogServer != nil {
+		if err := c.syslogServer.Close(); err != nil {
+			c.logger.Error("failed to stop the syslog receiver", zap.Error(err))
+		}
+	}
+
+	// Stop Fluent Forward receiver
+	if c.fluentServer != nil {
+		if err := c.fluentServer.Close(); err != nil {
+			c.logger.Error("failed to stop the Fluent Forward receiver", zap.Error(err))
+		}
+	}
+
+	// Stop GELF receiver
+	if c.gelfServer != nil {
+		if err := c.gelfServer.Close(); err != nil {
+			c.logger.Error("failed to stop the GELF receiver", zap.Error(err))
+		}
+	}
+
 	// Stop OpenTelemetry OTLP receiver
 	// if c.otlpReceiver != nil {
 	// 	timeout, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -192,12 +253,21 @@ func (c *Collector) Close() error {
 	// }
 
 	// // watchers actually never return errors from Close
-	// if c.tlsGRPCCertWatcherCloser != nil {
-	// 	_ = c.tlsGRPCCertWatcherCloser.Close()
-	// }
+	if c.tlsGRPCCertWatcherCloser != nil {
+		_ = c.tlsGRPCCertWatcherCloser.Close()
+	}
 	if c.tlsHTTPCertWatcherCloser != nil {
 		_ = c.tlsHTTPCertWatcherCloser.Close()
 	}
+	if c.tlsSyslogCertWatcherCloser != nil {
+		_ = c.tlsSyslogCertWatcherCloser.Close()
+	}
+	if c.tlsFluentCertWatcherCloser != nil {
+		_ = c.tlsFluentCertWatcherCloser.Close()
+	}
+	if c.tlsGELFCertWatcherCloser != nil {
+		_ = c.tlsGELFCertWatcherCloser.Close()
+	}
 	// if c.tlsZipkinCertWatcherCloser != nil {
 	// 	_ = c.tlsZipkinCertWatcherCloser.Close()
 	// }
@@ -16,8 +16,12 @@
 package flags
 
 import (
+	"encoding/json"
 	"flag"
 	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/spf13/viper"
@@ -42,6 +46,7 @@ const (
 	flagSuffixHTTPReadTimeout       = "read-timeout"
 	flagSuffixHTTPReadHeaderTimeout = "read-header-timeout"
 	flagSuffixHTTPIdleTimeout       = "idle-timeout"
+	flagSuffixHTTPMaxRequestSize    = "max-request-size"
 
 	flagSuffixGRPCMaxReceiveMessageLength = "max-message-size"
 	flagSuffixGRPCMaxConnectionAge        = "max-connection-age"
@@ -52,12 +57,45 @@ const (
 	flagZipkinHTTPHostPort     = "collector.zipkin.host-port"
 	flagZipkinKeepAliveEnabled = "collector.zipkin.keep-alive"
 
+	flagSyslogUDPHostPort    = "collector.syslog.udp.host-port"
+	flagSyslogTCPHostPort    = "collector.syslog.tcp.host-port"
+	flagSyslogMaxMessageSize = "collector.syslog.max-message-size"
+
+	flagFluentHostPort       = "collector.fluent.host-port"
+	flagFluentSharedKey      = "collector.fluent.shared-key"
+	flagFluentSelfHostname   = "collector.fluent.self-hostname"
+	flagFluentMaxMessageSize = "collector.fluent.max-message-size"
+
+	flagGELFUDPHostPort    = "collector.gelf.udp.host-port"
+	flagGELFTCPHostPort    = "collector.gelf.tcp.host-port"
+	flagGELFMaxMessageSize = "collector.gelf.max-message-size"
+	flagGELFChunkTimeout   = "collector.gelf.chunk-timeout"
+
+	flagLokiServiceLabels = "collector.loki.service-labels"
+
+	flagElasticsearchTimestampField = "collector.elasticsearch.timestamp-field"
+	flagElasticsearchMessageField   = "collector.elasticsearch.message-field"
+	flagElasticsearchLevelField     = "collector.elasticsearch.level-field"
+	flagElasticsearchServiceField   = "collector.elasticsearch.service-field"
+
+	flagHECTokensFile = "collector.hec.tokens-file"
+
 	// DefaultNumWorkers is the default number of workers consuming from the processor queue
 	DefaultNumWorkers = 50
 	// DefaultQueueSize is the size of the processor's queue
 	DefaultQueueSize = 2000
 	// DefaultGRPCMaxReceiveMessageLength is the default max receivable message size for the gRPC Collector
 	DefaultGRPCMaxReceiveMessageLength = 4 * 1024 * 1024
+	// DefaultHTTPMaxRequestSize is the default max size of a decompressed request body for the HTTP Collector
+	DefaultHTTPMaxRequestSize = 16 * 1024 * 1024
+	// DefaultSyslogMaxMessageSize is the default max size of a syslog message
+	DefaultSyslogMaxMessageSize = 64 * 1024
+	// DefaultFluentMaxMessageSize is the default max size of a Forward protocol message, once decompressed
+	DefaultFluentMaxMessageSize = 16 * 1024 * 1024
+	// DefaultGELFMaxMessageSize is the default max size of a GELF message, once reassembled and decompressed
+	DefaultGELFMaxMessageSize = 1024 * 1024
+	// DefaultGELFChunkTimeout is the default time the chunks of a GELF message have to arrive
+	DefaultGELFChunkTimeout = 5 * time.Second
 )
 
 var grpcServerFlagsCfg = serverFlagsConfig{
@@ -100,10 +138,26 @@ var tlsZipkinFlagsConfig = tlscfg.ServerFlagsConfig{
 	Prefix: "collector.zipkin",
 }
 
+var tlsSyslogFlagsConfig = tlscfg.ServerFlagsConfig{
+	Prefix: "collector.syslog.tcp",
+}
+
+var tlsFluentFlagsConfig = tlscfg.ServerFlagsConfig{
+	Prefix: "collector.fluent",
+}
+
+var tlsGELFFlagsConfig = tlscfg.ServerFlagsConfig{
+	Prefix: "collector.gelf.tcp",
+}
+
 var corsZipkinFlags = corscfg.Flags{
 	Prefix: "collector.zipkin",
 }
 
+var corsHTTPFlags = corscfg.Flags{
+	Prefix: "collector.http-server",
+}
+
 var corsOTLPFlags = corscfg.Flags{
 	Prefix: "collector.otlp.http",
 }
@@ -137,6 +191,18 @@ type CollectorOptions struct {
 		// KeepAlive configures allow Keep-Alive for Zipkin HTTP server
 		KeepAlive bool
 	}
+	// Syslog section defines options for the syslog receiver
+	Syslog SyslogOptions
+	// Fluent section defines options for the Fluent Forward protocol receiver
+	Fluent FluentOptions
+	// GELF section defines options for the GELF receiver
+	GELF GELFOptions
+	// Loki section defines options for the Loki push API of the HTTP server
+	Loki LokiOptions
+	// Elasticsearch section defines options for the Elasticsearch bulk API of the HTTP server
+	Elasticsearch ElasticsearchOptions
+	// HEC section defines options for the Splunk HTTP Event Collector API of the HTTP server
+	HEC HECOptions
 	// CollectorTags is the string representing collector tags to append to each and every span
 	CollectorTags map[string]string
 	// SpanSizeMetricsEnabled determines whether to enable metrics based on processed span size
@@ -160,6 +226,8 @@ type HTTPOptions struct {
 	ReadHeaderTimeout time.Duration
 	// IdleTimeout sets the respective parameter of http.Server
 	IdleTimeout time.Duration
+	// MaxRequestSize is the maximum size of a request body, once decompressed
+	MaxRequestSize int
 	// CORS allows CORS requests , sets the values for Allowed Headers and Allowed Origins.
 	CORS corscfg.Options
 }
@@ -182,6 +250,81 @@ type GRPCOptions struct {
 	Tenancy tenancy.Options
 }
 
+// SyslogOptions defines options for the syslog receiver
+type SyslogOptions struct {
+	// UDPHostPort is the host:port address the receiver listens on for datagrams, disabled when empty
+	UDPHostPort string
+	// TCPHostPort is the host:port address the receiver listens on for streams, disabled when empty
+	TCPHostPort string
+	// TLS configures secure transport for the TCP endpoint
+	TLS tlscfg.Options
+	// MaxMessageSize is the maximum size of a message, larger ones are dropped
+	MaxMessageSize int
+}
+
+// FluentOptions defines options for the Fluent Forward protocol receiver
+type FluentOptions struct {
+	// HostPort is the host:port address the receiver listens on, disabled when empty
+	HostPort string
+	// TLS configures secure transport for the receiver
+	TLS tlscfg.Options
+	// SharedKey enables the handshake, the clients must authenticate with this key
+	SharedKey string
+	// SelfHostname is the hostname the receiver authenticates with during the handshake
+	SelfHostname string
+	// MaxMessageSize is the maximum size of a message, once decompressed
+	MaxMessageSize int
+}
+
+// GELFOptions defines options for the GELF receiver
+type GELFOptions struct {
+	// UDPHostPort is the host:port address the receiver listens on for datagrams, disabled when empty
+	UDPHostPort string
+	// TCPHostPort is the host:port address the receiver listens on for streams, disabled when empty
+	TCPHostPort string
+	// TLS configures secure transport for the TCP endpoint
+	TLS tlscfg.Options
+	// MaxMessageSize is the maximum size of a message, once reassembled and decompressed
+	MaxMessageSize int
+	// ChunkTimeout is the time the chunks of a message have to arrive, the message is dropped after
+	ChunkTimeout time.Duration
+}
+
+// LokiOptions defines options for the Loki push API
+type LokiOptions struct {
+	// ServiceLabels are the stream labels naming the service, by precedence
+	ServiceLabels []string
+}
+
+// ElasticsearchOptions defines options for the Elasticsearch bulk API. The fields are
+// dotted names, matching nested or flat document fields.
+type ElasticsearchOptions struct {
+	// TimestampField is the document field holding the time of the log
+	TimestampField string
+	// MessageField is the document field holding the log line
+	MessageField string
+	// LevelField is the document field holding the severity of the log
+	LevelField string
+	// ServiceField is the document field naming the service
+	ServiceField string
+}
+
+// HECOptions defines options for the Splunk HTTP Event Collector API
+type HECOptions struct {
+	// Tokens are the tokens the senders authenticate with, the API is disabled when empty
+	Tokens map[string]HECToken
+}
+
+// HECToken is what a Splunk HTTP Event Collector token grants
+type HECToken struct {
+	// Tenant is the tenant the events are written for
+	Tenant string `json:"tenant"`
+	// Service names the service of the events
+	Service string `json:"service"`
+	// Ack requires the senders to name a channel and returns an ack ID per request
+	Ack bool `json:"ack"`
+}
+
 // AddFlags adds flags for CollectorOptions
 func AddFlags(flags *flag.FlagSet) {
 	flags.Int(flagNumWorkers, DefaultNumWorkers, "The number of workers pulling items from the queue")
@@ -191,6 +334,7 @@ func AddFlags(flags *flag.FlagSet) {
 	flags.Bool(flagSpanSizeMetricsEnabled, false, "Enables metrics based on processed span size, which are more expensive to calculate.")
 
 	addHTTPFlags(flags, httpServerFlagsCfg, ports.PortToHostPort(ports.CollectorHTTP))
+	corsHTTPFlags.AddFlags(flags)
 	addGRPCFlags(flags, grpcServerFlagsCfg, ports.PortToHostPort(ports.CollectorGRPC))
 
 	flags.Bool(flagCollectorOTLPEnabled, true, "Enables OpenTelemetry OTLP receiver on dedicated HTTP and gRPC ports")
@@ -203,6 +347,32 @@ func AddFlags(flags *flag.FlagSet) {
 	tlsZipkinFlagsConfig.AddFlags(flags)
 	corsZipkinFlags.AddFlags(flags)
 
+	flags.String(flagSyslogUDPHostPort, "", "The host:port (e.g. 127.0.0.1:514 or :514) of the collector's syslog UDP receiver (disabled by default)")
+	flags.String(flagSyslogTCPHostPort, "", "The host:port (e.g. 127.0.0.1:601 or :601) of the collector's syslog TCP receiver (disabled by default)")
+	flags.Int(flagSyslogMaxMessageSize, DefaultSyslogMaxMessageSize, "The maximum size in bytes of a syslog message")
+	tlsSyslogFlagsConfig.AddFlags(flags)
+
+	flags.String(flagFluentHostPort, "", "The host:port (e.g. 127.0.0.1:24224 or :24224) of the collector's Fluent Forward protocol receiver (disabled by default)")
+	flags.String(flagFluentSharedKey, "", "The shared key the Fluentd or Fluent Bit clients authenticate with (no handshake if empty)")
+	flags.String(flagFluentSelfHostname, "", "The hostname the Fluent Forward protocol receiver authenticates with (the host name if empty)")
+	flags.Int(flagFluentMaxMessageSize, DefaultFluentMaxMessageSize, "The maximum size in bytes of a Fluent Forward protocol message, once decompressed and decoded in memory")
+	tlsFluentFlagsConfig.AddFlags(flags)
+
+	flags.String(flagGELFUDPHostPort, "", "The host:port (e.g. 127.0.0.1:12201 or :12201) of the collector's GELF UDP receiver (disabled by default)")
+	flags.String(flagGELFTCPHostPort, "", "The host:port (e.g. 127.0.0.1:12201 or :12201) of the collector's GELF TCP receiver (disabled by default)")
+	flags.Int(flagGELFMaxMessageSize, DefaultGELFMaxMessageSize, "The maximum size in bytes of a GELF message, once reassembled and decompressed")
+	flags.Duration(flagGELFChunkTimeout, DefaultGELFChunkTimeout, "The time the chunks of a GELF UDP message have to arrive before the message is dropped")
+	tlsGELFFlagsConfig.AddFlags(flags)
+
+	flags.String(flagLokiServiceLabels, "service_name,job", "Comma-separated stream labels of the Loki push API whose value names the service, by precedence")
+
+	flags.String(flagElasticsearchTimestampField, "@timestamp", "The document field of the Elasticsearch bulk API holding the time of the log")
+	flags.String(flagElasticsearchMessageField, "message", "The document field of the Elasticsearch bulk API holding the log line")
+	flags.String(flagElasticsearchLevelField, "log.level", "The document field of the Elasticsearch bulk API holding the severity of the log")
+	flags.String(flagElasticsearchServiceField, "service.name", "The document field of the Elasticsearch bulk API naming the service")
+
+	flags.String(flagHECTokensFile, "", `Path to a JSON file of the Splunk HTTP Event Collector tokens, e.g. {"<token>": {"tenant": "t1", "service": "app", "ack": true}} (the API is disabled if empty)`)
+
 	tenancy.AddFlags(flags)
 }
 
@@ -211,6 +381,7 @@ func addHTTPFlags(flags *flag.FlagSet, cfg serverFlagsConfig, defaultHostPort st
 	flags.Duration(cfg.prefix+"."+flagSuffixHTTPIdleTimeout, 0, "See https://pkg.go.dev/net/http#Server")
 	flags.Duration(cfg.prefix+"."+flagSuffixHTTPReadTimeout, 0, "See https://pkg.go.dev/net/http#Server")
 	flags.Duration(cfg.prefix+"."+flagSuffixHTTPReadHeaderTimeout, 2*time.Second, "See https://pkg.go.dev/net/http#Server")
+	flags.Int(cfg.prefix+"."+flagSuffixHTTPMaxRequestSize, DefaultHTTPMaxRequestSize, "The maximum size in bytes of a request body of the collector's HTTP server, once decompressed")
 	cfg.tls.AddFlags(flags)
 }
 
@@ -239,6 +410,7 @@ func (opts *HTTPOptions) initFromViper(v *viper.Viper, logger *zap.Logger, cfg s
 	opts.IdleTimeout = v.GetDuration(cfg.prefix + "." + flagSuffixHTTPIdleTimeout)
 	opts.ReadTimeout = v.GetDuration(cfg.prefix + "." + flagSuffixHTTPReadTimeout)
 	opts.ReadHeaderTimeout = v.GetDuration(cfg.prefix + "." + flagSuffixHTTPReadHeaderTimeout)
+	opts.MaxRequestSize = v.GetInt(cfg.prefix + "." + flagSuffixHTTPMaxRequestSize)
 	if tlsOpts, err := cfg.tls.InitFromViper(v); err == nil {
 		opts.TLS = tlsOpts
 	} else {
@@ -273,6 +445,7 @@ func (cOpts *CollectorOptions) InitFromViper(v *viper.Viper, logger *zap.Logger)
 	if err := cOpts.HTTP.initFromViper(v, logger, httpServerFlagsCfg); err != nil {
 		return cOpts, fmt.Errorf("failed to parse HTTP server options: %w", err)
 	}
+	cOpts.HTTP.CORS = corsHTTPFlags.InitFromViper(v)
 
 	if err := cOpts.GRPC.initFromViper(v, logger, grpcServerFlagsCfg); err != nil {
 		return cOpts, fmt.Errorf("failed to parse gRPC server options: %w", err)
@@ -296,5 +469,77 @@ func (cOpts *CollectorOptions) InitFromViper(v *viper.Viper, logger *zap.Logger)
 	}
 	cOpts.Zipkin.CORS = corsZipkinFlags.InitFromViper(v)
 
+	cOpts.Syslog.UDPHostPort = ports.FormatHostPort(v.GetString(flagSyslogUDPHostPort))
+	cOpts.Syslog.TCPHostPort = ports.FormatHostPort(v.GetString(flagSyslogTCPHostPort))
+	cOpts.Syslog.MaxMessageSize = v.GetInt(flagSyslogMaxMessageSize)
+	if tlsSyslog, err := tlsSyslogFlagsConfig.InitFromViper(v); err == nil {
+		cOpts.Syslog.TLS = tlsSyslog
+	} else {
+		return cOpts, fmt.Errorf("failed to parse syslog TLS options: %w", err)
+	}
+
+	cOpts.Fluent.HostPort = ports.FormatHostPort(v.GetString(flagFluentHostPort))
+	cOpts.Fluent.SharedKey = v.GetString(flagFluentSharedKey)
+	cOpts.Fluent.SelfHostname = v.GetString(flagFluentSelfHostname)
+	cOpts.Fluent.MaxMessageSize = v.GetInt(flagFluentMaxMessageSize)
+	if tlsFluent, err := tlsFluentFlagsConfig.InitFromViper(v); err == nil {
+		cOpts.Fluent.TLS = tlsFluent
+	} else {
+		return cOpts, fmt.Errorf("failed to parse Fluent TLS options: %w", err)
+	}
+
+	cOpts.GELF.UDPHostPort = ports.FormatHostPort(v.GetString(flagGELFUDPHostPort))
+	cOpts.GELF.TCPHostPort = ports.FormatHostPort(v.GetString(flagGELFTCPHostPort))
+	cOpts.GELF.MaxMessageSize = v.GetInt(flagGELFMaxMessageSize)
+	cOpts.GELF.ChunkTimeout = v.GetDuration(flagGELFChunkTimeout)
+	if cOpts.GELF.ChunkTimeout <= 0 {
+		return cOpts, fmt.Errorf("%s must be positive, got %v", flagGELFChunkTimeout, cOpts.GELF.ChunkTimeout)
+	}
+	if tlsGELF, err := tlsGELFFlagsConfig.InitFromViper(v); err == nil {
+		cOpts.GELF.TLS = tlsGELF
+	} else {
+		return cOpts, fmt.Errorf("failed to parse GELF TLS options: %w", err)
+	}
+
+	cOpts.Loki.ServiceLabels = parseList(v.GetString(flagLokiServiceLabels))
+
+	cOpts.Elasticsearch.TimestampField = v.GetString(flagElasticsearchTimestampField)
+	cOpts.Elasticsearch.MessageField = v.GetString(flagElasticsearchMessageField)
+	cOpts.Elasticsearch.LevelField = v.GetString(flagElasticsearchLevelField)
+	cOpts.Elasticsearch.ServiceField = v.GetString(flagElasticsearchServiceField)
+
+	if tokens, err := loadHECTokens(v.GetString(flagHECTokensFile)); err == nil {
+		cOpts.HEC.Tokens = tokens
+	} else {
+		return cOpts, fmt.Errorf("failed to load HEC tokens: %w", err)
+	}
+
 	return cOpts, nil
 }
+
+// parseList splits a comma-separated flag value, dropping the empty items.
+func parseList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// loadHECTokens reads the JSON object of the tokens file, mapping each token to what it grants.
+func loadHECTokens(path string) (map[string]HECToken, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, err
+	}
+	var tokens map[string]HECToken
+	if err := json.Unmarshal(data, &tokens); err != nil {
+		return nil, fmt.Errorf("cannot parse %s: %w", path, err)
+	}
+	return tokens, nil
+}
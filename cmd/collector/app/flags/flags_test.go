@@ -15,6 +15,8 @@
 package flags
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -190,6 +192,114 @@ func TestCollectorOptionsWithFlags_CheckZipkinKeepAlive(t *testing.T) {
 	assert.False(t, c.Zipkin.KeepAlive)
 }
 
+func TestCollectorOptionsWithFlags_CheckSyslog(t *testing.T) {
+	c := &CollectorOptions{}
+	v, command := config.Viperize(AddFlags)
+	command.ParseFlags([]string{
+		"--collector.syslog.udp.host-port=5514",
+		"--collector.syslog.tcp.host-port=127.0.0.1:5514",
+		"--collector.syslog.max-message-size=1024",
+	})
+	_, err := c.InitFromViper(v, zap.NewNop())
+	require.NoError(t, err)
+
+	assert.Equal(t, ":5514", c.Syslog.UDPHostPort)
+	assert.Equal(t, "127.0.0.1:5514", c.Syslog.TCPHostPort)
+	assert.Equal(t, 1024, c.Syslog.MaxMessageSize)
+	assert.False(t, c.Syslog.TLS.Enabled)
+}
+
+func TestCollectorOptionsWithFlags_CheckFluent(t *testing.T) {
+	c := &CollectorOptions{}
+	v, command := config.Viperize(AddFlags)
+	command.ParseFlags([]string{
+		"--collector.fluent.host-port=24224",
+		"--collector.fluent.shared-key=secret",
+		"--collector.fluent.self-hostname=collector-1",
+	})
+	_, err := c.InitFromViper(v, zap.NewNop())
+	require.NoError(t, err)
+
+	assert.Equal(t, ":24224", c.Fluent.HostPort)
+	assert.Equal(t, "secret", c.Fluent.SharedKey)
+	assert.Equal(t, "collector-1", c.Fluent.SelfHostname)
+	assert.Equal(t, DefaultFluentMaxMessageSize, c.Fluent.MaxMessageSize)
+}
+
+func TestCollectorOptionsWithFlags_CheckLoki(t *testing.T) {
+	c := &CollectorOptions{}
+	v, command := config.Viperize(AddFlags)
+	command.ParseFlags([]string{})
+	_, err := c.InitFromViper(v, zap.NewNop())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"service_name", "job"}, c.Loki.ServiceLabels)
+
+	command.ParseFlags([]string{"--collector.loki.service-labels=app, ,container"})
+	_, err = c.InitFromViper(v, zap.NewNop())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"app", "container"}, c.Loki.ServiceLabels)
+}
+
+func TestCollectorOptionsWithFlags_CheckElasticsearch(t *testing.T) {
+	c := &CollectorOptions{}
+	v, command := config.Viperize(AddFlags)
+	command.ParseFlags([]string{"--collector.elasticsearch.message-field=msg"})
+	_, err := c.InitFromViper(v, zap.NewNop())
+	require.NoError(t, err)
+	assert.Equal(t, ElasticsearchOptions{
+		TimestampField: "@timestamp",
+		MessageField:   "msg",
+		LevelField:     "log.level",
+		ServiceField:   "service.name",
+	}, c.Elasticsearch)
+}
+
+func TestCollectorOptionsWithFlags_CheckHEC(t *testing.T) {
+	c := &CollectorOptions{}
+	v, command := config.Viperize(AddFlags)
+	command.ParseFlags([]string{})
+	_, err := c.InitFromViper(v, zap.NewNop())
+	require.NoError(t, err)
+	assert.Empty(t, c.HEC.Tokens)
+
+	path := filepath.Join(t.TempDir(), "tokens.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"abc":{"tenant":"t1","service":"app","ack":true},"def":{}}`), 0o600))
+	command.ParseFlags([]string{"--collector.hec.tokens-file=" + path})
+	_, err = c.InitFromViper(v, zap.NewNop())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]HECToken{
+		"abc": {Tenant: "t1", Service: "app", Ack: true},
+		"def": {},
+	}, c.HEC.Tokens)
+
+	require.NoError(t, os.WriteFile(path, []byte(`["abc"]`), 0o600))
+	_, err = c.InitFromViper(v, zap.NewNop())
+	require.ErrorContains(t, err, "failed to load HEC tokens")
+}
+
+func TestCollectorOptionsWithFlags_CheckGELF(t *testing.T) {
+	c := &CollectorOptions{}
+	v, command := config.Viperize(AddFlags)
+	command.ParseFlags([]string{
+		"--collector.gelf.udp.host-port=12201",
+		"--collector.gelf.chunk-timeout=1s",
+	})
+	_, err := c.InitFromViper(v, zap.NewNop())
+	require.NoError(t, err)
+
+	assert.Equal(t, ":12201", c.GELF.UDPHostPort)
+	assert.Empty(t, c.GELF.TCPHostPort)
+	assert.Equal(t, DefaultGELFMaxMessageSize, c.GELF.MaxMessageSize)
+	assert.Equal(t, time.Second, c.GELF.ChunkTimeout)
+
+	for _, timeout := range []string{"0s", "-1s"} {
+		v, command := config.Viperize(AddFlags)
+		command.ParseFlags([]string{"--collector.gelf.chunk-timeout=" + timeout})
+		_, err := c.InitFromViper(v, zap.NewNop())
+		require.ErrorContains(t, err, "collector.gelf.chunk-timeout must be positive", timeout)
+	}
+}
+
 func TestMain(m *testing.M) {
 	testutils.VerifyGoLeaks(m)
 }
@@ -0,0 +1,219 @@
+package handler
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/savsgio/atreugo/v11"
+	"go.uber.org/zap"
+
+	"logger/cmd/collector/app/processor"
+	"logger/model"
+	"logger/model/converter/elasticsearch"
+)
+
+const (
+	// elasticsearchVersion is the version reported to the shippers, which check it to pick their request format
+	elasticsearchVersion = "8.11.0"
+	// elasticsearchClusterName is the cluster name reported to the shippers
+	elasticsearchClusterName = "logger"
+)
+
+// ElasticsearchHandler implements enough of the Elasticsearch API for Filebeat, Logstash
+// and Vector to ship documents to the collector: the bulk API and the endpoints they probe.
+type ElasticsearchHandler struct {
+	logger         *zap.Logger
+	modelProcessor processor.LogProcessor
+	mapping        elasticsearch.Mapping
+	// maxRequestSize caps the size of a decompressed request body, zero disables it
+	maxRequestSize int
+	now            func() time.Time
+}
+
+// NewElasticsearchHandler returns an ElasticsearchHandler submitting the documents to
+// modelProcessor, with their fields mapped to the log records by mapping.
+func NewElasticsearchHandler(logger *zap.Logger, modelProcessor processor.LogProcessor, mapping elasticsearch.Mapping, maxRequestSize int) *ElasticsearchHandler {
+	return &ElasticsearchHandler{
+		logger:         logger,
+		modelProcessor: modelProcessor,
+		mapping:        mapping,
+		maxRequestSize: maxRequestSize,
+		now:            time.Now,
+	}
+}
+
+func (h *ElasticsearchHandler) RegisterRoutes(router *atreugo.Atreugo) {
+	router.GET("/", h.Info)
+	router.HEAD("/", h.Info)
+	router.GET("/_license", h.License)
+	router.POST("/_bulk", h.Bulk)
+	router.PUT("/_bulk", h.Bulk)
+	router.POST("/{index}/_bulk", h.Bulk)
+	router.PUT("/{index}/_bulk", h.Bulk)
+}
+
+// Info answers the cluster information request the shippers send to check the version.
+func (h *ElasticsearchHandler) Info(c *atreugo.RequestCtx) error {
+	hostname, _ := os.Hostname()
+	return elasticsearchResponse(c, http.StatusOK, map[string]interface{}{
+		"name":         hostname,
+		"cluster_name": elasticsearchClusterName,
+		"cluster_uuid": "_na_",
+		"version": map[string]interface{}{
+			"number":                              elasticsearchVersion,
+			"build_flavor":                        "default",
+			"build_type":                          "docker",
+			"build_snapshot":                      false,
+			"lucene_version":                      "9.8.0",
+			"minimum_wire_compatibility_version":  "7.17.0",
+			"minimum_index_compatibility_version": "7.0.0",
+		},
+		"tagline": "You Know, for Search",
+	})
+}
+
+// License answers the license request Filebeat sends before using the cluster.
+func (h *ElasticsearchHandler) License(c *atreugo.RequestCtx) error {
+	return elasticsearchResponse(c, http.StatusOK, map[string]interface{}{
+		"license": map[string]interface{}{
+			"status": "active",
+			"uid":    "00000000-0000-0000-0000-000000000000",
+			"type":   "basic",
+			"mode":   "basic",
+		},
+	})
+}
+
+// bulkResponse is the response to a bulk request, with an item per action in the request order.
+type bulkResponse struct {
+	Took   int64                       `json:"took"`
+	Errors bool                        `json:"errors"`
+	Items  []map[string]bulkItemResult `json:"items"`
+}
+
+type bulkItemResult struct {
+	Index   string              `json:"_index"`
+	ID      string              `json:"_id"`
+	Version int                 `json:"_version,omitempty"`
+	Result  string              `json:"result,omitempty"`
+	Status  int                 `json:"status"`
+	Error   *elasticsearchError `json:"error,omitempty"`
+}
+
+type elasticsearchError struct {
+	Type   string `json:"type"`
+	Reason string `json:"reason"`
+}
+
+// Bulk ingests the documents of the index and create actions of a bulk request. The
+// response reports the status of each action, 429 for those the collector queue rejected
+// so that the shippers retry them only.
+func (h *ElasticsearchHandler) Bulk(c *atreugo.RequestCtx) error {
+	start := h.now()
+	body, err := decompress(string(c.Request.Header.Peek("Content-Encoding")), c.PostBody(), h.maxRequestSize)
+	switch {
+	case errors.Is(err, errBodyTooLarge):
+		return elasticsearchErrorResponse(c, http.StatusRequestEntityTooLarge, "content_too_long_exception", err)
+	case err != nil:
+		return elasticsearchErrorResponse(c, http.StatusBadRequest, "parse_exception", err)
+	}
+	index, _ := c.UserValue("index").(string)
+	items, err := elasticsearch.ParseBulk(body, index)
+	if err != nil {
+		return elasticsearchErrorResponse(c, http.StatusBadRequest, "illegal_argument_exception", err)
+	}
+
+	results := make([]bulkItemResult, len(items))
+	var logs []*model.LogRecord
+	var logItems []int
+	for i, item := range items {
+		results[i] = bulkItemResult{Index: item.Index, ID: item.ID}
+		if item.Err != nil {
+			results[i].Status = http.StatusBadRequest
+			results[i].Error = itemError(item.Err)
+			continue
+		}
+		if results[i].ID == "" {
+			results[i].ID = newDocumentID()
+		}
+		logs = append(logs, elasticsearch.ToDomainLog(item.Document, item.Index, h.mapping, start))
+		logItems = append(logItems, i)
+	}
+
+	if len(logs) > 0 {
+		oks, err := h.modelProcessor.ProcessLogs(logs, processor.LogOptions{
+			InboundTransport: processor.HTTPTransport,
+			LogFormat:        processor.ElasticsearchLogFormat,
+		})
+		if err != nil {
+			c.Response.Header.Set("Retry-After", strconv.Itoa(int(busyRetryAfter.Seconds())))
+			if errors.Is(err, processor.ErrBusy) {
+				return elasticsearchErrorResponse(c, http.StatusTooManyRequests, "es_rejected_execution_exception", err)
+			}
+			return elasticsearchErrorResponse(c, http.StatusServiceUnavailable, "unavailable_shards_exception", err)
+		}
+		for j, i := range logItems {
+			if j < len(oks) && !oks[j] {
+				results[i].Status = http.StatusTooManyRequests
+				results[i].Error = &elasticsearchError{Type: "es_rejected_execution_exception", Reason: processor.ErrBusy.Error()}
+				continue
+			}
+			results[i].Version = 1
+			results[i].Result = "created"
+			results[i].Status = http.StatusCreated
+		}
+	}
+
+	res := bulkResponse{Took: h.now().Sub(start).Milliseconds(), Items: make([]map[string]bulkItemResult, len(items))}
+	for i, item := range items {
+		res.Items[i] = map[string]bulkItemResult{item.Action: results[i]}
+		res.Errors = res.Errors || results[i].Error != nil
+	}
+	return elasticsearchResponse(c, http.StatusOK, res)
+}
+
+func itemError(err error) *elasticsearchError {
+	errType := "illegal_argument_exception"
+	if errors.Is(err, elasticsearch.ErrInvalidDocument) {
+		errType = "document_parsing_exception"
+	}
+	return &elasticsearchError{Type: errType, Reason: err.Error()}
+}
+
+// newDocumentID returns a random ID of the length and alphabet of the IDs Elasticsearch generates.
+func newDocumentID() string {
+	var id [15]byte
+	_, _ = rand.Read(id[:])
+	return base64.RawURLEncoding.EncodeToString(id[:])
+}
+
+func elasticsearchErrorResponse(c *atreugo.RequestCtx, statusCode int, errType string, err error) error {
+	return elasticsearchResponse(c, statusCode, map[string]interface{}{
+		"error":  elasticsearchError{Type: errType, Reason: err.Error()},
+		"status": statusCode,
+	})
+}
+
+// elasticsearchResponse writes v as JSON, with the header the Elasticsearch clients check
+// to tell they talk to Elasticsearch.
+func elasticsearchResponse(c *atreugo.RequestCtx, statusCode int, v interface{}) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("cannot marshal Elasticsearch response: %w", err)
+	}
+	c.Response.Header.Set("X-Elastic-Product", "Elasticsearch")
+	c.Response.Header.SetContentType("application/json")
+	c.SetStatusCode(statusCode)
+	if c.IsHead() {
+		return nil
+	}
+	c.SetBody(body)
+	return nil
+}
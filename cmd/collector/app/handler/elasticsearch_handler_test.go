@@ -0,0 +1,139 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/savsgio/atreugo/v11"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valyala/fasthttp"
+	"go.uber.org/zap"
+
+	"logger/cmd/collector/app/processor"
+	"logger/model/converter/elasticsearch"
+)
+
+var testMapping = elasticsearch.Mapping{
+	TimestampField: "@timestamp",
+	MessageField:   "message",
+	LevelField:     "log.level",
+	ServiceField:   "service.name",
+}
+
+func bulk(t *testing.T, h *ElasticsearchHandler, index string, body string) (*fasthttp.Response, map[string]interface{}) {
+	var fctx fasthttp.RequestCtx
+	fctx.Request.Header.SetMethod(http.MethodPost)
+	fctx.Request.Header.SetContentType("application/x-ndjson")
+	fctx.Request.SetBodyString(body)
+	if index != "" {
+		fctx.SetUserValue("index", index)
+	}
+	ctx := atreugo.AcquireRequestCtx(&fctx)
+	defer atreugo.ReleaseRequestCtx(ctx)
+	require.NoError(t, h.Bulk(ctx))
+	res := &fasthttp.Response{}
+	fctx.Response.CopyTo(res)
+	assert.Equal(t, "Elasticsearch", string(res.Header.Peek("X-Elastic-Product")))
+	var doc map[string]interface{}
+	require.NoError(t, json.Unmarshal(res.Body(), &doc))
+	return res, doc
+}
+
+func TestElasticsearchBulk(t *testing.T) {
+	p := &fakeLogProcessor{}
+	h := NewElasticsearchHandler(zap.NewNop(), p, testMapping, 0)
+	res, doc := bulk(t, h, "logs", strings.Join([]string{
+		`{"index":{"_id":"1"}}`,
+		`{"@timestamp":"2024-03-10T12:00:00Z","message":"hello","log":{"level":"error"},"service":{"name":"checkout"}}`,
+		`{"create":{"_index":"other"}}`,
+		`{"message":"world"}`,
+		`{"delete":{"_id":"1"}}`,
+		`{"index":{}}`,
+		`not json`,
+	}, "\n")+"\n")
+
+	assert.Equal(t, http.StatusOK, res.StatusCode())
+	assert.Equal(t, true, doc["errors"])
+	items := doc["items"].([]interface{})
+	require.Len(t, items, 4)
+	assert.Equal(t, map[string]interface{}{
+		"index": map[string]interface{}{"_index": "logs", "_id": "1", "_version": 1.0, "result": "created", "status": 201.0},
+	}, items[0])
+	created := items[1].(map[string]interface{})["create"].(map[string]interface{})
+	assert.Equal(t, "other", created["_index"])
+	assert.Len(t, created["_id"], 20)
+	assert.Equal(t, 201.0, created["status"])
+	assert.Equal(t, 400.0, items[2].(map[string]interface{})["delete"].(map[string]interface{})["status"])
+	failed := items[3].(map[string]interface{})["index"].(map[string]interface{})
+	assert.Equal(t, 400.0, failed["status"])
+	assert.Equal(t, "document_parsing_exception", failed["error"].(map[string]interface{})["type"])
+
+	require.Len(t, p.logs, 2)
+	assert.Equal(t, "hello", p.logs[0].Body)
+	assert.Equal(t, "checkout", p.logs[0].Process.ServiceName)
+	assert.Equal(t, "error", p.logs[0].SeverityText)
+	assert.Equal(t, processor.ElasticsearchLogFormat, p.opts.LogFormat)
+}
+
+func TestElasticsearchBulkRejected(t *testing.T) {
+	h := NewElasticsearchHandler(zap.NewNop(), &fakeLogProcessor{reject: true}, testMapping, 0)
+	res, doc := bulk(t, h, "logs", "{\"index\":{}}\n{\"message\":\"hello\"}\n")
+	assert.Equal(t, http.StatusOK, res.StatusCode())
+	assert.Equal(t, true, doc["errors"])
+	item := doc["items"].([]interface{})[0].(map[string]interface{})["index"].(map[string]interface{})
+	assert.Equal(t, 429.0, item["status"])
+	assert.Equal(t, "es_rejected_execution_exception", item["error"].(map[string]interface{})["type"])
+}
+
+func TestElasticsearchBulkErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		processor *fakeLogProcessor
+		body      string
+		status    int
+	}{
+		{"malformed action", &fakeLogProcessor{}, "{\"index\":\n", http.StatusBadRequest},
+		{"too large", &fakeLogProcessor{}, "{\"index\":{}}\n{\"message\":\"" + strings.Repeat("x", 64) + "\"}\n", http.StatusRequestEntityTooLarge},
+		{"busy", &fakeLogProcessor{err: processor.ErrBusy}, "{\"index\":{}}\n{\"message\":\"hello\"}\n", http.StatusTooManyRequests},
+		{"failed", &fakeLogProcessor{err: errors.New("boom")}, "{\"index\":{}}\n{\"message\":\"hello\"}\n", http.StatusServiceUnavailable},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			res, doc := bulk(t, NewElasticsearchHandler(zap.NewNop(), test.processor, testMapping, 64), "", test.body)
+			assert.Equal(t, test.status, res.StatusCode())
+			assert.Equal(t, float64(test.status), doc["status"])
+			assert.NotEmpty(t, doc["error"])
+		})
+	}
+}
+
+func TestElasticsearchHandshake(t *testing.T) {
+	h := NewElasticsearchHandler(zap.NewNop(), &fakeLogProcessor{}, testMapping, 0)
+	for _, view := range []atreugo.View{h.Info, h.License} {
+		var fctx fasthttp.RequestCtx
+		fctx.Request.Header.SetMethod(http.MethodGet)
+		ctx := atreugo.AcquireRequestCtx(&fctx)
+		require.NoError(t, view(ctx))
+		atreugo.ReleaseRequestCtx(ctx)
+		assert.Equal(t, http.StatusOK, fctx.Response.StatusCode())
+		assert.Equal(t, "Elasticsearch", string(fctx.Response.Header.Peek("X-Elastic-Product")))
+		assert.True(t, json.Valid(fctx.Response.Body()))
+	}
+
+	var fctx fasthttp.RequestCtx
+	fctx.Request.Header.SetMethod(http.MethodGet)
+	ctx := atreugo.AcquireRequestCtx(&fctx)
+	defer atreugo.ReleaseRequestCtx(ctx)
+	require.NoError(t, h.Info(ctx))
+	var info struct {
+		Version struct {
+			Number string `json:"number"`
+		} `json:"version"`
+	}
+	require.NoError(t, json.Unmarshal(fctx.Response.Body(), &info))
+	assert.Equal(t, elasticsearchVersion, info.Version.Number)
+}
@@ -0,0 +1,47 @@
+package handler
+
+import (
+	"time"
+
+	"go.uber.org/zap"
+
+	"logger/cmd/collector/app/processor"
+	"logger/model/converter/fluent"
+)
+
+// FluentHandler submits the events of Fluent Forward protocol messages to the log processor.
+type FluentHandler struct {
+	logger         *zap.Logger
+	modelProcessor processor.LogProcessor
+	now            func() time.Time
+}
+
+// NewFluentHandler returns a FluentHandler submitting the events to modelProcessor.
+func NewFluentHandler(logger *zap.Logger, modelProcessor processor.LogProcessor) *FluentHandler {
+	return &FluentHandler{
+		logger:         logger,
+		modelProcessor: modelProcessor,
+		now:            time.Now,
+	}
+}
+
+// HandleMessage processes the events of a message received over transport. It returns
+// processor.ErrBusy when some events were rejected, so that the chunk is not acknowledged.
+func (h *FluentHandler) HandleMessage(m *fluent.Message, transport processor.InboundTransport) error {
+	if len(m.Entries) == 0 {
+		return nil
+	}
+	oks, err := h.modelProcessor.ProcessLogs(fluent.ToDomainLogs(m, h.now()), processor.LogOptions{
+		InboundTransport: transport,
+		LogFormat:        processor.FluentLogFormat,
+	})
+	if err != nil {
+		return err
+	}
+	for _, ok := range oks {
+		if !ok {
+			return processor.ErrBusy
+		}
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package handler
+
+import (
+	"time"
+
+	"go.uber.org/zap"
+
+	"logger/cmd/collector/app/processor"
+	"logger/model"
+	"logger/model/converter/gelf"
+)
+
+// GELFHandler parses GELF messages and submits them to the log processor.
+type GELFHandler struct {
+	logger         *zap.Logger
+	modelProcessor processor.LogProcessor
+	maxSize        int
+	now            func() time.Time
+}
+
+// NewGELFHandler returns a GELFHandler submitting the messages to modelProcessor. Messages
+// larger than maxSize once decompressed are dropped.
+func NewGELFHandler(logger *zap.Logger, modelProcessor processor.LogProcessor, maxSize int) *GELFHandler {
+	return &GELFHandler{
+		logger:         logger,
+		modelProcessor: modelProcessor,
+		maxSize:        maxSize,
+		now:            time.Now,
+	}
+}
+
+// HandleMessage decompresses and parses a message received over transport and processes
+// it. It returns processor.ErrBusy when the collector queue is full.
+func (h *GELFHandler) HandleMessage(data []byte, transport processor.InboundTransport) error {
+	payload, err := gelf.Decompress(data, h.maxSize)
+	if err != nil {
+		return err
+	}
+	m, err := gelf.Parse(payload)
+	if err != nil {
+		return err
+	}
+	oks, err := h.modelProcessor.ProcessLogs([]*model.LogRecord{gelf.ToDomainLog(m, h.now())}, processor.LogOptions{
+		InboundTransport: transport,
+		LogFormat:        processor.GELFLogFormat,
+	})
+	if err != nil {
+		return err
+	}
+	if len(oks) > 0 && !oks[0] {
+		return processor.ErrBusy
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package handler
+
+import (
+	"context"
+	"errors"
+
+	"go.uber.org/zap"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	"logger/cmd/collector/app/processor"
+	pb "logger/model/proto/v1"
+	"logger/pkg/tenancy"
+)
+
+// GRPCHandler implements the OTLP LogsService, feeding the exported logs to a BatchesHandler.
+type GRPCHandler struct {
+	pb.UnimplementedLogsServiceServer
+	logger         *zap.Logger
+	batchesHandler BatchesHandler
+}
+
+// NewGRPCHandler returns a GRPCHandler submitting the logs to batchesHandler.
+func NewGRPCHandler(logger *zap.Logger, batchesHandler BatchesHandler) *GRPCHandler {
+	return &GRPCHandler{
+		logger:         logger,
+		batchesHandler: batchesHandler,
+	}
+}
+
+// Export implements LogsServiceServer#Export. The tenant is the one the tenancy
+// interceptor attached to ctx from the gRPC metadata.
+func (g *GRPCHandler) Export(ctx context.Context, r *pb.ExportLogsServiceRequest) (*pb.ExportLogsServiceResponse, error) {
+	responses, err := g.batchesHandler.SubmitBatches(r.GetResourceLogs(), SubmitBatchOptions{
+		InboundTransport: processor.GRPCTransport,
+		Tenant:           tenancy.GetTenant(ctx),
+	})
+	if err != nil {
+		return nil, g.exportError(err)
+	}
+	return exportResponse(responses), nil
+}
+
+// exportError maps a processing error to a status the OTLP exporters retry: ResourceExhausted
+// with a retry delay when the collector is busy, Unavailable otherwise.
+func (g *GRPCHandler) exportError(err error) error {
+	if !errors.Is(err, processor.ErrBusy) {
+		g.logger.Error("cannot process logs", zap.Error(err))
+		return status.Error(codes.Unavailable, err.Error())
+	}
+	st, detailsErr := status.New(codes.ResourceExhausted, err.Error()).WithDetails(&errdetails.RetryInfo{
+		RetryDelay: durationpb.New(busyRetryAfter),
+	})
+	if detailsErr != nil {
+		return status.Error(codes.ResourceExhausted, err.Error())
+	}
+	return st.Err()
+}
@@ -0,0 +1,358 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/savsgio/atreugo/v11"
+	"go.uber.org/zap"
+
+	"logger/cmd/collector/app/flags"
+	"logger/cmd/collector/app/processor"
+	"logger/model"
+	"logger/model/converter/splunk"
+)
+
+const (
+	// hecAuthScheme is the scheme of the Authorization header carrying a HEC token
+	hecAuthScheme = "Splunk "
+	// hecChannelHeader names the channel of the requests whose acks the sender polls
+	hecChannelHeader = "X-Splunk-Request-Channel"
+	// maxAckChannels bounds the memory used to track the acks of the channels
+	maxAckChannels = 10000
+	// maxPendingAcks bounds the acks a channel keeps until they are polled, the oldest are forgotten
+	maxPendingAcks = 10000
+	// ackChannelIdleTimeout is how long the acks of a channel are kept once it is no longer used
+	ackChannelIdleTimeout = 10 * time.Minute
+)
+
+// hecResponse is the body of the HEC responses, the code is the HEC status of the request.
+type hecResponse struct {
+	Text               string  `json:"text"`
+	Code               int     `json:"code"`
+	AckID              *uint64 `json:"ackId,omitempty"`
+	InvalidEventNumber *int    `json:"invalid-event-number,omitempty"`
+}
+
+// HEC statuses, as documented for Splunk HTTP Event Collector.
+var (
+	hecSuccess        = hecStatus{http.StatusOK, "Success", 0}
+	hecTokenRequired  = hecStatus{http.StatusUnauthorized, "Token is required", 2}
+	hecInvalidAuth    = hecStatus{http.StatusUnauthorized, "Invalid authorization", 3}
+	hecInvalidToken   = hecStatus{http.StatusForbidden, "Invalid token", 4}
+	hecNoData         = hecStatus{http.StatusBadRequest, "No data", 5}
+	hecInvalidFormat  = hecStatus{http.StatusBadRequest, "Invalid data format", 6}
+	hecTooLarge       = hecStatus{http.StatusRequestEntityTooLarge, "Content too large", 6}
+	hecBusy           = hecStatus{http.StatusServiceUnavailable, "Server is busy", 9}
+	hecChannelMissing = hecStatus{http.StatusBadRequest, "Data channel is missing", 10}
+	hecEventRequired  = hecStatus{http.StatusBadRequest, "Event field is required", 12}
+	hecEventBlank     = hecStatus{http.StatusBadRequest, "Event field cannot be blank", 13}
+	hecAckDisabled    = hecStatus{http.StatusBadRequest, "ACK is disabled", 14}
+	hecHealthy        = hecStatus{http.StatusOK, "HEC is healthy", 17}
+)
+
+type hecStatus struct {
+	statusCode int
+	text       string
+	code       int
+}
+
+// HECHandler implements the Splunk HTTP Event Collector API, so that Splunk forwarders and
+// logging libraries can send to the collector. Each token names the tenant and the service
+// of the events sent with it.
+type HECHandler struct {
+	logger         *zap.Logger
+	modelProcessor processor.LogProcessor
+	tokens         map[string]flags.HECToken
+	// maxRequestSize caps the size of a decompressed request body, zero disables it
+	maxRequestSize int
+	acks           *hecAcks
+	now            func() time.Time
+}
+
+// NewHECHandler returns a HECHandler submitting the events sent with one of tokens to modelProcessor.
+func NewHECHandler(logger *zap.Logger, modelProcessor processor.LogProcessor, tokens map[string]flags.HECToken, maxRequestSize int) *HECHandler {
+	return &HECHandler{
+		logger:         logger,
+		modelProcessor: modelProcessor,
+		tokens:         tokens,
+		maxRequestSize: maxRequestSize,
+		acks:           newHECAcks(),
+		now:            time.Now,
+	}
+}
+
+func (h *HECHandler) RegisterRoutes(router *atreugo.Atreugo) {
+	for _, path := range []string{"/services/collector", "/services/collector/event", "/services/collector/event/1.0"} {
+		router.POST(path, h.Event)
+	}
+	for _, path := range []string{"/services/collector/raw", "/services/collector/raw/1.0"} {
+		router.POST(path, h.Raw)
+	}
+	router.POST("/services/collector/ack", h.Ack)
+	router.GET("/services/collector/health", h.Health)
+	router.GET("/services/collector/health/1.0", h.Health)
+}
+
+// Event accepts JSON events, concatenated in the request body.
+func (h *HECHandler) Event(c *atreugo.RequestCtx) error {
+	return h.ingest(c, splunk.ParseEvents)
+}
+
+// Raw accepts a text body, each line of which is an event described by the query string.
+func (h *HECHandler) Raw(c *atreugo.RequestCtx) error {
+	return h.ingest(c, func(data []byte, metadata splunk.Metadata) ([]splunk.Event, error) {
+		return splunk.ParseRaw(data, metadata), nil
+	})
+}
+
+func (h *HECHandler) ingest(c *atreugo.RequestCtx, parse func([]byte, splunk.Metadata) ([]splunk.Event, error)) error {
+	key, token, authErr := h.authenticate(c)
+	if authErr != nil {
+		return hecReply(c, *authErr, hecResponse{})
+	}
+	channel := ackChannel{token: key, name: hecChannel(c)}
+	if token.Ack && channel.name == "" {
+		return hecReply(c, hecChannelMissing, hecResponse{})
+	}
+	body, err := decompress(string(c.Request.Header.Peek("Content-Encoding")), c.PostBody(), h.maxRequestSize)
+	switch {
+	case errors.Is(err, errBodyTooLarge):
+		return hecReply(c, hecTooLarge, hecResponse{})
+	case err != nil:
+		return hecReply(c, hecInvalidFormat, hecResponse{})
+	}
+
+	args := c.QueryArgs()
+	events, err := parse(body, splunk.Metadata{
+		Host:       string(args.Peek("host")),
+		Source:     string(args.Peek("source")),
+		SourceType: string(args.Peek("sourcetype")),
+		Index:      string(args.Peek("index")),
+	})
+	var eventErr *splunk.EventError
+	if errors.As(err, &eventErr) {
+		status := hecInvalidFormat
+		switch {
+		case errors.Is(err, splunk.ErrMissingEvent):
+			status = hecEventRequired
+		case errors.Is(err, splunk.ErrBlankEvent):
+			status = hecEventBlank
+		}
+		return hecReply(c, status, hecResponse{InvalidEventNumber: &eventErr.Index})
+	}
+	if len(events) == 0 {
+		return hecReply(c, hecNoData, hecResponse{})
+	}
+
+	received := h.now()
+	var res hecResponse
+	if token.Ack {
+		// the ID is taken before queueing, so that a request is never refused once queued
+		id, ok := h.acks.next(channel, received)
+		if !ok {
+			return h.busy(c, errTooManyAckChannels)
+		}
+		res.AckID = &id
+	}
+	logs := make([]*model.LogRecord, len(events))
+	for i := range events {
+		logs[i] = splunk.ToDomainLog(&events[i], token.Service, received)
+	}
+	oks, err := h.modelProcessor.ProcessLogs(logs, processor.LogOptions{
+		InboundTransport: processor.HTTPTransport,
+		LogFormat:        processor.HECLogFormat,
+		Tenant:           token.Tenant,
+	})
+	if err == nil {
+		for _, ok := range oks {
+			if !ok {
+				err = processor.ErrBusy
+				break
+			}
+		}
+	}
+	if err != nil {
+		// the senders retry the whole request, the ID of this one is never acked
+		return h.busy(c, err)
+	}
+	if res.AckID != nil {
+		h.acks.ack(channel, *res.AckID)
+	}
+	return hecReply(c, hecSuccess, res)
+}
+
+// busy asks the sender to retry the request later.
+func (h *HECHandler) busy(c *atreugo.RequestCtx, err error) error {
+	h.logger.Debug("Rejecting HEC request", zap.Error(err))
+	c.Response.Header.Set("Retry-After", strconv.Itoa(int(busyRetryAfter.Seconds())))
+	return hecReply(c, hecBusy, hecResponse{})
+}
+
+// Ack answers the senders polling the acks of their requests. A request is acked once
+// the collector queue accepted all its events, which is when the request succeeds. An
+// ack is answered true once, and only to the token of the request.
+func (h *HECHandler) Ack(c *atreugo.RequestCtx) error {
+	key, token, authErr := h.authenticate(c)
+	if authErr != nil {
+		return hecReply(c, *authErr, hecResponse{})
+	}
+	if !token.Ack {
+		return hecReply(c, hecAckDisabled, hecResponse{})
+	}
+	channel := ackChannel{token: key, name: hecChannel(c)}
+	if channel.name == "" {
+		return hecReply(c, hecChannelMissing, hecResponse{})
+	}
+	var req struct {
+		Acks []uint64 `json:"acks"`
+	}
+	if err := json.Unmarshal(c.PostBody(), &req); err != nil {
+		return hecReply(c, hecInvalidFormat, hecResponse{})
+	}
+	acks := make(map[string]bool, len(req.Acks))
+	for _, id := range req.Acks {
+		acks[strconv.FormatUint(id, 10)] = h.acks.acked(channel, id, h.now())
+	}
+	return writeJSON(c, http.StatusOK, map[string]interface{}{"acks": acks})
+}
+
+// Health answers the health checks of the senders.
+func (h *HECHandler) Health(c *atreugo.RequestCtx) error {
+	return hecReply(c, hecHealthy, hecResponse{})
+}
+
+// authenticate returns the token of the request and what it grants, or the status to
+// reply when the request has no valid token.
+func (h *HECHandler) authenticate(c *atreugo.RequestCtx) (string, flags.HECToken, *hecStatus) {
+	auth := string(c.Request.Header.Peek("Authorization"))
+	if auth == "" {
+		return "", flags.HECToken{}, &hecTokenRequired
+	}
+	if !strings.HasPrefix(auth, hecAuthScheme) {
+		return "", flags.HECToken{}, &hecInvalidAuth
+	}
+	key := strings.TrimSpace(auth[len(hecAuthScheme):])
+	token, ok := h.tokens[key]
+	if !ok {
+		return "", flags.HECToken{}, &hecInvalidToken
+	}
+	return key, token, nil
+}
+
+func hecChannel(c *atreugo.RequestCtx) string {
+	if channel := c.Request.Header.Peek(hecChannelHeader); len(channel) > 0 {
+		return string(channel)
+	}
+	return string(c.QueryArgs().Peek("channel"))
+}
+
+func hecReply(c *atreugo.RequestCtx, status hecStatus, res hecResponse) error {
+	res.Text, res.Code = status.text, status.code
+	return writeJSON(c, status.statusCode, res)
+}
+
+func writeJSON(c *atreugo.RequestCtx, statusCode int, v interface{}) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	c.Response.Header.SetContentType("application/json")
+	c.SetStatusCode(statusCode)
+	c.SetBody(body)
+	return nil
+}
+
+// errTooManyAckChannels is returned when no more channels can be tracked for acks.
+var errTooManyAckChannels = errors.New("too many HEC ack channels")
+
+// ackChannel names a channel of a token; the same channel name used with another token
+// is another channel, so that a sender cannot poll the acks of another.
+type ackChannel struct {
+	token string
+	name  string
+}
+
+// ackState is the ack state of a channel.
+type ackState struct {
+	// next is the ID of the next request of the channel
+	next uint64
+	// acked holds the IDs of the successful requests of the channel until they are polled
+	acked    map[uint64]struct{}
+	lastUsed time.Time
+}
+
+// hecAcks issues the ack IDs of the channels. The IDs of a channel start at zero and each
+// request of the channel gets the next one, it is acked if the request succeeds. The
+// channels idle for longer than ackChannelIdleTimeout are forgotten.
+type hecAcks struct {
+	mu       sync.Mutex
+	channels map[ackChannel]*ackState
+}
+
+func newHECAcks() *hecAcks {
+	return &hecAcks{channels: make(map[ackChannel]*ackState)}
+}
+
+// next returns the ID of a request of channel, false when there are too many channels.
+func (a *hecAcks) next(channel ackChannel, now time.Time) (uint64, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	state, ok := a.channels[channel]
+	if !ok {
+		if len(a.channels) >= maxAckChannels {
+			a.evictIdle(now)
+		}
+		if len(a.channels) >= maxAckChannels {
+			return 0, false
+		}
+		state = &ackState{acked: make(map[uint64]struct{})}
+		a.channels[channel] = state
+	}
+	state.lastUsed = now
+	id := state.next
+	state.next++
+	return id, true
+}
+
+// ack records that the request of channel with the given ID succeeded.
+func (a *hecAcks) ack(channel ackChannel, id uint64) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	state, ok := a.channels[channel]
+	if !ok {
+		return
+	}
+	state.acked[id] = struct{}{}
+	if id >= maxPendingAcks {
+		delete(state.acked, id-maxPendingAcks)
+	}
+}
+
+// acked tells whether the request of channel with the given ID succeeded, and forgets it.
+func (a *hecAcks) acked(channel ackChannel, id uint64, now time.Time) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	state, ok := a.channels[channel]
+	if !ok {
+		return false
+	}
+	state.lastUsed = now
+	_, ok = state.acked[id]
+	delete(state.acked, id)
+	return ok
+}
+
+// evictIdle forgets the channels idle for longer than ackChannelIdleTimeout.
+func (a *hecAcks) evictIdle(now time.Time) {
+	for channel, state := range a.channels {
+		if now.Sub(state.lastUsed) > ackChannelIdleTimeout {
+			delete(a.channels, channel)
+		}
+	}
+}
@@ -0,0 +1,211 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/savsgio/atreugo/v11"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valyala/fasthttp"
+	"go.uber.org/zap"
+
+	"logger/cmd/collector/app/flags"
+	"logger/cmd/collector/app/processor"
+)
+
+var hecTokens = map[string]flags.HECToken{
+	"plain": {Tenant: "t1", Service: "checkout"},
+	"acked": {Tenant: "t2", Service: "billing", Ack: true},
+	"peer":  {Tenant: "t2", Service: "billing", Ack: true},
+}
+
+type hecRequest struct {
+	auth    string
+	channel string
+	query   string
+	body    string
+}
+
+func callHEC(t *testing.T, view atreugo.View, req hecRequest) (int, map[string]interface{}) {
+	var fctx fasthttp.RequestCtx
+	fctx.Request.Header.SetMethod(http.MethodPost)
+	fctx.Request.SetRequestURI("/services/collector?" + req.query)
+	if req.auth != "" {
+		fctx.Request.Header.Set("Authorization", req.auth)
+	}
+	if req.channel != "" {
+		fctx.Request.Header.Set(hecChannelHeader, req.channel)
+	}
+	fctx.Request.SetBodyString(req.body)
+	ctx := atreugo.AcquireRequestCtx(&fctx)
+	defer atreugo.ReleaseRequestCtx(ctx)
+	require.NoError(t, view(ctx))
+	var res map[string]interface{}
+	require.NoError(t, json.Unmarshal(fctx.Response.Body(), &res))
+	return fctx.Response.StatusCode(), res
+}
+
+func TestHECEvent(t *testing.T) {
+	p := &fakeLogProcessor{}
+	h := NewHECHandler(zap.NewNop(), p, hecTokens, 0)
+	status, res := callHEC(t, h.Event, hecRequest{
+		auth:  "Splunk plain",
+		query: "sourcetype=app",
+		body:  `{"time":1710072000,"host":"web-1","event":"hello","fields":{"region":"eu"}}{"event":{"message":"world"}}`,
+	})
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, map[string]interface{}{"text": "Success", "code": 0.0}, res)
+
+	require.Len(t, p.logs, 2)
+	assert.Equal(t, "hello", p.logs[0].Body)
+	assert.Equal(t, "checkout", p.logs[0].Process.ServiceName)
+	assert.Equal(t, `{"message":"world"}`, p.logs[1].Body)
+	assert.Equal(t, processor.LogOptions{InboundTransport: processor.HTTPTransport, LogFormat: processor.HECLogFormat, Tenant: "t1"}, p.opts)
+}
+
+func TestHECRaw(t *testing.T) {
+	p := &fakeLogProcessor{}
+	h := NewHECHandler(zap.NewNop(), p, hecTokens, 0)
+	status, _ := callHEC(t, h.Raw, hecRequest{auth: "Splunk plain", query: "host=web-1&source=app.log", body: "first\nsecond\n"})
+	assert.Equal(t, http.StatusOK, status)
+	require.Len(t, p.logs, 2)
+	assert.Equal(t, "second", p.logs[1].Body)
+	assert.Equal(t, "web-1", p.logs[1].Process.Attributes[0].Value.GetStringValue())
+}
+
+func TestHECAcks(t *testing.T) {
+	h := NewHECHandler(zap.NewNop(), &fakeLogProcessor{}, hecTokens, 0)
+
+	status, res := callHEC(t, h.Event, hecRequest{auth: "Splunk acked", body: `{"event":"hello"}`})
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Equal(t, 10.0, res["code"])
+
+	for i := 0; i < 2; i++ {
+		status, res = callHEC(t, h.Event, hecRequest{auth: "Splunk acked", channel: "c1", body: `{"event":"hello"}`})
+		assert.Equal(t, http.StatusOK, status)
+		assert.Equal(t, float64(i), res["ackId"])
+	}
+	status, res = callHEC(t, h.Raw, hecRequest{auth: "Splunk acked", query: "channel=c2", body: "hello"})
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, 0.0, res["ackId"])
+
+	// the channels of a token are not seen by another
+	status, res = callHEC(t, h.Ack, hecRequest{auth: "Splunk peer", channel: "c1", body: `{"acks":[0]}`})
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, map[string]interface{}{"acks": map[string]interface{}{"0": false}}, res)
+
+	status, res = callHEC(t, h.Ack, hecRequest{auth: "Splunk acked", channel: "c1", body: `{"acks":[0,1,2]}`})
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, map[string]interface{}{"acks": map[string]interface{}{"0": true, "1": true, "2": false}}, res)
+
+	// an ack is answered true once
+	status, res = callHEC(t, h.Ack, hecRequest{auth: "Splunk acked", channel: "c1", body: `{"acks":[1]}`})
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, map[string]interface{}{"acks": map[string]interface{}{"1": false}}, res)
+
+	status, res = callHEC(t, h.Ack, hecRequest{auth: "Splunk plain", channel: "c1", body: `{"acks":[0]}`})
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Equal(t, 14.0, res["code"])
+}
+
+func TestHECErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		processor *fakeLogProcessor
+		req       hecRequest
+		status    int
+		code      float64
+		invalid   interface{}
+	}{
+		{"no token", &fakeLogProcessor{}, hecRequest{body: `{"event":"a"}`}, http.StatusUnauthorized, 2, nil},
+		{"wrong scheme", &fakeLogProcessor{}, hecRequest{auth: "Bearer plain", body: `{"event":"a"}`}, http.StatusUnauthorized, 3, nil},
+		{"unknown token", &fakeLogProcessor{}, hecRequest{auth: "Splunk other", body: `{"event":"a"}`}, http.StatusForbidden, 4, nil},
+		{"no data", &fakeLogProcessor{}, hecRequest{auth: "Splunk plain"}, http.StatusBadRequest, 5, nil},
+		{"invalid format", &fakeLogProcessor{}, hecRequest{auth: "Splunk plain", body: `{"event":"a"}{`}, http.StatusBadRequest, 6, 1.0},
+		{"missing event", &fakeLogProcessor{}, hecRequest{auth: "Splunk plain", body: `{"host":"a"}`}, http.StatusBadRequest, 12, 0.0},
+		{"blank event", &fakeLogProcessor{}, hecRequest{auth: "Splunk plain", body: `{"event":"a"}{"event":""}`}, http.StatusBadRequest, 13, 1.0},
+		{"too large", &fakeLogProcessor{}, hecRequest{auth: "Splunk plain", body: `{"event":"too large for the limit"}`}, http.StatusRequestEntityTooLarge, 6, nil},
+		{"busy", &fakeLogProcessor{reject: true}, hecRequest{auth: "Splunk plain", body: `{"event":"a"}`}, http.StatusServiceUnavailable, 9, nil},
+		{"failed", &fakeLogProcessor{err: errors.New("boom")}, hecRequest{auth: "Splunk plain", body: `{"event":"a"}`}, http.StatusServiceUnavailable, 9, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			h := NewHECHandler(zap.NewNop(), test.processor, hecTokens, 32)
+			status, res := callHEC(t, h.Event, test.req)
+			assert.Equal(t, test.status, status)
+			assert.Equal(t, test.code, res["code"])
+			assert.NotEmpty(t, res["text"])
+			assert.Equal(t, test.invalid, res["invalid-event-number"])
+		})
+	}
+}
+
+func TestHECPartiallyQueued(t *testing.T) {
+	p := &fakeLogProcessor{capacity: 1}
+	h := NewHECHandler(zap.NewNop(), p, hecTokens, 0)
+
+	// the sender retries the whole request, which is not acked
+	status, res := callHEC(t, h.Event, hecRequest{auth: "Splunk acked", channel: "c1", body: `{"event":"a"}{"event":"b"}`})
+	assert.Equal(t, http.StatusServiceUnavailable, status)
+	assert.Equal(t, 9.0, res["code"])
+	assert.NotContains(t, res, "ackId")
+	assert.Len(t, p.logs, 2)
+
+	p.capacity = 0
+	status, res = callHEC(t, h.Event, hecRequest{auth: "Splunk acked", channel: "c1", body: `{"event":"a"}{"event":"b"}`})
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, 1.0, res["ackId"])
+
+	status, res = callHEC(t, h.Ack, hecRequest{auth: "Splunk acked", channel: "c1", body: `{"acks":[0,1]}`})
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, map[string]interface{}{"acks": map[string]interface{}{"0": false, "1": true}}, res)
+}
+
+func TestHECHealth(t *testing.T) {
+	h := NewHECHandler(zap.NewNop(), &fakeLogProcessor{}, hecTokens, 0)
+	status, res := callHEC(t, h.Health, hecRequest{})
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, 17.0, res["code"])
+}
+
+func TestHECAckChannelsBounded(t *testing.T) {
+	acks := newHECAcks()
+	now := time.Now()
+	for i := 0; i < maxAckChannels; i++ {
+		_, ok := acks.next(ackChannel{token: "acked", name: strconv.Itoa(i)}, now)
+		require.True(t, ok)
+	}
+	_, ok := acks.next(ackChannel{token: "acked", name: "one more"}, now)
+	assert.False(t, ok)
+	id, ok := acks.next(ackChannel{token: "acked", name: "0"}, now)
+	assert.True(t, ok)
+	assert.Equal(t, uint64(1), id)
+
+	// the idle channels are evicted to make room for new ones
+	later := now.Add(ackChannelIdleTimeout + time.Second)
+	acks.ack(ackChannel{token: "acked", name: "0"}, id)
+	assert.True(t, acks.acked(ackChannel{token: "acked", name: "0"}, id, later))
+	id, ok = acks.next(ackChannel{token: "acked", name: "one more"}, later)
+	assert.True(t, ok)
+	assert.Equal(t, uint64(0), id)
+	assert.Len(t, acks.channels, 2)
+}
+
+func TestHECPendingAcksBounded(t *testing.T) {
+	acks := newHECAcks()
+	channel := ackChannel{token: "acked", name: "c1"}
+	now := time.Now()
+	for i := 0; i <= maxPendingAcks; i++ {
+		id, ok := acks.next(channel, now)
+		require.True(t, ok)
+		acks.ack(channel, id)
+	}
+	assert.Len(t, acks.channels[channel].acked, maxPendingAcks)
+	assert.False(t, acks.acked(channel, 0, now))
+	assert.True(t, acks.acked(channel, maxPendingAcks, now))
+}
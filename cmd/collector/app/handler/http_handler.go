@@ -1,8 +1,10 @@
 package handler
 
 import (
-	"log"
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
 
 	"github.com/savsgio/atreugo/v11"
 
@@ -10,20 +12,24 @@ import (
 	"logger/cmd/collector/app/processor"
 	pb "logger/model/proto/v1"
 
-	"github.com/golang/protobuf/proto"
-	// "encoding/json"
-	// "fmt"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
 )
 
 type APIHandler struct {
 	BatchesHandler BatchesHandler
+	// MaxRequestBodySize caps the size of a decompressed request body, zero disables it
+	MaxRequestBodySize int
 }
 
 func NewAPIHandler(
 	BatchesHandler BatchesHandler,
+	maxRequestBodySize int,
 ) *APIHandler {
 	return &APIHandler{
-		BatchesHandler: BatchesHandler,
+		BatchesHandler:     BatchesHandler,
+		MaxRequestBodySize: maxRequestBodySize,
 	}
 }
 
@@ -32,32 +38,54 @@ func (h *APIHandler)RegisterRoutes(router *atreugo.Atreugo) {
 	router.POST("/v1/logs", h.Logs)
 }
 
-
-
-
+// Logs accepts an OTLP export request encoded in protobuf or JSON, according to its
+// Content-Type, and compressed with gzip, deflate or zstd. The response is encoded like the request.
 func (h *APIHandler) Logs(c *atreugo.RequestCtx) (err error) {
-	var data pb.ExportLogsServiceRequest
-	err = proto.Unmarshal(c.PostBody(), &data)
+	mt, err := mediaType(string(c.Request.Header.ContentType()))
 	if err != nil {
-		log.Fatalf("Failed to parse data: %v", err)
+		return otlpError(c, protobufContentType, codes.InvalidArgument, http.StatusUnsupportedMediaType, err)
+	}
+	body, err := decompress(string(c.Request.Header.Peek("Content-Encoding")), c.PostBody(), h.MaxRequestBodySize)
+	switch {
+	case errors.Is(err, errBodyTooLarge):
+		return otlpError(c, mt, codes.InvalidArgument, http.StatusRequestEntityTooLarge, err)
+	case errors.Is(err, errUnsupportedMediaType):
+		return otlpError(c, mt, codes.InvalidArgument, http.StatusUnsupportedMediaType, err)
+	case err != nil:
+		return otlpError(c, mt, codes.InvalidArgument, http.StatusBadRequest, err)
+	}
+	var data pb.ExportLogsServiceRequest
+	if err := unmarshalOTLP(mt, body, &data); err != nil {
+		return otlpError(c, mt, codes.InvalidArgument, http.StatusBadRequest, fmt.Errorf("cannot parse request: %w", err))
 	}
-	// log.Println(data.GetResourceLogs())
-	
+
 	batches := data.GetResourceLogs()
-	// for _,b := range batches {
-	// 	for _,v := range b.ScopeLogs {
-	// 		for _,l := range v.LogRecords {
-	// 			l.Body.GetStringValue()
-	// 			fmt.Println("Number",l.SeverityNumber.Number(),
-	// 			"String",l.SeverityNumber.String())
-	// 		}
-	// 	}
-	// }
 	opts := SubmitBatchOptions{InboundTransport: processor.HTTPTransport}
-	if _, err = h.BatchesHandler.SubmitBatches(batches, opts); err != nil {
-		return c.JSONResponse("ERROR", http.StatusBadRequest)
+	responses, err := h.BatchesHandler.SubmitBatches(batches, opts)
+	if err != nil {
+		// the exporters retry after the delay of Retry-After on 429 and 503
+		c.Response.Header.Set("Retry-After", strconv.Itoa(int(busyRetryAfter.Seconds())))
+		if errors.Is(err, processor.ErrBusy) {
+			return otlpError(c, mt, codes.ResourceExhausted, http.StatusTooManyRequests, err)
+		}
+		return otlpError(c, mt, codes.Unavailable, http.StatusServiceUnavailable, err)
 	}
+	return otlpResponse(c, mt, exportResponse(responses), http.StatusOK)
+}
 
-	// fmt.Printf("Scheme URL: %s\n", data.GetResourceLogs())
-	return c.JSONResponse("SUCESS", http.StatusOK)
+// otlpError writes a google.rpc.Status in the OTLP encoding mt, as the OTLP/HTTP specification requires.
+func otlpError(c *atreugo.RequestCtx, mt string, code codes.Code, statusCode int, err error) error {
+	return otlpResponse(c, mt, status.New(code, err.Error()).Proto(), statusCode)
+}
+
+// otlpResponse writes m encoded in the OTLP encoding mt.
+func otlpResponse(c *atreugo.RequestCtx, mt string, m proto.Message, statusCode int) error {
+	body, err := marshalOTLP(mt, m)
+	if err != nil {
+		return err
+	}
+	c.Response.Header.SetContentType(mt)
+	c.SetStatusCode(statusCode)
+	c.SetBody(body)
+	return nil
 }
@@ -0,0 +1,91 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/savsgio/atreugo/v11"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valyala/fasthttp"
+	spb "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	"logger/cmd/collector/app/processor"
+	pbL "logger/model/proto/logs/v1"
+	pb "logger/model/proto/v1"
+)
+
+type fakeBatchesHandler struct {
+	responses []*BatchSubmitResponse
+	err       error
+}
+
+func (h *fakeBatchesHandler) SubmitBatches([]*pbL.ResourceLogs, SubmitBatchOptions) ([]*BatchSubmitResponse, error) {
+	return h.responses, h.err
+}
+
+func postLogs(t *testing.T, h *APIHandler, contentType string, body []byte) *fasthttp.Response {
+	var fctx fasthttp.RequestCtx
+	fctx.Request.Header.SetMethod(http.MethodPost)
+	fctx.Request.Header.SetContentType(contentType)
+	fctx.Request.SetBody(body)
+	ctx := atreugo.AcquireRequestCtx(&fctx)
+	defer atreugo.ReleaseRequestCtx(ctx)
+	require.NoError(t, h.Logs(ctx))
+	res := &fasthttp.Response{}
+	fctx.Response.CopyTo(res)
+	return res
+}
+
+func TestLogsPartialSuccess(t *testing.T) {
+	h := NewAPIHandler(&fakeBatchesHandler{responses: []*BatchSubmitResponse{{Ok: true}, {Rejected: 2}, {Rejected: 1}}}, 0)
+	res := postLogs(t, h, protobufContentType, nil)
+	assert.Equal(t, http.StatusOK, res.StatusCode())
+	assert.Equal(t, protobufContentType, string(res.Header.ContentType()))
+	var export pb.ExportLogsServiceResponse
+	require.NoError(t, proto.Unmarshal(res.Body(), &export))
+	assert.Equal(t, int64(3), export.GetPartialSuccess().GetRejectedLogRecords())
+
+	h = NewAPIHandler(&fakeBatchesHandler{responses: []*BatchSubmitResponse{{Ok: true}}}, 0)
+	res = postLogs(t, h, jsonContentType, []byte(`{}`))
+	assert.Equal(t, http.StatusOK, res.StatusCode())
+	assert.Equal(t, jsonContentType, string(res.Header.ContentType()))
+	assert.JSONEq(t, `{}`, string(res.Body()))
+}
+
+func TestLogsErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		handler     *fakeBatchesHandler
+		contentType string
+		body        []byte
+		status      int
+		code        codes.Code
+		retryAfter  string
+	}{
+		{"malformed protobuf", &fakeBatchesHandler{}, protobufContentType, []byte{0xff}, http.StatusBadRequest, codes.InvalidArgument, ""},
+		{"malformed json", &fakeBatchesHandler{}, jsonContentType, []byte(`{`), http.StatusBadRequest, codes.InvalidArgument, ""},
+		{"unsupported content type", &fakeBatchesHandler{}, "text/plain", nil, http.StatusUnsupportedMediaType, codes.InvalidArgument, ""},
+		{"busy", &fakeBatchesHandler{err: processor.ErrBusy}, jsonContentType, []byte(`{}`), http.StatusTooManyRequests, codes.ResourceExhausted, "5"},
+		{"failed", &fakeBatchesHandler{err: errors.New("boom")}, protobufContentType, nil, http.StatusServiceUnavailable, codes.Unavailable, "5"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			res := postLogs(t, NewAPIHandler(test.handler, 0), test.contentType, test.body)
+			assert.Equal(t, test.status, res.StatusCode())
+			assert.Equal(t, test.retryAfter, string(res.Header.Peek("Retry-After")))
+			var st spb.Status
+			if string(res.Header.ContentType()) == jsonContentType {
+				require.NoError(t, protojson.Unmarshal(res.Body(), &st))
+			} else {
+				require.NoError(t, proto.Unmarshal(res.Body(), &st))
+			}
+			assert.Equal(t, int32(test.code), st.GetCode())
+			assert.NotEmpty(t, st.GetMessage())
+		})
+	}
+}
@@ -2,6 +2,8 @@ package handler
 
 import (
 	"log"
+	"time"
+
 	"logger/cmd/collector/app/processor"
 	"logger/model"
 	pbL "logger/model/proto/logs/v1"
@@ -12,13 +14,20 @@ import (
 	"go.uber.org/zap"
 )
 
+// busyRetryAfter is how long the senders are asked to wait before retrying when the collector is busy
+const busyRetryAfter = 5 * time.Second
+
 // SubmitBatchOptions are passed to Submit methods of the handlers.
 type SubmitBatchOptions struct {
 	InboundTransport processor.InboundTransport
+	// Tenant is the tenant the logs are written for, empty when tenancy is disabled
+	Tenant string
 }
 
 type BatchSubmitResponse struct {
 	Ok bool
+	// Rejected is the number of records of the batch the processor dropped
+	Rejected int
 }
 
 type Batch pb.ExportLogsServiceRequest
@@ -63,24 +72,26 @@ func (h *batchesHandler) SubmitBatches(batches []*pbL.ResourceLogs, opts SubmitB
 			oks, err := h.modelProcessor.ProcessLogs(mLogs, processor.LogOptions{
 				InboundTransport: opts.InboundTransport,
 				LogFormat:        processor.OTLPLogFormat,
+				Tenant:           opts.Tenant,
 			})
 			log.Println("ProcessLogs ------------------------------------ 2")
 
-			if err != nil {	
+			if err != nil {
 				h.logger.Error("Collector failed to process span batch", zap.Error(err))
 				return nil, err
 			}
-			batchOk := true
+			rejected := 0
 			for _, ok := range oks {
 				if !ok {
-					batchOk = false
-					break
+					rejected++
 				}
 			}
+			batchOk := rejected == 0
 
 			h.logger.Debug("Span batch processed by the collector.", zap.Bool("ok", batchOk))
 			res := &BatchSubmitResponse{
-				Ok: batchOk,
+				Ok:       batchOk,
+				Rejected: rejected,
 			}
 			responses = append(responses, res)
 		}
@@ -95,10 +106,10 @@ func (h *batchesHandler) getProcess(r *pbL.ResourceLogs) *model.Process {
 	resources := r.GetResource()
 	var serviceName string
 	attributes := make([]model.KeyValue, 0, len(resources.GetAttributes()))
-	
+
 	for _, attr := range resources.GetAttributes() {
 		keyValue := model.KeyValue{
-			Key: attr.Key,
+			Key:   attr.Key,
 			Value: attr.GetValue(),
 		}
 		if attr == nil {
@@ -115,3 +126,20 @@ func (h *batchesHandler) getProcess(r *pbL.ResourceLogs) *model.Process {
 		Attributes:  attributes,
 	}
 }
+
+// exportResponse returns the OTLP response to an export whose batches were submitted
+// with the given results, reporting the records dropped by the processor as a partial success.
+func exportResponse(responses []*BatchSubmitResponse) *pb.ExportLogsServiceResponse {
+	rejected := 0
+	for _, res := range responses {
+		rejected += res.Rejected
+	}
+	res := &pb.ExportLogsServiceResponse{}
+	if rejected > 0 {
+		res.PartialSuccess = &pb.ExportLogsPartialSuccess{
+			RejectedLogRecords: int64(rejected),
+			ErrorMessage:       "the collector queue is full",
+		}
+	}
+	return res
+}
@@ -0,0 +1,121 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/savsgio/atreugo/v11"
+	"go.uber.org/zap"
+
+	"logger/cmd/collector/app/processor"
+	"logger/model/converter/loki"
+)
+
+// LokiHandler implements the push API of Loki, so that Promtail, Grafana Alloy and the
+// Docker Loki driver can send to the collector.
+type LokiHandler struct {
+	logger         *zap.Logger
+	modelProcessor processor.LogProcessor
+	serviceLabels  []string
+	// maxRequestSize caps the size of a decompressed request body, zero disables it
+	maxRequestSize int
+	now            func() time.Time
+}
+
+// NewLokiHandler returns a LokiHandler submitting the pushed entries to modelProcessor. The
+// first of serviceLabels a stream has names the service of its entries.
+func NewLokiHandler(logger *zap.Logger, modelProcessor processor.LogProcessor, serviceLabels []string, maxRequestSize int) *LokiHandler {
+	return &LokiHandler{
+		logger:         logger,
+		modelProcessor: modelProcessor,
+		serviceLabels:  serviceLabels,
+		maxRequestSize: maxRequestSize,
+		now:            time.Now,
+	}
+}
+
+func (h *LokiHandler) RegisterRoutes(router *atreugo.Atreugo) {
+	router.POST("/loki/api/v1/push", h.Push)
+}
+
+// Push accepts a push request in snappy compressed protobuf or in JSON, according to its
+// Content-Type. Like Loki, it replies 204 on success and a plain text error otherwise.
+func (h *LokiHandler) Push(c *atreugo.RequestCtx) error {
+	mt, err := mediaType(string(c.Request.Header.ContentType()))
+	if err != nil {
+		return lokiError(c, http.StatusUnsupportedMediaType, err)
+	}
+	body, err := decompress(string(c.Request.Header.Peek("Content-Encoding")), c.PostBody(), h.maxRequestSize)
+	if err == nil && mt == protobufContentType {
+		body, err = h.decodeSnappy(body)
+	}
+	switch {
+	case errors.Is(err, errBodyTooLarge):
+		return lokiError(c, http.StatusRequestEntityTooLarge, err)
+	case errors.Is(err, errUnsupportedMediaType):
+		return lokiError(c, http.StatusUnsupportedMediaType, err)
+	case err != nil:
+		return lokiError(c, http.StatusBadRequest, err)
+	}
+
+	var req *loki.PushRequest
+	if mt == jsonContentType {
+		req, err = loki.UnmarshalJSON(body)
+	} else {
+		req, err = loki.UnmarshalProto(body)
+	}
+	if err != nil {
+		return lokiError(c, http.StatusBadRequest, fmt.Errorf("cannot parse request: %w", err))
+	}
+
+	logs := loki.ToDomainLogs(req, h.serviceLabels, h.now())
+	oks, err := h.modelProcessor.ProcessLogs(logs, processor.LogOptions{
+		InboundTransport: processor.HTTPTransport,
+		LogFormat:        processor.LokiLogFormat,
+	})
+	if err == nil {
+		for _, ok := range oks {
+			if !ok {
+				err = processor.ErrBusy
+				break
+			}
+		}
+	}
+	if err != nil {
+		// the clients retry the whole request on 429 and 5xx
+		c.Response.Header.Set("Retry-After", strconv.Itoa(int(busyRetryAfter.Seconds())))
+		if errors.Is(err, processor.ErrBusy) {
+			return lokiError(c, http.StatusTooManyRequests, err)
+		}
+		return lokiError(c, http.StatusServiceUnavailable, err)
+	}
+	c.SetStatusCode(http.StatusNoContent)
+	return nil
+}
+
+// decodeSnappy decodes a snappy block, checking its decoded length before allocating it.
+func (h *LokiHandler) decodeSnappy(body []byte) ([]byte, error) {
+	n, err := snappy.DecodedLen(body)
+	if err != nil {
+		return nil, fmt.Errorf("invalid snappy body: %w", err)
+	}
+	if h.maxRequestSize > 0 && n > h.maxRequestSize {
+		return nil, errBodyTooLarge
+	}
+	res, err := snappy.Decode(nil, body)
+	if err != nil {
+		return nil, fmt.Errorf("invalid snappy body: %w", err)
+	}
+	return res, nil
+}
+
+func lokiError(c *atreugo.RequestCtx, statusCode int, err error) error {
+	c.Response.Header.SetContentType("text/plain; charset=utf-8")
+	c.SetStatusCode(statusCode)
+	c.SetBodyString(err.Error())
+	return nil
+}
@@ -0,0 +1,134 @@
+package handler
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/golang/snappy"
+	"github.com/savsgio/atreugo/v11"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valyala/fasthttp"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"logger/cmd/collector/app/processor"
+	"logger/model"
+)
+
+type fakeLogProcessor struct {
+	logs []*model.LogRecord
+	opts processor.LogOptions
+	// reject makes the processor reject the logs, like a full queue
+	reject bool
+	// capacity, when set, makes the processor reject the logs past the first capacity ones
+	capacity int
+	err      error
+}
+
+func (p *fakeLogProcessor) ProcessLogs(logs []*model.LogRecord, opts processor.LogOptions) ([]bool, error) {
+	p.logs, p.opts = logs, opts
+	oks := make([]bool, len(logs))
+	for i := range oks {
+		oks[i] = !p.reject && (p.capacity == 0 || i < p.capacity)
+	}
+	return oks, p.err
+}
+
+func (*fakeLogProcessor) Close() error {
+	return nil
+}
+
+func push(t *testing.T, h *LokiHandler, contentType, contentEncoding string, body []byte) *fasthttp.Response {
+	var fctx fasthttp.RequestCtx
+	fctx.Request.Header.SetMethod(http.MethodPost)
+	fctx.Request.Header.SetContentType(contentType)
+	if contentEncoding != "" {
+		fctx.Request.Header.Set("Content-Encoding", contentEncoding)
+	}
+	fctx.Request.SetBody(body)
+	ctx := atreugo.AcquireRequestCtx(&fctx)
+	defer atreugo.ReleaseRequestCtx(ctx)
+	require.NoError(t, h.Push(ctx))
+	res := &fasthttp.Response{}
+	fctx.Response.CopyTo(res)
+	return res
+}
+
+// lokiPushRequest encodes a push request of one stream and one entry in protobuf.
+func lokiPushRequest(labels, line string) []byte {
+	var entry []byte
+	entry = protowire.AppendTag(entry, 2, protowire.BytesType)
+	entry = protowire.AppendString(entry, line)
+	var stream []byte
+	stream = protowire.AppendTag(stream, 1, protowire.BytesType)
+	stream = protowire.AppendString(stream, labels)
+	stream = protowire.AppendTag(stream, 2, protowire.BytesType)
+	stream = protowire.AppendBytes(stream, entry)
+	req := protowire.AppendTag(nil, 1, protowire.BytesType)
+	return protowire.AppendBytes(req, stream)
+}
+
+func TestLokiPushProtobuf(t *testing.T) {
+	p := &fakeLogProcessor{}
+	h := NewLokiHandler(zap.NewNop(), p, []string{"service_name", "job"}, 0)
+	body := snappy.Encode(nil, lokiPushRequest(`{job="varlogs",host="web-1"}`, "hello"))
+
+	res := push(t, h, "application/x-protobuf", "", body)
+	assert.Equal(t, http.StatusNoContent, res.StatusCode())
+	require.Len(t, p.logs, 1)
+	assert.Equal(t, "hello", p.logs[0].Body)
+	assert.Equal(t, "varlogs", p.logs[0].Process.ServiceName)
+	assert.Equal(t, processor.LogOptions{InboundTransport: processor.HTTPTransport, LogFormat: processor.LokiLogFormat}, p.opts)
+
+	// the Content-Type may be omitted
+	res = push(t, h, "", "", body)
+	assert.Equal(t, http.StatusNoContent, res.StatusCode())
+}
+
+func TestLokiPushJSON(t *testing.T) {
+	p := &fakeLogProcessor{}
+	h := NewLokiHandler(zap.NewNop(), p, []string{"service_name"}, 0)
+	var body bytes.Buffer
+	gw := gzip.NewWriter(&body)
+	gw.Write([]byte(`{"streams":[{"stream":{"service_name":"checkout"},"values":[["1710072000000000000","a"],["1710072000000000001","b"]]}]}`))
+	require.NoError(t, gw.Close())
+
+	res := push(t, h, "application/json", "gzip", body.Bytes())
+	assert.Equal(t, http.StatusNoContent, res.StatusCode())
+	require.Len(t, p.logs, 2)
+	assert.Equal(t, "checkout", p.logs[1].Process.ServiceName)
+	assert.Equal(t, "b", p.logs[1].Body)
+}
+
+func TestLokiPushErrors(t *testing.T) {
+	valid := snappy.Encode(nil, lokiPushRequest(`{job="varlogs"}`, "hello"))
+	tests := []struct {
+		name        string
+		processor   *fakeLogProcessor
+		contentType string
+		body        []byte
+		status      int
+		retryAfter  string
+	}{
+		{"not snappy", &fakeLogProcessor{}, "application/x-protobuf", []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, http.StatusBadRequest, ""},
+		{"malformed protobuf", &fakeLogProcessor{}, "application/x-protobuf", snappy.Encode(nil, []byte{0x0a, 0x05}), http.StatusBadRequest, ""},
+		{"malformed labels", &fakeLogProcessor{}, "application/x-protobuf", snappy.Encode(nil, lokiPushRequest(`job=varlogs`, "hello")), http.StatusBadRequest, ""},
+		{"malformed json", &fakeLogProcessor{}, "application/json", []byte(`{`), http.StatusBadRequest, ""},
+		{"too large", &fakeLogProcessor{}, "application/x-protobuf", snappy.Encode(nil, make([]byte, 1024)), http.StatusRequestEntityTooLarge, ""},
+		{"unsupported content type", &fakeLogProcessor{}, "text/plain", valid, http.StatusUnsupportedMediaType, ""},
+		{"busy", &fakeLogProcessor{reject: true}, "application/x-protobuf", valid, http.StatusTooManyRequests, "5"},
+		{"failed", &fakeLogProcessor{err: errors.New("boom")}, "application/x-protobuf", valid, http.StatusServiceUnavailable, "5"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			res := push(t, NewLokiHandler(zap.NewNop(), test.processor, nil, 512), test.contentType, "", test.body)
+			assert.Equal(t, test.status, res.StatusCode())
+			assert.Equal(t, test.retryAfter, string(res.Header.Peek("Retry-After")))
+			assert.NotEmpty(t, res.Body())
+		})
+	}
+}
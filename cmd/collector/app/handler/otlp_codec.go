@@ -0,0 +1,168 @@
+package handler
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// protobufContentType is the content type of binary OTLP, the default
+	protobufContentType = "application/x-protobuf"
+	// jsonContentType is the content type of OTLP JSON
+	jsonContentType = "application/json"
+)
+
+var (
+	// errBodyTooLarge is returned when a decompressed body exceeds the size cap
+	errBodyTooLarge = errors.New("request body too large")
+	// errUnsupportedMediaType is returned for a content type or encoding the collector cannot read
+	errUnsupportedMediaType = errors.New("unsupported media type")
+)
+
+// mediaType returns the OTLP encoding of a Content-Type header, protobuf when it is empty.
+func mediaType(contentType string) (string, error) {
+	if contentType == "" {
+		return protobufContentType, nil
+	}
+	mt, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", errUnsupportedMediaType, contentType)
+	}
+	switch mt {
+	case protobufContentType, "application/protobuf":
+		return protobufContentType, nil
+	case jsonContentType:
+		return jsonContentType, nil
+	}
+	return "", fmt.Errorf("%w: %s", errUnsupportedMediaType, mt)
+}
+
+// decompress decodes body according to a Content-Encoding header, refusing to
+// produce more than limit bytes. A limit of zero or less disables the cap.
+func decompress(encoding string, body []byte, limit int) ([]byte, error) {
+	var r io.Reader
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "", "identity":
+		if limit > 0 && len(body) > limit {
+			return nil, errBodyTooLarge
+		}
+		return body, nil
+	case "gzip", "x-gzip":
+		gr, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return nil, fmt.Errorf("invalid gzip body: %w", err)
+		}
+		defer gr.Close()
+		r = gr
+	case "deflate":
+		// "deflate" is zlib wrapped per RFC 9110, yet some clients send raw deflate
+		zr, err := zlib.NewReader(bytes.NewReader(body))
+		if err != nil {
+			zr = flate.NewReader(bytes.NewReader(body))
+		}
+		defer zr.Close()
+		r = zr
+	case "zstd":
+		zr, err := zstd.NewReader(bytes.NewReader(body), zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, fmt.Errorf("invalid zstd body: %w", err)
+		}
+		defer zr.Close()
+		r = zr
+	default:
+		return nil, fmt.Errorf("%w: content encoding %s", errUnsupportedMediaType, encoding)
+	}
+	if limit > 0 {
+		// read one byte past the limit to tell a body of exactly limit bytes from a larger one
+		r = io.LimitReader(r, int64(limit)+1)
+	}
+	res, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("cannot decompress %s body: %w", encoding, err)
+	}
+	if limit > 0 && len(res) > limit {
+		return nil, errBodyTooLarge
+	}
+	return res, nil
+}
+
+// unmarshalOTLP decodes body in the OTLP encoding mt into m.
+func unmarshalOTLP(mt string, body []byte, m proto.Message) error {
+	if mt != jsonContentType {
+		return proto.Unmarshal(body, m)
+	}
+	body, err := hexToBase64IDs(body)
+	if err != nil {
+		return err
+	}
+	return protojson.UnmarshalOptions{DiscardUnknown: true}.Unmarshal(body, m)
+}
+
+// marshalOTLP encodes m in the OTLP encoding mt.
+func marshalOTLP(mt string, m proto.Message) ([]byte, error) {
+	if mt == jsonContentType {
+		return protojson.Marshal(m)
+	}
+	return proto.Marshal(m)
+}
+
+// idFields are the keys of the IDs that OTLP JSON encodes in hex, where protojson expects base64.
+var idFields = map[string]bool{
+	"traceId": true, "trace_id": true,
+	"spanId": true, "span_id": true,
+}
+
+// hexToBase64IDs rewrites the hex encoded trace and span IDs of an OTLP JSON document in base64.
+func hexToBase64IDs(body []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	// numbers are kept as written, int64 values would lose precision as float64
+	dec.UseNumber()
+	var doc interface{}
+	if err := dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+	if err := convertIDs(doc); err != nil {
+		return nil, err
+	}
+	return json.Marshal(doc)
+}
+
+func convertIDs(v interface{}) error {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			if s, ok := value.(string); ok && idFields[key] {
+				id, err := hex.DecodeString(s)
+				if err != nil {
+					return fmt.Errorf("invalid %s %q: %w", key, s, err)
+				}
+				v[key] = base64.StdEncoding.EncodeToString(id)
+				continue
+			}
+			if err := convertIDs(value); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for _, value := range v {
+			if err := convertIDs(value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
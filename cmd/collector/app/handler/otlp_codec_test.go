@@ -0,0 +1,113 @@
+package handler
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	pb "logger/model/proto/v1"
+)
+
+func compress(t *testing.T, encoding string, data []byte) []byte {
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	switch encoding {
+	case "gzip":
+		w = gzip.NewWriter(&buf)
+	case "deflate":
+		w = zlib.NewWriter(&buf)
+	case "raw-deflate":
+		fw, err := flate.NewWriter(&buf, flate.DefaultCompression)
+		require.NoError(t, err)
+		w = fw
+	case "zstd":
+		zw, err := zstd.NewWriter(&buf)
+		require.NoError(t, err)
+		w = zw
+	}
+	_, err := w.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+func TestDecompress(t *testing.T) {
+	data := bytes.Repeat([]byte("log line "), 100)
+	for _, encoding := range []string{"gzip", "deflate", "raw-deflate", "zstd"} {
+		header := encoding
+		if encoding == "raw-deflate" {
+			header = "deflate"
+		}
+		res, err := decompress(header, compress(t, encoding, data), len(data))
+		require.NoError(t, err, encoding)
+		assert.Equal(t, data, res, encoding)
+
+		_, err = decompress(header, compress(t, encoding, data), len(data)-1)
+		assert.ErrorIs(t, err, errBodyTooLarge, encoding)
+	}
+
+	res, err := decompress("", data, 0)
+	require.NoError(t, err)
+	assert.Equal(t, data, res)
+	_, err = decompress("identity", data, 10)
+	assert.ErrorIs(t, err, errBodyTooLarge)
+	_, err = decompress("br", data, 0)
+	assert.ErrorIs(t, err, errUnsupportedMediaType)
+	_, err = decompress("gzip", data, 0)
+	assert.Error(t, err)
+}
+
+func TestMediaType(t *testing.T) {
+	for contentType, expected := range map[string]string{
+		"":                                protobufContentType,
+		"application/x-protobuf":          protobufContentType,
+		"application/json":                jsonContentType,
+		"application/json; charset=utf-8": jsonContentType,
+	} {
+		mt, err := mediaType(contentType)
+		require.NoError(t, err, contentType)
+		assert.Equal(t, expected, mt, contentType)
+	}
+	_, err := mediaType("text/plain")
+	assert.ErrorIs(t, err, errUnsupportedMediaType)
+}
+
+func TestUnmarshalOTLPJSON(t *testing.T) {
+	body := []byte(`{"resourceLogs":[{"scopeLogs":[{"logRecords":[{
+		"timeUnixNano":"1700000000000000001",
+		"severityNumber":9,
+		"body":{"stringValue":"hello"},
+		"traceId":"5b8efff798038103d269b633813fc60c",
+		"spanId":"eee19b7ec3c1b174",
+		"attributes":[{"key":"count","value":{"intValue":"9007199254740993"}}],
+		"unknownField":true
+	}]}]}]}`)
+	var req pb.ExportLogsServiceRequest
+	require.NoError(t, unmarshalOTLP(jsonContentType, body, &req))
+	log := req.GetResourceLogs()[0].GetScopeLogs()[0].GetLogRecords()[0]
+	assert.Equal(t, uint64(1700000000000000001), log.GetTimeUnixNano())
+	assert.Equal(t, "hello", log.GetBody().GetStringValue())
+	assert.Equal(t, []byte{0x5b, 0x8e, 0xff, 0xf7, 0x98, 0x03, 0x81, 0x03, 0xd2, 0x69, 0xb6, 0x33, 0x81, 0x3f, 0xc6, 0x0c}, log.GetTraceId())
+	assert.Equal(t, []byte{0xee, 0xe1, 0x9b, 0x7e, 0xc3, 0xc1, 0xb1, 0x74}, log.GetSpanId())
+	assert.Equal(t, int64(9007199254740993), log.GetAttributes()[0].GetValue().GetIntValue())
+
+	err := unmarshalOTLP(jsonContentType, []byte(`{"resourceLogs":[{"scopeLogs":[{"logRecords":[{"traceId":"xyz"}]}]}]}`), &req)
+	assert.Error(t, err)
+}
+
+func TestMarshalOTLPRoundTrip(t *testing.T) {
+	for _, mt := range []string{protobufContentType, jsonContentType} {
+		data, err := marshalOTLP(mt, &pb.ExportLogsServiceResponse{PartialSuccess: &pb.ExportLogsPartialSuccess{RejectedLogRecords: 2}})
+		require.NoError(t, err)
+		var res pb.ExportLogsServiceResponse
+		require.NoError(t, unmarshalOTLP(mt, data, &res), mt)
+		assert.Equal(t, int64(2), res.GetPartialSuccess().GetRejectedLogRecords())
+	}
+}
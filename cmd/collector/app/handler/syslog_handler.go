@@ -0,0 +1,48 @@
+package handler
+
+import (
+	"time"
+
+	"go.uber.org/zap"
+
+	"logger/cmd/collector/app/processor"
+	"logger/model"
+	"logger/model/converter/syslog"
+)
+
+// SyslogHandler parses syslog messages and submits them to the log processor.
+type SyslogHandler struct {
+	logger         *zap.Logger
+	modelProcessor processor.LogProcessor
+	now            func() time.Time
+}
+
+// NewSyslogHandler returns a SyslogHandler submitting the messages to modelProcessor.
+func NewSyslogHandler(logger *zap.Logger, modelProcessor processor.LogProcessor) *SyslogHandler {
+	return &SyslogHandler{
+		logger:         logger,
+		modelProcessor: modelProcessor,
+		now:            time.Now,
+	}
+}
+
+// HandleMessage parses an RFC 5424 or RFC 3164 message received over transport and
+// processes it. It returns processor.ErrBusy when the collector queue is full.
+func (h *SyslogHandler) HandleMessage(data []byte, transport processor.InboundTransport) error {
+	received := h.now()
+	m, err := syslog.Parse(data, received)
+	if err != nil {
+		return err
+	}
+	oks, err := h.modelProcessor.ProcessLogs([]*model.LogRecord{syslog.ToDomainLog(m, received)}, processor.LogOptions{
+		InboundTransport: transport,
+		LogFormat:        processor.SyslogLogFormat,
+	})
+	if err != nil {
+		return err
+	}
+	if len(oks) > 0 && !oks[0] {
+		return processor.ErrBusy
+	}
+	return nil
+}
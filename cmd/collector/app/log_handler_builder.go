@@ -12,6 +12,7 @@ import (
 	"logger/pkg/tenancy"
 
 	"logger/model"
+	"logger/model/converter/elasticsearch"
 
 	"go.uber.org/zap"
 )
@@ -25,7 +26,15 @@ type LogHandlerBuilder struct {
 }
 
 type LogHandlers struct {
-	BatchesHandler handler.BatchesHandler
+	BatchesHandler       handler.BatchesHandler
+	GRPCHandler          *handler.GRPCHandler
+	SyslogHandler        *handler.SyslogHandler
+	FluentHandler        *handler.FluentHandler
+	GELFHandler          *handler.GELFHandler
+	LokiHandler          *handler.LokiHandler
+	ElasticsearchHandler *handler.ElasticsearchHandler
+	// HECHandler is nil when no HEC token is configured
+	HECHandler *handler.HECHandler
 }
 
 func (b *LogHandlerBuilder) BuildLogProcessor(additional ...ProcessLog) processor.LogProcessor {
@@ -45,12 +54,30 @@ func (b *LogHandlerBuilder) BuildLogProcessor(additional ...ProcessLog) processo
 		Options.DynQueueSizeWarmup(uint(b.CollectorOpts.QueueSize)), // same as queue size for now
 		Options.DynQueueSizeMemory(b.CollectorOpts.DynQueueSizeMemory),
 		Options.SpanSizeMetricsEnabled(b.CollectorOpts.SpanSizeMetricsEnabled),
+		Options.ExtraFormatTypes([]processor.LogFormat{processor.SyslogLogFormat, processor.FluentLogFormat, processor.GELFLogFormat, processor.LokiLogFormat, processor.ElasticsearchLogFormat, processor.HECLogFormat}),
 	)
 }
 
 func (b *LogHandlerBuilder) BuildHandlers(spanProcessor processor.LogProcessor) *LogHandlers {
+	batchesHandler := handler.NewLogHandler(b.logger(), spanProcessor)
+	var hecHandler *handler.HECHandler
+	if len(b.CollectorOpts.HEC.Tokens) > 0 {
+		hecHandler = handler.NewHECHandler(b.logger(), spanProcessor, b.CollectorOpts.HEC.Tokens, b.CollectorOpts.HTTP.MaxRequestSize)
+	}
 	return &LogHandlers{
-		handler.NewLogHandler(b.Logger, spanProcessor),
+		BatchesHandler: batchesHandler,
+		GRPCHandler:    handler.NewGRPCHandler(b.logger(), batchesHandler),
+		SyslogHandler:  handler.NewSyslogHandler(b.logger(), spanProcessor),
+		FluentHandler:  handler.NewFluentHandler(b.logger(), spanProcessor),
+		GELFHandler:    handler.NewGELFHandler(b.logger(), spanProcessor, b.CollectorOpts.GELF.MaxMessageSize),
+		LokiHandler:    handler.NewLokiHandler(b.logger(), spanProcessor, b.CollectorOpts.Loki.ServiceLabels, b.CollectorOpts.HTTP.MaxRequestSize),
+		ElasticsearchHandler: handler.NewElasticsearchHandler(b.logger(), spanProcessor, elasticsearch.Mapping{
+			TimestampField: b.CollectorOpts.Elasticsearch.TimestampField,
+			MessageField:   b.CollectorOpts.Elasticsearch.MessageField,
+			LevelField:     b.CollectorOpts.Elasticsearch.LevelField,
+			ServiceField:   b.CollectorOpts.Elasticsearch.ServiceField,
+		}, b.CollectorOpts.HTTP.MaxRequestSize),
+		HECHandler: hecHandler,
 	}
 }
 
@@ -186,6 +186,7 @@ func (sp *logProcessor) ProcessLogs(mSpans []*model.LogRecord, options processor
 	// kicks in.
 	for _, span := range mSpans {
 		sp.addCollectorTags(span)
+		assignLogID(span)
 	}
 
 	for i, mSpan := range mSpans {
@@ -203,6 +204,12 @@ func (sp *logProcessor) processItemFromQueue(item *queueItem) {
 	// sp.metrics.InQueueLatency.Record(time.Since(item.queuedTime))
 }
 
+// assignLogID gives the record the ID it is linked by once stored. IDs are always assigned
+// here, never taken from the client, so that a sender cannot choose or collide with them.
+func assignLogID(log *model.LogRecord) {
+	log.ID = model.NewLogIDFor(log)
+}
+
 func (sp *logProcessor) addCollectorTags(span *model.LogRecord) {
 	if len(sp.collectorTags) == 0 {
 		return
@@ -209,6 +209,8 @@ func newCountsByTransport(factory metrics.Factory, format processor.LogFormat) S
 	return SpanCountsByTransport{
 		processor.HTTPTransport:    newCounts(factory, processor.HTTPTransport),
 		processor.GRPCTransport:    newCounts(factory, processor.GRPCTransport),
+		processor.TCPTransport:     newCounts(factory, processor.TCPTransport),
+		processor.UDPTransport:     newCounts(factory, processor.UDPTransport),
 		processor.UnknownTransport: newCounts(factory, processor.UnknownTransport),
 	}
 }
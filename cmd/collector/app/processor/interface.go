@@ -46,6 +46,10 @@ const (
 	GRPCTransport InboundTransport = "grpc"
 	// HTTPTransport indicates spans received over HTTP.
 	HTTPTransport InboundTransport = "http"
+	// TCPTransport indicates logs received over a plain or TLS TCP stream.
+	TCPTransport InboundTransport = "tcp"
+	// UDPTransport indicates logs received in UDP datagrams.
+	UDPTransport InboundTransport = "udp"
 	// UnknownTransport is the fallback/catch-all category.
 	UnknownTransport InboundTransport = "unknown"
 )
@@ -62,6 +66,18 @@ const (
 	ProtoLogFormat LogFormat = "proto"
 	// OTLPLogFormat is for OpenTelemetry OTLP format.
 	OTLPLogFormat LogFormat = "otlp"
+	// SyslogLogFormat is for RFC 5424 and RFC 3164 syslog messages.
+	SyslogLogFormat LogFormat = "syslog"
+	// FluentLogFormat is for Fluentd and Fluent Bit Forward protocol events.
+	FluentLogFormat LogFormat = "fluent"
+	// GELFLogFormat is for Graylog Extended Log Format messages.
+	GELFLogFormat LogFormat = "gelf"
+	// LokiLogFormat is for Loki push API streams.
+	LokiLogFormat LogFormat = "loki"
+	// ElasticsearchLogFormat is for documents of Elasticsearch bulk requests.
+	ElasticsearchLogFormat LogFormat = "elasticsearch"
+	// HECLogFormat is for Splunk HTTP Event Collector events.
+	HECLogFormat LogFormat = "hec"
 	// UnknownSpanFormat is the fallback/catch-all category.
 	UnknownLogFormat LogFormat = "unknown"
 )
@@ -0,0 +1,141 @@
+package server
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"time"
+
+	"go.uber.org/zap"
+
+	"logger/cmd/collector/app/flags"
+	"logger/cmd/collector/app/handler"
+	"logger/cmd/collector/app/processor"
+	"logger/model/converter/fluent"
+	"logger/pkg/healthcheck"
+)
+
+// fluentHandshakeTimeout is how long a client has to answer the HELO of the receiver
+const fluentHandshakeTimeout = 10 * time.Second
+
+// FluentServerParams to construct a new Fluent Forward protocol receiver.
+type FluentServerParams struct {
+	flags.FluentOptions
+	Handler     *handler.FluentHandler
+	HealthCheck *healthcheck.HealthCheck
+	Logger      *zap.Logger
+
+	// set by StartFluentServer
+	listenAddr net.Addr
+}
+
+// FluentServer receives Fluent Forward protocol messages from Fluentd and Fluent Bit.
+type FluentServer struct {
+	params   *FluentServerParams
+	hostname string
+	stream   *streamServer
+}
+
+// StartFluentServer starts listening for Forward protocol connections, without blocking.
+func StartFluentServer(params *FluentServerParams) (*FluentServer, error) {
+	hostname := params.SelfHostname
+	if hostname == "" {
+		hostname, _ = os.Hostname()
+	}
+	params.Logger.Info("Starting Fluent Forward receiver", zap.String("fluent.host-port", params.HostPort))
+	listener, err := listenStream("Fluent Forward receiver", params.HostPort, &params.TLS, params.Logger)
+	if err != nil {
+		return nil, err
+	}
+	params.listenAddr = listener.Addr()
+	s := &FluentServer{
+		params:   params,
+		hostname: hostname,
+		stream:   newStreamServer("Fluent Forward receiver", listener, params.Logger, params.HealthCheck),
+	}
+	s.stream.serve(s.serveConn)
+	return s, nil
+}
+
+func (s *FluentServer) serveConn(conn net.Conn) {
+	logger := s.params.Logger.With(zap.Stringer("remote", conn.RemoteAddr()))
+	decoder := fluent.NewDecoder(bufio.NewReader(conn), s.params.MaxMessageSize)
+	if s.params.SharedKey != "" {
+		if err := s.handshake(conn, decoder); err != nil {
+			logger.Debug("Fluent Forward handshake failed", zap.Error(err))
+			return
+		}
+	}
+	var ack []byte
+	for {
+		v, err := decoder.Decode()
+		if err != nil {
+			if !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) {
+				// the stream cannot be read past a malformed value
+				logger.Debug("Closing Fluent Forward connection", zap.Error(err))
+			}
+			return
+		}
+		m, err := fluent.ParseMessage(v, s.params.MaxMessageSize)
+		if err != nil {
+			logger.Debug("Dropping Fluent Forward message", zap.Error(err))
+			continue
+		}
+		if err := s.params.Handler.HandleMessage(m, processor.TCPTransport); err != nil {
+			// without an ack the client sends the chunk again
+			logger.Debug("Dropping Fluent Forward message", zap.String("tag", m.Tag), zap.Error(err))
+			continue
+		}
+		if m.Option.Chunk != "" {
+			ack = fluent.AppendAck(ack[:0], m.Option.Chunk)
+			if _, err := conn.Write(ack); err != nil {
+				logger.Debug("Cannot acknowledge Fluent Forward chunk", zap.Error(err))
+				return
+			}
+		}
+	}
+}
+
+// handshake authenticates the client with the shared key, as HELO, PING and PONG messages.
+func (s *FluentServer) handshake(conn net.Conn, decoder *fluent.Decoder) error {
+	if err := conn.SetDeadline(time.Now().Add(fluentHandshakeTimeout)); err != nil {
+		return err
+	}
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	nonceText := hex.EncodeToString(nonce)
+	if _, err := conn.Write(fluent.AppendHelo(nil, nonceText)); err != nil {
+		return err
+	}
+	v, err := decoder.Decode()
+	if err != nil {
+		return err
+	}
+	ping, err := fluent.ParsePing(v)
+	if err != nil {
+		return err
+	}
+	expected := fluent.SharedKeyDigest(ping.SharedKeySalt, ping.Hostname, nonceText, s.params.SharedKey)
+	if subtle.ConstantTimeCompare([]byte(expected), []byte(ping.SharedKeyDigest)) != 1 {
+		_, _ = conn.Write(fluent.AppendPong(nil, false, "shared_key mismatch", s.hostname, ""))
+		return fmt.Errorf("shared key mismatch for %s", ping.Hostname)
+	}
+	digest := fluent.SharedKeyDigest(ping.SharedKeySalt, s.hostname, nonceText, s.params.SharedKey)
+	if _, err := conn.Write(fluent.AppendPong(nil, true, "", s.hostname, digest)); err != nil {
+		return err
+	}
+	return conn.SetDeadline(time.Time{})
+}
+
+// Close stops listening, closes the open connections and waits for the messages being handled.
+func (s *FluentServer) Close() error {
+	return s.stream.close()
+}
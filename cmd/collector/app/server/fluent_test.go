@@ -0,0 +1,135 @@
+package server
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"logger/cmd/collector/app/flags"
+	"logger/cmd/collector/app/handler"
+	"logger/cmd/collector/app/processor"
+	"logger/model/converter/fluent"
+)
+
+// msgpackStr encodes a str of less than 256 bytes.
+func msgpackStr(s string) []byte {
+	if len(s) <= 31 {
+		return append([]byte{0xa0 | byte(len(s))}, s...)
+	}
+	return append([]byte{0xd9, byte(len(s))}, s...)
+}
+
+// forwardMessage encodes [tag, time, {"log": line}, {"chunk": chunk}] in msgpack.
+func forwardMessage(tag, line, chunk string) []byte {
+	b := []byte{0x94}
+	b = append(b, msgpackStr(tag)...)
+	b = append(b, 0xce, 0x65, 0x53, 0xf1, 0x00)
+	b = append(b, 0x81)
+	b = append(b, msgpackStr("log")...)
+	b = append(b, msgpackStr(line)...)
+	b = append(b, 0x81)
+	b = append(b, msgpackStr("chunk")...)
+	return append(b, msgpackStr(chunk)...)
+}
+
+func pingMessage(hostname, salt, digest string) []byte {
+	b := []byte{0x96}
+	for _, s := range []string{"PING", hostname, salt, digest, "", ""} {
+		b = append(b, msgpackStr(s)...)
+	}
+	return b
+}
+
+func startTestFluentServer(t *testing.T, logs processor.LogProcessor, sharedKey string) (net.Conn, *fluent.Decoder) {
+	params := &FluentServerParams{
+		FluentOptions: flags.FluentOptions{
+			HostPort:       "127.0.0.1:0",
+			SharedKey:      sharedKey,
+			SelfHostname:   "collector",
+			MaxMessageSize: 1024,
+		},
+		Handler: handler.NewFluentHandler(zap.NewNop(), logs),
+		Logger:  zap.NewNop(),
+	}
+	server, err := StartFluentServer(params)
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, server.Close()) })
+
+	conn, err := net.Dial("tcp", params.listenAddr.String())
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	require.NoError(t, conn.SetDeadline(time.Now().Add(5*time.Second)))
+	return conn, fluent.NewDecoder(conn, 1024)
+}
+
+func handshake(t *testing.T, conn net.Conn, decoder *fluent.Decoder, sharedKey string) []interface{} {
+	helo, err := decoder.Decode()
+	require.NoError(t, err)
+	require.Equal(t, "HELO", helo.([]interface{})[0])
+	options := helo.([]interface{})[1].(fluent.Map)
+	nonce, ok := options.Get("nonce")
+	require.True(t, ok)
+
+	digest := fluent.SharedKeyDigest("salt", "fluent-bit", nonce.(string), sharedKey)
+	_, err = conn.Write(pingMessage("fluent-bit", "salt", digest))
+	require.NoError(t, err)
+	pong, err := decoder.Decode()
+	require.NoError(t, err)
+	res := pong.([]interface{})
+	require.Len(t, res, 5)
+	if res[1] == true {
+		assert.Equal(t, fluent.SharedKeyDigest("salt", "collector", nonce.(string), "secret"), res[4])
+	}
+	return res
+}
+
+func TestFluentServerAcknowledgesChunks(t *testing.T) {
+	logs := &recordingLogProcessor{}
+	conn, decoder := startTestFluentServer(t, logs, "secret")
+
+	pong := handshake(t, conn, decoder, "secret")
+	assert.Equal(t, []interface{}{"PONG", true, "", "collector"}, pong[:4])
+
+	_, err := conn.Write(forwardMessage("app.web", "hello", "c1"))
+	require.NoError(t, err)
+	ack, err := decoder.Decode()
+	require.NoError(t, err)
+	assert.Equal(t, fluent.Map{{Key: "ack", Value: "c1"}}, ack)
+
+	bodies, transports := logs.received()
+	assert.Equal(t, []string{"hello"}, bodies)
+	assert.Equal(t, []processor.InboundTransport{processor.TCPTransport}, transports)
+}
+
+func TestFluentServerRejectsWrongSharedKey(t *testing.T) {
+	logs := &recordingLogProcessor{}
+	conn, decoder := startTestFluentServer(t, logs, "secret")
+
+	pong := handshake(t, conn, decoder, "wrong")
+	assert.Equal(t, []interface{}{"PONG", false, "shared_key mismatch", "collector", ""}, pong)
+
+	// the connection is closed
+	_, err := decoder.Decode()
+	assert.Error(t, err)
+}
+
+func TestFluentServerDoesNotAcknowledgeRejectedChunks(t *testing.T) {
+	logs := &recordingLogProcessor{rejectBody: "dropped"}
+	conn, decoder := startTestFluentServer(t, logs, "")
+
+	_, err := conn.Write(forwardMessage("app.web", "dropped", "c1"))
+	require.NoError(t, err)
+	// a malformed message is skipped, the stream goes on
+	_, err = conn.Write(msgpackStr("not a message"))
+	require.NoError(t, err)
+	_, err = conn.Write(forwardMessage("app.web", "kept", "c2"))
+	require.NoError(t, err)
+
+	ack, err := decoder.Decode()
+	require.NoError(t, err)
+	assert.Equal(t, fluent.Map{{Key: "ack", Value: "c2"}}, ack)
+}
@@ -0,0 +1,154 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"logger/cmd/collector/app/flags"
+	"logger/cmd/collector/app/handler"
+	"logger/cmd/collector/app/processor"
+	"logger/model/converter/gelf"
+	"logger/pkg/healthcheck"
+)
+
+const (
+	// maxDatagramSize is the largest UDP payload, chunked or not
+	maxDatagramSize = 65535
+	// maxPendingChunkedSize bounds the memory held by incomplete chunked messages, in bytes
+	maxPendingChunkedSize = 64 * 1024 * 1024
+)
+
+// GELFServerParams to construct a new GELF receiver.
+type GELFServerParams struct {
+	flags.GELFOptions
+	Handler     *handler.GELFHandler
+	HealthCheck *healthcheck.HealthCheck
+	Logger      *zap.Logger
+
+	// set by StartGELFServer
+	udpAddr net.Addr
+	tcpAddr net.Addr
+}
+
+// GELFServer receives GELF messages in UDP datagrams, possibly chunked and compressed,
+// and in null-delimited TCP streams.
+type GELFServer struct {
+	params    *GELFServerParams
+	udpConn   net.PacketConn
+	assembler *gelf.Assembler
+	tcp       *streamServer
+	done      chan struct{}
+	wg        sync.WaitGroup
+}
+
+// StartGELFServer starts listening on the UDP and TCP host-ports that are set, without blocking.
+func StartGELFServer(params *GELFServerParams) (*GELFServer, error) {
+	s := &GELFServer{params: params, done: make(chan struct{})}
+	if params.UDPHostPort != "" {
+		params.Logger.Info("Starting GELF UDP receiver", zap.String("gelf.udp.host-port", params.UDPHostPort))
+		conn, err := net.ListenPacket("udp", params.UDPHostPort)
+		if err != nil {
+			return nil, fmt.Errorf("failed to listen on GELF UDP port: %w", err)
+		}
+		s.udpConn = conn
+		s.assembler = gelf.NewAssembler(params.ChunkTimeout, params.MaxMessageSize, maxPendingChunkedSize)
+		params.udpAddr = conn.LocalAddr()
+	}
+	if params.TCPHostPort != "" {
+		params.Logger.Info("Starting GELF TCP receiver", zap.String("gelf.tcp.host-port", params.TCPHostPort))
+		listener, err := listenStream("GELF TCP receiver", params.TCPHostPort, &params.TLS, params.Logger)
+		if err != nil {
+			s.Close()
+			return nil, err
+		}
+		s.tcp = newStreamServer("GELF TCP receiver", listener, params.Logger, params.HealthCheck)
+		params.tcpAddr = listener.Addr()
+	}
+	if s.udpConn != nil {
+		s.wg.Add(2)
+		go s.serveUDP()
+		go s.expireChunks()
+	}
+	if s.tcp != nil {
+		s.tcp.serve(s.serveConn)
+	}
+	return s, nil
+}
+
+func (s *GELFServer) serveUDP() {
+	defer s.wg.Done()
+	buf := make([]byte, maxDatagramSize)
+	for {
+		n, _, err := s.udpConn.ReadFrom(buf)
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				serveFailed(s.params.Logger, s.params.HealthCheck, "GELF UDP receiver", err)
+			}
+			return
+		}
+		data := buf[:n]
+		if gelf.IsChunk(data) {
+			data, err = s.assembler.Add(data, time.Now())
+			if err != nil {
+				s.params.Logger.Debug("Dropping GELF chunk", zap.Error(err))
+				continue
+			}
+		}
+		s.handle(data, processor.UDPTransport)
+	}
+}
+
+// expireChunks drops the chunked messages that did not complete in time.
+func (s *GELFServer) expireChunks() {
+	defer s.wg.Done()
+	ticker := time.NewTicker(s.params.ChunkTimeout)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			if n := s.assembler.Expire(now); n > 0 {
+				s.params.Logger.Debug("Dropping incomplete GELF chunked messages", zap.Int("count", n))
+			}
+		case <-s.done:
+			return
+		}
+	}
+}
+
+func (s *GELFServer) serveConn(conn net.Conn) {
+	scanner := gelf.NewScanner(conn, s.params.MaxMessageSize)
+	for scanner.Scan() {
+		s.handle(scanner.Bytes(), processor.TCPTransport)
+	}
+	if err := scanner.Err(); err != nil && !errors.Is(err, net.ErrClosed) {
+		s.params.Logger.Debug("Closing GELF connection", zap.Stringer("remote", conn.RemoteAddr()), zap.Error(err))
+	}
+}
+
+func (s *GELFServer) handle(data []byte, transport processor.InboundTransport) {
+	if len(data) == 0 {
+		return
+	}
+	if err := s.params.Handler.HandleMessage(data, transport); err != nil {
+		s.params.Logger.Debug("Dropping GELF message", zap.String("transport", string(transport)), zap.Error(err))
+	}
+}
+
+// Close stops listening, closes the open connections and waits for the messages being handled.
+func (s *GELFServer) Close() error {
+	var errs []error
+	close(s.done)
+	if s.udpConn != nil {
+		errs = append(errs, s.udpConn.Close())
+	}
+	if s.tcp != nil {
+		errs = append(errs, s.tcp.close())
+	}
+	s.wg.Wait()
+	return errors.Join(errs...)
+}
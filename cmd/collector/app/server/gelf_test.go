@@ -0,0 +1,100 @@
+package server
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"logger/cmd/collector/app/flags"
+	"logger/cmd/collector/app/handler"
+	"logger/cmd/collector/app/processor"
+)
+
+func startTestGELFServer(t *testing.T, logs processor.LogProcessor) *GELFServerParams {
+	params := &GELFServerParams{
+		GELFOptions: flags.GELFOptions{
+			UDPHostPort:    "127.0.0.1:0",
+			TCPHostPort:    "127.0.0.1:0",
+			MaxMessageSize: 128,
+			ChunkTimeout:   time.Second,
+		},
+		Handler: handler.NewGELFHandler(zap.NewNop(), logs, 128),
+		Logger:  zap.NewNop(),
+	}
+	server, err := StartGELFServer(params)
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, server.Close()) })
+	return params
+}
+
+func gelfChunks(id byte, data []byte, size int) [][]byte {
+	var chunks [][]byte
+	count := (len(data) + size - 1) / size
+	for i := 0; i < count; i++ {
+		header := []byte{0x1e, 0x0f, id, 0, 0, 0, 0, 0, 0, 0, byte(i), byte(count)}
+		chunks = append(chunks, append(header, data[i*size:min((i+1)*size, len(data))]...))
+	}
+	return chunks
+}
+
+func TestGELFServerUDP(t *testing.T) {
+	logs := &recordingLogProcessor{}
+	params := startTestGELFServer(t, logs)
+
+	conn, err := net.Dial("udp", params.udpAddr.String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.Write([]byte(`{"version":"1.1","host":"web-1","short_message":"plain"}`))
+	require.NoError(t, err)
+
+	var compressed bytes.Buffer
+	gw := gzip.NewWriter(&compressed)
+	gw.Write([]byte(`{"version":"1.1","host":"web-1","short_message":"chunked and compressed"}`))
+	require.NoError(t, gw.Close())
+	chunks := gelfChunks(1, compressed.Bytes(), 16)
+	require.Greater(t, len(chunks), 1)
+	// chunks may arrive out of order
+	for i := len(chunks) - 1; i >= 0; i-- {
+		_, err = conn.Write(chunks[i])
+		require.NoError(t, err)
+	}
+
+	_, err = conn.Write([]byte(`{"short_message":"` + string(bytes.Repeat([]byte("x"), 128)) + `"}`))
+	require.NoError(t, err)
+	_, err = conn.Write([]byte(`{"short_message":"last"}`))
+	require.NoError(t, err)
+
+	assert.Eventually(t, func() bool {
+		bodies, _ := logs.received()
+		return len(bodies) == 3
+	}, 5*time.Second, 10*time.Millisecond)
+	bodies, transports := logs.received()
+	assert.Equal(t, []string{"plain", "chunked and compressed", "last"}, bodies)
+	assert.Equal(t, processor.UDPTransport, transports[0])
+}
+
+func TestGELFServerTCP(t *testing.T) {
+	logs := &recordingLogProcessor{}
+	params := startTestGELFServer(t, logs)
+
+	conn, err := net.Dial("tcp", params.tcpAddr.String())
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte("{\"short_message\":\"one\"}\x00not json\x00{\"short_message\":\"two\"}\x00"))
+	require.NoError(t, err)
+
+	assert.Eventually(t, func() bool {
+		bodies, _ := logs.received()
+		return len(bodies) == 2
+	}, 5*time.Second, 10*time.Millisecond)
+	bodies, transports := logs.received()
+	assert.Equal(t, []string{"one", "two"}, bodies)
+	assert.Equal(t, processor.TCPTransport, transports[0])
+}
@@ -0,0 +1,86 @@
+package server
+
+import (
+	"fmt"
+	"net"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/reflection"
+
+	"logger/cmd/collector/app/flags"
+	"logger/cmd/collector/app/handler"
+	pb "logger/model/proto/v1"
+	"logger/pkg/healthcheck"
+	"logger/pkg/tenancy"
+)
+
+const logsServiceName = "opentelemetry.proto.collector.logs.v1.LogsService"
+
+// GRPCServerParams to construct a new collector gRPC server.
+type GRPCServerParams struct {
+	flags.GRPCOptions
+	Handler     *handler.GRPCHandler
+	TenancyMgr  *tenancy.Manager
+	HealthCheck *healthcheck.HealthCheck
+	Logger      *zap.Logger
+
+	// set by StartGRPCServer
+	listenAddr net.Addr
+}
+
+// StartGRPCServer starts a gRPC server serving the OTLP LogsService, without blocking.
+func StartGRPCServer(params *GRPCServerParams) (*grpc.Server, error) {
+	var grpcOpts []grpc.ServerOption
+
+	if params.MaxReceiveMessageLength > 0 {
+		grpcOpts = append(grpcOpts, grpc.MaxRecvMsgSize(params.MaxReceiveMessageLength))
+	}
+	grpcOpts = append(grpcOpts, grpc.KeepaliveParams(keepalive.ServerParameters{
+		MaxConnectionAge:      params.MaxConnectionAge,
+		MaxConnectionAgeGrace: params.MaxConnectionAgeGrace,
+	}))
+	if params.TLS.Enabled {
+		tlsCfg, err := params.TLS.Config(params.Logger)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load TLS config for gRPC server: %w", err)
+		}
+		grpcOpts = append(grpcOpts, grpc.Creds(credentials.NewTLS(tlsCfg)))
+	}
+	if params.TenancyMgr != nil && params.TenancyMgr.Enabled {
+		grpcOpts = append(grpcOpts, grpc.UnaryInterceptor(tenancy.NewGuardingUnaryInterceptor(params.TenancyMgr)))
+	}
+
+	server := grpc.NewServer(grpcOpts...)
+	reflection.Register(server)
+
+	listener, err := net.Listen("tcp", params.HostPort)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on gRPC port: %w", err)
+	}
+	params.listenAddr = listener.Addr()
+	serveGRPC(server, listener, params)
+	return server, nil
+}
+
+func serveGRPC(server *grpc.Server, listener net.Listener, params *GRPCServerParams) {
+	pb.RegisterLogsServiceServer(server, params.Handler)
+
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus(logsServiceName, grpc_health_v1.HealthCheckResponse_SERVING)
+	grpc_health_v1.RegisterHealthServer(server, healthServer)
+
+	params.Logger.Info("Starting collector gRPC server", zap.String("grpc.host-port", params.HostPort))
+	go func() {
+		if err := server.Serve(listener); err != nil {
+			params.Logger.Error("Could not launch gRPC service", zap.Error(err))
+			if params.HealthCheck != nil {
+				params.HealthCheck.Set(healthcheck.Unavailable)
+			}
+		}
+	}()
+}
@@ -0,0 +1,105 @@
+package server
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"logger/cmd/collector/app/flags"
+	"logger/cmd/collector/app/handler"
+	"logger/cmd/collector/app/processor"
+	pbL "logger/model/proto/logs/v1"
+	pb "logger/model/proto/v1"
+	"logger/pkg/tenancy"
+)
+
+type recordingBatchesHandler struct {
+	mu      sync.Mutex
+	tenants []string
+	batches int
+	err     error
+}
+
+func (h *recordingBatchesHandler) SubmitBatches(batches []*pbL.ResourceLogs, opts handler.SubmitBatchOptions) ([]*handler.BatchSubmitResponse, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.tenants = append(h.tenants, opts.Tenant)
+	h.batches += len(batches)
+	return []*handler.BatchSubmitResponse{{Rejected: 1}}, h.err
+}
+
+func startTestGRPCServer(t *testing.T, batches handler.BatchesHandler, tm *tenancy.Manager) pb.LogsServiceClient {
+	params := &GRPCServerParams{
+		GRPCOptions: flags.GRPCOptions{HostPort: "127.0.0.1:0", MaxReceiveMessageLength: 1024},
+		Handler:     handler.NewGRPCHandler(zap.NewNop(), batches),
+		TenancyMgr:  tm,
+		Logger:      zap.NewNop(),
+	}
+	server, err := StartGRPCServer(params)
+	require.NoError(t, err)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.Dial(params.listenAddr.String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return pb.NewLogsServiceClient(conn)
+}
+
+func TestGRPCServerExport(t *testing.T) {
+	batches := &recordingBatchesHandler{}
+	client := startTestGRPCServer(t, batches, tenancy.NewManager(&tenancy.Options{}))
+
+	res, err := client.Export(context.Background(), &pb.ExportLogsServiceRequest{
+		ResourceLogs: []*pbL.ResourceLogs{{}, {}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, batches.batches)
+	assert.Equal(t, int64(1), res.GetPartialSuccess().GetRejectedLogRecords())
+	assert.Equal(t, []string{""}, batches.tenants)
+
+	// the max message size is honored
+	_, err = client.Export(context.Background(), &pb.ExportLogsServiceRequest{
+		ResourceLogs: make([]*pbL.ResourceLogs, 1024),
+	})
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+}
+
+func TestGRPCServerTenancy(t *testing.T) {
+	batches := &recordingBatchesHandler{}
+	tm := tenancy.NewManager(&tenancy.Options{Enabled: true, Tenants: []string{"acme"}})
+	client := startTestGRPCServer(t, batches, tm)
+
+	_, err := client.Export(context.Background(), &pb.ExportLogsServiceRequest{})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), tm.Header, "other")
+	_, err = client.Export(ctx, &pb.ExportLogsServiceRequest{})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	ctx = metadata.AppendToOutgoingContext(context.Background(), tm.Header, "acme")
+	_, err = client.Export(ctx, &pb.ExportLogsServiceRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"acme"}, batches.tenants)
+}
+
+func TestGRPCServerBusy(t *testing.T) {
+	client := startTestGRPCServer(t, &recordingBatchesHandler{err: processor.ErrBusy}, tenancy.NewManager(&tenancy.Options{}))
+
+	_, err := client.Export(context.Background(), &pb.ExportLogsServiceRequest{})
+	st := status.Convert(err)
+	assert.Equal(t, codes.ResourceExhausted, st.Code())
+	require.Len(t, st.Details(), 1)
+	retryInfo, ok := st.Details()[0].(*errdetails.RetryInfo)
+	require.True(t, ok)
+	assert.Positive(t, retryInfo.GetRetryDelay().AsDuration())
+}
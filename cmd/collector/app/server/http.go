@@ -15,37 +15,108 @@
 package server
 
 import (
-	"logger/cmd/collector/app/handler"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"time"
 
 	"github.com/savsgio/atreugo/v11"
+	"github.com/valyala/fasthttp"
 	"go.uber.org/zap"
-	// "go.uber.org/zap/zapcore"
+
+	"logger/cmd/collector/app/flags"
+	"logger/cmd/collector/app/handler"
+	"logger/pkg/config/corscfg"
+	"logger/pkg/healthcheck"
 )
 
+// unboundedReadTimeout stands for no body read timeout, since fasthttp cannot clear the
+// read deadline set for the header once it is received
+const unboundedReadTimeout = 24 * time.Hour
+
+// HttpServerParams to construct a new collector HTTP server.
 type HttpServerParams struct {
-	Handler handler.BatchesHandler
-	Logger *zap.Logger
-	HostPort string
+	flags.HTTPOptions
+	Handler              handler.BatchesHandler
+	LokiHandler          *handler.LokiHandler
+	ElasticsearchHandler *handler.ElasticsearchHandler
+	HECHandler           *handler.HECHandler
+	HealthCheck          *healthcheck.HealthCheck
+	Logger               *zap.Logger
+
+	// set by StartHTTPServer
+	listenAddr net.Addr
 }
 
-func StartHTTPServer(params *HttpServerParams)(error){
+// StartHTTPServer starts the collector HTTP server without blocking. The server is
+// returned so that it can be shut down.
+func StartHTTPServer(params *HttpServerParams) (*atreugo.Atreugo, error) {
 	params.Logger.Info("Starting collector HTTP server", zap.String("http host-port", params.HostPort))
-	// errorLog, _ := zap.NewStdLogAt(params.Logger, zapcore.ErrorLevel)
 
+	listener, err := net.Listen("tcp", params.HostPort)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on HTTP port: %w", err)
+	}
+	if params.TLS.Enabled {
+		// the TLS config reloads the certificates through the cert watcher of params.TLS
+		tlsCfg, err := params.TLS.Config(params.Logger)
+		if err != nil {
+			listener.Close()
+			return nil, fmt.Errorf("failed to load TLS config for HTTP server: %w", err)
+		}
+		listener = tls.NewListener(listener, tlsCfg)
+	}
+	params.listenAddr = listener.Addr()
+
+	server := atreugo.New(serverConfig(&params.HTTPOptions))
+	serveHttp(server, listener, params)
+	return server, nil
+}
+
+// serverConfig maps the options of an http.Server to fasthttp. fasthttp has a single
+// read timeout, so with a read header timeout the read timeout starts once the header is read.
+func serverConfig(opts *flags.HTTPOptions) atreugo.Config {
 	config := atreugo.Config{
-		Addr:      "0.0.0.0:8000",
-		TLSEnable: false,
+		ReadTimeout:        opts.ReadTimeout,
+		IdleTimeout:        opts.IdleTimeout,
+		MaxRequestBodySize: opts.MaxRequestSize,
 	}
-	server := atreugo.New(config)
-	serveHttp(server,params)
-	err := server.ListenAndServe();
-	if err != nil {
-     	params.Logger.Info("Fail to start collector HTTP server", zap.Error(err))
+	if opts.ReadHeaderTimeout > 0 {
+		config.ReadTimeout = opts.ReadHeaderTimeout
+		bodyTimeout := opts.ReadTimeout
+		if bodyTimeout <= 0 {
+			bodyTimeout = unboundedReadTimeout
+		}
+		config.HeaderReceived = func(*fasthttp.RequestHeader) fasthttp.RequestConfig {
+			return fasthttp.RequestConfig{ReadTimeout: bodyTimeout}
+		}
+	}
+	if config.IdleTimeout == 0 && opts.ReadTimeout > 0 {
+		// like http.Server, the idle timeout falls back to the read timeout, not the header one
+		config.IdleTimeout = opts.ReadTimeout
 	}
-	return err
+	return config
 }
 
-func serveHttp(server *atreugo.Atreugo,params *HttpServerParams){
-	apiHandler := handler.NewAPIHandler(params.Handler)
+func serveHttp(server *atreugo.Atreugo, listener net.Listener, params *HttpServerParams) {
+	server.UseBefore(corscfg.Middleware(params.CORS))
+	apiHandler := handler.NewAPIHandler(params.Handler, params.MaxRequestSize)
 	apiHandler.RegisterRoutes(server)
-}
\ No newline at end of file
+	if params.LokiHandler != nil {
+		params.LokiHandler.RegisterRoutes(server)
+	}
+	if params.ElasticsearchHandler != nil {
+		params.ElasticsearchHandler.RegisterRoutes(server)
+	}
+	if params.HECHandler != nil {
+		params.HECHandler.RegisterRoutes(server)
+	}
+	go func() {
+		if err := server.Serve(listener); err != nil {
+			params.Logger.Error("Could not start HTTP collector", zap.Error(err))
+			if params.HealthCheck != nil {
+				params.HealthCheck.Set(healthcheck.Unavailable)
+			}
+		}
+	}()
+}
@@ -0,0 +1,124 @@
+package server
+
+import (
+	"bytes"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"logger/cmd/collector/app/flags"
+	"logger/cmd/collector/app/handler"
+	"logger/model/converter/elasticsearch"
+	"logger/pkg/config/corscfg"
+)
+
+func TestStartHTTPServer(t *testing.T) {
+	batches := &recordingBatchesHandler{}
+	params := &HttpServerParams{
+		HTTPOptions: flags.HTTPOptions{
+			HostPort:          "127.0.0.1:0",
+			ReadHeaderTimeout: time.Second,
+			CORS:              corscfg.Options{AllowedOrigins: []string{"https://ui.example.com"}},
+		},
+		Handler: batches,
+		Logger:  zap.NewNop(),
+	}
+	server, err := StartHTTPServer(params)
+	require.NoError(t, err)
+	defer server.Shutdown()
+	url := "http://" + params.listenAddr.String() + "/v1/logs"
+
+	res, err := http.Post(url, "application/json", bytes.NewReader([]byte(`{"resourceLogs":[{}]}`)))
+	require.NoError(t, err)
+	res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, 1, batches.batches)
+
+	req, err := http.NewRequest(http.MethodOptions, url, nil)
+	require.NoError(t, err)
+	req.Header.Set("Origin", "https://ui.example.com")
+	req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+	res, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	res.Body.Close()
+	assert.Equal(t, http.StatusNoContent, res.StatusCode)
+	assert.Equal(t, "https://ui.example.com", res.Header.Get("Access-Control-Allow-Origin"))
+	http.DefaultClient.CloseIdleConnections()
+}
+
+func TestStartHTTPServerRoutes(t *testing.T) {
+	logs := &recordingLogProcessor{}
+	params := &HttpServerParams{
+		HTTPOptions:          flags.HTTPOptions{HostPort: "127.0.0.1:0"},
+		Handler:              &recordingBatchesHandler{},
+		LokiHandler:          handler.NewLokiHandler(zap.NewNop(), logs, []string{"job"}, 0),
+		ElasticsearchHandler: handler.NewElasticsearchHandler(zap.NewNop(), logs, elasticsearch.Mapping{MessageField: "message"}, 0),
+		HECHandler:           handler.NewHECHandler(zap.NewNop(), logs, map[string]flags.HECToken{"token": {}}, 0),
+		Logger:               zap.NewNop(),
+	}
+	server, err := StartHTTPServer(params)
+	require.NoError(t, err)
+	defer server.Shutdown()
+	defer http.DefaultClient.CloseIdleConnections()
+	url := "http://" + params.listenAddr.String()
+
+	tests := []struct {
+		method, path, contentType, body string
+		status                          int
+	}{
+		{http.MethodPost, "/v1/logs", "application/json", `{}`, http.StatusOK},
+		{http.MethodPost, "/loki/api/v1/push", "application/json", `{"streams":[{"stream":{"job":"a"},"values":[["1","loki"]]}]}`, http.StatusNoContent},
+		{http.MethodPost, "/_bulk", "application/x-ndjson", "{\"index\":{}}\n{\"message\":\"bulk\"}\n", http.StatusOK},
+		{http.MethodPut, "/logs/_bulk", "application/x-ndjson", "{\"index\":{}}\n{\"message\":\"index bulk\"}\n", http.StatusOK},
+		{http.MethodGet, "/", "", "", http.StatusOK},
+		{http.MethodGet, "/_license", "", "", http.StatusOK},
+		{http.MethodPost, "/services/collector/event", "application/json", `{"event":"hec"}`, http.StatusOK},
+		{http.MethodPost, "/services/collector/raw", "text/plain", "hec raw", http.StatusOK},
+		{http.MethodGet, "/services/collector/health", "", "", http.StatusOK},
+	}
+	for _, test := range tests {
+		req, err := http.NewRequest(test.method, url+test.path, bytes.NewReader([]byte(test.body)))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", test.contentType)
+		req.Header.Set("Authorization", "Splunk token")
+		res, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		res.Body.Close()
+		assert.Equal(t, test.status, res.StatusCode, test.path)
+	}
+	bodies, _ := logs.received()
+	assert.Equal(t, []string{"loki", "bulk", "index bulk", "hec", "hec raw"}, bodies)
+}
+
+func TestStartHTTPServerAddressInUse(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	_, err = StartHTTPServer(&HttpServerParams{HTTPOptions: flags.HTTPOptions{HostPort: listener.Addr().String()}, Logger: zap.NewNop()})
+	assert.Error(t, err)
+}
+
+func TestServerConfigTimeouts(t *testing.T) {
+	config := serverConfig(&flags.HTTPOptions{ReadTimeout: time.Minute, IdleTimeout: time.Hour, MaxRequestSize: 10})
+	assert.Equal(t, time.Minute, config.ReadTimeout)
+	assert.Equal(t, time.Hour, config.IdleTimeout)
+	assert.Equal(t, 10, config.MaxRequestBodySize)
+	assert.Nil(t, config.HeaderReceived)
+
+	config = serverConfig(&flags.HTTPOptions{ReadTimeout: time.Minute, ReadHeaderTimeout: time.Second})
+	assert.Equal(t, time.Second, config.ReadTimeout)
+	assert.Equal(t, time.Minute, config.IdleTimeout)
+	require.NotNil(t, config.HeaderReceived)
+	assert.Equal(t, time.Minute, config.HeaderReceived(nil).ReadTimeout)
+
+	config = serverConfig(&flags.HTTPOptions{ReadHeaderTimeout: time.Second})
+	assert.Equal(t, time.Second, config.ReadTimeout)
+	assert.Equal(t, time.Duration(0), config.IdleTimeout)
+	assert.Equal(t, unboundedReadTimeout, config.HeaderReceived(nil).ReadTimeout)
+}
@@ -21,5 +21,5 @@ import (
 )
 
 func TestMain(m *testing.M) {
-	testutils.VerifyGoLeaks(m)
+	testutils.VerifyGoLeaks(m, testutils.IgnoreFastHTTPLeak()...)
 }
@@ -0,0 +1,122 @@
+package server
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+
+	"go.uber.org/zap"
+
+	"logger/pkg/config/tlscfg"
+	"logger/pkg/healthcheck"
+)
+
+// streamServer accepts TCP connections and serves each of them in its own goroutine,
+// until it is closed. It backs the receivers of stream protocols such as syslog.
+type streamServer struct {
+	name        string
+	listener    net.Listener
+	logger      *zap.Logger
+	healthCheck *healthcheck.HealthCheck
+
+	mu     sync.Mutex
+	conns  map[net.Conn]struct{}
+	closed bool
+	wg     sync.WaitGroup
+}
+
+// listenStream listens on hostPort, over TLS when it is enabled.
+func listenStream(name, hostPort string, tlsOpts *tlscfg.Options, logger *zap.Logger) (net.Listener, error) {
+	listener, err := net.Listen("tcp", hostPort)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s port: %w", name, err)
+	}
+	if tlsOpts.Enabled {
+		tlsCfg, err := tlsOpts.Config(logger)
+		if err != nil {
+			listener.Close()
+			return nil, fmt.Errorf("failed to load TLS config for %s: %w", name, err)
+		}
+		listener = tls.NewListener(listener, tlsCfg)
+	}
+	return listener, nil
+}
+
+func newStreamServer(name string, listener net.Listener, logger *zap.Logger, healthCheck *healthcheck.HealthCheck) *streamServer {
+	return &streamServer{
+		name:        name,
+		listener:    listener,
+		logger:      logger,
+		healthCheck: healthCheck,
+		conns:       make(map[net.Conn]struct{}),
+	}
+}
+
+// serve accepts the connections without blocking, handing each to handle, which
+// returns once it is done with the connection. The connection is closed afterwards.
+func (s *streamServer) serve(handle func(net.Conn)) {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		for {
+			conn, err := s.listener.Accept()
+			if err != nil {
+				if !errors.Is(err, net.ErrClosed) {
+					serveFailed(s.logger, s.healthCheck, s.name, err)
+				}
+				return
+			}
+			if !s.track(conn) {
+				conn.Close()
+				return
+			}
+			s.wg.Add(1)
+			go func() {
+				defer s.wg.Done()
+				defer s.untrack(conn)
+				handle(conn)
+			}()
+		}
+	}()
+}
+
+func (s *streamServer) track(conn net.Conn) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return false
+	}
+	s.conns[conn] = struct{}{}
+	return true
+}
+
+func (s *streamServer) untrack(conn net.Conn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.conns, conn)
+	conn.Close()
+}
+
+// close stops listening, closes the open connections and waits for their handlers to return.
+func (s *streamServer) close() error {
+	s.mu.Lock()
+	s.closed = true
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mu.Unlock()
+
+	err := s.listener.Close()
+	s.wg.Wait()
+	return err
+}
+
+// serveFailed reports a receiver that stopped serving on its own.
+func serveFailed(logger *zap.Logger, healthCheck *healthcheck.HealthCheck, name string, err error) {
+	logger.Error("Could not serve "+name, zap.Error(err))
+	if healthCheck != nil {
+		healthCheck.Set(healthcheck.Unavailable)
+	}
+}
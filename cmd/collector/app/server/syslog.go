@@ -0,0 +1,121 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+
+	"go.uber.org/zap"
+
+	"logger/cmd/collector/app/flags"
+	"logger/cmd/collector/app/handler"
+	"logger/cmd/collector/app/processor"
+	"logger/model/converter/syslog"
+	"logger/pkg/healthcheck"
+)
+
+// SyslogServerParams to construct a new syslog receiver.
+type SyslogServerParams struct {
+	flags.SyslogOptions
+	Handler     *handler.SyslogHandler
+	HealthCheck *healthcheck.HealthCheck
+	Logger      *zap.Logger
+
+	// set by StartSyslogServer
+	udpAddr net.Addr
+	tcpAddr net.Addr
+}
+
+// SyslogServer receives syslog messages in UDP datagrams and TCP streams.
+type SyslogServer struct {
+	params  *SyslogServerParams
+	udpConn net.PacketConn
+	tcp     *streamServer
+	wg      sync.WaitGroup
+}
+
+// StartSyslogServer starts listening on the UDP and TCP host-ports that are set, without blocking.
+func StartSyslogServer(params *SyslogServerParams) (*SyslogServer, error) {
+	s := &SyslogServer{params: params}
+	if params.UDPHostPort != "" {
+		params.Logger.Info("Starting syslog UDP receiver", zap.String("syslog.udp.host-port", params.UDPHostPort))
+		conn, err := net.ListenPacket("udp", params.UDPHostPort)
+		if err != nil {
+			return nil, fmt.Errorf("failed to listen on syslog UDP port: %w", err)
+		}
+		s.udpConn = conn
+		params.udpAddr = conn.LocalAddr()
+	}
+	if params.TCPHostPort != "" {
+		params.Logger.Info("Starting syslog TCP receiver", zap.String("syslog.tcp.host-port", params.TCPHostPort))
+		listener, err := listenStream("syslog TCP receiver", params.TCPHostPort, &params.TLS, params.Logger)
+		if err != nil {
+			s.Close()
+			return nil, err
+		}
+		s.tcp = newStreamServer("syslog TCP receiver", listener, params.Logger, params.HealthCheck)
+		params.tcpAddr = listener.Addr()
+	}
+	if s.udpConn != nil {
+		s.wg.Add(1)
+		go s.serveUDP()
+	}
+	if s.tcp != nil {
+		s.tcp.serve(s.serveConn)
+	}
+	return s, nil
+}
+
+func (s *SyslogServer) serveUDP() {
+	defer s.wg.Done()
+	// one more byte than allowed tells a truncated datagram
+	buf := make([]byte, s.params.MaxMessageSize+1)
+	for {
+		n, _, err := s.udpConn.ReadFrom(buf)
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				serveFailed(s.params.Logger, s.params.HealthCheck, "syslog UDP receiver", err)
+			}
+			return
+		}
+		if n > s.params.MaxMessageSize {
+			s.params.Logger.Debug("Dropping syslog datagram larger than the max message size", zap.Int("size", n))
+			continue
+		}
+		s.handle(buf[:n], processor.UDPTransport)
+	}
+}
+
+func (s *SyslogServer) serveConn(conn net.Conn) {
+	scanner := syslog.NewScanner(conn, s.params.MaxMessageSize)
+	for scanner.Scan() {
+		s.handle(scanner.Bytes(), processor.TCPTransport)
+	}
+	if err := scanner.Err(); err != nil && !errors.Is(err, net.ErrClosed) {
+		// framing is lost, so is the rest of the stream
+		s.params.Logger.Debug("Closing syslog connection", zap.Stringer("remote", conn.RemoteAddr()), zap.Error(err))
+	}
+}
+
+func (s *SyslogServer) handle(data []byte, transport processor.InboundTransport) {
+	if len(data) == 0 {
+		return
+	}
+	if err := s.params.Handler.HandleMessage(data, transport); err != nil {
+		s.params.Logger.Debug("Dropping syslog message", zap.String("transport", string(transport)), zap.Error(err))
+	}
+}
+
+// Close stops listening, closes the open connections and waits for the messages being handled.
+func (s *SyslogServer) Close() error {
+	var errs []error
+	if s.udpConn != nil {
+		errs = append(errs, s.udpConn.Close())
+	}
+	if s.tcp != nil {
+		errs = append(errs, s.tcp.close())
+	}
+	s.wg.Wait()
+	return errors.Join(errs...)
+}
@@ -0,0 +1,128 @@
+package server
+
+import (
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"logger/cmd/collector/app/flags"
+	"logger/cmd/collector/app/handler"
+	"logger/cmd/collector/app/processor"
+	"logger/model"
+)
+
+type recordingLogProcessor struct {
+	// rejectBody makes the processor reject the logs with this body, like a full queue
+	rejectBody string
+	mu         sync.Mutex
+	logs       []*model.LogRecord
+	transports []processor.InboundTransport
+}
+
+func (p *recordingLogProcessor) ProcessLogs(logs []*model.LogRecord, opts processor.LogOptions) ([]bool, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	oks := make([]bool, len(logs))
+	for i, log := range logs {
+		p.logs = append(p.logs, log)
+		p.transports = append(p.transports, opts.InboundTransport)
+		oks[i] = p.rejectBody == "" || log.Body != p.rejectBody
+	}
+	return oks, nil
+}
+
+func (p *recordingLogProcessor) received() ([]string, []processor.InboundTransport) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	bodies := make([]string, len(p.logs))
+	for i, log := range p.logs {
+		bodies[i] = log.Body
+	}
+	return bodies, append([]processor.InboundTransport(nil), p.transports...)
+}
+
+func (*recordingLogProcessor) Close() error {
+	return nil
+}
+
+func startTestSyslogServer(t *testing.T, logs processor.LogProcessor) *SyslogServerParams {
+	params := &SyslogServerParams{
+		SyslogOptions: flags.SyslogOptions{
+			UDPHostPort:    "127.0.0.1:0",
+			TCPHostPort:    "127.0.0.1:0",
+			MaxMessageSize: 64,
+		},
+		Handler: handler.NewSyslogHandler(zap.NewNop(), logs),
+		Logger:  zap.NewNop(),
+	}
+	server, err := StartSyslogServer(params)
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, server.Close()) })
+	return params
+}
+
+func TestSyslogServerUDP(t *testing.T) {
+	logs := &recordingLogProcessor{}
+	params := startTestSyslogServer(t, logs)
+
+	conn, err := net.Dial("udp", params.udpAddr.String())
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte("<34>Oct 11 22:14:15 mymachine su: 'su root' failed"))
+	require.NoError(t, err)
+	_, err = conn.Write([]byte("<34>1 - - - - - - this message is larger than the max message size of the test"))
+	require.NoError(t, err)
+	_, err = conn.Write([]byte("<34>1 - host app - - - last"))
+	require.NoError(t, err)
+
+	assert.Eventually(t, func() bool {
+		bodies, _ := logs.received()
+		return len(bodies) == 2
+	}, 5*time.Second, 10*time.Millisecond)
+	bodies, transports := logs.received()
+	assert.Equal(t, []string{"'su root' failed", "last"}, bodies)
+	assert.Equal(t, []processor.InboundTransport{processor.UDPTransport, processor.UDPTransport}, transports)
+}
+
+func TestSyslogServerTCP(t *testing.T) {
+	logs := &recordingLogProcessor{}
+	params := startTestSyslogServer(t, logs)
+
+	conn, err := net.Dial("tcp", params.tcpAddr.String())
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte("<13>1 - - - - - - one\n26 <13>1 - - - - - - two\nand\n<13>1 - - - - - - three\n"))
+	require.NoError(t, err)
+
+	assert.Eventually(t, func() bool {
+		bodies, _ := logs.received()
+		return len(bodies) == 3
+	}, 5*time.Second, 10*time.Millisecond)
+	bodies, transports := logs.received()
+	assert.Equal(t, []string{"one", "two\nand", "three"}, bodies)
+	assert.Equal(t, processor.TCPTransport, transports[0])
+}
+
+func TestSyslogServerCloseWithOpenConnection(t *testing.T) {
+	params := &SyslogServerParams{
+		SyslogOptions: flags.SyslogOptions{TCPHostPort: "127.0.0.1:0", MaxMessageSize: 64},
+		Handler:       handler.NewSyslogHandler(zap.NewNop(), &recordingLogProcessor{}),
+		Logger:        zap.NewNop(),
+	}
+	server, err := StartSyslogServer(params)
+	require.NoError(t, err)
+	assert.Nil(t, params.udpAddr)
+
+	conn, err := net.Dial("tcp", params.tcpAddr.String())
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte("<13>1 - - - - - - partial"))
+	require.NoError(t, err)
+
+	require.NoError(t, server.Close())
+}
@@ -2,7 +2,7 @@ package main
 
 import (
 	"fmt"
-	// "io"
+	"io"
 	"log"
 	"logger/cmd/collector/app"
 	"logger/cmd/collector/app/flags"
@@ -97,20 +97,20 @@ func main(){
 				logger.Fatal("Failed to start collector", zap.Error(err))
 			}
 			// Wait for shutdown
-			// svc.RunAndThen(func() {
-			// 	if err := collector.Close(); err != nil {
-			// 		logger.Error("failed to cleanly close the collector", zap.Error(err))
-			// 	}
-			// 	if closer, ok := logWriter.(io.Closer); ok {
-			// 		err := closer.Close()
-			// 		if err != nil {
-			// 			logger.Error("failed to close span writer", zap.Error(err))
-			// 		}
-			// 	}
-			// 	if err := storageFactory.Close(); err != nil {
-			// 		logger.Error("Failed to close storage factory", zap.Error(err))
-			// 	}
-			// })
+			svc.RunAndThen(func() {
+				if err := collector.Close(); err != nil {
+					logger.Error("failed to cleanly close the collector", zap.Error(err))
+				}
+				if closer, ok := logWriter.(io.Closer); ok {
+					err := closer.Close()
+					if err != nil {
+						logger.Error("failed to close log writer", zap.Error(err))
+					}
+				}
+				if err := storageFactory.Close(); err != nil {
+					logger.Error("Failed to close storage factory", zap.Error(err))
+				}
+			})
 			return nil
 		},
 	}
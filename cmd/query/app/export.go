@@ -0,0 +1,314 @@
+package app
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"logger/model"
+	protoconv "logger/model/converter/proto"
+	v1 "logger/model/proto/v1"
+	"logger/storage/logstore"
+
+	"github.com/savsgio/atreugo/v11"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	formatParam     = "format"
+	attributesParam = "attributes"
+
+	ndjsonFormat = "ndjson"
+	csvFormat    = "csv"
+	otlpFormat   = "otlp"
+
+	// exportBufferSize is the number of records read ahead of the client
+	exportBufferSize = 256
+	// otlpChunkSize is the number of records in each ExportLogsServiceRequest written to the stream
+	otlpChunkSize = 100
+)
+
+var exportContentTypes = map[string]string{
+	ndjsonFormat: "application/x-ndjson",
+	csvFormat:    "text/csv; charset=utf-8",
+	otlpFormat:   "application/x-protobuf",
+}
+
+// csvColumns are written before the attribute columns selected by the attributes parameter.
+var csvColumns = []string{"id", "time", "observed_time", "severity_text", "severity_number", "service_name", "trace_id", "span_id", "body"}
+
+// logEncoder writes log records to an export stream in one of the supported formats.
+type logEncoder interface {
+	Encode(log *model.LogRecord) error
+	// Close writes whatever the encoder still holds; it does not close the underlying writer.
+	Close() error
+}
+
+// ExportLogs streams every log matching the query in the request body, encoded as
+// NDJSON, CSV or a sequence of OTLP ExportLogsServiceRequest messages. With explain=true,
+// which only NDJSON supports, the storage plan and statistics of the query follow the
+// records as a last {"explain":...} line.
+// Records are handed from the storage to the client as they are read, so the size of
+// an export is not bound by memory. A query failing before the first record is answered
+// with an error status; one failing later closes the connection before the end of the
+// chunked response, so that a truncated export is never mistaken for a complete one.
+func (aH *APIHandler) ExportLogs(c *atreugo.RequestCtx) error {
+	var query logstore.LogQueryParameters
+	if err := json.Unmarshal(c.PostBody(), &query); err != nil {
+		return c.JSONResponse(structuredError{
+			Msg:  err.Error(),
+			Code: http.StatusUnprocessableEntity,
+		}, http.StatusUnprocessableEntity)
+	}
+	format := queryParam(c, formatParam)
+	if format == "" {
+		format = ndjsonFormat
+	}
+	contentType, ok := exportContentTypes[format]
+	if !ok {
+		return c.JSONResponse(structuredError{
+			Msg:  fmt.Sprintf("unsupported export format %q", format),
+			Code: http.StatusBadRequest,
+		}, http.StatusBadRequest)
+	}
+	var attributes []string
+	if value := queryParam(c, attributesParam); value != "" {
+		attributes = strings.Split(value, ",")
+	}
+	ctx, stats, err := explainContext(c)
+	if err != nil {
+		return c.JSONResponse(structuredError{
+			Msg:  err.Error(),
+			Code: http.StatusBadRequest,
+		}, http.StatusBadRequest)
+	}
+	if stats != nil && format != ndjsonFormat {
+		return c.JSONResponse(structuredError{
+			Msg:  fmt.Sprintf("explain is not supported by the %s export format", format),
+			Code: http.StatusBadRequest,
+		}, http.StatusBadRequest)
+	}
+
+	// the stream writer must not touch the request, so the query runs on its own context
+	ctx, cancel := context.WithCancel(ctx)
+	logs := make(chan *model.LogRecord, exportBufferSize)
+	errc := make(chan error, 1)
+	go func() {
+		defer close(logs)
+		errc <- aH.queryService.StreamLogs(ctx, query, func(log *model.LogRecord) error {
+			select {
+			case logs <- log:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+	}()
+
+	// wait for the first record so that a failing query is still reported with an error status
+	first, ok := <-logs
+	if !ok {
+		if err := <-errc; err != nil {
+			cancel()
+			aH.logger.Error("ExportLogs", zap.Error(err))
+			return queryErrorResponse(c, err)
+		}
+	}
+
+	logger := aH.logger
+	conn := c.Conn()
+	c.SetContentType(contentType)
+	c.SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer cancel()
+		enc := newLogEncoder(format, attributes, w)
+		if stats != nil {
+			enc = &explainEncoder{logEncoder: enc, w: w, stats: stats}
+		}
+		if err := writeExport(enc, first, logs, errc); err != nil {
+			logger.Error("ExportLogs interrupted", zap.Error(err))
+			// the status is already sent, closing the connection before the last chunk
+			// is what tells the client that the export is truncated
+			_ = conn.Close()
+		}
+	})
+	return nil
+}
+
+// writeExport encodes first and the records read from logs, then checks the result of
+// the query sent on errc. It returns the error of the query or of the encoding, if any.
+// A nil first means the query returned nothing and its result was already checked.
+func writeExport(enc logEncoder, first *model.LogRecord, logs <-chan *model.LogRecord, errc <-chan error) error {
+	if first != nil {
+		if err := enc.Encode(first); err != nil {
+			return err
+		}
+		for log := range logs {
+			if err := enc.Encode(log); err != nil {
+				return err
+			}
+		}
+		// the result is sent before logs is closed
+		if err := <-errc; err != nil {
+			return err
+		}
+	}
+	return enc.Close()
+}
+
+func newLogEncoder(format string, attributes []string, w io.Writer) logEncoder {
+	switch format {
+	case csvFormat:
+		return &csvEncoder{w: csv.NewWriter(w), attributes: attributes}
+	case otlpFormat:
+		return &otlpEncoder{w: w}
+	default:
+		return &ndjsonEncoder{enc: json.NewEncoder(w)}
+	}
+}
+
+type ndjsonEncoder struct {
+	enc *json.Encoder
+}
+
+func (e *ndjsonEncoder) Encode(log *model.LogRecord) error {
+	return e.enc.Encode(log)
+}
+
+func (e *ndjsonEncoder) Close() error {
+	return nil
+}
+
+// explainEncoder writes the explain of the query once the records are written. It is
+// closed after the result of the query is checked, so the statistics are complete.
+type explainEncoder struct {
+	logEncoder
+	w     io.Writer
+	stats *logstore.QueryStats
+}
+
+func (e *explainEncoder) Close() error {
+	if err := e.logEncoder.Close(); err != nil {
+		return err
+	}
+	return json.NewEncoder(e.w).Encode(struct {
+		Explain *logstore.QueryExplain `json:"explain"`
+	}{Explain: e.stats.Explain()})
+}
+
+type csvEncoder struct {
+	w             *csv.Writer
+	attributes    []string
+	headerWritten bool
+}
+
+func (e *csvEncoder) writeHeader() error {
+	e.headerWritten = true
+	header := make([]string, 0, len(csvColumns)+len(e.attributes))
+	header = append(header, csvColumns...)
+	header = append(header, e.attributes...)
+	return e.w.Write(header)
+}
+
+func (e *csvEncoder) Encode(log *model.LogRecord) error {
+	if !e.headerWritten {
+		if err := e.writeHeader(); err != nil {
+			return err
+		}
+	}
+	serviceName := ""
+	if log.Process != nil {
+		serviceName = log.Process.ServiceName
+	}
+	row := make([]string, 0, len(csvColumns)+len(e.attributes))
+	id := ""
+	if !log.ID.IsZero() {
+		id = log.ID.String()
+	}
+	row = append(row,
+		id,
+		time.Unix(0, int64(log.TimeUnixNano)).UTC().Format(time.RFC3339Nano),
+		time.Unix(0, int64(log.ObservedTimeUnixNano)).UTC().Format(time.RFC3339Nano),
+		log.SeverityText,
+		strconv.Itoa(int(log.SeverityNumber)),
+		serviceName,
+		hex.EncodeToString(log.TraceId),
+		hex.EncodeToString(log.SpanId),
+		log.Body,
+	)
+	for _, key := range e.attributes {
+		row = append(row, attributeString(log, key))
+	}
+	return e.w.Write(row)
+}
+
+func (e *csvEncoder) Close() error {
+	if !e.headerWritten {
+		if err := e.writeHeader(); err != nil {
+			return err
+		}
+	}
+	e.w.Flush()
+	return e.w.Error()
+}
+
+// otlpEncoder writes the records as consecutive ExportLogsServiceRequest messages.
+// Concatenated encodings of a message merge their repeated fields, so the whole
+// stream decodes as a single request.
+type otlpEncoder struct {
+	w       io.Writer
+	pending []*model.LogRecord
+}
+
+func (e *otlpEncoder) Encode(log *model.LogRecord) error {
+	e.pending = append(e.pending, log)
+	if len(e.pending) < otlpChunkSize {
+		return nil
+	}
+	return e.flush()
+}
+
+func (e *otlpEncoder) Close() error {
+	if len(e.pending) == 0 {
+		return nil
+	}
+	return e.flush()
+}
+
+func (e *otlpEncoder) flush() error {
+	req := &v1.ExportLogsServiceRequest{
+		ResourceLogs: protoconv.FromDomainLogs(e.pending),
+	}
+	e.pending = e.pending[:0]
+	data, err := proto.Marshal(req)
+	if err != nil {
+		return err
+	}
+	_, err = e.w.Write(data)
+	return err
+}
+
+// attributeString looks key up in the record attributes, then in the process attributes.
+func attributeString(log *model.LogRecord, key string) string {
+	for _, kv := range log.Attributes {
+		if kv.Key == key {
+			return model.AnyValueString(kv.Value)
+		}
+	}
+	if log.Process != nil {
+		for _, kv := range log.Process.Attributes {
+			if kv.Key == key {
+				return model.AnyValueString(kv.Value)
+			}
+		}
+	}
+	return ""
+}
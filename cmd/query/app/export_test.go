@@ -0,0 +1,162 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valyala/fasthttp"
+	"google.golang.org/protobuf/proto"
+
+	"logger/model"
+	common "logger/model/proto/common/v1"
+	v1 "logger/model/proto/v1"
+	"logger/storage/logstore"
+)
+
+func exportedLog(i int) *model.LogRecord {
+	ts := time.Date(2024, time.March, 10, 12, 0, i, 0, time.UTC)
+	return &model.LogRecord{
+		ID:           model.NewLogID(ts),
+		TimeUnixNano: uint64(ts.UnixNano()),
+		SeverityText: "INFO",
+		Body:         "line, \"quoted\"",
+		TraceId:      []byte{1, 2},
+		Attributes:   []model.KeyValue{{Key: "region", Value: &common.AnyValue{Value: &common.AnyValue_StringValue{StringValue: "eu"}}}},
+		Process: &model.Process{
+			ServiceName: "svc",
+			Attributes:  []model.KeyValue{{Key: "host.name", Value: &common.AnyValue{Value: &common.AnyValue_StringValue{StringValue: "web-1"}}}},
+		},
+	}
+}
+
+func TestNDJSONEncoder(t *testing.T) {
+	var buf bytes.Buffer
+	enc := newLogEncoder(ndjsonFormat, nil, &buf)
+	second := exportedLog(1)
+	require.NoError(t, enc.Encode(exportedLog(0)))
+	require.NoError(t, enc.Encode(second))
+	require.NoError(t, enc.Close())
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	require.Len(t, lines, 2)
+	var log struct {
+		ID   model.LogID `json:"id"`
+		Body string      `json:"body"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &log))
+	assert.Equal(t, second.ID, log.ID)
+	assert.Equal(t, second.Body, log.Body)
+}
+
+func TestCSVEncoder(t *testing.T) {
+	var buf bytes.Buffer
+	log := exportedLog(0)
+	enc := newLogEncoder(csvFormat, []string{"region", "host.name", "missing"}, &buf)
+	require.NoError(t, enc.Encode(log))
+	require.NoError(t, enc.Close())
+	assert.Equal(t, "id,time,observed_time,severity_text,severity_number,service_name,trace_id,span_id,body,region,host.name,missing\n"+
+		log.ID.String()+`,2024-03-10T12:00:00Z,1970-01-01T00:00:00Z,INFO,0,svc,0102,,"line, ""quoted""",eu,web-1,`+"\n", buf.String())
+
+	// an empty export still has its header
+	buf.Reset()
+	require.NoError(t, newLogEncoder(csvFormat, nil, &buf).Close())
+	assert.Equal(t, strings.Join(csvColumns, ",")+"\n", buf.String())
+}
+
+func TestOTLPEncoder(t *testing.T) {
+	var buf bytes.Buffer
+	enc := newLogEncoder(otlpFormat, nil, &buf)
+	n := otlpChunkSize + otlpChunkSize/2
+	for i := 0; i < n; i++ {
+		require.NoError(t, enc.Encode(exportedLog(i)))
+	}
+	require.NoError(t, enc.Close())
+
+	// the chunks of the stream decode as a single request
+	var req v1.ExportLogsServiceRequest
+	require.NoError(t, proto.Unmarshal(buf.Bytes(), &req))
+	count := 0
+	for _, rl := range req.ResourceLogs {
+		for _, sl := range rl.ScopeLogs {
+			count += len(sl.LogRecords)
+		}
+	}
+	assert.Equal(t, n, count)
+}
+
+func TestExportLogs(t *testing.T) {
+	s := newTestServer(t, &fakeReader{logs: []*model.LogRecord{exportedLog(0), exportedLog(1)}}, &QueryOptions{})
+	req, resp := fasthttp.AcquireRequest(), fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseRequest(req)
+	defer fasthttp.ReleaseResponse(resp)
+	req.Header.SetMethod(http.MethodPost)
+	req.SetRequestURI("http://query/v1/logs/export?format=csv")
+	req.SetBodyString(`{"service_name":"svc"}`)
+	require.NoError(t, s.client.DoTimeout(req, resp, 5*time.Second))
+	assert.Equal(t, http.StatusOK, resp.StatusCode())
+	assert.Equal(t, "text/csv; charset=utf-8", string(resp.Header.ContentType()))
+	assert.Len(t, strings.Split(strings.TrimSpace(string(resp.Body())), "\n"), 3)
+}
+
+func TestExportLogsExplain(t *testing.T) {
+	s := newTestServer(t, &fakeReader{logs: []*model.LogRecord{exportedLog(0), exportedLog(1)}}, &QueryOptions{})
+	req, resp := fasthttp.AcquireRequest(), fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseRequest(req)
+	defer fasthttp.ReleaseResponse(resp)
+	req.Header.SetMethod(http.MethodPost)
+	req.SetRequestURI("http://query/v1/logs/export?explain=true")
+	req.SetBodyString(`{"service_name":"svc"}`)
+	require.NoError(t, s.client.DoTimeout(req, resp, 5*time.Second))
+	require.Equal(t, http.StatusOK, resp.StatusCode())
+
+	// the explain follows the records
+	lines := strings.Split(strings.TrimSpace(string(resp.Body())), "\n")
+	require.Len(t, lines, 3)
+	var last struct {
+		Explain *logstore.QueryExplain `json:"explain"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(lines[2]), &last))
+	require.NotNil(t, last.Explain)
+	assert.Equal(t, 2, last.Explain.RowsReturned)
+
+	var res structuredError
+	status := s.do(t, http.MethodPost, "/v1/logs/export?format=csv&explain=true", `{"service_name":"svc"}`, nil, &res)
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Contains(t, res.Msg, "explain")
+}
+
+func TestExportLogsErrors(t *testing.T) {
+	t.Run("before the first record", func(t *testing.T) {
+		s := newTestServer(t, &fakeReader{err: errors.New("unavailable")}, &QueryOptions{})
+		var res structuredError
+		status := s.do(t, http.MethodPost, "/v1/logs/export", `{"service_name":"svc"}`, nil, &res)
+		assert.Equal(t, http.StatusBadRequest, status)
+		assert.Equal(t, "unavailable", res.Msg)
+	})
+
+	t.Run("mid-stream", func(t *testing.T) {
+		// the first record is read before the query fails, so the status is already decided
+		s := newTestServer(t, &fakeReader{logs: []*model.LogRecord{exportedLog(0), exportedLog(1)}, err: errors.New("unavailable")}, &QueryOptions{})
+		req, resp := fasthttp.AcquireRequest(), fasthttp.AcquireResponse()
+		defer fasthttp.ReleaseRequest(req)
+		defer fasthttp.ReleaseResponse(resp)
+		req.Header.SetMethod(http.MethodPost)
+		req.SetRequestURI("http://query/v1/logs/export")
+		req.SetBodyString(`{"service_name":"svc"}`)
+		// the connection is closed before the end of the response, the client cannot take it for complete
+		assert.Error(t, s.client.DoTimeout(req, resp, 5*time.Second))
+	})
+
+	t.Run("unsupported format", func(t *testing.T) {
+		s := newTestServer(t, &fakeReader{}, &QueryOptions{})
+		status := s.do(t, http.MethodPost, "/v1/logs/export?format=xml", `{"service_name":"svc"}`, nil, nil)
+		assert.Equal(t, http.StatusBadRequest, status)
+	})
+}
@@ -29,13 +29,18 @@ import (
 	"github.com/spf13/viper"
 	"go.uber.org/zap"
 
-	// "logger/cmd/query/app/querysvc"
+	"logger/cmd/query/app/querysvc"
 	// "logger/model/adjuster"
+	"logger/pkg/bearertoken"
 	"logger/pkg/config"
+	"logger/pkg/config/corscfg"
 	"logger/pkg/config/tlscfg"
+	"logger/pkg/jtracer"
 	"logger/pkg/tenancy"
+	"logger/plugin/storage/memory"
 	"logger/ports"
-	// "logger/storage"
+	"logger/storage"
+	"logger/storage/logstore/caching"
 )
 
 const (
@@ -46,9 +51,31 @@ const (
 	queryLogStaticAssetsAccess = "query.log-static-assets-access"
 	queryUIConfig              = "query.ui-config"
 	queryTokenPropagation      = "query.bearer-token-propagation"
+	queryTokenHeader           = "query.bearer-token-header"
 	queryAdditionalHeaders     = "query.additional-headers"
 	queryMaxClockSkewAdjust    = "query.max-clock-skew-adjustment"
 	queryEnableTracing         = "query.enable-tracing"
+	queryTracingExporter       = "query.tracing.exporter"
+	queryTracingEndpoint       = "query.tracing.endpoint"
+	queryTracingInsecure       = "query.tracing.insecure"
+	queryCacheNamesTTL         = "query.cache.names-ttl"
+	queryCacheLogsTTL          = "query.cache.logs-ttl"
+	queryCacheMaxEntries       = "query.cache.max-entries"
+	queryCacheMaxEntryLogs     = "query.cache.max-entry-logs"
+	queryMaxRange              = "query.limits.max-range"
+	queryMaxResults            = "query.limits.max-results"
+	queryTimeout               = "query.limits.timeout"
+	queryLimitsFile            = "query.limits.file"
+	querySavedSearchesStorage  = "query.saved-searches.storage"
+)
+
+const (
+	// SavedSearchesInStorage stores the saved searches in the backend the logs are read from
+	SavedSearchesInStorage = "storage"
+	// SavedSearchesInMemory keeps the saved searches in memory, they are lost on restart
+	SavedSearchesInMemory = "memory"
+	// SavedSearchesDisabled disables the saved searches
+	SavedSearchesDisabled = "none"
 )
 
 var tlsGRPCFlagsConfig = tlscfg.ServerFlagsConfig{
@@ -59,6 +86,10 @@ var tlsHTTPFlagsConfig = tlscfg.ServerFlagsConfig{
 	Prefix: "query.http",
 }
 
+var corsFlags = corscfg.Flags{
+	Prefix: "query",
+}
+
 // QueryOptionsStaticAssets contains configuration for handling static assets
 type QueryOptionsStaticAssets struct {
 	// Path is the path for the static assets for the UI (https://github.com/uber/jaeger-ui)
@@ -78,14 +109,18 @@ type QueryOptionsBase struct {
 	UIConfig string `valid:"optional" mapstructure:"ui_config"`
 	// BearerTokenPropagation activate/deactivate bearer token propagation to storage
 	BearerTokenPropagation bool
+	// BearerTokenHeader is the header the propagated token is read from
+	BearerTokenHeader string
 	// AdditionalHeaders
 	AdditionalHeaders http.Header
 	// MaxClockSkewAdjust is the maximum duration by which jaeger-query will adjust a span
 	MaxClockSkewAdjust time.Duration
 	// Tenancy configures tenancy for query
 	Tenancy tenancy.Options
-	// EnableTracing determines whether traces will be emitted by jaeger-query.
+	// EnableTracing determines whether traces will be emitted by the query service.
 	EnableTracing bool
+	// Tracing configures where the traces of the query service are exported to
+	Tracing jtracer.Options
 }
 
 // QueryOptions holds configuration for query service
@@ -100,6 +135,16 @@ type QueryOptions struct {
 	TLSGRPC tlscfg.Options
 	// TLSHTTP configures secure transport (Consumer to Query service HTTP API)
 	TLSHTTP tlscfg.Options
+	// CORS configures the origins and headers allowed to call the HTTP API from a browser
+	CORS corscfg.Options
+	// Cache configures the caching of the results read from storage
+	Cache caching.Options
+	// Limits are the default guardrails of the queries
+	Limits querysvc.Limits
+	// LimitsFile is the path to a JSON file overriding the limits per tenant
+	LimitsFile string
+	// SavedSearchesStorage is where the saved searches are stored, SavedSearchesInMemory by default
+	SavedSearchesStorage string
 }
 
 // AddFlags adds flags for QueryOptions
@@ -112,10 +157,26 @@ func AddFlags(flagSet *flag.FlagSet) {
 	flagSet.Bool(queryLogStaticAssetsAccess, false, "Log when static assets are accessed (for debugging)")
 	flagSet.String(queryUIConfig, "", "The path to the UI configuration file in JSON format")
 	flagSet.Bool(queryTokenPropagation, false, "Allow propagation of bearer token to be used by storage plugins")
+	flagSet.String(queryTokenHeader, bearertoken.DefaultHeader, "The header the propagated bearer token is read from; the Authorization header must use the Bearer scheme, any other header holds the bare token")
 	flagSet.Duration(queryMaxClockSkewAdjust, 0, "The maximum delta by which span timestamps may be adjusted in the UI due to clock skew; set to 0s to disable clock skew adjustments")
-	flagSet.Bool(queryEnableTracing, false, "Enables emitting jaeger-query traces")
+	flagSet.Bool(queryEnableTracing, false, "Enables emitting the query service traces")
+	flagSet.String(queryTracingExporter, jtracer.ExporterOTLPHTTP, fmt.Sprintf("The exporter of the query service traces, %s or %s", jtracer.ExporterOTLPHTTP, jtracer.ExporterStdout))
+	flagSet.String(queryTracingEndpoint, "", "The host:port of the OTLP/HTTP receiver of the query service traces; by default the OTEL_EXPORTER_OTLP_ENDPOINT environment variable applies")
+	flagSet.Bool(queryTracingInsecure, true, "Send the query service traces to the OTLP/HTTP receiver without TLS, as the OpenTelemetry SDK does by default; set it to false to use TLS")
+	flagSet.Duration(queryCacheNamesTTL, 30*time.Second, "How long the service and operation names read from storage are cached; set to 0s to disable")
+	flagSet.Duration(queryCacheLogsTTL, 5*time.Minute, "How long the logs of a query over a time range already over are cached; set to 0s to disable")
+	flagSet.Int(queryCacheMaxEntries, 1000, "The maximum number of entries of each query cache")
+	flagSet.Int(queryCacheMaxEntryLogs, 100, "The largest number of logs of a query result that is cached, larger results are read from storage every time; set to 0 to cache results of any size")
+	flagSet.Duration(queryMaxRange, 7*24*time.Hour, "The longest time range a query may cover; set to 0s to disable")
+	flagSet.Int(queryMaxResults, 10000, "The largest number of records a query may ask for; set to 0 to disable")
+	flagSet.Duration(queryTimeout, 30*time.Second, "The time after which a query is cancelled; set to 0s to disable")
+	flagSet.String(queryLimitsFile, "", `The path to a JSON file overriding the limits per tenant, e.g. {"default": {"max_range": "72h"}, "tenants": {"acme": {"max_results": 50000, "timeout": "1m"}}}`)
+	flagSet.String(querySavedSearchesStorage, SavedSearchesInMemory, fmt.Sprintf("Where the saved searches are stored: %s, where they are lost on restart, %s for the log storage backend, or %s to disable them; "+
+		"Cassandra stores them in the saved_searches table of the v005 schema, added to older keyspaces by plugin/storage/cassandra/schema/migration/v004tov005.sh",
+		SavedSearchesInMemory, SavedSearchesInStorage, SavedSearchesDisabled))
 	tlsGRPCFlagsConfig.AddFlags(flagSet)
 	tlsHTTPFlagsConfig.AddFlags(flagSet)
+	corsFlags.AddFlags(flagSet)
 }
 
 // InitFromViper initializes QueryOptions with properties from viper
@@ -132,11 +193,13 @@ func (qOpts *QueryOptions) InitFromViper(v *viper.Viper, logger *zap.Logger) (*Q
 	} else {
 		return qOpts, fmt.Errorf("failed to process HTTP TLS options: %w", err)
 	}
+	qOpts.CORS = corsFlags.InitFromViper(v)
 	qOpts.BasePath = v.GetString(queryBasePath)
 	qOpts.StaticAssets.Path = v.GetString(queryStaticFiles)
 	qOpts.StaticAssets.LogAccess = v.GetBool(queryLogStaticAssetsAccess)
 	qOpts.UIConfig = v.GetString(queryUIConfig)
 	qOpts.BearerTokenPropagation = v.GetBool(queryTokenPropagation)
+	qOpts.BearerTokenHeader = v.GetString(queryTokenHeader)
 
 	qOpts.MaxClockSkewAdjust = v.GetDuration(queryMaxClockSkewAdjust)
 	stringSlice := v.GetStringSlice(queryAdditionalHeaders)
@@ -148,20 +211,52 @@ func (qOpts *QueryOptions) InitFromViper(v *viper.Viper, logger *zap.Logger) (*Q
 	}
 	qOpts.Tenancy = tenancy.InitFromViper(v)
 	qOpts.EnableTracing = v.GetBool(queryEnableTracing)
+	qOpts.Tracing = jtracer.Options{
+		Exporter: v.GetString(queryTracingExporter),
+		Endpoint: v.GetString(queryTracingEndpoint),
+		Insecure: v.GetBool(queryTracingInsecure),
+	}
+	qOpts.Limits = querysvc.Limits{
+		MaxRange:   querysvc.Duration(v.GetDuration(queryMaxRange)),
+		MaxResults: v.GetInt(queryMaxResults),
+		Timeout:    querysvc.Duration(v.GetDuration(queryTimeout)),
+	}
+	qOpts.LimitsFile = v.GetString(queryLimitsFile)
+	qOpts.SavedSearchesStorage = v.GetString(querySavedSearchesStorage)
+	qOpts.Cache = caching.Options{
+		NamesTTL:     v.GetDuration(queryCacheNamesTTL),
+		LogsTTL:      v.GetDuration(queryCacheLogsTTL),
+		MaxEntries:   v.GetInt(queryCacheMaxEntries),
+		MaxEntryLogs: v.GetInt(queryCacheMaxEntryLogs),
+	}
 	return qOpts, nil
 }
 
-// BuildQueryServiceOptions creates a QueryServiceOptions struct with appropriate adjusters and archive config
-// func (qOpts *QueryOptions) BuildQueryServiceOptions(storageFactory storage.Factory, logger *zap.Logger) *querysvc.QueryServiceOptions {
-// 	opts := &querysvc.QueryServiceOptions{}
-// 	if !opts.InitArchiveStorage(storageFactory, logger) {
-// 		logger.Info("Archive storage not initialized")
-// 	}
-
-// 	opts.Adjuster = adjuster.Sequence(querysvc.StandardAdjusters(qOpts.MaxClockSkewAdjust)...)
-
-// 	return opts
-// }
+// BuildQueryServiceOptions creates a QueryServiceOptions struct with the guardrails of the
+// queries and the saved search store
+func (qOpts *QueryOptions) BuildQueryServiceOptions(storageFactory storage.FactoryBase) (querysvc.QueryServiceOptions, error) {
+	limits, err := querysvc.LoadLimitsFile(qOpts.LimitsFile, qOpts.Limits)
+	if err != nil {
+		return querysvc.QueryServiceOptions{}, err
+	}
+	opts := querysvc.QueryServiceOptions{Limits: limits}
+	switch qOpts.SavedSearchesStorage {
+	case SavedSearchesInStorage:
+		ssFactory, ok := storageFactory.(storage.SavedSearchStoreFactory)
+		if !ok {
+			return opts, errors.New("the storage backend cannot store saved searches")
+		}
+		if opts.SavedSearches, err = ssFactory.CreateSavedSearchStore(); err != nil {
+			return opts, fmt.Errorf("failed to create saved search store: %w", err)
+		}
+	case SavedSearchesInMemory:
+		opts.SavedSearches = memory.NewSavedSearchStore()
+	case SavedSearchesDisabled:
+	default:
+		return opts, fmt.Errorf("invalid %s %q", querySavedSearchesStorage, qOpts.SavedSearchesStorage)
+	}
+	return opts, nil
+}
 
 // stringSliceAsHeader parses a slice of strings and returns a http.Header.
 // Each string in the slice is expected to be in the format "key: value"
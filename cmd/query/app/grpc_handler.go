@@ -0,0 +1,178 @@
+package app
+
+import (
+	"context"
+	"errors"
+
+	"logger/cmd/query/app/querysvc"
+	"logger/model"
+	protoconv "logger/model/converter/proto"
+	api_v1 "logger/model/proto/query/v1"
+	"logger/storage/logstore"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// maxChunkSize is the number of logs sent in each LogsResponseChunk
+const maxChunkSize = 100
+
+var errNilRequest = status.Error(codes.InvalidArgument, "a nil argument is not allowed")
+
+// GRPCHandler implements the gRPC QueryService API on top of querysvc.QueryService.
+type GRPCHandler struct {
+	api_v1.UnimplementedQueryServiceServer
+	queryService *querysvc.QueryService
+	logger       *zap.Logger
+}
+
+// NewGRPCHandler returns a GRPCHandler.
+func NewGRPCHandler(queryService *querysvc.QueryService, logger *zap.Logger) *GRPCHandler {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &GRPCHandler{
+		queryService: queryService,
+		logger:       logger,
+	}
+}
+
+// FindLogs streams the logs matching the query in chunks of maxChunkSize. A request
+// asking for explain gets the plan and statistics of the query in a last chunk.
+func (g *GRPCHandler) FindLogs(r *api_v1.FindLogsRequest, stream api_v1.QueryService_FindLogsServer) error {
+	query := r.GetQuery()
+	if query == nil {
+		return errNilRequest
+	}
+	// AsTime reads an unset bound as the epoch, which would silently query everything
+	if query.StartTimeMin == nil || query.StartTimeMax == nil {
+		return status.Error(codes.InvalidArgument, "start_time_min and start_time_max must be set")
+	}
+	order, err := logstore.ParseOrder(query.Order)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	params := logstore.LogQueryParameters{
+		ServiceName:    query.ServiceName,
+		OperationName:  query.OperationName,
+		StartTimeMin:   query.StartTimeMin.AsTime(),
+		StartTimeMax:   query.StartTimeMax.AsTime(),
+		NumTraces:      int(query.NumLogs),
+		SeverityNumber: int(query.SeverityNumber),
+		ShouldFetchAll: query.FetchAll,
+		Order:          order,
+	}
+	ctx := stream.Context()
+	var stats *logstore.QueryStats
+	if r.Explain {
+		stats = logstore.NewQueryStats()
+		ctx = logstore.WithQueryStats(ctx, stats)
+	}
+	err = sendLogChunks(stream, func(fn func(*model.LogRecord) error) error {
+		return g.queryService.StreamLogs(ctx, params, fn)
+	})
+	if err == nil && stats != nil {
+		err = stream.Send(&api_v1.LogsResponseChunk{Explain: toProtoExplain(stats.Explain())})
+	}
+	return g.handleErr("failed to find logs", err)
+}
+
+func toProtoExplain(explain *logstore.QueryExplain) *api_v1.QueryExplain {
+	res := &api_v1.QueryExplain{
+		Plan:              explain.Plan,
+		PartitionsTouched: int64(explain.PartitionsTouched),
+		RowsScanned:       int64(explain.RowsScanned),
+		RowsReturned:      int64(explain.RowsReturned),
+		RowsRejected:      int64(explain.RowsRejected),
+		Cache:             explain.Cache,
+		Indexes:           explain.Indexes,
+	}
+	for _, stage := range explain.Stages {
+		res.Stages = append(res.Stages, &api_v1.StageLatency{Name: stage.Name, DurationNs: int64(stage.Duration)})
+	}
+	return res
+}
+
+// GetServices returns the names of the services that have logs.
+func (g *GRPCHandler) GetServices(ctx context.Context, r *api_v1.GetServicesRequest) (*api_v1.GetServicesResponse, error) {
+	services, err := g.queryService.GetServices(ctx)
+	if err != nil {
+		return nil, g.handleErr("failed to fetch services", err)
+	}
+	return &api_v1.GetServicesResponse{Services: services}, nil
+}
+
+// GetOperations returns the operations of a service.
+func (g *GRPCHandler) GetOperations(ctx context.Context, r *api_v1.GetOperationsRequest) (*api_v1.GetOperationsResponse, error) {
+	operations, err := g.queryService.GetOperations(ctx, logstore.OperationQueryParameters{
+		ServiceName: r.GetService(),
+	})
+	if err != nil {
+		return nil, g.handleErr("failed to fetch operations", err)
+	}
+	res := make([]*api_v1.Operation, len(operations))
+	for i, operation := range operations {
+		res[i] = &api_v1.Operation{Name: operation.Name}
+	}
+	return &api_v1.GetOperationsResponse{Operations: res}, nil
+}
+
+// GetLogsByTrace streams the logs correlated with a trace in chunks of maxChunkSize.
+func (g *GRPCHandler) GetLogsByTrace(r *api_v1.GetLogsByTraceRequest, stream api_v1.QueryService_GetLogsByTraceServer) error {
+	if len(r.GetTraceId()) == 0 {
+		return status.Error(codes.InvalidArgument, "trace_id must be set")
+	}
+	err := sendLogChunks(stream, func(fn func(*model.LogRecord) error) error {
+		return g.queryService.StreamLogsByTrace(stream.Context(), r.GetTraceId(), fn)
+	})
+	return g.handleErr("failed to fetch logs by trace", err)
+}
+
+type logChunkSender interface {
+	Send(*api_v1.LogsResponseChunk) error
+}
+
+// sendLogChunks sends the logs streamed by read in chunks of maxChunkSize, as they are read.
+func sendLogChunks(stream logChunkSender, read func(fn func(*model.LogRecord) error) error) error {
+	chunk := make([]*model.LogRecord, 0, maxChunkSize)
+	err := read(func(log *model.LogRecord) error {
+		chunk = append(chunk, log)
+		if len(chunk) < maxChunkSize {
+			return nil
+		}
+		err := sendLogChunk(stream, chunk)
+		chunk = chunk[:0]
+		return err
+	})
+	if err == nil && len(chunk) > 0 {
+		err = sendLogChunk(stream, chunk)
+	}
+	return err
+}
+
+func sendLogChunk(stream logChunkSender, logs []*model.LogRecord) error {
+	return stream.Send(&api_v1.LogsResponseChunk{
+		ResourceLogs: protoconv.FromDomainLogs(logs),
+	})
+}
+
+func (g *GRPCHandler) handleErr(msg string, err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	if guardrailErr, ok := querysvc.AsGuardrailError(err); ok {
+		if guardrailErr.Guardrail == querysvc.GuardrailTimeout {
+			return status.Error(codes.DeadlineExceeded, guardrailErr.Error())
+		}
+		return status.Error(codes.InvalidArgument, guardrailErr.Error())
+	}
+	if errors.Is(err, context.Canceled) {
+		return status.Error(codes.Canceled, err.Error())
+	}
+	g.logger.Error(msg, zap.Error(err))
+	return status.Errorf(codes.Internal, "%s: %v", msg, err)
+}
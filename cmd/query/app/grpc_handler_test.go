@@ -0,0 +1,181 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"logger/cmd/query/app/querysvc"
+	"logger/model"
+	api_v1 "logger/model/proto/query/v1"
+	"logger/storage/logstore"
+)
+
+// fakeChunkStream collects the chunks sent on a server stream.
+type fakeChunkStream struct {
+	grpc.ServerStream
+	chunks []*api_v1.LogsResponseChunk
+}
+
+func (s *fakeChunkStream) Context() context.Context {
+	return context.Background()
+}
+
+func (s *fakeChunkStream) Send(chunk *api_v1.LogsResponseChunk) error {
+	s.chunks = append(s.chunks, chunk)
+	return nil
+}
+
+// records returns the number of logs in each chunk sent.
+func (s *fakeChunkStream) records() []int {
+	res := make([]int, len(s.chunks))
+	for i, chunk := range s.chunks {
+		for _, rl := range chunk.ResourceLogs {
+			for _, sl := range rl.ScopeLogs {
+				res[i] += len(sl.LogRecords)
+			}
+		}
+	}
+	return res
+}
+
+func newTestGRPCHandler(reader logstore.Reader, limits querysvc.Limits) *GRPCHandler {
+	qs := querysvc.NewQueryService(reader, querysvc.QueryServiceOptions{Limits: querysvc.LimitsConfig{Default: limits}})
+	return NewGRPCHandler(qs, nil)
+}
+
+func grpcLogs(n int) []*model.LogRecord {
+	logs := make([]*model.LogRecord, n)
+	for i := range logs {
+		logs[i] = &model.LogRecord{Body: "log", Process: &model.Process{ServiceName: "svc"}}
+	}
+	return logs
+}
+
+func TestGRPCFindLogs(t *testing.T) {
+	reader := &fakeReader{logs: grpcLogs(2*maxChunkSize + 1)}
+	h := newTestGRPCHandler(reader, querysvc.Limits{})
+	start := time.Date(2024, time.March, 10, 12, 0, 0, 0, time.UTC)
+	stream := &fakeChunkStream{}
+
+	err := h.FindLogs(&api_v1.FindLogsRequest{Query: &api_v1.LogQueryParameters{
+		ServiceName:  "svc",
+		StartTimeMin: timestamppb.New(start),
+		StartTimeMax: timestamppb.New(start.Add(time.Hour)),
+		NumLogs:      500,
+		Order:        "asc",
+	}}, stream)
+	require.NoError(t, err)
+	assert.Equal(t, []int{maxChunkSize, maxChunkSize, 1}, stream.records())
+	assert.Equal(t, logstore.LogQueryParameters{
+		ServiceName:  "svc",
+		StartTimeMin: start,
+		StartTimeMax: start.Add(time.Hour),
+		NumTraces:    500,
+		Order:        logstore.OrderAsc,
+	}, reader.query)
+}
+
+func TestGRPCFindLogsExplain(t *testing.T) {
+	h := newTestGRPCHandler(&fakeReader{logs: grpcLogs(maxChunkSize + 1)}, querysvc.Limits{})
+	start := time.Date(2024, time.March, 10, 12, 0, 0, 0, time.UTC)
+	stream := &fakeChunkStream{}
+	require.NoError(t, h.FindLogs(&api_v1.FindLogsRequest{
+		Query: &api_v1.LogQueryParameters{
+			ServiceName:  "svc",
+			StartTimeMin: timestamppb.New(start),
+			StartTimeMax: timestamppb.New(start.Add(time.Hour)),
+			NumLogs:      500,
+		},
+		Explain: true,
+	}, stream))
+
+	// the explain comes in a last chunk without logs
+	assert.Equal(t, []int{maxChunkSize, 1, 0}, stream.records())
+	for _, chunk := range stream.chunks[:2] {
+		assert.Nil(t, chunk.Explain)
+	}
+	explain := stream.chunks[2].Explain
+	require.NotNil(t, explain)
+	assert.Equal(t, int64(maxChunkSize+1), explain.RowsReturned)
+}
+
+func TestGRPCFindLogsErrors(t *testing.T) {
+	start := timestamppb.New(time.Date(2024, time.March, 10, 12, 0, 0, 0, time.UTC))
+	end := timestamppb.New(start.AsTime().Add(48 * time.Hour))
+	tests := []struct {
+		name   string
+		query  *api_v1.LogQueryParameters
+		err    error
+		limits querysvc.Limits
+		code   codes.Code
+	}{
+		{name: "nil query", code: codes.InvalidArgument},
+		{name: "no start", query: &api_v1.LogQueryParameters{ServiceName: "svc", StartTimeMax: end}, code: codes.InvalidArgument},
+		{name: "no end", query: &api_v1.LogQueryParameters{ServiceName: "svc", StartTimeMin: start}, code: codes.InvalidArgument},
+		{name: "bad order", query: &api_v1.LogQueryParameters{StartTimeMin: start, StartTimeMax: end, Order: "up"}, code: codes.InvalidArgument},
+		{
+			name:   "guardrail",
+			query:  &api_v1.LogQueryParameters{StartTimeMin: start, StartTimeMax: end},
+			limits: querysvc.Limits{MaxRange: querysvc.Duration(time.Hour)},
+			code:   codes.InvalidArgument,
+		},
+		{name: "storage", query: &api_v1.LogQueryParameters{StartTimeMin: start, StartTimeMax: end}, err: errors.New("unavailable"), code: codes.Internal},
+		{name: "canceled", query: &api_v1.LogQueryParameters{StartTimeMin: start, StartTimeMax: end}, err: context.Canceled, code: codes.Canceled},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			h := newTestGRPCHandler(&fakeReader{err: test.err}, test.limits)
+			err := h.FindLogs(&api_v1.FindLogsRequest{Query: test.query}, &fakeChunkStream{})
+			assert.Equal(t, test.code, status.Code(err), err)
+		})
+	}
+}
+
+func TestGRPCGetLogsByTrace(t *testing.T) {
+	reader := &fakeReader{logs: grpcLogs(maxChunkSize + 1)}
+	h := newTestGRPCHandler(reader, querysvc.Limits{})
+	stream := &fakeChunkStream{}
+	require.NoError(t, h.GetLogsByTrace(&api_v1.GetLogsByTraceRequest{TraceId: []byte{1, 2}}, stream))
+	assert.Equal(t, []int{maxChunkSize, 1}, stream.records())
+	assert.Equal(t, []byte{1, 2}, reader.traceID)
+
+	err := h.GetLogsByTrace(&api_v1.GetLogsByTraceRequest{}, &fakeChunkStream{})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	h = newTestGRPCHandler(&fakeReader{err: errors.New("unavailable")}, querysvc.Limits{})
+	err = h.GetLogsByTrace(&api_v1.GetLogsByTraceRequest{TraceId: []byte{1}}, &fakeChunkStream{})
+	assert.Equal(t, codes.Internal, status.Code(err))
+}
+
+func TestGRPCGetServicesAndOperations(t *testing.T) {
+	h := newTestGRPCHandler(&fakeNamesReader{}, querysvc.Limits{})
+	services, err := h.GetServices(context.Background(), &api_v1.GetServicesRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"svc"}, services.Services)
+
+	operations, err := h.GetOperations(context.Background(), &api_v1.GetOperationsRequest{Service: "svc"})
+	require.NoError(t, err)
+	require.Len(t, operations.Operations, 1)
+	assert.Equal(t, "svc/op", operations.Operations[0].Name)
+}
+
+type fakeNamesReader struct {
+	logstore.Reader
+}
+
+func (r *fakeNamesReader) GetServices(context.Context) ([]string, error) {
+	return []string{"svc"}, nil
+}
+
+func (r *fakeNamesReader) GetOperations(_ context.Context, p logstore.OperationQueryParameters) ([]logstore.Operation, error) {
+	return []logstore.Operation{{Name: p.ServiceName + "/op"}}, nil
+}
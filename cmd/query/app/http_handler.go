@@ -1,11 +1,19 @@
 package app
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"logger/cmd/query/app/querysvc"
+	"logger/model"
+	"logger/pkg/bearertoken"
+	"logger/pkg/config/corscfg"
+	"logger/pkg/jtracer"
+	"logger/pkg/tenancy"
 	"logger/storage/logstore"
 	"net/http"
+	"strings"
 
 	"github.com/savsgio/atreugo/v11"
 	"go.uber.org/zap"
@@ -15,29 +23,136 @@ import (
 type structuredError struct {
 	Code    int        `json:"code,omitempty"`
 	Msg     string     `json:"msg"`
+	// Guardrail names the limit a refused or interrupted query hit
+	Guardrail string `json:"guardrail,omitempty"`
 }
 
+// queryErrorResponse answers a failed query with 400, 404 when the record it names
+// does not exist, or 408 when it was interrupted by the timeout guardrail, naming the
+// guardrail the query hit if any.
+func queryErrorResponse(c *atreugo.RequestCtx, err error) error {
+	res := structuredError{Msg: err.Error(), Code: http.StatusBadRequest}
+	if errors.Is(err, logstore.ErrLogNotFound) {
+		res.Code = http.StatusNotFound
+	}
+	if guardrailErr, ok := querysvc.AsGuardrailError(err); ok {
+		res.Guardrail = guardrailErr.Guardrail
+		if guardrailErr.Guardrail == querysvc.GuardrailTimeout {
+			res.Code = http.StatusRequestTimeout
+		}
+	}
+	return c.JSONResponse(res, res.Code)
+}
 
-type HttpHandler interface {
-	RegisterRoutes(router *atreugo.Atreugo)
+
+// explainContext attaches a stats collector to the context of the request when it
+// asks for explain=true; the collector is nil otherwise.
+func explainContext(c *atreugo.RequestCtx) (context.Context, *logstore.QueryStats, error) {
+	ctx := requestContext(c)
+	explain, err := parseBoolParam(c, explainParam)
+	if err != nil || !explain {
+		return ctx, nil, err
+	}
+	stats := logstore.NewQueryStats()
+	return logstore.WithQueryStats(ctx, stats), stats, nil
 }
 
+// explainedLogs is the response of a log query run with explain=true.
+type explainedLogs struct {
+	Logs    []*model.LogRecord     `json:"logs"`
+	Explain *logstore.QueryExplain `json:"explain"`
+}
+
+// explainedLogContext is the response of a context query run with explain=true.
+type explainedLogContext struct {
+	*logstore.LogContext
+	Explain *logstore.QueryExplain `json:"explain"`
+}
 
+// requestContext returns the context attached to the request, or an empty one when none was attached.
+func requestContext(c *atreugo.RequestCtx) context.Context {
+	if ctx := c.AttachedContext(); ctx != nil {
+		return ctx
+	}
+	return context.Background()
+}
 
-func NewRouter() *atreugo.Atreugo {
+// bearerTokenMiddleware attaches the bearer token found in header to the request context.
+func bearerTokenMiddleware(header string) atreugo.Middleware {
+	if header == "" {
+		header = bearertoken.DefaultHeader
+	}
+	return func(c *atreugo.RequestCtx) error {
+		token := bearertoken.TokenFromHeader(header, string(c.Request.Header.Peek(header)))
+		if token != "" {
+			c.AttachContext(bearertoken.ContextWithBearerToken(requestContext(c), token))
+		}
+		return c.Next()
+	}
+}
+
+// tenancyMiddleware rejects the requests without a valid tenant header and attaches
+// the tenant to the request context, as tenancy.ExtractTenantHTTPHandler does.
+func tenancyMiddleware(tm *tenancy.Manager) atreugo.Middleware {
+	return func(c *atreugo.RequestCtx) error {
+		tenant := string(c.Request.Header.Peek(tm.Header))
+		if tenant == "" {
+			return c.TextResponse("missing tenant header", http.StatusUnauthorized)
+		}
+		if !tm.Valid(tenant) {
+			return c.TextResponse("unknown tenant", http.StatusUnauthorized)
+		}
+		c.AttachContext(tenancy.WithTenant(requestContext(c), tenant))
+		return c.Next()
+	}
+}
+
+type HttpHandler interface {
+	RegisterRoutes(router *atreugo.Router)
+}
+
+// NewRouter returns the HTTP server of the query service, listening on the configured
+// host-port, adding the configured response headers and CORS policy and
+// propagating the bearer token of the caller and tracing the requests when enabled.
+func NewRouter(queryOpts *QueryOptions, tracer *jtracer.JTracer) *atreugo.Atreugo {
 	config := atreugo.Config{
-		Addr:      "0.0.0.0:8001",
-		TLSEnable: false,	
-	}	
+		Addr: queryOpts.HTTPHostPort,
+	}
 	server := atreugo.New(config)
-	server.UseBefore(func(rc *atreugo.RequestCtx) error {
-		rc.Response.Header.Set("Access-Control-Allow-Origin", "*")
-		return rc.Next()
-	})
+	if queryOpts.EnableTracing && tracer != nil {
+		start, end := tracingMiddlewares(tracer.OTEL)
+		server.UseBefore(start)
+		server.UseFinal(end)
+	}
+	if len(queryOpts.AdditionalHeaders) > 0 {
+		headers := queryOpts.AdditionalHeaders
+		server.UseBefore(func(rc *atreugo.RequestCtx) error {
+			for key, values := range headers {
+				for _, value := range values {
+					rc.Response.Header.Add(key, value)
+				}
+			}
+			return rc.Next()
+		})
+	}
+	server.UseBefore(corscfg.Middleware(queryOpts.CORS))
+	if queryOpts.BearerTokenPropagation {
+		server.UseBefore(bearerTokenMiddleware(queryOpts.BearerTokenHeader))
+	}
 
 	return server
 }
 
+// basePathRouter returns the router for the routes of the query service,
+// grouped under basePath unless it is the root.
+func basePathRouter(server *atreugo.Atreugo, basePath string) *atreugo.Router {
+	basePath = strings.TrimSuffix(basePath, "/")
+	if basePath == "" {
+		return server.Router
+	}
+	return server.NewGroupPath(basePath)
+}
+
 
 type APIHandler struct {
 	queryService *querysvc.QueryService
@@ -58,15 +173,20 @@ func NewAPIHandler(qsvc *querysvc.QueryService,options ...HandlerOption) *APIHan
 	return aH
 }
 
-func (aH *APIHandler) RegisterRoutes(router *atreugo.Atreugo){
+func (aH *APIHandler) RegisterRoutes(router *atreugo.Router){
 	router.POST("/v1/logs/",aH.GetLogs)
 	router.GET("/v1/services/",aH.GetServices)
 	router.POST("/v1/operations/",aH.GetOperations)
+	router.GET("/v1/logs/context",aH.GetLogContext)
+	router.GET("/v1/logs/{id}", aH.GetLog)
+	router.GET("/v1/fields", aH.GetFields)
+	router.POST("/v1/logs/export", aH.ExportLogs)
+	aH.registerSavedSearchRoutes(router)
 }
 
 
 func (aH *APIHandler) GetOperations(c *atreugo.RequestCtx) error {
-	ctx := c.AttachedContext()
+	ctx := requestContext(c)
 	data := c.PostBody()
 	var query logstore.OperationQueryParameters
 	err := json.Unmarshal(data,&query)
@@ -80,33 +200,35 @@ func (aH *APIHandler) GetOperations(c *atreugo.RequestCtx) error {
 	operations,err := aH.queryService.GetOperations(ctx,query)
 	if err != nil {
 		aH.logger.Error("GetOperations",zap.Error(err))
-		return c.JSONResponse(structuredError{
-			Msg: err.Error(),
-			Code: http.StatusBadRequest,
-		})
+		return queryErrorResponse(c, err)
 	}
 	return c.JSONResponse(operations,http.StatusOK)
 }
 
 func (aH *APIHandler) GetServices(c *atreugo.RequestCtx) error {
-	ctx := c.AttachedContext()
+	ctx := requestContext(c)
 	// c. .Header.Set("Access-Control-Allow-Origin", "*")
 	services,err := aH.queryService.GetServices(ctx)
 	if err != nil {
 		aH.logger.Error("GetServices",zap.Error(err))
-		return c.JSONResponse(structuredError{
-			Msg: err.Error(),
-			Code: http.StatusBadRequest,
-		})
+		return queryErrorResponse(c, err)
 	}
 	return c.JSONResponse(services,http.StatusOK)
 }
 
+// GetLogs returns the logs matching the query in the body. With explain=true, they are
+// returned with the storage plan and execution statistics of the query.
 func (aH *APIHandler) GetLogs(c *atreugo.RequestCtx)error {
-	ctx := c.AttachedContext()
+	ctx, stats, err := explainContext(c)
+	if err != nil {
+		return c.JSONResponse(structuredError{
+			Msg:  err.Error(),
+			Code: http.StatusBadRequest,
+		}, http.StatusBadRequest)
+	}
 	data := c.PostBody()
 	var query logstore.LogQueryParameters
-	err := json.Unmarshal(data,&query)
+	err = json.Unmarshal(data,&query)
 	if err != nil {
 		aH.logger.Error("GetOperations",zap.Error(err))
 		return c.JSONResponse(structuredError{
@@ -117,11 +239,124 @@ func (aH *APIHandler) GetLogs(c *atreugo.RequestCtx)error {
 	logs,err := aH.queryService.GetLogs(ctx,query)
 	if err != nil {
 		aH.logger.Error("GerLogs",zap.Error(err))
-		return c.JSONResponse(structuredError{
-			Msg: err.Error(),
-			Code: http.StatusBadRequest,
-		})
+		return queryErrorResponse(c, err)
 	}
 	fmt.Println("LOGS",logs)
+	if stats != nil {
+		return c.JSONResponse(explainedLogs{Logs: logs, Explain: stats.Explain()}, http.StatusOK)
+	}
 	return c.JSONResponse(logs,http.StatusOK)
-}
\ No newline at end of file
+}
+
+// GetLog returns the record with the ID in the path, so that it can be linked to.
+func (aH *APIHandler) GetLog(c *atreugo.RequestCtx) error {
+	value, _ := c.UserValue("id").(string)
+	id, err := model.ParseLogID(value)
+	if err != nil {
+		return c.JSONResponse(structuredError{
+			Msg:  err.Error(),
+			Code: http.StatusBadRequest,
+		}, http.StatusBadRequest)
+	}
+	log, err := aH.queryService.GetLog(requestContext(c), id)
+	if err != nil {
+		if !errors.Is(err, logstore.ErrLogNotFound) {
+			aH.logger.Error("GetLog", zap.Error(err))
+		}
+		return queryErrorResponse(c, err)
+	}
+	return c.JSONResponse(log, http.StatusOK)
+}
+
+// GetLogContext returns the records written right before and after the given timestamp.
+func (aH *APIHandler) GetLogContext(c *atreugo.RequestCtx) error {
+	query, err := aH.parseLogContextQuery(c)
+	if err != nil {
+		return c.JSONResponse(structuredError{
+			Msg:  err.Error(),
+			Code: http.StatusBadRequest,
+		}, http.StatusBadRequest)
+	}
+	ctx, stats, err := explainContext(c)
+	if err != nil {
+		return c.JSONResponse(structuredError{
+			Msg:  err.Error(),
+			Code: http.StatusBadRequest,
+		}, http.StatusBadRequest)
+	}
+	logContext, err := aH.queryService.GetLogContext(ctx, query)
+	if err != nil {
+		if !errors.Is(err, logstore.ErrLogNotFound) {
+			aH.logger.Error("GetLogContext", zap.Error(err))
+		}
+		return queryErrorResponse(c, err)
+	}
+	if stats != nil {
+		return c.JSONResponse(explainedLogContext{LogContext: logContext, Explain: stats.Explain()}, http.StatusOK)
+	}
+	return c.JSONResponse(logContext, http.StatusOK)
+}
+
+// GetFields returns the attribute keys seen on a sample of the records of a service,
+// with their types and most frequent values.
+func (aH *APIHandler) GetFields(c *atreugo.RequestCtx) error {
+	query, err := parseFieldQuery(c)
+	if err != nil {
+		return c.JSONResponse(structuredError{
+			Msg:  err.Error(),
+			Code: http.StatusBadRequest,
+		}, http.StatusBadRequest)
+	}
+	fields, err := aH.queryService.GetFields(requestContext(c), query)
+	if err != nil {
+		aH.logger.Error("GetFields", zap.Error(err))
+		return queryErrorResponse(c, err)
+	}
+	return c.JSONResponse(fields, http.StatusOK)
+}
+
+func parseFieldQuery(c *atreugo.RequestCtx) (querysvc.FieldQueryParameters, error) {
+	query := querysvc.FieldQueryParameters{ServiceName: queryParam(c, serviceParam)}
+	if query.ServiceName == "" {
+		return query, fmt.Errorf("parameter %s is required", serviceParam)
+	}
+	var err error
+	if query.StartTimeMin, err = parseTimeParam(c, fromParam); err != nil {
+		return query, err
+	}
+	if query.StartTimeMax, err = parseTimeParam(c, toParam); err != nil {
+		return query, err
+	}
+	if query.SampleSize, err = parseIntParam(c, sampleParam, maxSampleSize); err != nil {
+		return query, err
+	}
+	if query.TopN, err = parseIntParam(c, topParam, maxTopValues); err != nil {
+		return query, err
+	}
+	return query, nil
+}
+
+func (aH *APIHandler) parseLogContextQuery(c *atreugo.RequestCtx) (logstore.LogContextParameters, error) {
+	query := logstore.LogContextParameters{
+		ServiceName:   queryParam(c, serviceParam),
+		OperationName: queryParam(c, operationParam),
+		Host:          queryParam(c, hostParam),
+	}
+	var err error
+	if query.ID, err = parseLogIDParam(c, idParam); err != nil {
+		return query, err
+	}
+	if query.Timestamp, err = parseTimeParam(c, timestampParam); err != nil {
+		return query, err
+	}
+	if query.Before, err = parseIntParam(c, beforeParam, maxContextSize); err != nil {
+		return query, err
+	}
+	if query.After, err = parseIntParam(c, afterParam, maxContextSize); err != nil {
+		return query, err
+	}
+	if query.TraceID, err = parseTraceIDParam(c, traceIDParam); err != nil {
+		return query, err
+	}
+	return query, nil
+}
@@ -0,0 +1,275 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/savsgio/atreugo/v11"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valyala/fasthttp"
+	"github.com/valyala/fasthttp/fasthttputil"
+
+	"logger/cmd/query/app/querysvc"
+	"logger/model"
+	"logger/pkg/bearertoken"
+	"logger/pkg/tenancy"
+	"logger/storage/logstore"
+)
+
+// fakeReader answers the context queries with logContext and the ID lookups with log,
+// streams logs followed by err and records the queries it was given.
+type fakeReader struct {
+	logstore.Reader
+	ctx          context.Context
+	query        logstore.LogQueryParameters
+	contextQuery logstore.LogContextParameters
+	id           model.LogID
+	log          *model.LogRecord
+	traceID      []byte
+	logContext   *logstore.LogContext
+	logs         []*model.LogRecord
+	err          error
+}
+
+func (r *fakeReader) StreamLogs(ctx context.Context, p logstore.LogQueryParameters, fn func(*model.LogRecord) error) error {
+	r.query = p
+	logstore.QueryStatsFromContext(ctx).AddReturned(len(r.logs))
+	return r.stream(fn)
+}
+
+func (r *fakeReader) StreamLogsByTrace(_ context.Context, traceID []byte, fn func(*model.LogRecord) error) error {
+	r.traceID = traceID
+	return r.stream(fn)
+}
+
+func (r *fakeReader) stream(fn func(*model.LogRecord) error) error {
+	for _, log := range r.logs {
+		if err := fn(log); err != nil {
+			return err
+		}
+	}
+	return r.err
+}
+
+func (r *fakeReader) GetLogContext(ctx context.Context, p logstore.LogContextParameters) (*logstore.LogContext, error) {
+	r.ctx = ctx
+	r.contextQuery = p
+	return r.logContext, r.err
+}
+
+func (r *fakeReader) GetLog(ctx context.Context, id model.LogID) (*model.LogRecord, error) {
+	r.ctx = ctx
+	r.id = id
+	if r.err != nil {
+		return nil, r.err
+	}
+	if r.log == nil {
+		return nil, logstore.ErrLogNotFound
+	}
+	return r.log, nil
+}
+
+// testServer serves the API of the query service from memory.
+type testServer struct {
+	client *fasthttp.Client
+}
+
+func newTestServer(t *testing.T, reader logstore.Reader, queryOpts *QueryOptions) *testServer {
+	return newTestServerWithOptions(t, reader, queryOpts, querysvc.QueryServiceOptions{})
+}
+
+func newTestServerWithOptions(t *testing.T, reader logstore.Reader, queryOpts *QueryOptions, options querysvc.QueryServiceOptions) *testServer {
+	server := NewRouter(queryOpts, nil)
+	r := basePathRouter(server, queryOpts.BasePath)
+	if queryOpts.Tenancy.Enabled {
+		r.UseBefore(tenancyMiddleware(tenancy.NewManager(&queryOpts.Tenancy)))
+	}
+	NewAPIHandler(querysvc.NewQueryService(reader, options)).RegisterRoutes(r)
+	return serve(t, server)
+}
+
+// serve serves server from memory until the end of the test.
+func serve(t *testing.T, server *atreugo.Atreugo) *testServer {
+	ln := fasthttputil.NewInmemoryListener()
+	go func() { _ = server.Serve(ln) }()
+	t.Cleanup(func() { _ = ln.Close() })
+	return &testServer{client: &fasthttp.Client{Dial: func(string) (net.Conn, error) { return ln.Dial() }}}
+}
+
+// do sends a request and decodes the JSON response into res, which may be nil.
+func (s *testServer) do(t *testing.T, method, uri, body string, header map[string]string, res interface{}) int {
+	req, resp := fasthttp.AcquireRequest(), fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseRequest(req)
+	defer fasthttp.ReleaseResponse(resp)
+	req.Header.SetMethod(method)
+	req.SetRequestURI("http://query" + uri)
+	for key, value := range header {
+		req.Header.Set(key, value)
+	}
+	req.SetBodyString(body)
+	require.NoError(t, s.client.DoTimeout(req, resp, 5*time.Second))
+	if res != nil {
+		require.NoError(t, json.Unmarshal(resp.Body(), res), string(resp.Body()))
+	}
+	return resp.StatusCode()
+}
+
+func TestGetLogContextHandler(t *testing.T) {
+	reader := &fakeReader{logContext: &logstore.LogContext{
+		Before: []*model.LogRecord{{Body: "before"}},
+		Anchor: []*model.LogRecord{{Body: "anchor"}},
+	}}
+	s := newTestServer(t, reader, &QueryOptions{})
+
+	var res logstore.LogContext
+	status := s.do(t, http.MethodGet, "/v1/logs/context?service=svc&operation=op&timestamp=2024-03-10T12:00:00Z&before=5&after=2&host=web-1&trace_id=0102", "", nil, &res)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "before", res.Before[0].Body)
+	assert.Equal(t, "anchor", res.Anchor[0].Body)
+	assert.Equal(t, logstore.LogContextParameters{
+		ServiceName:   "svc",
+		OperationName: "op",
+		Timestamp:     time.Date(2024, time.March, 10, 12, 0, 0, 0, time.UTC),
+		Before:        5,
+		After:         2,
+		Host:          "web-1",
+		TraceID:       []byte{1, 2},
+	}, reader.contextQuery)
+
+	var explained struct {
+		Anchor  []*model.LogRecord     `json:"anchor"`
+		Explain *logstore.QueryExplain `json:"explain"`
+	}
+	status = s.do(t, http.MethodGet, "/v1/logs/context?service=svc&timestamp=1710072000000000000&explain=true", "", nil, &explained)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Len(t, explained.Anchor, 1)
+	assert.NotNil(t, explained.Explain)
+}
+
+func TestGetLogContextHandlerErrors(t *testing.T) {
+	s := newTestServer(t, &fakeReader{err: errors.New("timestamp must be set")}, &QueryOptions{})
+	for _, uri := range []string{
+		"/v1/logs/context?service=svc&timestamp=yesterday",
+		"/v1/logs/context?service=svc&before=-1",
+		"/v1/logs/context?service=svc&after=1001",
+		"/v1/logs/context?service=svc&trace_id=xyz",
+		"/v1/logs/context?service=svc&id=nope",
+		"/v1/logs/context?service=svc&explain=maybe",
+		"/v1/logs/context?service=svc",
+	} {
+		var res structuredError
+		status := s.do(t, http.MethodGet, uri, "", nil, &res)
+		assert.Equal(t, http.StatusBadRequest, status, uri)
+		assert.Equal(t, http.StatusBadRequest, res.Code, uri)
+		assert.NotEmpty(t, res.Msg, uri)
+	}
+}
+
+func TestRequestContext(t *testing.T) {
+	reader := &fakeReader{logContext: &logstore.LogContext{}}
+	s := newTestServer(t, reader, &QueryOptions{QueryOptionsBase: QueryOptionsBase{
+		BearerTokenPropagation: true,
+		Tenancy:                tenancy.Options{Enabled: true, Tenants: []string{"acme"}},
+	}})
+	uri := "/v1/logs/context?service=svc&timestamp=2024-03-10T12:00:00Z"
+
+	// both middlewares attach their value to the same request context
+	status := s.do(t, http.MethodGet, uri, "", map[string]string{"Authorization": "Bearer abc", "x-tenant": "acme"}, nil)
+	assert.Equal(t, http.StatusOK, status)
+	token, ok := bearertoken.GetBearerToken(reader.ctx)
+	assert.True(t, ok)
+	assert.Equal(t, "abc", token)
+	assert.Equal(t, "acme", tenancy.GetTenant(reader.ctx))
+
+	reader.ctx = nil
+	status = s.do(t, http.MethodGet, uri, "", map[string]string{"x-tenant": "acme"}, nil)
+	assert.Equal(t, http.StatusOK, status)
+	_, ok = bearertoken.GetBearerToken(reader.ctx)
+	assert.False(t, ok)
+	assert.Equal(t, "acme", tenancy.GetTenant(reader.ctx))
+
+	status = s.do(t, http.MethodGet, uri, "", map[string]string{"x-tenant": "other"}, nil)
+	assert.Equal(t, http.StatusUnauthorized, status)
+}
+
+func TestGetLogHandler(t *testing.T) {
+	ts := time.Date(2024, time.March, 10, 12, 0, 0, 0, time.UTC)
+	id := model.NewLogID(ts)
+	reader := &fakeReader{log: &model.LogRecord{ID: id, Body: "found"}}
+	s := newTestServer(t, reader, &QueryOptions{})
+
+	var res struct {
+		Body string `json:"body"`
+	}
+	status := s.do(t, http.MethodGet, "/v1/logs/"+id.String(), "", nil, &res)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "found", res.Body)
+	assert.Equal(t, id, reader.id)
+
+	var errRes structuredError
+	status = s.do(t, http.MethodGet, "/v1/logs/nope", "", nil, &errRes)
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	reader.log = nil
+	status = s.do(t, http.MethodGet, "/v1/logs/"+id.String(), "", nil, &errRes)
+	assert.Equal(t, http.StatusNotFound, status)
+	assert.Equal(t, http.StatusNotFound, errRes.Code)
+
+	reader.err = errors.New("unavailable")
+	status = s.do(t, http.MethodGet, "/v1/logs/"+id.String(), "", nil, &errRes)
+	assert.Equal(t, http.StatusBadRequest, status)
+}
+
+func TestGetLogContextByIDHandler(t *testing.T) {
+	ts := time.Date(2024, time.March, 10, 12, 0, 0, 0, time.UTC)
+	id := model.NewLogID(ts)
+	reader := &fakeReader{
+		log:        &model.LogRecord{ID: id, TimeUnixNano: uint64(ts.UnixNano()), Process: &model.Process{ServiceName: "svc"}},
+		logContext: &logstore.LogContext{Anchor: []*model.LogRecord{{Body: "anchor"}}},
+	}
+	s := newTestServer(t, reader, &QueryOptions{})
+
+	var res logstore.LogContext
+	status := s.do(t, http.MethodGet, "/v1/logs/context?id="+id.String()+"&before=2", "", nil, &res)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "anchor", res.Anchor[0].Body)
+	// the anchor is located from the record
+	assert.Equal(t, id, reader.id)
+	assert.Equal(t, "svc", reader.contextQuery.ServiceName)
+	assert.True(t, ts.Equal(reader.contextQuery.Timestamp))
+	assert.Equal(t, 2, reader.contextQuery.Before)
+
+	reader.log = nil
+	var errRes structuredError
+	status = s.do(t, http.MethodGet, "/v1/logs/context?id="+id.String(), "", nil, &errRes)
+	assert.Equal(t, http.StatusNotFound, status)
+	assert.Equal(t, http.StatusNotFound, errRes.Code)
+}
+
+func TestGetLogsGuardrails(t *testing.T) {
+	reader := &fakeReader{}
+	s := newTestServerWithOptions(t, reader, &QueryOptions{}, querysvc.QueryServiceOptions{
+		Limits: querysvc.LimitsConfig{Default: querysvc.Limits{MaxResults: 1000}},
+	})
+
+	// fetching every record does not lift the limit on the number of results
+	var res structuredError
+	code := s.do(t, http.MethodPost, "/v1/logs/",
+		`{"service_name":"api","start_time_min":"2024-01-01T00:00:00Z","start_time_max":"2024-01-01T01:00:00Z","should_fetch_all":true,"num_traces":1000000}`,
+		nil, &res)
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, querysvc.GuardrailMaxResults, res.Guardrail)
+
+	// an export streams the records, it is bounded by its time range only
+	code = s.do(t, http.MethodPost, "/v1/logs/export",
+		`{"service_name":"api","start_time_min":"2024-01-01T00:00:00Z","start_time_max":"2024-01-01T01:00:00Z","should_fetch_all":true}`,
+		nil, nil)
+	assert.Equal(t, http.StatusOK, code)
+	assert.True(t, reader.query.ShouldFetchAll)
+}
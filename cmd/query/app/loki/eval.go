@@ -0,0 +1,291 @@
+package loki
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"logger/model"
+	logs "logger/model/proto/logs/v1"
+	"logger/storage/logstore"
+)
+
+const (
+	serviceLabel   = "service_name"
+	operationLabel = "operation"
+	levelLabel     = "level"
+
+	// maxPoints is the number of points per series above which a metric query is refused, as in Loki
+	maxPoints = 11000
+)
+
+var knownLabels = []string{levelLabel, operationLabel, serviceLabel}
+
+var errLimitReached = errors.New("limit reached")
+
+// partition is a service and operation pair, the unit the storage is read by.
+type partition struct {
+	service   string
+	operation string
+}
+
+// labelSet holds the labels a log record is exposed with.
+type labelSet struct {
+	service   string
+	operation string
+	level     string
+}
+
+func (l labelSet) get(name string) string {
+	switch name {
+	case serviceLabel:
+		return l.service
+	case operationLabel:
+		return l.operation
+	case levelLabel:
+		return l.level
+	}
+	return ""
+}
+
+func (l labelSet) toMap() map[string]string {
+	return map[string]string{
+		serviceLabel:   l.service,
+		operationLabel: l.operation,
+		levelLabel:     l.level,
+	}
+}
+
+type entry struct {
+	labels labelSet
+	log    *model.LogRecord
+}
+
+// levelOf maps a severity number to the level names used by Grafana.
+func levelOf(severity logs.SeverityNumber) string {
+	switch {
+	case severity >= logs.SeverityNumber_SEVERITY_NUMBER_FATAL:
+		return "critical"
+	case severity >= logs.SeverityNumber_SEVERITY_NUMBER_ERROR:
+		return "error"
+	case severity >= logs.SeverityNumber_SEVERITY_NUMBER_WARN:
+		return "warn"
+	case severity >= logs.SeverityNumber_SEVERITY_NUMBER_INFO:
+		return "info"
+	case severity >= logs.SeverityNumber_SEVERITY_NUMBER_DEBUG:
+		return "debug"
+	case severity >= logs.SeverityNumber_SEVERITY_NUMBER_TRACE:
+		return "trace"
+	}
+	return "unknown"
+}
+
+var levels = []string{"critical", "debug", "error", "info", "trace", "unknown", "warn"}
+
+// partitions lists the service and operation pairs selected by the stream selector of q.
+func (h *Handler) partitions(ctx context.Context, q *logQuery) ([]partition, error) {
+	// a matcher on a label we do not have sees the empty value, as in Loki
+	for i := range q.matchers {
+		m := &q.matchers[i]
+		if m.name != serviceLabel && m.name != operationLabel && m.name != levelLabel && !m.matches("") {
+			return nil, nil
+		}
+	}
+	services, err := h.queryService.GetServices(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var res []partition
+	for _, service := range services {
+		if !q.matchLabel(serviceLabel, service) {
+			continue
+		}
+		operations, err := h.queryService.GetOperations(ctx, logstore.OperationQueryParameters{ServiceName: service})
+		if err != nil {
+			return nil, err
+		}
+		for _, operation := range operations {
+			if q.matchLabel(operationLabel, operation.Name) {
+				res = append(res, partition{service: service, operation: operation.Name})
+			}
+		}
+	}
+	return res, nil
+}
+
+// scan hands the records of p matching q and written between start and end to fn in order.
+func (h *Handler) scan(ctx context.Context, p partition, q *logQuery, start, end time.Time, order logstore.Order, fn func(entry) error) error {
+	params := logstore.LogQueryParameters{
+		ServiceName:    p.service,
+		OperationName:  p.operation,
+		StartTimeMin:   start,
+		StartTimeMax:   end,
+		ShouldFetchAll: true,
+		Order:          order,
+	}
+	err := h.queryService.StreamLogs(ctx, params, func(log *model.LogRecord) error {
+		labels := labelSet{service: p.service, operation: p.operation, level: levelOf(log.SeverityNumber)}
+		if !q.matchLabel(levelLabel, labels.level) || !q.matchLine(log.Body) {
+			return nil
+		}
+		return fn(entry{labels: labels, log: log})
+	})
+	if errors.Is(err, errLimitReached) {
+		return nil
+	}
+	return err
+}
+
+// selectLogs returns the limit first records matching q in order.
+func (h *Handler) selectLogs(ctx context.Context, q *logQuery, start, end time.Time, limit int, order logstore.Order) ([]entry, error) {
+	partitions, err := h.partitions(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	var res []entry
+	for _, p := range partitions {
+		n := 0
+		err := h.scan(ctx, p, q, start, end, order, func(e entry) error {
+			res = append(res, e)
+			if n++; n >= limit {
+				return errLimitReached
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	sort.SliceStable(res, func(i, j int) bool {
+		return order.Less(res[i].log, res[j].log)
+	})
+	if len(res) > limit {
+		res = res[:limit]
+	}
+	return res, nil
+}
+
+// toStreams groups entries by label set, keeping their order within each stream.
+func toStreams(entries []entry) []stream {
+	index := make(map[labelSet]int)
+	res := make([]stream, 0)
+	for _, e := range entries {
+		i, ok := index[e.labels]
+		if !ok {
+			i = len(res)
+			index[e.labels] = i
+			res = append(res, stream{Stream: e.labels.toMap()})
+		}
+		res[i].Values = append(res[i].Values, [2]string{
+			strconv.FormatUint(e.log.TimeUnixNano, 10),
+			e.log.Body,
+		})
+	}
+	return res
+}
+
+// evalMetric counts the records matching m over a sliding window evaluated every step between start and end.
+func (h *Handler) evalMetric(ctx context.Context, m *metricQuery, start, end time.Time, step time.Duration) ([]series, error) {
+	numPoints := int64(end.Sub(start)/step) + 1
+	if numPoints > maxPoints {
+		return nil, fmt.Errorf("exceeded maximum resolution of %d points per timeseries. Try increasing the value of the step parameter", maxPoints)
+	}
+	partitions, err := h.partitions(ctx, &m.query)
+	if err != nil {
+		return nil, err
+	}
+	type accumulator struct {
+		metric map[string]string
+		points map[int64]float64
+	}
+	acc := make(map[string]*accumulator)
+	startNanos, stepNanos, windowNanos := start.UnixNano(), int64(step), int64(m.window)
+	for _, p := range partitions {
+		err := h.scan(ctx, p, &m.query, start.Add(-m.window), end, logstore.OrderAsc, func(e entry) error {
+			metric := m.groupLabels(e.labels)
+			key := seriesKey(metric)
+			a, ok := acc[key]
+			if !ok {
+				a = &accumulator{metric: metric, points: make(map[int64]float64)}
+				acc[key] = a
+			}
+			// the record is counted at every point t where t-window < ts <= t
+			ts := int64(e.log.TimeUnixNano) - startNanos
+			first := ceilDiv(ts, stepNanos)
+			last := floorDiv(ts+windowNanos-1, stepNanos)
+			for k := max(first, 0); k <= min(last, numPoints-1); k++ {
+				a.points[k]++
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	res := make([]series, 0, len(acc))
+	for _, a := range acc {
+		steps := make([]int64, 0, len(a.points))
+		for k := range a.points {
+			steps = append(steps, k)
+		}
+		sort.Slice(steps, func(i, j int) bool { return steps[i] < steps[j] })
+		s := series{Metric: a.metric, Values: make([][2]interface{}, len(steps))}
+		for i, k := range steps {
+			value := a.points[k]
+			if m.function == rate {
+				value /= m.window.Seconds()
+			}
+			t := start.Add(time.Duration(k) * step)
+			s.Values[i] = [2]interface{}{float64(t.UnixNano()) / 1e9, strconv.FormatFloat(value, 'f', -1, 64)}
+		}
+		res = append(res, s)
+	}
+	sort.Slice(res, func(i, j int) bool { return seriesKey(res[i].Metric) < seriesKey(res[j].Metric) })
+	return res, nil
+}
+
+// groupLabels returns the labels of the series a record with labels is counted in.
+func (m *metricQuery) groupLabels(labels labelSet) map[string]string {
+	if !m.sum {
+		return labels.toMap()
+	}
+	res := make(map[string]string, len(m.by))
+	for _, name := range m.by {
+		if value := labels.get(name); value != "" {
+			res[name] = value
+		}
+	}
+	return res
+}
+
+func seriesKey(metric map[string]string) string {
+	keys := make([]string, 0, len(metric))
+	for k := range metric {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var sb strings.Builder
+	for _, k := range keys {
+		sb.WriteString(k)
+		sb.WriteByte('=')
+		sb.WriteString(strconv.Quote(metric[k]))
+		sb.WriteByte(',')
+	}
+	return sb.String()
+}
+
+func floorDiv(a, b int64) int64 {
+	q := a / b
+	if a%b != 0 && a < 0 {
+		q--
+	}
+	return q
+}
+
+func ceilDiv(a, b int64) int64 {
+	return -floorDiv(-a, b)
+}
@@ -0,0 +1,118 @@
+package loki
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"logger/cmd/query/app/querysvc"
+	"logger/model"
+	logs "logger/model/proto/logs/v1"
+	"logger/storage/logstore"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var baseTime = time.Unix(1700000000, 0)
+
+// fakeReader serves the records of a single service in the requested order like the storage.
+type fakeReader struct {
+	logstore.Reader
+	logs map[string][]*model.LogRecord
+}
+
+func (r *fakeReader) GetServices(ctx context.Context) ([]string, error) {
+	return []string{"api"}, nil
+}
+
+func (r *fakeReader) GetOperations(ctx context.Context, p logstore.OperationQueryParameters) ([]logstore.Operation, error) {
+	return []logstore.Operation{{Name: "GET"}, {Name: "POST"}}, nil
+}
+
+func (r *fakeReader) StreamLogs(ctx context.Context, p logstore.LogQueryParameters, fn func(*model.LogRecord) error) error {
+	logstore.QueryStatsFromContext(ctx).AddPlan("operation " + p.OperationName)
+	records := r.logs[p.OperationName]
+	for k := range records {
+		i := len(records) - 1 - k
+		if p.Order.Ascending() {
+			i = k
+		}
+		ts := time.Unix(0, int64(records[i].TimeUnixNano))
+		if !ts.After(p.StartTimeMin) || !ts.Before(p.StartTimeMax) {
+			continue
+		}
+		if err := fn(records[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func record(offset time.Duration, severity logs.SeverityNumber, body string) *model.LogRecord {
+	return &model.LogRecord{
+		TimeUnixNano:   uint64(baseTime.Add(offset).UnixNano()),
+		SeverityNumber: severity,
+		Body:           body,
+	}
+}
+
+func newTestHandler() *Handler {
+	reader := &fakeReader{logs: map[string][]*model.LogRecord{
+		"GET": {
+			record(1*time.Second, logs.SeverityNumber_SEVERITY_NUMBER_INFO, "get 1"),
+			record(3*time.Second, logs.SeverityNumber_SEVERITY_NUMBER_ERROR, "get 2"),
+			record(5*time.Second, logs.SeverityNumber_SEVERITY_NUMBER_INFO, "get 3"),
+		},
+		"POST": {
+			record(2*time.Second, logs.SeverityNumber_SEVERITY_NUMBER_INFO, "post 1"),
+			record(4*time.Second, logs.SeverityNumber_SEVERITY_NUMBER_INFO, "post 2"),
+		},
+	}}
+	return NewHandler(querysvc.NewQueryService(reader, querysvc.QueryServiceOptions{}), nil)
+}
+
+func TestSelectLogs(t *testing.T) {
+	h := newTestHandler()
+	e, err := parseExpr(`{service_name="api"}`)
+	require.NoError(t, err)
+
+	backward, err := h.selectLogs(context.Background(), e.log, baseTime, baseTime.Add(time.Minute), 3, logstore.OrderDesc)
+	require.NoError(t, err)
+	var bodies []string
+	for _, entry := range backward {
+		bodies = append(bodies, entry.log.Body)
+	}
+	assert.Equal(t, []string{"get 3", "post 2", "get 2"}, bodies)
+
+	forward, err := h.selectLogs(context.Background(), e.log, baseTime, baseTime.Add(time.Minute), 2, logstore.OrderAsc)
+	require.NoError(t, err)
+	bodies = bodies[:0]
+	for _, entry := range forward {
+		bodies = append(bodies, entry.log.Body)
+	}
+	assert.Equal(t, []string{"get 1", "post 1"}, bodies)
+
+	streams := toStreams(forward)
+	require.Len(t, streams, 2)
+	assert.Equal(t, map[string]string{serviceLabel: "api", operationLabel: "GET", levelLabel: "info"}, streams[0].Stream)
+}
+
+func TestEvalMetric(t *testing.T) {
+	h := newTestHandler()
+	e, err := parseExpr(`sum by (level) (count_over_time({service_name="api"}[2s]))`)
+	require.NoError(t, err)
+
+	result, err := h.evalMetric(context.Background(), e.metric, baseTime, baseTime.Add(6*time.Second), 2*time.Second)
+	require.NoError(t, err)
+	require.Len(t, result, 2)
+	assert.Equal(t, map[string]string{levelLabel: "error"}, result[0].Metric)
+	// the record at 3s is counted at the point 4s only, as the window is (t-2s, t]
+	assert.Equal(t, [][2]interface{}{{float64(baseTime.Unix() + 4), "1"}}, result[0].Values)
+	assert.Equal(t, map[string]string{levelLabel: "info"}, result[1].Metric)
+	assert.Equal(t, [][2]interface{}{
+		{float64(baseTime.Unix() + 2), "2"},
+		{float64(baseTime.Unix() + 4), "1"},
+		{float64(baseTime.Unix() + 6), "1"},
+	}, result[1].Values)
+}
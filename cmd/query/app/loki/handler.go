@@ -0,0 +1,396 @@
+// Package loki exposes the read API of Grafana Loki on top of the query service,
+// so that the standard Loki data source of Grafana can explore the stored logs.
+package loki
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"logger/cmd/query/app/querysvc"
+	"logger/storage/logstore"
+
+	"github.com/fasthttp/websocket"
+	"github.com/savsgio/atreugo/v11"
+	"go.uber.org/zap"
+)
+
+const (
+	queryParam     = "query"
+	startParam     = "start"
+	endParam       = "end"
+	limitParam     = "limit"
+	directionParam = "direction"
+	stepParam      = "step"
+	explainParam   = "explain"
+	delayForParam  = "delay_for"
+
+	defaultLimit    = 100
+	maxLimit        = 5000
+	defaultLookback = time.Hour
+	maxDelayFor     = 5 * time.Second
+
+	// tailPollInterval is how often the storage is read while tailing
+	tailPollInterval = time.Second
+	// maxDroppedEntries bounds the records reported as dropped in a tail response, as in Loki
+	maxDroppedEntries = 1000
+)
+
+type response struct {
+	Status string      `json:"status"`
+	Data   interface{} `json:"data"`
+}
+
+type queryData struct {
+	ResultType string      `json:"resultType"`
+	Result     interface{} `json:"result"`
+	Stats      queryStats  `json:"stats"`
+}
+
+// queryStats holds the storage plan and statistics of a query run with explain=true.
+type queryStats struct {
+	Explain *logstore.QueryExplain `json:"explain,omitempty"`
+}
+
+type stream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+type series struct {
+	Metric map[string]string `json:"metric"`
+	Values [][2]interface{}  `json:"values"`
+}
+
+type tailResponse struct {
+	Streams        []stream       `json:"streams"`
+	DroppedEntries []droppedEntry `json:"dropped_entries"`
+}
+
+// droppedEntry identifies a record tail did not send because there were more than the limit.
+type droppedEntry struct {
+	Labels    map[string]string `json:"labels"`
+	Timestamp string            `json:"timestamp"`
+}
+
+// Handler serves the Loki query API.
+type Handler struct {
+	queryService *querysvc.QueryService
+	logger       *zap.Logger
+	upgrader     websocket.FastHTTPUpgrader
+}
+
+// NewHandler returns a Handler reading from queryService.
+func NewHandler(queryService *querysvc.QueryService, logger *zap.Logger) *Handler {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &Handler{
+		queryService: queryService,
+		logger:       logger,
+	}
+}
+
+func (h *Handler) RegisterRoutes(router *atreugo.Router) {
+	router.GET("/loki/api/v1/query_range", h.QueryRange)
+	router.GET("/loki/api/v1/labels", h.Labels)
+	router.GET("/loki/api/v1/label/{name}/values", h.LabelValues)
+	router.GET("/loki/api/v1/tail", h.Tail)
+}
+
+// QueryRange evaluates a log or metric query over a time range. With explain=true, the
+// storage plan and statistics of the query are returned in data.stats.explain.
+func (h *Handler) QueryRange(c *atreugo.RequestCtx) error {
+	e, err := parseExpr(queryArg(c, queryParam))
+	if err != nil {
+		return badRequest(c, err)
+	}
+	end, err := parseTime(queryArg(c, endParam), time.Now())
+	if err != nil {
+		return badRequest(c, err)
+	}
+	start, err := parseTime(queryArg(c, startParam), end.Add(-defaultLookback))
+	if err != nil {
+		return badRequest(c, err)
+	}
+	if end.Before(start) {
+		return badRequest(c, errors.New("end timestamp must not be before start time"))
+	}
+	ctx := requestContext(c)
+	var stats *logstore.QueryStats
+	if value := queryArg(c, explainParam); value != "" {
+		explain, err := strconv.ParseBool(value)
+		if err != nil {
+			return badRequest(c, fmt.Errorf("cannot parse %s: %w", explainParam, err))
+		}
+		if explain {
+			stats = logstore.NewQueryStats()
+			ctx = logstore.WithQueryStats(ctx, stats)
+		}
+	}
+	// the partitions are scanned by several queries, bounded together by the timeout
+	ctx, cancel := h.queryService.WithDeadline(ctx)
+	defer cancel()
+
+	if e.metric != nil {
+		step, err := parseStep(queryArg(c, stepParam), start, end)
+		if err != nil {
+			return badRequest(c, err)
+		}
+		result, err := h.evalMetric(ctx, e.metric, start, end, step)
+		if err != nil {
+			return h.queryError(c, err)
+		}
+		return c.JSONResponse(response{Status: "success", Data: queryData{
+			ResultType: "matrix",
+			Result:     result,
+			Stats:      queryStats{Explain: stats.Explain()},
+		}}, http.StatusOK)
+	}
+
+	limit, err := parseLimit(queryArg(c, limitParam))
+	if err != nil {
+		return badRequest(c, err)
+	}
+	order := logstore.OrderDesc
+	switch direction := queryArg(c, directionParam); direction {
+	case "", "backward":
+	case "forward":
+		order = logstore.OrderAsc
+	default:
+		return badRequest(c, fmt.Errorf("invalid direction %q", direction))
+	}
+	entries, err := h.selectLogs(ctx, e.log, start, end, limit, order)
+	if err != nil {
+		return h.queryError(c, err)
+	}
+	return c.JSONResponse(response{Status: "success", Data: queryData{
+		ResultType: "streams",
+		Result:     toStreams(entries),
+		Stats:      queryStats{Explain: stats.Explain()},
+	}}, http.StatusOK)
+}
+
+// Labels returns the names of the labels logs are exposed with.
+func (h *Handler) Labels(c *atreugo.RequestCtx) error {
+	return c.JSONResponse(response{Status: "success", Data: knownLabels}, http.StatusOK)
+}
+
+// LabelValues returns the values of a label.
+func (h *Handler) LabelValues(c *atreugo.RequestCtx) error {
+	name, _ := c.UserValue("name").(string)
+	ctx, cancel := h.queryService.WithDeadline(requestContext(c))
+	defer cancel()
+	var values []string
+	switch name {
+	case serviceLabel:
+		services, err := h.queryService.GetServices(ctx)
+		if err != nil {
+			return h.queryError(c, err)
+		}
+		values = services
+	case operationLabel:
+		services, err := h.queryService.GetServices(ctx)
+		if err != nil {
+			return h.queryError(c, err)
+		}
+		seen := make(map[string]bool)
+		for _, service := range services {
+			operations, err := h.queryService.GetOperations(ctx, logstore.OperationQueryParameters{ServiceName: service})
+			if err != nil {
+				return h.queryError(c, err)
+			}
+			for _, operation := range operations {
+				if !seen[operation.Name] {
+					seen[operation.Name] = true
+					values = append(values, operation.Name)
+				}
+			}
+		}
+	case levelLabel:
+		values = levels
+	}
+	if values == nil {
+		values = []string{}
+	}
+	return c.JSONResponse(response{Status: "success", Data: values}, http.StatusOK)
+}
+
+// Tail streams the records matching a log query over a websocket as they are written.
+func (h *Handler) Tail(c *atreugo.RequestCtx) error {
+	e, err := parseExpr(queryArg(c, queryParam))
+	if err != nil {
+		return badRequest(c, err)
+	}
+	if e.log == nil {
+		return badRequest(c, errors.New("tail requires a log query"))
+	}
+	start, err := parseTime(queryArg(c, startParam), time.Now().Add(-defaultLookback))
+	if err != nil {
+		return badRequest(c, err)
+	}
+	limit, err := parseLimit(queryArg(c, limitParam))
+	if err != nil {
+		return badRequest(c, err)
+	}
+	var delay time.Duration
+	if value := queryArg(c, delayForParam); value != "" {
+		seconds, err := strconv.Atoi(value)
+		if err != nil || seconds < 0 || time.Duration(seconds)*time.Second > maxDelayFor {
+			return badRequest(c, fmt.Errorf("delay_for must be between 0 and %d seconds", int(maxDelayFor.Seconds())))
+		}
+		delay = time.Duration(seconds) * time.Second
+	}
+	// the connection outlives the request, so the context is taken before upgrading
+	ctx, cancel := context.WithCancel(requestContext(c))
+	err = h.upgrader.Upgrade(c.RequestCtx, func(conn *websocket.Conn) {
+		defer cancel()
+		defer conn.Close()
+		// the client never writes, reading only notices when it goes away
+		go func() {
+			defer cancel()
+			for {
+				if _, _, err := conn.ReadMessage(); err != nil {
+					return
+				}
+			}
+		}()
+		h.tail(ctx, conn, e.log, start, limit, delay)
+	})
+	if err != nil {
+		cancel()
+	}
+	return err
+}
+
+func (h *Handler) tail(ctx context.Context, conn *websocket.Conn, q *logQuery, from time.Time, limit int, delay time.Duration) {
+	ticker := time.NewTicker(tailPollInterval)
+	defer ticker.Stop()
+	for {
+		to := time.Now().Add(-delay)
+		pollCtx, cancel := h.queryService.WithDeadline(ctx)
+		entries, err := h.selectLogs(pollCtx, q, from, to, limit+maxDroppedEntries, logstore.OrderDesc)
+		cancel()
+		if err != nil {
+			if ctx.Err() == nil {
+				h.logger.Error("Tail", zap.Error(err))
+				_ = conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseInternalServerErr, err.Error()))
+			}
+			return
+		}
+		if len(entries) > 0 {
+			from = time.Unix(0, int64(entries[0].log.TimeUnixNano))
+			if err := conn.WriteJSON(newTailResponse(entries, limit)); err != nil {
+				return
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// newTailResponse sends the limit newest of entries, which are sorted newest first, and
+// reports the older ones as dropped. Both are listed oldest first.
+func newTailResponse(entries []entry, limit int) tailResponse {
+	var dropped []entry
+	if len(entries) > limit {
+		entries, dropped = entries[:limit], entries[limit:]
+	}
+	sent := make([]entry, len(entries))
+	for i, e := range entries {
+		sent[len(entries)-1-i] = e
+	}
+	res := tailResponse{Streams: toStreams(sent)}
+	for i := len(dropped) - 1; i >= 0; i-- {
+		res.DroppedEntries = append(res.DroppedEntries, droppedEntry{
+			Labels:    dropped[i].labels.toMap(),
+			Timestamp: strconv.FormatUint(dropped[i].log.TimeUnixNano, 10),
+		})
+	}
+	return res
+}
+
+// queryError answers a query refused or interrupted by a guardrail with a 4xx status
+// explaining it, and any other failure with 500.
+func (h *Handler) queryError(c *atreugo.RequestCtx, err error) error {
+	h.logger.Error("Loki query", zap.Error(err))
+	if guardrailErr, ok := querysvc.AsGuardrailError(err); ok {
+		if guardrailErr.Guardrail == querysvc.GuardrailTimeout {
+			return c.TextResponse(guardrailErr.Error(), http.StatusRequestTimeout)
+		}
+		return badRequest(c, guardrailErr)
+	}
+	return c.TextResponse(err.Error(), http.StatusInternalServerError)
+}
+
+// badRequest answers with a plain text error, which is what the Loki data source displays.
+func badRequest(c *atreugo.RequestCtx, err error) error {
+	return c.TextResponse(err.Error(), http.StatusBadRequest)
+}
+
+func queryArg(c *atreugo.RequestCtx, key string) string {
+	return string(c.QueryArgs().Peek(key))
+}
+
+func requestContext(c *atreugo.RequestCtx) context.Context {
+	if ctx := c.AttachedContext(); ctx != nil {
+		return ctx
+	}
+	return context.Background()
+}
+
+// parseTime accepts nanoseconds since epoch, fractional seconds since epoch or an RFC3339 timestamp.
+func parseTime(value string, defaultValue time.Time) (time.Time, error) {
+	if value == "" {
+		return defaultValue, nil
+	}
+	if nanos, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(0, nanos), nil
+	}
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+		whole, frac := math.Modf(seconds)
+		return time.Unix(int64(whole), int64(frac*1e9)), nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("cannot parse %q to a valid timestamp", value)
+	}
+	return t, nil
+}
+
+// parseStep accepts a duration or a number of seconds; by default the range is split in 250 steps of at least a second.
+func parseStep(value string, start, end time.Time) (time.Duration, error) {
+	if value == "" {
+		step := end.Sub(start) / 250
+		return max(step.Truncate(time.Second), time.Second), nil
+	}
+	var step time.Duration
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+		step = time.Duration(seconds * float64(time.Second))
+	} else if step, err = time.ParseDuration(value); err != nil {
+		return 0, fmt.Errorf("cannot parse %q to a valid duration", value)
+	}
+	if step <= 0 {
+		return 0, errors.New("zero or negative query resolution step widths are not accepted. Try a positive integer")
+	}
+	return step, nil
+}
+
+func parseLimit(value string) (int, error) {
+	if value == "" {
+		return defaultLimit, nil
+	}
+	limit, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || limit <= 0 || limit > maxLimit {
+		return 0, fmt.Errorf("limit must be between 1 and %d", maxLimit)
+	}
+	return limit, nil
+}
@@ -0,0 +1,117 @@
+package loki
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"logger/cmd/query/app/querysvc"
+	"logger/model"
+	"logger/storage/logstore"
+
+	"github.com/savsgio/atreugo/v11"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valyala/fasthttp"
+)
+
+func TestNewTailResponse(t *testing.T) {
+	h := newTestHandler()
+	e, err := parseExpr(`{service_name="api"}`)
+	require.NoError(t, err)
+	entries, err := h.selectLogs(context.Background(), e.log, baseTime, baseTime.Add(time.Minute), 10, logstore.OrderDesc)
+	require.NoError(t, err)
+
+	res := newTailResponse(entries, 2)
+	var bodies []string
+	for _, s := range res.Streams {
+		for _, v := range s.Values {
+			bodies = append(bodies, v[1])
+		}
+	}
+	assert.ElementsMatch(t, []string{"post 2", "get 3"}, bodies)
+	require.Len(t, res.DroppedEntries, 3)
+	assert.Equal(t, strconv.FormatInt(baseTime.Add(time.Second).UnixNano(), 10), res.DroppedEntries[0].Timestamp)
+	assert.Equal(t, map[string]string{serviceLabel: "api", operationLabel: "GET", levelLabel: "info"}, res.DroppedEntries[0].Labels)
+	assert.Equal(t, strconv.FormatInt(baseTime.Add(3*time.Second).UnixNano(), 10), res.DroppedEntries[2].Timestamp)
+
+	res = newTailResponse(entries, 10)
+	assert.Empty(t, res.DroppedEntries)
+}
+
+// deadlineReader records the deadline of the queries it is given.
+type deadlineReader struct {
+	fakeReader
+	deadlines []time.Time
+}
+
+func (r *deadlineReader) StreamLogs(ctx context.Context, p logstore.LogQueryParameters, fn func(*model.LogRecord) error) error {
+	deadline, _ := ctx.Deadline()
+	r.deadlines = append(r.deadlines, deadline)
+	return r.fakeReader.StreamLogs(ctx, p, fn)
+}
+
+func TestQueryRangeDeadline(t *testing.T) {
+	reader := &deadlineReader{fakeReader: fakeReader{logs: map[string][]*model.LogRecord{
+		"GET":  {record(time.Second, 0, "get")},
+		"POST": {record(2*time.Second, 0, "post")},
+	}}}
+	qs := querysvc.NewQueryService(reader, querysvc.QueryServiceOptions{
+		Limits: querysvc.LimitsConfig{Default: querysvc.Limits{Timeout: querysvc.Duration(time.Minute)}},
+	})
+	h := NewHandler(qs, nil)
+
+	var fctx fasthttp.RequestCtx
+	fctx.Request.SetRequestURI("/loki/api/v1/query_range?query=" + `{service_name="api"}` +
+		"&start=" + strconv.FormatInt(baseTime.UnixNano(), 10) + "&end=" + strconv.FormatInt(baseTime.Add(time.Minute).UnixNano(), 10))
+	c := atreugo.AcquireRequestCtx(&fctx)
+	defer atreugo.ReleaseRequestCtx(c)
+	require.NoError(t, h.QueryRange(c))
+	assert.Equal(t, http.StatusOK, fctx.Response.StatusCode(), string(fctx.Response.Body()))
+
+	// every partition is read under the deadline of the request
+	require.Len(t, reader.deadlines, 2)
+	assert.False(t, reader.deadlines[0].IsZero())
+	assert.Equal(t, reader.deadlines[0], reader.deadlines[1])
+}
+
+func TestQueryRangeExplain(t *testing.T) {
+	h := newTestHandler()
+	query := func(uri string) *fasthttp.Response {
+		var fctx fasthttp.RequestCtx
+		fctx.Request.SetRequestURI(uri)
+		c := atreugo.AcquireRequestCtx(&fctx)
+		defer atreugo.ReleaseRequestCtx(c)
+		require.NoError(t, h.QueryRange(c))
+		res := &fasthttp.Response{}
+		fctx.Response.CopyTo(res)
+		return res
+	}
+	rangeArgs := "&start=" + strconv.FormatInt(baseTime.UnixNano(), 10) + "&end=" + strconv.FormatInt(baseTime.Add(time.Minute).UnixNano(), 10)
+
+	for _, q := range []string{`{service_name="api"}`, `count_over_time({service_name="api"}[1m])`} {
+		var res struct {
+			Data struct {
+				Stats struct {
+					Explain *logstore.QueryExplain `json:"explain"`
+				} `json:"stats"`
+			} `json:"data"`
+		}
+		r := query("/loki/api/v1/query_range?query=" + q + rangeArgs + "&explain=true")
+		require.Equal(t, http.StatusOK, r.StatusCode(), string(r.Body()))
+		require.NoError(t, json.Unmarshal(r.Body(), &res))
+		require.NotNil(t, res.Data.Stats.Explain, q)
+		assert.ElementsMatch(t, []string{"operation GET", "operation POST"}, res.Data.Stats.Explain.Plan, q)
+	}
+
+	// without explain, the stats stay empty
+	r := query("/loki/api/v1/query_range?query=" + `{service_name="api"}` + rangeArgs)
+	require.Equal(t, http.StatusOK, r.StatusCode())
+	assert.Contains(t, string(r.Body()), `"stats":{}`)
+
+	r = query("/loki/api/v1/query_range?query=" + `{service_name="api"}` + rangeArgs + "&explain=maybe")
+	assert.Equal(t, http.StatusBadRequest, r.StatusCode())
+}
@@ -0,0 +1,379 @@
+package loki
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// The subset of LogQL understood by the query service:
+//
+//	{service_name="api", operation=~"GET.*", level!="debug"} |= "timeout" != "retry" |~ "code=5.." !~ "health"
+//	count_over_time(<log query>[5m])
+//	rate(<log query>[1m])
+//	sum [by (labels)] (<metric query>)
+
+type matchType int
+
+const (
+	matchEqual matchType = iota
+	matchNotEqual
+	matchRegexp
+	matchNotRegexp
+)
+
+type labelMatcher struct {
+	name  string
+	typ   matchType
+	value string
+	re    *regexp.Regexp
+}
+
+func (m *labelMatcher) matches(v string) bool {
+	switch m.typ {
+	case matchNotEqual:
+		return v != m.value
+	case matchRegexp:
+		return m.re.MatchString(v)
+	case matchNotRegexp:
+		return !m.re.MatchString(v)
+	default:
+		return v == m.value
+	}
+}
+
+type lineFilter struct {
+	typ   matchType
+	value string
+	re    *regexp.Regexp
+}
+
+func (f *lineFilter) matches(line string) bool {
+	switch f.typ {
+	case matchNotEqual:
+		return !strings.Contains(line, f.value)
+	case matchRegexp:
+		return f.re.MatchString(line)
+	case matchNotRegexp:
+		return !f.re.MatchString(line)
+	default:
+		return strings.Contains(line, f.value)
+	}
+}
+
+// logQuery is a stream selector followed by line filters.
+type logQuery struct {
+	matchers []labelMatcher
+	filters  []lineFilter
+}
+
+// matchLabel reports whether every matcher on the label name accepts value.
+func (q *logQuery) matchLabel(name, value string) bool {
+	for i := range q.matchers {
+		if q.matchers[i].name == name && !q.matchers[i].matches(value) {
+			return false
+		}
+	}
+	return true
+}
+
+func (q *logQuery) matchLine(line string) bool {
+	for i := range q.filters {
+		if !q.filters[i].matches(line) {
+			return false
+		}
+	}
+	return true
+}
+
+const (
+	countOverTime = "count_over_time"
+	rate          = "rate"
+)
+
+// metricQuery counts the lines of a log query over a sliding window.
+type metricQuery struct {
+	function string
+	window   time.Duration
+	query    logQuery
+	// sum aggregates the series; by lists the labels kept by the aggregation.
+	sum bool
+	by  []string
+}
+
+// expr is a parsed LogQL expression, either a log or a metric query.
+type expr struct {
+	log    *logQuery
+	metric *metricQuery
+}
+
+func parseExpr(s string) (*expr, error) {
+	p := &parser{input: s}
+	var e expr
+	var err error
+	if p.peekIdent() == "" {
+		e.log, err = p.parseLogQuery()
+	} else {
+		e.metric, err = p.parseMetricQuery()
+	}
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpaces()
+	if p.pos < len(p.input) {
+		return nil, p.errorf("unexpected %q", p.input[p.pos:])
+	}
+	return &e, nil
+}
+
+type parser struct {
+	input string
+	pos   int
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("parse error at position %d: %s", p.pos+1, fmt.Sprintf(format, args...))
+}
+
+func (p *parser) skipSpaces() {
+	for p.pos < len(p.input) && unicode.IsSpace(rune(p.input[p.pos])) {
+		p.pos++
+	}
+}
+
+func isIdentChar(c byte, first bool) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || !first && c >= '0' && c <= '9'
+}
+
+// peekIdent returns the identifier at the current position without consuming it.
+func (p *parser) peekIdent() string {
+	p.skipSpaces()
+	end := p.pos
+	for end < len(p.input) && isIdentChar(p.input[end], end == p.pos) {
+		end++
+	}
+	return p.input[p.pos:end]
+}
+
+func (p *parser) ident() (string, error) {
+	id := p.peekIdent()
+	if id == "" {
+		return "", p.errorf("expected identifier")
+	}
+	p.pos += len(id)
+	return id, nil
+}
+
+// consume advances past tok if the input continues with it.
+func (p *parser) consume(tok string) bool {
+	p.skipSpaces()
+	if strings.HasPrefix(p.input[p.pos:], tok) {
+		p.pos += len(tok)
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(tok string) error {
+	if !p.consume(tok) {
+		return p.errorf("expected %q", tok)
+	}
+	return nil
+}
+
+func (p *parser) str() (string, error) {
+	p.skipSpaces()
+	if p.pos >= len(p.input) {
+		return "", p.errorf("expected string")
+	}
+	quote := p.input[p.pos]
+	if quote != '"' && quote != '`' {
+		return "", p.errorf("expected string")
+	}
+	end := p.pos + 1
+	for end < len(p.input) && p.input[end] != quote {
+		if quote == '"' && p.input[end] == '\\' {
+			end++
+		}
+		end++
+	}
+	if end >= len(p.input) {
+		return "", p.errorf("unterminated string")
+	}
+	s, err := strconv.Unquote(p.input[p.pos : end+1])
+	if err != nil {
+		return "", p.errorf("invalid string: %v", err)
+	}
+	p.pos = end + 1
+	return s, nil
+}
+
+// matchOperator parses one of the operators given, longest first.
+func (p *parser) matchOperator(ops map[string]matchType) (matchType, bool) {
+	p.skipSpaces()
+	for _, op := range []string{"!=", "=~", "!~", "|=", "|~", "="} {
+		if typ, ok := ops[op]; ok && p.consume(op) {
+			return typ, true
+		}
+	}
+	return 0, false
+}
+
+var labelOperators = map[string]matchType{
+	"=":  matchEqual,
+	"!=": matchNotEqual,
+	"=~": matchRegexp,
+	"!~": matchNotRegexp,
+}
+
+var lineOperators = map[string]matchType{
+	"|=": matchEqual,
+	"!=": matchNotEqual,
+	"|~": matchRegexp,
+	"!~": matchNotRegexp,
+}
+
+// compileLineRegexp compiles the regexp of a line filter, which unlike a label matcher is not anchored.
+func compileLineRegexp(typ matchType, value string) (*regexp.Regexp, error) {
+	if typ != matchRegexp && typ != matchNotRegexp {
+		return nil, nil
+	}
+	return regexp.Compile(value)
+}
+
+func (p *parser) parseLogQuery() (*logQuery, error) {
+	q := &logQuery{}
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	for !p.consume("}") {
+		if len(q.matchers) > 0 {
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+		}
+		name, err := p.ident()
+		if err != nil {
+			return nil, err
+		}
+		typ, ok := p.matchOperator(labelOperators)
+		if !ok {
+			return nil, p.errorf("expected label matcher operator")
+		}
+		value, err := p.str()
+		if err != nil {
+			return nil, err
+		}
+		m := labelMatcher{name: name, typ: typ, value: value}
+		if typ == matchRegexp || typ == matchNotRegexp {
+			if m.re, err = regexp.Compile("^(?:" + value + ")$"); err != nil {
+				return nil, p.errorf("invalid regexp %q: %v", value, err)
+			}
+		}
+		q.matchers = append(q.matchers, m)
+	}
+	if len(q.matchers) == 0 {
+		return nil, p.errorf("stream selector must contain at least one label matcher")
+	}
+	for {
+		typ, ok := p.matchOperator(lineOperators)
+		if !ok {
+			return q, nil
+		}
+		value, err := p.str()
+		if err != nil {
+			return nil, err
+		}
+		re, err := compileLineRegexp(typ, value)
+		if err != nil {
+			return nil, p.errorf("invalid regexp %q: %v", value, err)
+		}
+		q.filters = append(q.filters, lineFilter{typ: typ, value: value, re: re})
+	}
+}
+
+func (p *parser) parseGrouping() ([]string, error) {
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	var labels []string
+	for !p.consume(")") {
+		if len(labels) > 0 {
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+		}
+		label, err := p.ident()
+		if err != nil {
+			return nil, err
+		}
+		labels = append(labels, label)
+	}
+	return labels, nil
+}
+
+func (p *parser) parseMetricQuery() (*metricQuery, error) {
+	function, err := p.ident()
+	if err != nil {
+		return nil, err
+	}
+	if function == "sum" {
+		var by []string
+		if p.peekIdent() == "by" {
+			p.pos += len("by")
+			if by, err = p.parseGrouping(); err != nil {
+				return nil, err
+			}
+		}
+		if err := p.expect("("); err != nil {
+			return nil, err
+		}
+		q, err := p.parseMetricQuery()
+		if err != nil {
+			return nil, err
+		}
+		if q.sum {
+			return nil, p.errorf("nested aggregations are not supported")
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		if by == nil && p.peekIdent() == "by" {
+			p.pos += len("by")
+			if by, err = p.parseGrouping(); err != nil {
+				return nil, err
+			}
+		}
+		q.sum, q.by = true, by
+		return q, nil
+	}
+	if function != countOverTime && function != rate {
+		return nil, p.errorf("unsupported function %q", function)
+	}
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	q, err := p.parseLogQuery()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect("["); err != nil {
+		return nil, err
+	}
+	end := strings.IndexByte(p.input[p.pos:], ']')
+	if end < 0 {
+		return nil, p.errorf("expected \"]\"")
+	}
+	window, err := time.ParseDuration(strings.TrimSpace(p.input[p.pos : p.pos+end]))
+	if err != nil || window <= 0 {
+		return nil, p.errorf("invalid range %q", p.input[p.pos:p.pos+end])
+	}
+	p.pos += end + 1
+	if err := p.expect(")"); err != nil {
+		return nil, err
+	}
+	return &metricQuery{function: function, window: window, query: *q}, nil
+}
@@ -0,0 +1,73 @@
+package loki
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLogQuery(t *testing.T) {
+	e, err := parseExpr(`{service_name="api", operation=~"GET.*", level!="debug"} |= "timeout" != "retry" |~ "code=5.." !~ "health"`)
+	require.NoError(t, err)
+	require.NotNil(t, e.log)
+	assert.Nil(t, e.metric)
+	require.Len(t, e.log.matchers, 3)
+	assert.Equal(t, labelMatcher{name: "service_name", typ: matchEqual, value: "api"}, e.log.matchers[0])
+	assert.True(t, e.log.matchLabel(operationLabel, "GET /users"))
+	assert.False(t, e.log.matchLabel(operationLabel, "POST /users"))
+	assert.False(t, e.log.matchLabel(levelLabel, "debug"))
+	require.Len(t, e.log.filters, 4)
+	assert.True(t, e.log.matchLine("timeout code=503"))
+	assert.False(t, e.log.matchLine("timeout code=503 retry"))
+	assert.False(t, e.log.matchLine("timeout code=404"))
+	assert.False(t, e.log.matchLine("timeout code=503 health"))
+}
+
+func TestParseMetricQuery(t *testing.T) {
+	tests := []struct {
+		query    string
+		function string
+		window   time.Duration
+		sum      bool
+		by       []string
+	}{
+		{query: `count_over_time({service_name="api"}[5m])`, function: countOverTime, window: 5 * time.Minute},
+		{query: `rate({service_name="api"} |= "x" [1m])`, function: rate, window: time.Minute},
+		{query: `sum(count_over_time({service_name="api"}[1m]))`, function: countOverTime, window: time.Minute, sum: true},
+		{query: `sum by (level) (count_over_time({service_name="api"}[10s]))`, function: countOverTime, window: 10 * time.Second, sum: true, by: []string{"level"}},
+		{query: `sum(rate({service_name="api"}[1m])) by (level, operation)`, function: rate, window: time.Minute, sum: true, by: []string{"level", "operation"}},
+	}
+	for _, test := range tests {
+		t.Run(test.query, func(t *testing.T) {
+			e, err := parseExpr(test.query)
+			require.NoError(t, err)
+			require.NotNil(t, e.metric)
+			assert.Equal(t, test.function, e.metric.function)
+			assert.Equal(t, test.window, e.metric.window)
+			assert.Equal(t, test.sum, e.metric.sum)
+			assert.Equal(t, test.by, e.metric.by)
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, query := range []string{
+		``,
+		`{}`,
+		`{service_name="api"`,
+		`{service_name~"api"}`,
+		`{service_name="api"} |= foo`,
+		`{service_name=~"("}`,
+		`avg_over_time({service_name="api"}[1m])`,
+		`count_over_time({service_name="api"}[x])`,
+		`sum(sum(count_over_time({service_name="api"}[1m])))`,
+		`{service_name="api"} extra`,
+	} {
+		t.Run(query, func(t *testing.T) {
+			_, err := parseExpr(query)
+			assert.Error(t, err)
+		})
+	}
+}
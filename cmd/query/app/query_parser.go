@@ -0,0 +1,107 @@
+package app
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"time"
+
+	"logger/model"
+
+	"github.com/savsgio/atreugo/v11"
+)
+
+const (
+	serviceParam   = "service"
+	operationParam = "operation"
+	timestampParam = "timestamp"
+	beforeParam    = "before"
+	afterParam     = "after"
+	hostParam      = "host"
+	traceIDParam   = "trace_id"
+	idParam        = "id"
+	fromParam      = "from"
+	toParam        = "to"
+	sampleParam    = "sample"
+	topParam       = "top"
+	explainParam   = "explain"
+
+	maxContextSize = 1000
+	maxSampleSize  = 10000
+	maxTopValues   = 100
+)
+
+// queryParam returns the value of a URL query parameter, or "" when it is absent.
+func queryParam(c *atreugo.RequestCtx, key string) string {
+	return string(c.QueryArgs().Peek(key))
+}
+
+// parseTimeParam accepts either an RFC3339 timestamp or an integer number of nanoseconds since epoch.
+func parseTimeParam(c *atreugo.RequestCtx, key string) (time.Time, error) {
+	value := queryParam(c, key)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if nanos, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(0, nanos), nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("cannot parse %s: %w", key, err)
+	}
+	return t, nil
+}
+
+// parseIntParam parses a non-negative integer parameter no greater than maxValue.
+func parseIntParam(c *atreugo.RequestCtx, key string, maxValue int) (int, error) {
+	value := queryParam(c, key)
+	if value == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("cannot parse %s: %w", key, err)
+	}
+	if n < 0 || n > maxValue {
+		return 0, fmt.Errorf("%s must be between 0 and %d", key, maxValue)
+	}
+	return n, nil
+}
+
+// parseBoolParam parses a boolean parameter, false when it is absent.
+func parseBoolParam(c *atreugo.RequestCtx, key string) (bool, error) {
+	value := queryParam(c, key)
+	if value == "" {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("cannot parse %s: %w", key, err)
+	}
+	return b, nil
+}
+
+func parseTraceIDParam(c *atreugo.RequestCtx, key string) ([]byte, error) {
+	value := queryParam(c, key)
+	if value == "" {
+		return nil, nil
+	}
+	traceID, err := hex.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse %s: %w", key, err)
+	}
+	return traceID, nil
+}
+
+// parseLogIDParam parses an optional log ID parameter.
+func parseLogIDParam(c *atreugo.RequestCtx, key string) (model.LogID, error) {
+	value := queryParam(c, key)
+	if value == "" {
+		return model.LogID{}, nil
+	}
+	id, err := model.ParseLogID(value)
+	if err != nil {
+		return id, fmt.Errorf("cannot parse %s: %w", key, err)
+	}
+	return id, nil
+}
@@ -0,0 +1,178 @@
+package querysvc
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"logger/model"
+	"logger/storage/logstore"
+)
+
+const (
+	// FieldSourceLog marks the attributes of the log records.
+	FieldSourceLog = "log"
+	// FieldSourceResource marks the attributes of the process that emitted the records.
+	FieldSourceResource = "resource"
+
+	defaultFieldsSampleSize = 1000
+	defaultFieldsTopN       = 10
+	defaultFieldsLookback   = time.Hour
+)
+
+var errSampleFull = errors.New("sample full")
+
+// FieldQueryParameters contains the parameters of a query for the attribute keys of a service.
+type FieldQueryParameters struct {
+	ServiceName  string
+	StartTimeMin time.Time
+	StartTimeMax time.Time
+	// SampleSize is the number of records the fields are discovered from.
+	SampleSize int
+	// TopN is the number of most frequent values returned for each field.
+	TopN int
+}
+
+// FieldValue is a value of a field and the number of sampled records it was seen on.
+type FieldValue struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+// Field describes an attribute key seen on the sampled records.
+type Field struct {
+	Key    string `json:"key"`
+	Source string `json:"source"`
+	// Types lists the types the values of the key were seen with.
+	Types []string `json:"types"`
+	// Count is the number of sampled records carrying the key.
+	Count     int          `json:"count"`
+	TopValues []FieldValue `json:"top_values"`
+}
+
+// Fields holds the fields discovered on a sample of records.
+type Fields struct {
+	SampledRecords int     `json:"sampled_records"`
+	Fields         []Field `json:"fields"`
+}
+
+type fieldKey struct {
+	source string
+	key    string
+}
+
+type fieldStats struct {
+	count  int
+	types  map[string]bool
+	values map[string]int
+}
+
+// GetFields returns the attribute keys of the newest records of a service, with the
+// types and most frequent values they were seen with. The sample is spread evenly
+// over the operations of the service, and covers the last hour unless a range is given.
+func (s *QueryService) GetFields(ctx context.Context, query FieldQueryParameters) (*Fields, error) {
+	if query.SampleSize <= 0 {
+		query.SampleSize = defaultFieldsSampleSize
+	}
+	if query.TopN <= 0 {
+		query.TopN = defaultFieldsTopN
+	}
+	if query.StartTimeMax.IsZero() {
+		query.StartTimeMax = time.Now()
+	}
+	if query.StartTimeMin.IsZero() {
+		query.StartTimeMin = query.StartTimeMax.Add(-defaultFieldsLookback)
+	}
+	limits := s.options.Limits.ForTenant(ctx)
+	if err := limits.checkRange(query.StartTimeMin, query.StartTimeMax); err != nil {
+		return nil, err
+	}
+	ctx, cancel := limits.withTimeout(ctx)
+	defer cancel()
+	operations, err := s.logReader.GetOperations(ctx, logstore.OperationQueryParameters{ServiceName: query.ServiceName})
+	if err != nil {
+		return nil, limits.timeoutError(ctx, err)
+	}
+	res := &Fields{Fields: []Field{}}
+	if len(operations) == 0 {
+		return res, nil
+	}
+	perOperation := (query.SampleSize + len(operations) - 1) / len(operations)
+	stats := make(map[fieldKey]*fieldStats)
+	for _, operation := range operations {
+		sampled := 0
+		err := s.logReader.StreamLogs(ctx, logstore.LogQueryParameters{
+			ServiceName:    query.ServiceName,
+			OperationName:  operation.Name,
+			StartTimeMin:   query.StartTimeMin,
+			StartTimeMax:   query.StartTimeMax,
+			ShouldFetchAll: true,
+		}, func(log *model.LogRecord) error {
+			addFields(stats, FieldSourceLog, log.Attributes)
+			if log.Process != nil {
+				addFields(stats, FieldSourceResource, log.Process.Attributes)
+			}
+			sampled++
+			if sampled >= perOperation {
+				return errSampleFull
+			}
+			return nil
+		})
+		if err != nil && !errors.Is(err, errSampleFull) {
+			return nil, limits.timeoutError(ctx, err)
+		}
+		res.SampledRecords += sampled
+	}
+	for k, st := range stats {
+		res.Fields = append(res.Fields, st.toField(k, query.TopN))
+	}
+	sort.Slice(res.Fields, func(i, j int) bool {
+		if res.Fields[i].Count != res.Fields[j].Count {
+			return res.Fields[i].Count > res.Fields[j].Count
+		}
+		if res.Fields[i].Key != res.Fields[j].Key {
+			return res.Fields[i].Key < res.Fields[j].Key
+		}
+		return res.Fields[i].Source < res.Fields[j].Source
+	})
+	return res, nil
+}
+
+func addFields(stats map[fieldKey]*fieldStats, source string, attributes []model.KeyValue) {
+	for _, kv := range attributes {
+		k := fieldKey{source: source, key: kv.Key}
+		st, ok := stats[k]
+		if !ok {
+			st = &fieldStats{types: make(map[string]bool), values: make(map[string]int)}
+			stats[k] = st
+		}
+		st.count++
+		if t := model.AnyValueType(kv.Value); t != "" {
+			st.types[t] = true
+		}
+		st.values[model.AnyValueString(kv.Value)]++
+	}
+}
+
+func (st *fieldStats) toField(k fieldKey, topN int) Field {
+	f := Field{Key: k.key, Source: k.source, Count: st.count, Types: make([]string, 0, len(st.types))}
+	for t := range st.types {
+		f.Types = append(f.Types, t)
+	}
+	sort.Strings(f.Types)
+	f.TopValues = make([]FieldValue, 0, len(st.values))
+	for value, count := range st.values {
+		f.TopValues = append(f.TopValues, FieldValue{Value: value, Count: count})
+	}
+	sort.Slice(f.TopValues, func(i, j int) bool {
+		if f.TopValues[i].Count != f.TopValues[j].Count {
+			return f.TopValues[i].Count > f.TopValues[j].Count
+		}
+		return f.TopValues[i].Value < f.TopValues[j].Value
+	})
+	if len(f.TopValues) > topN {
+		f.TopValues = f.TopValues[:topN]
+	}
+	return f
+}
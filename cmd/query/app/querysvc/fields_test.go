@@ -0,0 +1,71 @@
+package querysvc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"logger/model"
+	common "logger/model/proto/common/v1"
+	"logger/storage/logstore"
+)
+
+type fakeReader struct {
+	logstore.Reader
+	logs map[string][]*model.LogRecord
+}
+
+func (r *fakeReader) GetOperations(_ context.Context, _ logstore.OperationQueryParameters) ([]logstore.Operation, error) {
+	return []logstore.Operation{{Name: "a"}, {Name: "b"}}, nil
+}
+
+func (r *fakeReader) StreamLogs(_ context.Context, p logstore.LogQueryParameters, fn func(*model.LogRecord) error) error {
+	for _, log := range r.logs[p.OperationName] {
+		if err := fn(log); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func stringAttr(key, value string) model.KeyValue {
+	return model.KeyValue{Key: key, Value: &common.AnyValue{Value: &common.AnyValue_StringValue{StringValue: value}}}
+}
+
+func intAttr(key string, value int64) model.KeyValue {
+	return model.KeyValue{Key: key, Value: &common.AnyValue{Value: &common.AnyValue_IntValue{IntValue: value}}}
+}
+
+func TestGetFields(t *testing.T) {
+	host := &model.Process{ServiceName: "svc", Attributes: []model.KeyValue{stringAttr("host.name", "h1")}}
+	reader := &fakeReader{logs: map[string][]*model.LogRecord{
+		"a": {
+			{Attributes: []model.KeyValue{stringAttr("user", "alice"), intAttr("status", 200)}, Process: host},
+			{Attributes: []model.KeyValue{stringAttr("user", "bob"), stringAttr("status", "ok")}, Process: host},
+			{Attributes: []model.KeyValue{stringAttr("user", "alice")}, Process: host},
+		},
+		"b": {
+			{Attributes: []model.KeyValue{stringAttr("user", "carol")}},
+		},
+	}}
+	qs := NewQueryService(reader, QueryServiceOptions{})
+
+	fields, err := qs.GetFields(context.Background(), FieldQueryParameters{ServiceName: "svc", SampleSize: 4, TopN: 1})
+	require.NoError(t, err)
+	// two records per operation are sampled
+	assert.Equal(t, 3, fields.SampledRecords)
+	require.Len(t, fields.Fields, 3)
+
+	assert.Equal(t, Field{
+		Key: "user", Source: FieldSourceLog, Types: []string{model.STRING_TYPE}, Count: 3,
+		TopValues: []FieldValue{{Value: "alice", Count: 1}},
+	}, fields.Fields[0])
+	assert.Equal(t, Field{
+		Key: "host.name", Source: FieldSourceResource, Types: []string{model.STRING_TYPE}, Count: 2,
+		TopValues: []FieldValue{{Value: "h1", Count: 2}},
+	}, fields.Fields[1])
+	assert.Equal(t, "status", fields.Fields[2].Key)
+	assert.Equal(t, []string{model.INT64_TYPE, model.STRING_TYPE}, fields.Fields[2].Types)
+}
@@ -0,0 +1,192 @@
+package querysvc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"logger/pkg/tenancy"
+	"logger/storage/logstore"
+)
+
+const (
+	// GuardrailMaxRange is hit by a query over a time range longer than allowed.
+	GuardrailMaxRange = "max-range"
+	// GuardrailMaxResults is hit by a query asking for more records than allowed.
+	GuardrailMaxResults = "max-results"
+	// GuardrailTimeout is hit by a query running for longer than allowed.
+	GuardrailTimeout = "timeout"
+)
+
+// GuardrailError is returned for a query refused or interrupted by one of the limits.
+type GuardrailError struct {
+	Guardrail string
+	Msg       string
+}
+
+func (e *GuardrailError) Error() string {
+	return e.Msg
+}
+
+// AsGuardrailError returns the GuardrailError wrapped by err, if any.
+func AsGuardrailError(err error) (*GuardrailError, bool) {
+	var guardrailErr *GuardrailError
+	ok := errors.As(err, &guardrailErr)
+	return guardrailErr, ok
+}
+
+// Limits bound the cost of a query; a zero value disables the corresponding limit.
+type Limits struct {
+	MaxRange   Duration `json:"max_range"`
+	MaxResults int      `json:"max_results"`
+	Timeout    Duration `json:"timeout"`
+}
+
+// Duration is a time.Duration written as a string such as "72h" in JSON.
+type Duration time.Duration
+
+// MarshalJSON implements json.Marshaler
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("duration must be a string such as \"72h\": %w", err)
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// LimitsConfig holds the default limits and the overrides of some tenants.
+// The unset fields of an override keep the default value.
+type LimitsConfig struct {
+	Default Limits            `json:"default"`
+	Tenants map[string]Limits `json:"tenants"`
+}
+
+// LoadLimitsFile reads the per-tenant overrides from a JSON file on top of defaults.
+// A default in the file takes precedence over the one passed.
+func LoadLimitsFile(path string, defaults Limits) (LimitsConfig, error) {
+	cfg := LimitsConfig{Default: defaults}
+	if path == "" {
+		return cfg, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cfg, fmt.Errorf("cannot read limits file %v: %w", path, err)
+	}
+	var file LimitsConfig
+	if err := json.Unmarshal(data, &file); err != nil {
+		return cfg, fmt.Errorf("cannot parse limits file %v: %w", path, err)
+	}
+	cfg.Default = file.Default.or(defaults)
+	cfg.Tenants = file.Tenants
+	return cfg, nil
+}
+
+// or returns l with its unset fields taken from defaults.
+func (l Limits) or(defaults Limits) Limits {
+	if l.MaxRange == 0 {
+		l.MaxRange = defaults.MaxRange
+	}
+	if l.MaxResults == 0 {
+		l.MaxResults = defaults.MaxResults
+	}
+	if l.Timeout == 0 {
+		l.Timeout = defaults.Timeout
+	}
+	return l
+}
+
+// ForTenant returns the limits applying to the tenant of ctx.
+func (c LimitsConfig) ForTenant(ctx context.Context) Limits {
+	if tenantLimits, ok := c.Tenants[tenancy.GetTenant(ctx)]; ok {
+		return tenantLimits.or(c.Default)
+	}
+	return c.Default
+}
+
+// checkRange refuses a time range longer than allowed. A missing bound leaves the
+// range open, so it is refused as well when the range is limited.
+func (l Limits) checkRange(start, end time.Time) error {
+	if l.MaxRange <= 0 {
+		return nil
+	}
+	if start.IsZero() || end.IsZero() {
+		return &GuardrailError{
+			Guardrail: GuardrailMaxRange,
+			Msg: fmt.Sprintf("the query must set the start and end of its time range, of at most %v",
+				time.Duration(l.MaxRange)),
+		}
+	}
+	if end.Sub(start) > time.Duration(l.MaxRange) {
+		return &GuardrailError{
+			Guardrail: GuardrailMaxRange,
+			Msg: fmt.Sprintf("the time range of the query (%v) exceeds the maximum of %v, narrow it down",
+				end.Sub(start), time.Duration(l.MaxRange)),
+		}
+	}
+	return nil
+}
+
+func (l Limits) checkResults(n int) error {
+	if l.MaxResults <= 0 || n <= l.MaxResults {
+		return nil
+	}
+	return &GuardrailError{
+		Guardrail: GuardrailMaxResults,
+		Msg:       fmt.Sprintf("the query asks for %d records, more than the maximum of %d", n, l.MaxResults),
+	}
+}
+
+// checkQuery validates a log query against the limits. ShouldFetchAll does not lift the
+// bound on the number of results, as the storage reads up to NumTraces records either way.
+func (l Limits) checkQuery(p logstore.LogQueryParameters) error {
+	if err := l.checkRange(p.StartTimeMin, p.StartTimeMax); err != nil {
+		return err
+	}
+	return l.checkResults(p.NumTraces)
+}
+
+type deadlineKeyType int
+
+const deadlineKey = deadlineKeyType(iota)
+
+// withTimeout bounds the execution of a query by the timeout of the limits, unless
+// it is part of a request whose deadline was already set by withDeadline.
+func (l Limits) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if l.Timeout <= 0 || ctx.Value(deadlineKey) != nil {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, time.Duration(l.Timeout))
+}
+
+// withDeadline bounds all the queries run with the returned context by a single timeout.
+func (l Limits) withDeadline(ctx context.Context) (context.Context, context.CancelFunc) {
+	if l.Timeout <= 0 {
+		return ctx, func() {}
+	}
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(l.Timeout))
+	return context.WithValue(ctx, deadlineKey, true), cancel
+}
+
+// timeoutError turns the error of a query interrupted by the timeout into a GuardrailError.
+func (l Limits) timeoutError(ctx context.Context, err error) error {
+	if err != nil && l.Timeout > 0 && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return &GuardrailError{
+			Guardrail: GuardrailTimeout,
+			Msg:       fmt.Sprintf("the query did not complete within %v, narrow it down", time.Duration(l.Timeout)),
+		}
+	}
+	return err
+}
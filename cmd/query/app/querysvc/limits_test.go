@@ -0,0 +1,85 @@
+package querysvc
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"logger/pkg/tenancy"
+	"logger/storage/logstore"
+)
+
+func TestLoadLimitsFile(t *testing.T) {
+	defaults := Limits{MaxRange: Duration(time.Hour), MaxResults: 100, Timeout: Duration(time.Second)}
+	path := filepath.Join(t.TempDir(), "limits.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{
+		"default": {"max_results": 50},
+		"tenants": {"acme": {"max_range": "72h"}}
+	}`), 0o600))
+
+	cfg, err := LoadLimitsFile(path, defaults)
+	require.NoError(t, err)
+	assert.Equal(t, Limits{MaxRange: Duration(time.Hour), MaxResults: 50, Timeout: Duration(time.Second)}, cfg.Default)
+
+	acme := cfg.ForTenant(tenancy.WithTenant(context.Background(), "acme"))
+	assert.Equal(t, Limits{MaxRange: Duration(72 * time.Hour), MaxResults: 50, Timeout: Duration(time.Second)}, acme)
+	assert.Equal(t, cfg.Default, cfg.ForTenant(context.Background()))
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"default": {"timeout": 30}}`), 0o600))
+	_, err = LoadLimitsFile(path, defaults)
+	require.Error(t, err)
+}
+
+func TestCheckQuery(t *testing.T) {
+	l := Limits{MaxRange: Duration(time.Hour), MaxResults: 10}
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	err := l.checkQuery(logstore.LogQueryParameters{StartTimeMin: start, StartTimeMax: start.Add(2 * time.Hour)})
+	guardrailErr, ok := AsGuardrailError(err)
+	require.True(t, ok)
+	assert.Equal(t, GuardrailMaxRange, guardrailErr.Guardrail)
+
+	err = l.checkQuery(logstore.LogQueryParameters{StartTimeMin: start, StartTimeMax: start.Add(time.Minute), NumTraces: 11})
+	guardrailErr, ok = AsGuardrailError(err)
+	require.True(t, ok)
+	assert.Equal(t, GuardrailMaxResults, guardrailErr.Guardrail)
+
+	// fetching every record does not lift the limit, the storage reads NumTraces records anyway
+	guardrailErr, ok = AsGuardrailError(l.checkQuery(logstore.LogQueryParameters{StartTimeMin: start, StartTimeMax: start.Add(time.Minute), NumTraces: 11, ShouldFetchAll: true}))
+	require.True(t, ok)
+	assert.Equal(t, GuardrailMaxResults, guardrailErr.Guardrail)
+
+	// a missing bound leaves the range open
+	for _, p := range []logstore.LogQueryParameters{{StartTimeMin: start}, {StartTimeMax: start}, {}} {
+		guardrailErr, ok = AsGuardrailError(l.checkQuery(p))
+		require.True(t, ok)
+		assert.Equal(t, GuardrailMaxRange, guardrailErr.Guardrail)
+	}
+	assert.NoError(t, Limits{MaxResults: 10}.checkQuery(logstore.LogQueryParameters{}))
+	assert.NoError(t, Limits{}.checkQuery(logstore.LogQueryParameters{StartTimeMin: start, StartTimeMax: start.Add(1000 * time.Hour), NumTraces: 1e6}))
+}
+
+func TestWithDeadline(t *testing.T) {
+	l := Limits{Timeout: Duration(time.Minute)}
+	ctx, cancel := l.withDeadline(context.Background())
+	defer cancel()
+	deadline, ok := ctx.Deadline()
+	require.True(t, ok)
+
+	// the queries of the request share its deadline
+	queryCtx, queryCancel := l.withTimeout(ctx)
+	defer queryCancel()
+	assert.Equal(t, ctx, queryCtx)
+	queryDeadline, _ := queryCtx.Deadline()
+	assert.Equal(t, deadline, queryDeadline)
+
+	ctx, cancel = Limits{}.withDeadline(context.Background())
+	defer cancel()
+	_, ok = ctx.Deadline()
+	assert.False(t, ok)
+}
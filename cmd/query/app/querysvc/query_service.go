@@ -2,29 +2,130 @@ package querysvc
 
 import (
 	"context"
+	"time"
+
 	"logger/model"
 	"logger/storage/logstore"
+	"logger/storage/savedstore"
 )
 
+// QueryServiceOptions has optional members of QueryService
+type QueryServiceOptions struct {
+	// Limits are the guardrails every query is checked against
+	Limits LimitsConfig
+	// SavedSearches stores the saved searches, they are disabled when nil
+	SavedSearches savedstore.Store
+}
+
 type QueryService struct {
 	logReader logstore.Reader
+	options   QueryServiceOptions
 }
 
-func NewQueryService(logReader logstore.Reader) *QueryService {
+func NewQueryService(logReader logstore.Reader, options QueryServiceOptions) *QueryService {
 	qsvc := &QueryService{
 		logReader: logReader,
+		options:   options,
 	}
 	return qsvc
 }
 
-func (s *QueryService)GetLogs(ctx context.Context,query logstore.LogQueryParameters)([]*model.LogRecord,error) {
-	return s.logReader.GetLogs(ctx,query)
+// WithDeadline returns a context bounding a request made of several queries by the
+// timeout of the tenant as a whole; the queries run with it do not get their own.
+func (s *QueryService) WithDeadline(ctx context.Context) (context.Context, context.CancelFunc) {
+	return s.options.Limits.ForTenant(ctx).withDeadline(ctx)
+}
+
+// GetLogs returns the logs matching query. When ctx carries a logstore.QueryStats,
+// the time spent checking the guardrails and reading the storage is recorded in it.
+func (s *QueryService) GetLogs(ctx context.Context, query logstore.LogQueryParameters) ([]*model.LogRecord, error) {
+	stats := logstore.QueryStatsFromContext(ctx)
+	done := stats.StartStage("guardrails")
+	limits := s.options.Limits.ForTenant(ctx)
+	err := limits.checkQuery(query)
+	done()
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := limits.withTimeout(ctx)
+	defer cancel()
+	defer stats.StartStage("storage")()
+	logs, err := s.logReader.GetLogs(ctx, query)
+	return logs, limits.timeoutError(ctx, err)
+}
+
+func (s *QueryService) GetServices(ctx context.Context) ([]string, error) {
+	limits := s.options.Limits.ForTenant(ctx)
+	ctx, cancel := limits.withTimeout(ctx)
+	defer cancel()
+	services, err := s.logReader.GetServices(ctx)
+	return services, limits.timeoutError(ctx, err)
+}
+
+func (s *QueryService) GetOperations(ctx context.Context, query logstore.OperationQueryParameters) ([]logstore.Operation, error) {
+	limits := s.options.Limits.ForTenant(ctx)
+	ctx, cancel := limits.withTimeout(ctx)
+	defer cancel()
+	operations, err := s.logReader.GetOperations(ctx, query)
+	return operations, limits.timeoutError(ctx, err)
+}
+
+func (s *QueryService) GetLogContext(ctx context.Context, query logstore.LogContextParameters) (*logstore.LogContext, error) {
+	stats := logstore.QueryStatsFromContext(ctx)
+	done := stats.StartStage("guardrails")
+	limits := s.options.Limits.ForTenant(ctx)
+	err := limits.checkResults(query.Before + query.After)
+	done()
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := limits.withTimeout(ctx)
+	defer cancel()
+	defer stats.StartStage("storage")()
+	if !query.ID.IsZero() {
+		log, err := s.logReader.GetLog(ctx, query.ID)
+		if err != nil {
+			return nil, limits.timeoutError(ctx, err)
+		}
+		if log.Process != nil {
+			query.ServiceName = log.Process.ServiceName
+		}
+		query.Timestamp = time.Unix(0, int64(log.TimeUnixNano))
+	}
+	logContext, err := s.logReader.GetLogContext(ctx, query)
+	return logContext, limits.timeoutError(ctx, err)
 }
 
-func (s *QueryService)GetServices(ctx context.Context)([]string,error){
-	return s.logReader.GetServices(ctx)
+// GetLog returns the log with the given ID, or logstore.ErrLogNotFound.
+func (s *QueryService) GetLog(ctx context.Context, id model.LogID) (*model.LogRecord, error) {
+	limits := s.options.Limits.ForTenant(ctx)
+	ctx, cancel := limits.withTimeout(ctx)
+	defer cancel()
+	log, err := s.logReader.GetLog(ctx, id)
+	return log, limits.timeoutError(ctx, err)
+}
+
+// StreamLogs hands the logs matching query to fn as they are read. A stream fetching every
+// record, such as an export, holds none of them in memory, so it is not bounded by the
+// number of results, only by its time range and the timeout.
+func (s *QueryService) StreamLogs(ctx context.Context, query logstore.LogQueryParameters, fn func(*model.LogRecord) error) error {
+	limits := s.options.Limits.ForTenant(ctx)
+	if err := limits.checkRange(query.StartTimeMin, query.StartTimeMax); err != nil {
+		return err
+	}
+	if !query.ShouldFetchAll {
+		if err := limits.checkResults(query.NumTraces); err != nil {
+			return err
+		}
+	}
+	ctx, cancel := limits.withTimeout(ctx)
+	defer cancel()
+	return limits.timeoutError(ctx, s.logReader.StreamLogs(ctx, query, fn))
 }
 
-func (s *QueryService) GetOperations(ctx context.Context,query logstore.OperationQueryParameters)([]logstore.Operation,error){
-	return s.logReader.GetOperations(ctx,query)
+func (s *QueryService) StreamLogsByTrace(ctx context.Context, traceID []byte, fn func(*model.LogRecord) error) error {
+	limits := s.options.Limits.ForTenant(ctx)
+	ctx, cancel := limits.withTimeout(ctx)
+	defer cancel()
+	return limits.timeoutError(ctx, s.logReader.StreamLogsByTrace(ctx, traceID, fn))
 }
@@ -0,0 +1,131 @@
+package querysvc
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"logger/model"
+	"logger/storage/savedstore"
+)
+
+// ErrSavedSearchesDisabled is returned when the query service has no saved search store.
+var ErrSavedSearchesDisabled = errors.New("saved searches are not enabled")
+
+// SavedSearchResult is the result of a saved search run at a given time.
+type SavedSearchResult struct {
+	Search       *savedstore.SavedSearch `json:"search"`
+	StartTimeMin time.Time               `json:"start_time_min"`
+	StartTimeMax time.Time               `json:"start_time_max"`
+	Logs         []*model.LogRecord      `json:"logs"`
+}
+
+func (s *QueryService) savedSearches() (savedstore.Store, error) {
+	if s.options.SavedSearches == nil {
+		return nil, ErrSavedSearchesDisabled
+	}
+	return s.options.SavedSearches, nil
+}
+
+// ListSavedSearches returns the saved searches of the tenant of ctx.
+func (s *QueryService) ListSavedSearches(ctx context.Context) ([]*savedstore.SavedSearch, error) {
+	store, err := s.savedSearches()
+	if err != nil {
+		return nil, err
+	}
+	return store.List(ctx)
+}
+
+// GetSavedSearch returns a saved search of the tenant of ctx.
+func (s *QueryService) GetSavedSearch(ctx context.Context, id string) (*savedstore.SavedSearch, error) {
+	store, err := s.savedSearches()
+	if err != nil {
+		return nil, err
+	}
+	return store.Get(ctx, id)
+}
+
+// CreateSavedSearch validates and stores a new search, ignoring its ID and timestamps.
+func (s *QueryService) CreateSavedSearch(ctx context.Context, search savedstore.SavedSearch) (*savedstore.SavedSearch, error) {
+	store, err := s.savedSearches()
+	if err != nil {
+		return nil, err
+	}
+	if err := search.Validate(); err != nil {
+		return nil, err
+	}
+	if search.ID, err = newSavedSearchID(); err != nil {
+		return nil, err
+	}
+	search.CreatedAt = time.Now().UTC()
+	search.UpdatedAt = search.CreatedAt
+	if err := store.Create(ctx, &search); err != nil {
+		return nil, err
+	}
+	return &search, nil
+}
+
+// UpdateSavedSearch validates and replaces the saved search id, keeping its creation time.
+func (s *QueryService) UpdateSavedSearch(ctx context.Context, id string, search savedstore.SavedSearch) (*savedstore.SavedSearch, error) {
+	store, err := s.savedSearches()
+	if err != nil {
+		return nil, err
+	}
+	if err := search.Validate(); err != nil {
+		return nil, err
+	}
+	existing, err := store.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	search.ID = id
+	search.CreatedAt = existing.CreatedAt
+	search.UpdatedAt = time.Now().UTC()
+	if err := store.Update(ctx, &search); err != nil {
+		return nil, err
+	}
+	return &search, nil
+}
+
+// DeleteSavedSearch removes a saved search of the tenant of ctx.
+func (s *QueryService) DeleteSavedSearch(ctx context.Context, id string) error {
+	store, err := s.savedSearches()
+	if err != nil {
+		return err
+	}
+	return store.Delete(ctx, id)
+}
+
+// RunSavedSearch runs a saved search over its range relative to now. The query is
+// subject to the same guardrails as any other.
+func (s *QueryService) RunSavedSearch(ctx context.Context, id string) (*SavedSearchResult, error) {
+	search, err := s.GetSavedSearch(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	query, err := search.Query.LogQuery(time.Now())
+	if err != nil {
+		return nil, err
+	}
+	logs, err := s.GetLogs(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return &SavedSearchResult{
+		Search:       search,
+		StartTimeMin: query.StartTimeMin,
+		StartTimeMax: query.StartTimeMax,
+		Logs:         logs,
+	}, nil
+}
+
+// newSavedSearchID returns a random ID, short enough for a permalink.
+func newSavedSearchID() (string, error) {
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b[:]), nil
+}
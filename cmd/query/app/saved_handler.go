@@ -0,0 +1,141 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/savsgio/atreugo/v11"
+	"go.uber.org/zap"
+
+	"logger/cmd/query/app/querysvc"
+	"logger/storage/logstore"
+	"logger/storage/savedstore"
+)
+
+// registerSavedSearchRoutes adds the saved search endpoints, scoped to the tenant of the request.
+func (aH *APIHandler) registerSavedSearchRoutes(router *atreugo.Router) {
+	router.GET("/v1/saved", aH.ListSavedSearches)
+	router.POST("/v1/saved", aH.CreateSavedSearch)
+	router.GET("/v1/saved/{id}", aH.GetSavedSearch)
+	router.PUT("/v1/saved/{id}", aH.UpdateSavedSearch)
+	router.DELETE("/v1/saved/{id}", aH.DeleteSavedSearch)
+	router.GET("/v1/saved/{id}/run", aH.RunSavedSearch)
+}
+
+// savedSearchErrorResponse answers 404 for an unknown search, 501 when saved searches
+// are disabled, and falls back to queryErrorResponse otherwise.
+func (aH *APIHandler) savedSearchErrorResponse(c *atreugo.RequestCtx, name string, err error) error {
+	code := 0
+	switch {
+	case errors.Is(err, savedstore.ErrNotFound):
+		code = http.StatusNotFound
+	case errors.Is(err, querysvc.ErrSavedSearchesDisabled):
+		code = http.StatusNotImplemented
+	}
+	if code != 0 {
+		return c.JSONResponse(structuredError{
+			Msg:  err.Error(),
+			Code: code,
+		}, code)
+	}
+	aH.logger.Error(name, zap.Error(err))
+	return queryErrorResponse(c, err)
+}
+
+func (aH *APIHandler) parseSavedSearch(c *atreugo.RequestCtx) (savedstore.SavedSearch, error) {
+	var search savedstore.SavedSearch
+	if err := json.Unmarshal(c.PostBody(), &search); err != nil {
+		return search, err
+	}
+	return search, nil
+}
+
+func savedSearchID(c *atreugo.RequestCtx) string {
+	id, _ := c.UserValue("id").(string)
+	return id
+}
+
+// ListSavedSearches returns the saved searches of the tenant, ordered by name.
+func (aH *APIHandler) ListSavedSearches(c *atreugo.RequestCtx) error {
+	searches, err := aH.queryService.ListSavedSearches(requestContext(c))
+	if err != nil {
+		return aH.savedSearchErrorResponse(c, "ListSavedSearches", err)
+	}
+	return c.JSONResponse(searches, http.StatusOK)
+}
+
+// CreateSavedSearch stores a new search and returns it with its ID.
+func (aH *APIHandler) CreateSavedSearch(c *atreugo.RequestCtx) error {
+	search, err := aH.parseSavedSearch(c)
+	if err != nil {
+		return c.JSONResponse(structuredError{
+			Msg:  err.Error(),
+			Code: http.StatusUnprocessableEntity,
+		}, http.StatusUnprocessableEntity)
+	}
+	created, err := aH.queryService.CreateSavedSearch(requestContext(c), search)
+	if err != nil {
+		return aH.savedSearchErrorResponse(c, "CreateSavedSearch", err)
+	}
+	return c.JSONResponse(created, http.StatusCreated)
+}
+
+// GetSavedSearch returns a saved search.
+func (aH *APIHandler) GetSavedSearch(c *atreugo.RequestCtx) error {
+	search, err := aH.queryService.GetSavedSearch(requestContext(c), savedSearchID(c))
+	if err != nil {
+		return aH.savedSearchErrorResponse(c, "GetSavedSearch", err)
+	}
+	return c.JSONResponse(search, http.StatusOK)
+}
+
+// UpdateSavedSearch replaces a saved search.
+func (aH *APIHandler) UpdateSavedSearch(c *atreugo.RequestCtx) error {
+	search, err := aH.parseSavedSearch(c)
+	if err != nil {
+		return c.JSONResponse(structuredError{
+			Msg:  err.Error(),
+			Code: http.StatusUnprocessableEntity,
+		}, http.StatusUnprocessableEntity)
+	}
+	updated, err := aH.queryService.UpdateSavedSearch(requestContext(c), savedSearchID(c), search)
+	if err != nil {
+		return aH.savedSearchErrorResponse(c, "UpdateSavedSearch", err)
+	}
+	return c.JSONResponse(updated, http.StatusOK)
+}
+
+// DeleteSavedSearch removes a saved search.
+func (aH *APIHandler) DeleteSavedSearch(c *atreugo.RequestCtx) error {
+	if err := aH.queryService.DeleteSavedSearch(requestContext(c), savedSearchID(c)); err != nil {
+		return aH.savedSearchErrorResponse(c, "DeleteSavedSearch", err)
+	}
+	c.SetStatusCode(http.StatusNoContent)
+	return nil
+}
+
+// explainedSavedSearchResult is the result of a saved search run with explain=true.
+type explainedSavedSearchResult struct {
+	*querysvc.SavedSearchResult
+	Explain *logstore.QueryExplain `json:"explain"`
+}
+
+// RunSavedSearch runs a saved search over its range relative to now.
+func (aH *APIHandler) RunSavedSearch(c *atreugo.RequestCtx) error {
+	ctx, stats, err := explainContext(c)
+	if err != nil {
+		return c.JSONResponse(structuredError{
+			Msg:  err.Error(),
+			Code: http.StatusBadRequest,
+		}, http.StatusBadRequest)
+	}
+	res, err := aH.queryService.RunSavedSearch(ctx, savedSearchID(c))
+	if err != nil {
+		return aH.savedSearchErrorResponse(c, "RunSavedSearch", err)
+	}
+	if stats != nil {
+		return c.JSONResponse(explainedSavedSearchResult{SavedSearchResult: res, Explain: stats.Explain()}, http.StatusOK)
+	}
+	return c.JSONResponse(res, http.StatusOK)
+}
@@ -1,19 +1,36 @@
 package app
 
 import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+
+	"logger/cmd/query/app/loki"
 	"logger/cmd/query/app/querysvc"
+	api_v1 "logger/model/proto/query/v1"
+	"logger/pkg/bearertoken"
 	"logger/pkg/healthcheck"
+	"logger/pkg/jtracer"
+	"logger/pkg/tenancy"
 
 	"github.com/savsgio/atreugo/v11"
 	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/reflection"
 )
 
 type Server struct {
-	logger *zap.Logger
-	querySvc *querysvc.QueryService
+	logger       *zap.Logger
+	querySvc     *querysvc.QueryService
 	healthCheck  *healthcheck.HealthCheck
 	queryOptions QueryOptions
-	server *atreugo.Atreugo
+	tenancyMgr   *tenancy.Manager
+	server       *atreugo.Atreugo
+	httpConn     net.Listener
+	grpcServer   *grpc.Server
+	grpcConn     net.Listener
 }
 
 func NewServer(
@@ -21,37 +38,154 @@ func NewServer(
 	healtcheck *healthcheck.HealthCheck,
 	querySvc *querysvc.QueryService,
 	options *QueryOptions,
-)(*Server,error){
-	server :=  createHttpServer(logger,querySvc)
+	tm *tenancy.Manager,
+	tracer *jtracer.JTracer,
+) (*Server, error) {
+	server, err := createHttpServer(logger, querySvc, options, tm, tracer)
+	if err != nil {
+		return nil, err
+	}
 
-	return &Server{
-		logger: logger,
-		querySvc: querySvc,
-		healthCheck: healtcheck,
+	s := &Server{
+		logger:       logger,
+		querySvc:     querySvc,
+		healthCheck:  healtcheck,
 		queryOptions: *options,
-		server: server,
-	},nil
+		tenancyMgr:   tm,
+		server:       server,
+	}
+	grpcServer, err := s.createGRPCServer()
+	if err != nil {
+		return nil, err
+	}
+	s.grpcServer = grpcServer
+	return s, nil
 }
 
 func createHttpServer(
 	logger *zap.Logger,
 	querySvc *querysvc.QueryService,
-)(*atreugo.Atreugo){
-	apiHandler := NewAPIHandler(querySvc,HandlerOptions.Logger(logger))
-	r := NewRouter()
+	queryOpts *QueryOptions,
+	tm *tenancy.Manager,
+	tracer *jtracer.JTracer,
+) (*atreugo.Atreugo, error) {
+	apiHandler := NewAPIHandler(querySvc, HandlerOptions.Logger(logger))
+	staticOptions := StaticAssetsHandlerOptions{
+		BasePath:     queryOpts.BasePath,
+		UIConfigPath: queryOpts.UIConfig,
+		LogAccess:    queryOpts.StaticAssets.LogAccess,
+		Logger:       logger,
+	}
+	server := NewRouter(queryOpts, tracer)
+	r := basePathRouter(server, queryOpts.BasePath)
+	if tm != nil && tm.Enabled {
+		// browsers do not send the tenant header when loading the UI
+		checkTenant := tenancyMiddleware(tm)
+		r.UseBefore(checkTenant)
+		staticOptions.SkipMiddlewares = append(staticOptions.SkipMiddlewares, checkTenant)
+	}
+	staticHandler, err := NewStaticAssetsHandler(queryOpts.StaticAssets.Path, staticOptions)
+	if err != nil {
+		return nil, fmt.Errorf("could not create static assets handler: %w", err)
+	}
 	apiHandler.RegisterRoutes(r)
-	return r
+	loki.NewHandler(querySvc, logger).RegisterRoutes(r)
+	// registered last, the catch-all route of the UI only gets the paths no API claimed
+	staticHandler.RegisterRoutes(r)
+	return server, nil
 }
 
-func (aH *Server) Start() error{
-	if err := aH.server.ListenAndServe(); err != nil {
-		aH.logger.Error("Fail to start server",zap.Error(err))
-	    return err
+// httpListener listens on the HTTP host-port, wrapping the listener in TLS when enabled.
+// The TLS config reloads the certificates through the cert watcher of TLSHTTP.
+func (aH *Server) httpListener() (net.Listener, error) {
+	conn, err := net.Listen("tcp", aH.queryOptions.HTTPHostPort)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on HTTP port: %w", err)
+	}
+	if !aH.queryOptions.TLSHTTP.Enabled {
+		return conn, nil
+	}
+	tlsCfg, err := aH.queryOptions.TLSHTTP.Config(aH.logger)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to load TLS config for HTTP server: %w", err)
+	}
+	return tls.NewListener(conn, tlsCfg), nil
+}
+
+func (aH *Server) createGRPCServer() (*grpc.Server, error) {
+	var grpcOpts []grpc.ServerOption
+
+	if aH.queryOptions.TLSGRPC.Enabled {
+		tlsCfg, err := aH.queryOptions.TLSGRPC.Config(aH.logger)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load TLS config for gRPC server: %w", err)
+		}
+		grpcOpts = append(grpcOpts, grpc.Creds(credentials.NewTLS(tlsCfg)))
 	}
+	var unaryInterceptors []grpc.UnaryServerInterceptor
+	var streamInterceptors []grpc.StreamServerInterceptor
+	if aH.tenancyMgr != nil && aH.tenancyMgr.Enabled {
+		unaryInterceptors = append(unaryInterceptors, tenancy.NewGuardingUnaryInterceptor(aH.tenancyMgr))
+		streamInterceptors = append(streamInterceptors, tenancy.NewGuardingStreamInterceptor(aH.tenancyMgr))
+	}
+	if aH.queryOptions.BearerTokenPropagation {
+		header := aH.queryOptions.BearerTokenHeader
+		if header == "" {
+			header = bearertoken.DefaultHeader
+		}
+		unaryInterceptors = append(unaryInterceptors, bearertoken.NewUnaryServerInterceptor(header))
+		streamInterceptors = append(streamInterceptors, bearertoken.NewStreamServerInterceptor(header))
+	}
+	grpcOpts = append(grpcOpts,
+		grpc.ChainUnaryInterceptor(unaryInterceptors...),
+		grpc.ChainStreamInterceptor(streamInterceptors...),
+	)
+
+	server := grpc.NewServer(grpcOpts...)
+	reflection.Register(server)
+	api_v1.RegisterQueryServiceServer(server, NewGRPCHandler(aH.querySvc, aH.logger))
+	return server, nil
+}
+
+func (aH *Server) Start() error {
+	grpcConn, err := net.Listen("tcp", aH.queryOptions.GRPCHostPort)
+	if err != nil {
+		return fmt.Errorf("failed to listen on gRPC port: %w", err)
+	}
+	aH.grpcConn = grpcConn
+	aH.logger.Info("Starting GRPC server", zap.String("addr", aH.queryOptions.GRPCHostPort))
+	go func() {
+		if err := aH.grpcServer.Serve(grpcConn); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+			aH.logger.Error("Could not start GRPC server", zap.Error(err))
+		}
+	}()
+
+	httpConn, err := aH.httpListener()
+	if err != nil {
+		return err
+	}
+	aH.httpConn = httpConn
+	aH.logger.Info("Starting HTTP server", zap.String("addr", aH.queryOptions.HTTPHostPort), zap.String("base-path", aH.queryOptions.BasePath))
+	go func() {
+		if err := aH.server.Serve(httpConn); err != nil {
+			aH.logger.Error("Could not start HTTP server", zap.Error(err))
+		}
+	}()
 	return nil
 }
 
 func (aH *Server) Close() error {
-	return aH.server.Shutdown()
+	var errs []error
+	if err := aH.queryOptions.TLSGRPC.Close(); err != nil {
+		errs = append(errs, fmt.Errorf("failed to close gRPC TLS cert watcher: %w", err))
+	}
+	if err := aH.queryOptions.TLSHTTP.Close(); err != nil {
+		errs = append(errs, fmt.Errorf("failed to close HTTP TLS cert watcher: %w", err))
+	}
+	aH.grpcServer.Stop()
+	if err := aH.server.Shutdown(); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
 }
-
@@ -0,0 +1,155 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+
+	"logger/cmd/query/app/ui"
+
+	"github.com/savsgio/atreugo/v11"
+	"go.uber.org/zap"
+)
+
+const indexHTML = "index.html"
+
+var basePathPattern = []byte(`<base href="/"`)
+
+// apiPathPrefixes are the paths of the APIs, which are never routes of the SPA
+var apiPathPrefixes = []string{"api/", "v1/", "loki/"}
+
+// StaticAssetsHandlerOptions defines options for NewStaticAssetsHandler
+type StaticAssetsHandlerOptions struct {
+	BasePath     string
+	UIConfigPath string
+	LogAccess    bool
+	Logger       *zap.Logger
+	// SkipMiddlewares are the middlewares of the API the assets are served without
+	SkipMiddlewares []atreugo.Middleware
+}
+
+// StaticAssetsHandler serves the UI single page application and its configuration.
+type StaticAssetsHandler struct {
+	options   StaticAssetsHandlerOptions
+	assetsFS  fs.FS
+	indexHTML []byte
+	uiConfig  []byte
+}
+
+// NewStaticAssetsHandler returns a StaticAssetsHandler serving the UI bundle from
+// staticAssetsRoot, or the bundle embedded in the binary when it is empty.
+func NewStaticAssetsHandler(staticAssetsRoot string, options StaticAssetsHandlerOptions) (*StaticAssetsHandler, error) {
+	if options.Logger == nil {
+		options.Logger = zap.NewNop()
+	}
+	assetsFS := ui.StaticFiles()
+	if staticAssetsRoot != "" {
+		assetsFS = os.DirFS(staticAssetsRoot)
+	}
+	index, err := fs.ReadFile(assetsFS, indexHTML)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read UI static assets: %w", err)
+	}
+	index, err = rewriteBasePath(index, options.BasePath)
+	if err != nil {
+		return nil, err
+	}
+	uiConfig, err := loadUIConfig(options.UIConfigPath)
+	if err != nil {
+		return nil, err
+	}
+	return &StaticAssetsHandler{
+		options:   options,
+		assetsFS:  assetsFS,
+		indexHTML: index,
+		uiConfig:  uiConfig,
+	}, nil
+}
+
+// rewriteBasePath points the <base> element of index.html at the base path, so that
+// the relative links of the bundle resolve behind a reverse proxy.
+func rewriteBasePath(index []byte, basePath string) ([]byte, error) {
+	basePath = strings.TrimSuffix(basePath, "/")
+	if basePath == "" {
+		return index, nil
+	}
+	if !strings.HasPrefix(basePath, "/") {
+		return nil, fmt.Errorf("invalid base path %q: it must start with a slash", basePath)
+	}
+	if !bytes.Contains(index, basePathPattern) {
+		return nil, errors.New("cannot find <base href=\"/\"> in index.html")
+	}
+	return bytes.Replace(index, basePathPattern, []byte(`<base href="`+basePath+`/"`), 1), nil
+}
+
+func loadUIConfig(uiConfigPath string) ([]byte, error) {
+	if uiConfigPath == "" {
+		return []byte("{}"), nil
+	}
+	data, err := os.ReadFile(uiConfigPath)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read UI config file %v: %w", uiConfigPath, err)
+	}
+	if !json.Valid(data) {
+		return nil, fmt.Errorf("cannot parse UI config file %v: invalid JSON", uiConfigPath)
+	}
+	return data, nil
+}
+
+// RegisterRoutes registers the UI config endpoint and a catch-all route for the assets,
+// which answers index.html for every path that is not a file so that the SPA can route
+// it, except the paths of the APIs.
+func (sH *StaticAssetsHandler) RegisterRoutes(router *atreugo.Router) {
+	router.GET("/api/ui-config", sH.GetUIConfig).SkipMiddlewares(sH.options.SkipMiddlewares...)
+	router.GET("/{filepath:*}", sH.ServeAsset).SkipMiddlewares(sH.options.SkipMiddlewares...)
+}
+
+// GetUIConfig returns the content of the file passed in query.ui-config.
+func (sH *StaticAssetsHandler) GetUIConfig(c *atreugo.RequestCtx) error {
+	c.SetContentType("application/json")
+	c.SetStatusCode(http.StatusOK)
+	c.SetBody(sH.uiConfig)
+	return nil
+}
+
+// ServeAsset serves a file of the UI bundle, or index.html when the path is not a file.
+// An unknown path of an API is answered with 404, a client must not take the SPA for
+// its response.
+func (sH *StaticAssetsHandler) ServeAsset(c *atreugo.RequestCtx) error {
+	name, _ := c.UserValue("filepath").(string)
+	name = strings.TrimPrefix(path.Clean("/"+name), "/")
+	if sH.options.LogAccess {
+		sH.options.Logger.Info("serving static asset", zap.String("path", name))
+	}
+	for _, prefix := range apiPathPrefixes {
+		if name+"/" == prefix || strings.HasPrefix(name, prefix) {
+			return c.JSONResponse(structuredError{
+				Msg:  fmt.Sprintf("unknown API path /%s", name),
+				Code: http.StatusNotFound,
+			}, http.StatusNotFound)
+		}
+	}
+	if name != "" && name != indexHTML {
+		if data, err := fs.ReadFile(sH.assetsFS, name); err == nil {
+			contentType := mime.TypeByExtension(path.Ext(name))
+			if contentType == "" {
+				contentType = http.DetectContentType(data)
+			}
+			c.SetContentType(contentType)
+			c.SetStatusCode(http.StatusOK)
+			c.SetBody(data)
+			return nil
+		}
+	}
+	c.SetContentType("text/html; charset=utf-8")
+	c.SetStatusCode(http.StatusOK)
+	c.SetBody(sH.indexHTML)
+	return nil
+}
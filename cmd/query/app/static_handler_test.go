@@ -0,0 +1,78 @@
+package app
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRewriteBasePath(t *testing.T) {
+	index := []byte(`<head><base href="/" data-inject-target="BASE_URL" /></head>`)
+
+	res, err := rewriteBasePath(index, "/")
+	require.NoError(t, err)
+	assert.Equal(t, index, res)
+
+	res, err = rewriteBasePath(index, "/logger/")
+	require.NoError(t, err)
+	assert.Equal(t, `<head><base href="/logger/" data-inject-target="BASE_URL" /></head>`, string(res))
+
+	_, err = rewriteBasePath(index, "logger")
+	require.Error(t, err)
+
+	_, err = rewriteBasePath([]byte(`<head></head>`), "/logger")
+	require.Error(t, err)
+}
+
+func TestNewStaticAssetsHandler(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "index.html"), []byte(`<base href="/" />`), 0o600))
+	configPath := filepath.Join(dir, "ui-config.json")
+	require.NoError(t, os.WriteFile(configPath, []byte(`{"menu":[]}`), 0o600))
+
+	handler, err := NewStaticAssetsHandler(dir, StaticAssetsHandlerOptions{BasePath: "/logger", UIConfigPath: configPath})
+	require.NoError(t, err)
+	assert.Equal(t, `<base href="/logger/" />`, string(handler.indexHTML))
+	assert.Equal(t, `{"menu":[]}`, string(handler.uiConfig))
+
+	require.NoError(t, os.WriteFile(configPath, []byte(`{`), 0o600))
+	_, err = NewStaticAssetsHandler(dir, StaticAssetsHandlerOptions{UIConfigPath: configPath})
+	require.Error(t, err)
+
+	_, err = NewStaticAssetsHandler(t.TempDir(), StaticAssetsHandlerOptions{})
+	require.Error(t, err)
+}
+
+func TestEmbeddedStaticAssets(t *testing.T) {
+	handler, err := NewStaticAssetsHandler("", StaticAssetsHandlerOptions{})
+	require.NoError(t, err)
+	assert.Contains(t, string(handler.indexHTML), `<base href="/"`)
+	assert.Equal(t, "{}", string(handler.uiConfig))
+}
+
+func TestServeAsset(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "index.html"), []byte(`<base href="/" />`), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "app.js"), []byte(`main()`), 0o600))
+	handler, err := NewStaticAssetsHandler(dir, StaticAssetsHandlerOptions{})
+	require.NoError(t, err)
+	server := NewRouter(&QueryOptions{}, nil)
+	handler.RegisterRoutes(server.Router)
+	s := serve(t, server)
+
+	assert.Equal(t, http.StatusOK, s.do(t, http.MethodGet, "/app.js", "", nil, nil))
+	assert.Equal(t, http.StatusOK, s.do(t, http.MethodGet, "/api/ui-config", "", nil, nil))
+	// the routes of the SPA get index.html
+	assert.Equal(t, http.StatusOK, s.do(t, http.MethodGet, "/search/saved", "", nil, nil))
+
+	// an unknown path of an API is not taken for a route of the SPA
+	for _, uri := range []string{"/api/unknown", "/v1/logs/unknown/path", "/v1", "/loki/api/v1/unknown"} {
+		var res structuredError
+		assert.Equal(t, http.StatusNotFound, s.do(t, http.MethodGet, uri, "", nil, &res), uri)
+		assert.Equal(t, http.StatusNotFound, res.Code, uri)
+	}
+}
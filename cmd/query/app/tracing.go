@@ -0,0 +1,63 @@
+package app
+
+import (
+	"fmt"
+
+	"github.com/savsgio/atreugo/v11"
+	"github.com/valyala/fasthttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// requestHeaderCarrier adapts the request headers to the propagation.TextMapCarrier interface.
+type requestHeaderCarrier struct {
+	header *fasthttp.RequestHeader
+}
+
+func (c requestHeaderCarrier) Get(key string) string {
+	return string(c.header.Peek(key))
+}
+
+func (c requestHeaderCarrier) Set(key, value string) {
+	c.header.Set(key, value)
+}
+
+func (c requestHeaderCarrier) Keys() []string {
+	var keys []string
+	c.header.VisitAll(func(key, _ []byte) {
+		keys = append(keys, string(key))
+	})
+	return keys
+}
+
+// tracingMiddlewares returns the middlewares recording a span around each HTTP request:
+// the first one starts the span, continuing the trace of the caller, and attaches it to
+// the request context, the final one ends it once the response is written.
+func tracingMiddlewares(tracerProvider trace.TracerProvider) (atreugo.Middleware, atreugo.FinalMiddleware) {
+	tracer := tracerProvider.Tracer("logger/cmd/query/app")
+	start := func(c *atreugo.RequestCtx) error {
+		ctx := otel.GetTextMapPropagator().Extract(requestContext(c), requestHeaderCarrier{&c.Request.Header})
+		method := string(c.Method())
+		ctx, _ = tracer.Start(ctx, fmt.Sprintf("HTTP %s %s", method, c.Path()),
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.request.method", method),
+				attribute.String("url.path", string(c.Path())),
+			),
+		)
+		c.AttachContext(ctx)
+		return c.Next()
+	}
+	end := func(c *atreugo.RequestCtx) {
+		span := trace.SpanFromContext(requestContext(c))
+		status := c.Response.StatusCode()
+		span.SetAttributes(attribute.Int("http.response.status_code", status))
+		if status >= fasthttp.StatusInternalServerError {
+			span.SetStatus(codes.Error, fasthttp.StatusMessage(status))
+		}
+		span.End()
+	}
+	return start, end
+}
@@ -0,0 +1,66 @@
+package app
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/savsgio/atreugo/v11"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"logger/cmd/query/app/querysvc"
+	"logger/pkg/jtracer"
+)
+
+func TestTracingMiddlewares(t *testing.T) {
+	propagator := otel.GetTextMapPropagator()
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() { otel.SetTextMapPropagator(propagator) })
+
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	reader := &fakeReader{}
+	server := NewRouter(&QueryOptions{QueryOptionsBase: QueryOptionsBase{EnableTracing: true}}, &jtracer.JTracer{OTEL: tp})
+	NewAPIHandler(querysvc.NewQueryService(reader, querysvc.QueryServiceOptions{})).RegisterRoutes(server.Router)
+	server.GET("/fail", func(c *atreugo.RequestCtx) error {
+		c.SetStatusCode(http.StatusServiceUnavailable)
+		return nil
+	})
+	s := serve(t, server)
+
+	// the span continues the trace of the caller and the handlers run within it
+	parent := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	status := s.do(t, http.MethodGet, "/v1/logs/01ARZ3NDEKTSV4RRFFQ69G5FAV", "", map[string]string{"traceparent": parent}, nil)
+	assert.Equal(t, http.StatusNotFound, status)
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 1)
+	span := spans[0]
+	assert.Equal(t, "HTTP GET /v1/logs/01ARZ3NDEKTSV4RRFFQ69G5FAV", span.Name)
+	assert.Equal(t, trace.SpanKindServer, span.SpanKind)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", span.SpanContext.TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", span.Parent.SpanID().String())
+	assert.Equal(t, codes.Unset, span.Status.Code)
+	assert.ElementsMatch(t, []attribute.KeyValue{
+		attribute.String("http.request.method", http.MethodGet),
+		attribute.String("url.path", "/v1/logs/01ARZ3NDEKTSV4RRFFQ69G5FAV"),
+		attribute.Int("http.response.status_code", http.StatusNotFound),
+	}, span.Attributes)
+	assert.Equal(t, span.SpanContext.SpanID(), trace.SpanContextFromContext(reader.ctx).SpanID())
+
+	// a server error marks the span as failed
+	exporter.Reset()
+	status = s.do(t, http.MethodGet, "/fail", "", nil, nil)
+	assert.Equal(t, http.StatusServiceUnavailable, status)
+	spans = exporter.GetSpans()
+	require.Len(t, spans, 1)
+	assert.False(t, spans[0].Parent.IsValid())
+	assert.Equal(t, codes.Error, spans[0].Status.Code)
+}
@@ -0,0 +1,12 @@
+//go:build ui
+
+package ui
+
+import "embed"
+
+// The UI bundle is copied to ./actual by `make build-ui` before building with the ui tag.
+//
+//go:embed all:actual
+var embeddedFiles embed.FS
+
+const embeddedRoot = "actual"
@@ -0,0 +1,14 @@
+// Package ui holds the static assets of the logger UI embedded in the query service.
+package ui
+
+import "io/fs"
+
+// StaticFiles returns the embedded UI assets, rooted at the directory holding index.html.
+func StaticFiles() fs.FS {
+	files, err := fs.Sub(embeddedFiles, embeddedRoot)
+	if err != nil {
+		// the root is a constant naming an embedded directory
+		panic(err)
+	}
+	return files
+}
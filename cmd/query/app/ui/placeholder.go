@@ -0,0 +1,12 @@
+//go:build !ui
+
+package ui
+
+import "embed"
+
+// embeddedFiles holds a placeholder page, served when the query service is built without the UI bundle.
+//
+//go:embed placeholder
+var embeddedFiles embed.FS
+
+const embeddedRoot = "placeholder"
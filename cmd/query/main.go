@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"logger/cmd/internal/docs"
@@ -9,10 +10,15 @@ import (
 	"logger/cmd/internal/printconfig"
 	"logger/cmd/internal/status"
 	"logger/pkg/config"
+	"logger/pkg/jtracer"
 	"logger/pkg/metrics"
+	"logger/pkg/tenancy"
 	"logger/pkg/version"
 	"logger/plugin/storage"
 	"logger/ports"
+	"logger/storage/logstore/caching"
+	storagemetrics "logger/storage/logstore/metrics"
+	"logger/storage/logstore/tracing"
 
 	"os"
 
@@ -24,6 +30,8 @@ import (
 	"go.uber.org/zap"
 )
 
+const serviceName = "query"
+
 func main(){
 	svc := flags.NewService(ports.QueryAdminHTTP)
 	storageFactory, err := storage.NewFactory(storage.FactoryConfigFromEnvAndCLI(os.Args, os.Stderr))
@@ -54,9 +62,25 @@ func main(){
 			if err != nil {
 				logger.Fatal("Failed to create span reader", zap.Error(err))
 			}
-			// queryServiceOptions := queryOpts.BuildQueryServiceOptions(storageFactory, logger)
-			queryService := querysvc.NewQueryService(logReader)
-			server, err := app.NewServer(svc.Logger, svc.HC(), queryService, queryOpts)
+			jt := jtracer.NoOp()
+			if queryOpts.EnableTracing {
+				jt, err = jtracer.New(serviceName, queryOpts.Tracing)
+				if err != nil {
+					logger.Fatal("Failed to create tracer", zap.Error(err))
+				}
+				logReader = tracing.NewReadTracingDecorator(logReader, jt.OTEL)
+			}
+			logReader = storagemetrics.NewReadMetricsDecorator(logReader, baseFactory.Namespace(metrics.NSOptions{Name: "query"}))
+			if queryOpts.Cache.Enabled() {
+				logReader = caching.NewReader(logReader, queryOpts.Cache, baseFactory.Namespace(metrics.NSOptions{Name: "query"}))
+			}
+			queryServiceOptions, err := queryOpts.BuildQueryServiceOptions(storageFactory)
+			if err != nil {
+				logger.Fatal("Failed to configure query service", zap.Error(err))
+			}
+			queryService := querysvc.NewQueryService(logReader, queryServiceOptions)
+			tm := tenancy.NewManager(&queryOpts.Tenancy)
+			server, err := app.NewServer(svc.Logger, svc.HC(), queryService, queryOpts, tm, jt)
 			if err != nil {
 				logger.Fatal("Failed to create server", zap.Error(err))
 			}
@@ -70,9 +94,9 @@ func main(){
 				if err := storageFactory.Close(); err != nil {
 					logger.Error("Failed to close storage factory", zap.Error(err))
 				}
-				// if err = jt.Close(context.Background()); err != nil {
-				// 	logger.Fatal("Error shutting down tracer provider", zap.Error(err))
-				// }
+				if err = jt.Close(context.Background()); err != nil {
+					logger.Fatal("Error shutting down tracer provider", zap.Error(err))
+				}
 			})
 			return nil
 
@@ -93,7 +117,7 @@ func main(){
 		app.AddFlags,
 		// metricsReaderFactory.AddFlags,
 		// add tenancy flags here to avoid panic caused by double registration in all-in-one
-		// tenancy.AddFlags,
+		tenancy.AddFlags,
 	)
 
 	if err := command.Execute(); err != nil {
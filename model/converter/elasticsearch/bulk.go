@@ -0,0 +1,104 @@
+package elasticsearch
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrInvalidRequest is returned for a bulk request whose action lines cannot be read.
+	ErrInvalidRequest = errors.New("invalid bulk request")
+	// ErrInvalidDocument is the error of an item whose document is not a JSON object.
+	ErrInvalidDocument = errors.New("invalid document")
+	// ErrUnsupportedAction is the error of an item whose action does not add a document.
+	ErrUnsupportedAction = errors.New("unsupported bulk action")
+)
+
+// Bulk actions, index and create add a document and are the only ones ingested.
+const (
+	ActionIndex  = "index"
+	ActionCreate = "create"
+	ActionUpdate = "update"
+	ActionDelete = "delete"
+)
+
+// Item is an operation of a bulk request.
+type Item struct {
+	Action string
+	Index  string
+	// ID is the document ID set by the client, empty when the server is to generate one
+	ID       string
+	Document map[string]interface{}
+	// Err is why the item cannot be ingested, nil when it can
+	Err error
+}
+
+// actionMetadata is the metadata of an action line, e.g. {"index":{"_index":"logs","_id":"1"}}.
+type actionMetadata struct {
+	Index string `json:"_index"`
+	ID    string `json:"_id"`
+}
+
+// ParseBulk reads the NDJSON body of a bulk request, made of action lines each followed by a
+// document line, but for delete actions. defaultIndex is the index of the request path, used
+// by the actions that do not name one. Numbers in documents are decoded as json.Number.
+func ParseBulk(data []byte, defaultIndex string) ([]Item, error) {
+	var items []Item
+	lines := bytes.Split(data, []byte{'\n'})
+	for i := 0; i < len(lines); i++ {
+		line := bytes.TrimSpace(lines[i])
+		if len(line) == 0 {
+			continue
+		}
+		var action map[string]actionMetadata
+		if err := json.Unmarshal(line, &action); err != nil || len(action) != 1 {
+			return nil, fmt.Errorf("%w: malformed action line %d", ErrInvalidRequest, i+1)
+		}
+		var item Item
+		for name, meta := range action {
+			item = Item{Action: name, Index: meta.Index, ID: meta.ID}
+		}
+		if item.Index == "" {
+			item.Index = defaultIndex
+		}
+		switch item.Action {
+		case ActionDelete:
+			item.Err = fmt.Errorf("%w: %s", ErrUnsupportedAction, item.Action)
+			items = append(items, item)
+			continue
+		case ActionIndex, ActionCreate, ActionUpdate:
+		default:
+			return nil, fmt.Errorf("%w: unknown action %q on line %d", ErrInvalidRequest, item.Action, i+1)
+		}
+
+		i++
+		if i == len(lines) || len(bytes.TrimSpace(lines[i])) == 0 {
+			return nil, fmt.Errorf("%w: missing document after line %d", ErrInvalidRequest, i)
+		}
+		if item.Action == ActionUpdate {
+			item.Err = fmt.Errorf("%w: %s", ErrUnsupportedAction, item.Action)
+		} else {
+			item.Document, item.Err = parseDocument(lines[i])
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+func parseDocument(line []byte) (map[string]interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(line))
+	dec.UseNumber()
+	var doc map[string]interface{}
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidDocument, err)
+	}
+	if doc == nil {
+		return nil, fmt.Errorf("%w: not a JSON object", ErrInvalidDocument)
+	}
+	if dec.More() {
+		return nil, fmt.Errorf("%w: trailing data", ErrInvalidDocument)
+	}
+	return doc, nil
+}
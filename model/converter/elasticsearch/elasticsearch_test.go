@@ -0,0 +1,136 @@
+package elasticsearch
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"logger/model"
+	common "logger/model/proto/common/v1"
+	pbL "logger/model/proto/logs/v1"
+)
+
+var (
+	now     = time.Date(2024, time.March, 10, 12, 0, 0, 0, time.UTC)
+	mapping = Mapping{
+		TimestampField: "@timestamp",
+		MessageField:   "message",
+		LevelField:     "log.level",
+		ServiceField:   "service.name",
+	}
+)
+
+func TestParseBulk(t *testing.T) {
+	items, err := ParseBulk([]byte(`{"index":{"_index":"app","_id":"1"}}
+{"message":"hello"}
+{"create":{}}
+{"message":"world","count":2}
+{"delete":{"_id":"2"}}
+{"update":{"_id":"3"}}
+{"doc":{"message":"updated"}}
+{"index":{}}
+not json
+{"index":{}}
+[]
+
+`), "logs")
+	require.NoError(t, err)
+	assert.Equal(t, []Item{
+		{Action: ActionIndex, Index: "app", ID: "1", Document: map[string]interface{}{"message": "hello"}},
+		{Action: ActionCreate, Index: "logs", Document: map[string]interface{}{"message": "world", "count": json.Number("2")}},
+		{Action: ActionDelete, Index: "logs", ID: "2", Err: items[2].Err},
+		{Action: ActionUpdate, Index: "logs", ID: "3", Err: items[3].Err},
+		{Action: ActionIndex, Index: "logs", Err: items[4].Err},
+		{Action: ActionIndex, Index: "logs", Err: items[5].Err},
+	}, items)
+	assert.ErrorIs(t, items[2].Err, ErrUnsupportedAction)
+	assert.ErrorIs(t, items[3].Err, ErrUnsupportedAction)
+	assert.ErrorIs(t, items[4].Err, ErrInvalidDocument)
+	assert.ErrorIs(t, items[5].Err, ErrInvalidDocument)
+}
+
+func TestParseBulkErrors(t *testing.T) {
+	for _, data := range []string{
+		`{"index":{}}`,
+		`{"index":{}}` + "\n\n" + `{"message":"hello"}`,
+		`{"upsert":{}}` + "\n" + `{"message":"hello"}`,
+		`{"index":{},"create":{}}` + "\n" + `{"message":"hello"}`,
+		`{"index":1}` + "\n" + `{"message":"hello"}`,
+		`{"message":"hello"}`,
+	} {
+		_, err := ParseBulk([]byte(data), "")
+		assert.ErrorIs(t, err, ErrInvalidRequest, data)
+	}
+}
+
+func TestToDomainLog(t *testing.T) {
+	doc, err := parseDocument([]byte(`{"@timestamp":"2024-03-10T13:00:00.5+01:00","message":"hello",
+		"log":{"level":"WARN","logger":"main"},"service.name":"checkout","host":{"name":"web-1","ip":["10.0.0.1"]},
+		"http":{"status":500,"ok":false,"ratio":0.5}}`))
+	require.NoError(t, err)
+	record := ToDomainLog(doc, "logs", mapping, now.Add(time.Minute))
+
+	assert.Equal(t, uint64(now.Add(500*time.Millisecond).UnixNano()), record.TimeUnixNano)
+	assert.Equal(t, uint64(now.Add(time.Minute).UnixNano()), record.ObservedTimeUnixNano)
+	assert.Equal(t, "hello", record.Body)
+	assert.Equal(t, "WARN", record.SeverityText)
+	assert.Equal(t, pbL.SeverityNumber_SEVERITY_NUMBER_WARN, record.SeverityNumber)
+	assert.Equal(t, &model.Process{
+		ServiceName: "checkout",
+		Attributes:  []model.KeyValue{{Key: "host.name", Value: stringValue("web-1")}},
+	}, record.Process)
+	assert.Equal(t, []model.KeyValue{
+		{Key: "elasticsearch.index", Value: stringValue("logs")},
+		{Key: "host.ip", Value: &common.AnyValue{Value: &common.AnyValue_ArrayValue{ArrayValue: &common.ArrayValue{
+			Values: []*common.AnyValue{stringValue("10.0.0.1")},
+		}}}},
+		{Key: "http.ok", Value: &common.AnyValue{Value: &common.AnyValue_BoolValue{BoolValue: false}}},
+		{Key: "http.ratio", Value: &common.AnyValue{Value: &common.AnyValue_DoubleValue{DoubleValue: 0.5}}},
+		{Key: "http.status", Value: &common.AnyValue{Value: &common.AnyValue_IntValue{IntValue: 500}}},
+		{Key: "log.logger", Value: stringValue("main")},
+	}, record.Attributes)
+}
+
+func TestToDomainLogDefaults(t *testing.T) {
+	doc, err := parseDocument([]byte(`{"@timestamp":"yesterday","event":"login"}`))
+	require.NoError(t, err)
+	record := ToDomainLog(doc, "", mapping, now)
+	assert.Equal(t, uint64(now.UnixNano()), record.TimeUnixNano)
+	assert.JSONEq(t, `{"@timestamp":"yesterday","event":"login"}`, record.Body)
+	assert.Empty(t, record.Process.ServiceName)
+	assert.Equal(t, []model.KeyValue{
+		{Key: "@timestamp", Value: stringValue("yesterday")},
+		{Key: "event", Value: stringValue("login")},
+	}, record.Attributes)
+}
+
+func TestParseTimestamp(t *testing.T) {
+	for _, v := range []interface{}{
+		"2024-03-10T12:00:00Z",
+		"2024-03-10T12:00:00",
+		"2024-03-10T14:00:00.000+02:00",
+		"1710072000000",
+		json.Number("1710072000000"),
+	} {
+		ts, ok := parseTimestamp(v)
+		require.True(t, ok, v)
+		assert.True(t, now.Equal(ts), v)
+	}
+	for _, v := range []interface{}{"yesterday", true, json.Number("1.5")} {
+		_, ok := parseTimestamp(v)
+		assert.False(t, ok, v)
+	}
+}
+
+func TestSeverityNumber(t *testing.T) {
+	assert.Equal(t, pbL.SeverityNumber_SEVERITY_NUMBER_ERROR, severityNumber("Error"))
+	assert.Equal(t, pbL.SeverityNumber_SEVERITY_NUMBER_FATAL, severityNumber("critical"))
+	assert.Equal(t, pbL.SeverityNumber_SEVERITY_NUMBER_UNSPECIFIED, severityNumber("verbose"))
+}
+
+func stringValue(s string) *common.AnyValue {
+	return &common.AnyValue{Value: &common.AnyValue_StringValue{StringValue: s}}
+}
@@ -0,0 +1,210 @@
+package elasticsearch
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"time"
+
+	"logger/model"
+	common "logger/model/proto/common/v1"
+	pbL "logger/model/proto/logs/v1"
+)
+
+const (
+	indexAttribute    = "elasticsearch.index"
+	hostNameAttribute = "host.name"
+)
+
+// Mapping names the document fields holding the parts of a log record. A field is found
+// by its dotted name, e.g. "log.level", whether the document nests it or not.
+type Mapping struct {
+	TimestampField string
+	MessageField   string
+	LevelField     string
+	ServiceField   string
+}
+
+// localTimestampLayout is a date without time zone, read as UTC like Elasticsearch does
+const localTimestampLayout = "2006-01-02T15:04:05.999999999"
+
+// ToDomainLog converts a document of the given index, received at the given time, to a
+// log record. The mapped fields and host.name are taken out of the document, the other
+// fields become attributes named by their dotted path. A document without a message
+// has its JSON as body.
+func ToDomainLog(doc map[string]interface{}, index string, mapping Mapping, received time.Time) *model.LogRecord {
+	record := &model.LogRecord{
+		TimeUnixNano:         uint64(received.UnixNano()),
+		ObservedTimeUnixNano: uint64(received.UnixNano()),
+		Process:              &model.Process{Attributes: []model.KeyValue{}},
+	}
+	if v, ok := take(doc, mapping.MessageField); ok {
+		record.Body = toString(v)
+	} else {
+		data, _ := json.Marshal(doc)
+		record.Body = string(data)
+	}
+	if parent, key, ok := find(doc, mapping.TimestampField); ok {
+		// a timestamp that cannot be read is kept as an attribute
+		if ts, ok := parseTimestamp(parent[key]); ok {
+			delete(parent, key)
+			record.TimeUnixNano = uint64(ts.UnixNano())
+		}
+	}
+	if v, ok := take(doc, mapping.LevelField); ok {
+		record.SeverityText = toString(v)
+		record.SeverityNumber = severityNumber(record.SeverityText)
+	}
+	if v, ok := take(doc, mapping.ServiceField); ok {
+		record.Process.ServiceName = toString(v)
+	}
+	if v, ok := take(doc, hostNameAttribute); ok {
+		record.Process.Attributes = append(record.Process.Attributes, model.KeyValue{Key: hostNameAttribute, Value: toAnyValue(v)})
+	}
+
+	if index != "" {
+		record.Attributes = append(record.Attributes, model.KeyValue{
+			Key:   indexAttribute,
+			Value: &common.AnyValue{Value: &common.AnyValue_StringValue{StringValue: index}},
+		})
+	}
+	record.Attributes = flatten(record.Attributes, "", doc)
+	return record
+}
+
+// find returns the map holding a dotted field and its key in this map, trying the longest
+// keys first so that {"log.level":...} and {"log":{"level":...}} are both found.
+func find(doc map[string]interface{}, field string) (map[string]interface{}, string, bool) {
+	if field == "" {
+		return nil, "", false
+	}
+	if _, ok := doc[field]; ok {
+		return doc, field, true
+	}
+	for i := strings.LastIndexByte(field, '.'); i > 0; i = strings.LastIndexByte(field[:i], '.') {
+		if nested, ok := doc[field[:i]].(map[string]interface{}); ok {
+			if parent, key, ok := find(nested, field[i+1:]); ok {
+				return parent, key, true
+			}
+		}
+	}
+	return nil, "", false
+}
+
+// take removes a dotted field from the document and returns its value.
+func take(doc map[string]interface{}, field string) (interface{}, bool) {
+	parent, key, ok := find(doc, field)
+	if !ok {
+		return nil, false
+	}
+	v := parent[key]
+	delete(parent, key)
+	return v, true
+}
+
+// parseTimestamp reads a date the way the default date format of Elasticsearch does,
+// an ISO 8601 date or milliseconds since the epoch.
+func parseTimestamp(v interface{}) (time.Time, bool) {
+	var s string
+	switch v := v.(type) {
+	case string:
+		s = v
+	case json.Number:
+		s = v.String()
+	default:
+		return time.Time{}, false
+	}
+	if millis, err := json.Number(s).Int64(); err == nil {
+		return time.UnixMilli(millis), true
+	}
+	if ts, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return ts, true
+	}
+	if ts, err := time.Parse(localTimestampLayout, s); err == nil {
+		return ts, true
+	}
+	return time.Time{}, false
+}
+
+// severityNumber maps the level names of the common logging libraries to a severity.
+func severityNumber(level string) pbL.SeverityNumber {
+	switch strings.ToLower(level) {
+	case "trace":
+		return pbL.SeverityNumber_SEVERITY_NUMBER_TRACE
+	case "debug":
+		return pbL.SeverityNumber_SEVERITY_NUMBER_DEBUG
+	case "info", "information", "informational":
+		return pbL.SeverityNumber_SEVERITY_NUMBER_INFO
+	case "notice":
+		return pbL.SeverityNumber_SEVERITY_NUMBER_INFO2
+	case "warn", "warning":
+		return pbL.SeverityNumber_SEVERITY_NUMBER_WARN
+	case "error", "err":
+		return pbL.SeverityNumber_SEVERITY_NUMBER_ERROR
+	case "crit", "critical", "fatal", "alert", "emerg", "emergency", "panic":
+		return pbL.SeverityNumber_SEVERITY_NUMBER_FATAL
+	}
+	return pbL.SeverityNumber_SEVERITY_NUMBER_UNSPECIFIED
+}
+
+// flatten appends the fields of a document as attributes named by their dotted path, in
+// the order of their names.
+func flatten(attributes []model.KeyValue, prefix string, doc map[string]interface{}) []model.KeyValue {
+	keys := make([]string, 0, len(doc))
+	for key := range doc {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if nested, ok := doc[key].(map[string]interface{}); ok {
+			attributes = flatten(attributes, prefix+key+".", nested)
+			continue
+		}
+		attributes = append(attributes, model.KeyValue{Key: prefix + key, Value: toAnyValue(doc[key])})
+	}
+	return attributes
+}
+
+func toAnyValue(v interface{}) *common.AnyValue {
+	switch v := v.(type) {
+	case string:
+		return &common.AnyValue{Value: &common.AnyValue_StringValue{StringValue: v}}
+	case bool:
+		return &common.AnyValue{Value: &common.AnyValue_BoolValue{BoolValue: v}}
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return &common.AnyValue{Value: &common.AnyValue_IntValue{IntValue: i}}
+		}
+		if f, err := v.Float64(); err == nil {
+			return &common.AnyValue{Value: &common.AnyValue_DoubleValue{DoubleValue: f}}
+		}
+		return &common.AnyValue{Value: &common.AnyValue_StringValue{StringValue: v.String()}}
+	case []interface{}:
+		values := make([]*common.AnyValue, len(v))
+		for i, value := range v {
+			values[i] = toAnyValue(value)
+		}
+		return &common.AnyValue{Value: &common.AnyValue_ArrayValue{ArrayValue: &common.ArrayValue{Values: values}}}
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		values := make([]*common.KeyValue, len(keys))
+		for i, key := range keys {
+			values[i] = &common.KeyValue{Key: key, Value: toAnyValue(v[key])}
+		}
+		return &common.AnyValue{Value: &common.AnyValue_KvlistValue{KvlistValue: &common.KeyValueList{Values: values}}}
+	}
+	return &common.AnyValue{}
+}
+
+// toString renders a field as text, strings as they are and other values as JSON.
+func toString(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	data, _ := json.Marshal(v)
+	return string(data)
+}
@@ -0,0 +1,210 @@
+package fluent
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// ErrInvalidMessage is returned for a value that is not a Forward protocol message.
+var ErrInvalidMessage = errors.New("invalid forward message")
+
+// Message is a Forward protocol event stream, unpacked from any of the Message, Forward,
+// PackedForward and CompressedPackedForward modes.
+type Message struct {
+	Tag     string
+	Entries []Entry
+	Option  Option
+}
+
+// Entry is an event of a Message.
+type Entry struct {
+	// Time is zero when the event has none
+	Time   time.Time
+	Record Map
+}
+
+// Option holds the options a message is sent with.
+type Option struct {
+	// Size is the number of events the sender announced
+	Size int
+	// Chunk is the ID of the chunk to acknowledge, empty when no ack is requested
+	Chunk string
+	// Compressed is the compression of a packed event stream, "gzip" or empty
+	Compressed string
+}
+
+// Decoder reads the values of a Forward protocol stream.
+type Decoder struct {
+	d *decoder
+}
+
+// NewDecoder returns a Decoder reading from r values of maxSize bytes at most.
+func NewDecoder(r io.Reader, maxSize int) *Decoder {
+	return &Decoder{d: newDecoder(r, maxSize)}
+}
+
+// Decode reads the next value of the stream, an event message or a handshake message.
+// It returns io.EOF when the stream ends between values.
+func (d *Decoder) Decode() (interface{}, error) {
+	d.d.reset()
+	return d.d.decode()
+}
+
+// ParseMessage reads a Message from a value returned by Decode. maxSize bounds the
+// size of a decompressed event stream.
+func ParseMessage(v interface{}, maxSize int) (*Message, error) {
+	arr, ok := v.([]interface{})
+	if !ok || len(arr) < 2 || len(arr) > 4 {
+		return nil, fmt.Errorf("%w: not an array of 2 to 4 elements", ErrInvalidMessage)
+	}
+	tag, ok := arr[0].(string)
+	if !ok {
+		return nil, fmt.Errorf("%w: the tag is not a string", ErrInvalidMessage)
+	}
+	m := &Message{Tag: tag}
+	var err error
+	switch events := arr[1].(type) {
+	case []interface{}:
+		// Forward mode: [tag, [[time, record], ...], option]
+		err = m.parseOption(arr[2:])
+		if err == nil {
+			m.Entries, err = parseEntries(events)
+		}
+	case string:
+		// PackedForward mode: [tag, entries, option]
+		err = m.parsePacked([]byte(events), arr[2:], maxSize)
+	case []byte:
+		err = m.parsePacked(events, arr[2:], maxSize)
+	default:
+		// Message mode: [tag, time, record, option]
+		if len(arr) < 3 {
+			return nil, fmt.Errorf("%w: missing record", ErrInvalidMessage)
+		}
+		var entry Entry
+		entry, err = parseEntry(arr[1], arr[2])
+		if err == nil {
+			m.Entries = []Entry{entry}
+			err = m.parseOption(arr[3:])
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (m *Message) parseOption(rest []interface{}) error {
+	if len(rest) == 0 || rest[0] == nil {
+		return nil
+	}
+	if len(rest) > 1 {
+		return fmt.Errorf("%w: too many elements", ErrInvalidMessage)
+	}
+	option, ok := rest[0].(Map)
+	if !ok {
+		return fmt.Errorf("%w: the option is not a map", ErrInvalidMessage)
+	}
+	for _, entry := range option {
+		switch entry.Key {
+		case "size":
+			if size, ok := entry.Value.(int64); ok && size <= math.MaxInt32 {
+				m.Option.Size = int(size)
+			}
+		case "chunk":
+			m.Option.Chunk, _ = entry.Value.(string)
+		case "compressed":
+			m.Option.Compressed, _ = entry.Value.(string)
+		}
+	}
+	return nil
+}
+
+// parsePacked reads the msgpack stream of [time, record] entries of the PackedForward mode.
+func (m *Message) parsePacked(packed []byte, rest []interface{}, maxSize int) error {
+	if err := m.parseOption(rest); err != nil {
+		return err
+	}
+	var r io.Reader = bytes.NewReader(packed)
+	switch m.Option.Compressed {
+	case "", "text":
+	case "gzip":
+		// a compressed stream may concatenate several gzip members, which the reader reads through
+		gr, err := gzip.NewReader(r)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidMessage, err)
+		}
+		defer gr.Close()
+		r = gr
+	default:
+		return fmt.Errorf("%w: unsupported compression %q", ErrInvalidMessage, m.Option.Compressed)
+	}
+	d := newDecoder(r, maxSize)
+	for {
+		v, err := d.decode()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%w: packed entries: %v", ErrInvalidMessage, err)
+		}
+		arr, ok := v.([]interface{})
+		if !ok || len(arr) != 2 {
+			return fmt.Errorf("%w: a packed entry is not a [time, record] array", ErrInvalidMessage)
+		}
+		entry, err := parseEntry(arr[0], arr[1])
+		if err != nil {
+			return err
+		}
+		m.Entries = append(m.Entries, entry)
+	}
+}
+
+func parseEntries(events []interface{}) ([]Entry, error) {
+	entries := make([]Entry, 0, len(events))
+	for _, event := range events {
+		arr, ok := event.([]interface{})
+		if !ok || len(arr) != 2 {
+			return nil, fmt.Errorf("%w: an entry is not a [time, record] array", ErrInvalidMessage)
+		}
+		entry, err := parseEntry(arr[0], arr[1])
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+func parseEntry(ts interface{}, record interface{}) (Entry, error) {
+	var entry Entry
+	switch ts := ts.(type) {
+	case time.Time:
+		entry.Time = ts
+	case int64:
+		if ts > 0 {
+			entry.Time = time.Unix(ts, 0).UTC()
+		}
+	case uint64:
+		return entry, fmt.Errorf("%w: time %d out of range", ErrInvalidMessage, ts)
+	case float64:
+		// some clients send fractional seconds
+		sec, frac := math.Modf(ts)
+		entry.Time = time.Unix(int64(sec), int64(frac*1e9)).UTC()
+	case nil:
+	default:
+		return entry, fmt.Errorf("%w: time of type %T", ErrInvalidMessage, ts)
+	}
+	switch record := record.(type) {
+	case Map:
+		entry.Record = record
+	case nil:
+	default:
+		return entry, fmt.Errorf("%w: record of type %T", ErrInvalidMessage, record)
+	}
+	return entry, nil
+}
@@ -0,0 +1,290 @@
+package fluent
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"io"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type bin []byte
+
+type eventTime time.Time
+
+// pack encodes v in msgpack, the way Fluent Bit does.
+func pack(v interface{}) []byte {
+	return appendValue(nil, v)
+}
+
+func appendValue(b []byte, v interface{}) []byte {
+	switch v := v.(type) {
+	case nil:
+		return append(b, 0xc0)
+	case bool:
+		return appendBool(b, v)
+	case int:
+		if v >= 0 && v <= 0x7f {
+			return append(b, byte(v))
+		}
+		return binary.BigEndian.AppendUint64(append(b, 0xd3), uint64(v))
+	case float64:
+		return binary.BigEndian.AppendUint64(append(b, 0xcb), math.Float64bits(v))
+	case string:
+		return appendString(b, v)
+	case bin:
+		b = binary.BigEndian.AppendUint32(append(b, 0xc6), uint32(len(v)))
+		return append(b, v...)
+	case eventTime:
+		t := time.Time(v)
+		b = append(b, 0xd7, eventTimeExtType)
+		b = binary.BigEndian.AppendUint32(b, uint32(t.Unix()))
+		return binary.BigEndian.AppendUint32(b, uint32(t.Nanosecond()))
+	case []interface{}:
+		b = appendArrayHeader(b, len(v))
+		for _, value := range v {
+			b = appendValue(b, value)
+		}
+		return b
+	case Map:
+		b = appendMapHeader(b, len(v))
+		for _, entry := range v {
+			b = appendValue(appendString(b, entry.Key), entry.Value)
+		}
+		return b
+	}
+	panic("unsupported type")
+}
+
+func decode(t *testing.T, data []byte) interface{} {
+	v, err := NewDecoder(bytes.NewReader(data), 1<<20).Decode()
+	require.NoError(t, err)
+	return v
+}
+
+var (
+	ts     = time.Date(2024, time.March, 10, 12, 0, 0, 123456789, time.UTC)
+	record = Map{{"log", "hello"}, {"stream", "stdout"}}
+)
+
+func TestDecodeValues(t *testing.T) {
+	value := []interface{}{
+		nil, true, false, 1, -5, 300, 1.5, "str", bin("bin"), eventTime(ts),
+		[]interface{}{1, "a"}, Map{{"k", Map{{"n", 2}}}},
+	}
+	assert.Equal(t, []interface{}{
+		nil, true, false, int64(1), int64(-5), int64(300), 1.5, "str", []byte("bin"), ts,
+		[]interface{}{int64(1), "a"}, Map{{"k", Map{{"n", int64(2)}}}},
+	}, decode(t, pack(value)))
+}
+
+func TestDecodeIntegers(t *testing.T) {
+	for data, expected := range map[string]interface{}{
+		"\xe0":                                 int64(-32),
+		"\xcc\xff":                             int64(255),
+		"\xcd\x01\x00":                         int64(256),
+		"\xce\x00\x01\x00\x00":                 int64(65536),
+		"\xcf\xff\xff\xff\xff\xff\xff\xff\xff": uint64(math.MaxUint64),
+		"\xd0\xff":                             int64(-1),
+		"\xd1\xff\xfe":                         int64(-2),
+		"\xd2\xff\xff\xff\xfd":                 int64(-3),
+		"\xca\x3f\xc0\x00\x00":                 1.5,
+		"\xd4\x05\x01":                         Ext{Type: 5, Data: []byte{1}},
+	} {
+		assert.Equal(t, expected, decode(t, []byte(data)), "%x", data)
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	d := NewDecoder(bytes.NewReader(nil), 100)
+	_, err := d.Decode()
+	assert.ErrorIs(t, err, io.EOF)
+
+	d = NewDecoder(bytes.NewReader([]byte{0x92, 0x01}), 100)
+	_, err = d.Decode()
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+
+	d = NewDecoder(bytes.NewReader([]byte{0xc1}), 100)
+	_, err = d.Decode()
+	assert.Error(t, err)
+
+	// a length prefix cannot allocate past the budget
+	d = NewDecoder(bytes.NewReader([]byte{0xdd, 0xff, 0xff, 0xff, 0xff}), 100)
+	_, err = d.Decode()
+	assert.ErrorIs(t, err, ErrTooLarge)
+
+	d = NewDecoder(bytes.NewReader(pack(bin(bytes.Repeat([]byte("x"), 200)))), 100)
+	_, err = d.Decode()
+	assert.ErrorIs(t, err, ErrTooLarge)
+
+	// arrays and maps are charged their size in memory, not the bytes they are read from
+	array := append([]byte{0xdc, 0x00, 0x40}, bytes.Repeat([]byte{0x01}, 64)...)
+	_, err = NewDecoder(bytes.NewReader(array), 64*elementSize).Decode()
+	assert.ErrorIs(t, err, ErrTooLarge)
+	_, err = NewDecoder(bytes.NewReader(array), 64*elementSize+len(array)).Decode()
+	assert.NoError(t, err)
+
+	m := []byte{0xde, 0x00, 0x20}
+	for i := 0; i < 32; i++ {
+		m = append(m, byte(i), 0x01)
+	}
+	_, err = NewDecoder(bytes.NewReader(m), 32*entrySize).Decode()
+	assert.ErrorIs(t, err, ErrTooLarge)
+	_, err = NewDecoder(bytes.NewReader(m), 32*entrySize+len(m)).Decode()
+	assert.NoError(t, err)
+}
+
+func TestDecodeDepth(t *testing.T) {
+	// nested arrays and maps of a single element, one byte each
+	nested := func(depth int) []byte {
+		data := make([]byte, 0, 2*depth+1)
+		for i := 0; i < depth; i++ {
+			if i%2 == 0 {
+				data = append(data, 0x91)
+			} else {
+				data = append(data, 0x81, 0xa1, 'k')
+			}
+		}
+		return append(data, 0x01)
+	}
+	d := NewDecoder(bytes.NewReader(nested(maxDepth)), 10000)
+	_, err := d.Decode()
+	require.NoError(t, err)
+
+	d = NewDecoder(bytes.NewReader(nested(maxDepth+1)), 10000)
+	_, err = d.Decode()
+	assert.ErrorIs(t, err, ErrInvalidMessage)
+
+	// a payload deep enough to exhaust the stack is refused early
+	d = NewDecoder(bytes.NewReader(bytes.Repeat([]byte{0x91}, 1<<20)), 32<<20)
+	_, err = d.Decode()
+	assert.ErrorIs(t, err, ErrInvalidMessage)
+}
+
+func TestDecoderBudgetIsPerValue(t *testing.T) {
+	data := append(pack("0123456789"), pack("0123456789")...)
+	d := NewDecoder(bytes.NewReader(data), 12)
+	for i := 0; i < 2; i++ {
+		v, err := d.Decode()
+		require.NoError(t, err)
+		assert.Equal(t, "0123456789", v)
+	}
+}
+
+func TestParseMessageModes(t *testing.T) {
+	entry := []interface{}{eventTime(ts), record}
+	var packed bytes.Buffer
+	packed.Write(pack(entry))
+	packed.Write(pack([]interface{}{int(ts.Unix()), record}))
+
+	var compressed bytes.Buffer
+	// two gzip members, as sent when chunks are appended
+	for i := 0; i < 2; i++ {
+		gw := gzip.NewWriter(&compressed)
+		_, err := gw.Write(pack(entry))
+		require.NoError(t, err)
+		require.NoError(t, gw.Close())
+	}
+
+	tests := []struct {
+		name    string
+		message []interface{}
+		entries int
+		option  Option
+	}{
+		{
+			name:    "message",
+			message: []interface{}{"app.web", eventTime(ts), record},
+			entries: 1,
+		},
+		{
+			name:    "message with option",
+			message: []interface{}{"app.web", eventTime(ts), record, Map{{"chunk", "abc"}}},
+			entries: 1,
+			option:  Option{Chunk: "abc"},
+		},
+		{
+			name:    "forward",
+			message: []interface{}{"app.web", []interface{}{entry, entry, entry}, Map{{"size", 3}}},
+			entries: 3,
+			option:  Option{Size: 3},
+		},
+		{
+			name:    "packed forward",
+			message: []interface{}{"app.web", bin(packed.Bytes()), Map{{"size", 2}, {"chunk", "p1"}}},
+			entries: 2,
+			option:  Option{Size: 2, Chunk: "p1"},
+		},
+		{
+			name:    "packed forward as str",
+			message: []interface{}{"app.web", packed.String()},
+			entries: 2,
+		},
+		{
+			name:    "compressed packed forward",
+			message: []interface{}{"app.web", bin(compressed.Bytes()), Map{{"compressed", "gzip"}}},
+			entries: 2,
+			option:  Option{Compressed: "gzip"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			m, err := ParseMessage(decode(t, pack(test.message)), 1<<20)
+			require.NoError(t, err)
+			assert.Equal(t, "app.web", m.Tag)
+			assert.Equal(t, test.option, m.Option)
+			require.Len(t, m.Entries, test.entries)
+			assert.Equal(t, ts, m.Entries[0].Time)
+			for _, entry := range m.Entries {
+				assert.Equal(t, Map{{"log", "hello"}, {"stream", "stdout"}}, entry.Record)
+			}
+		})
+	}
+}
+
+func TestParseMessageErrors(t *testing.T) {
+	tooLarge := pack([]interface{}{eventTime(ts), Map{{"log", string(bytes.Repeat([]byte("x"), 100))}}})
+	for name, message := range map[string]interface{}{
+		"not an array":       "app",
+		"no tag":             []interface{}{1, 2, Map{}},
+		"no record":          []interface{}{"app", 1},
+		"record not a map":   []interface{}{"app", 1, "record"},
+		"bad time":           []interface{}{"app", "time", record, nil, nil},
+		"bad entry":          []interface{}{"app", []interface{}{1}},
+		"bad option":         []interface{}{"app", 1, record, "option"},
+		"bad packed entry":   []interface{}{"app", bin(pack(1))},
+		"truncated packed":   []interface{}{"app", bin(pack(record)[:3])},
+		"unknown compressor": []interface{}{"app", bin(pack(record)), Map{{"compressed", "lz4"}}},
+		"bad gzip":           []interface{}{"app", bin(pack(record)), Map{{"compressed", "gzip"}}},
+		"packed too large":   []interface{}{"app", bin(tooLarge)},
+	} {
+		_, err := ParseMessage(decode(t, pack(message)), 64)
+		assert.ErrorIs(t, err, ErrInvalidMessage, name)
+	}
+}
+
+func TestHandshake(t *testing.T) {
+	helo := decode(t, AppendHelo(nil, "nonce"))
+	assert.Equal(t, []interface{}{"HELO", Map{{"nonce", "nonce"}, {"auth", ""}, {"keepalive", true}}}, helo)
+
+	digest := SharedKeyDigest("salt", "client", "nonce", "secret")
+	assert.Len(t, digest, 128)
+	assert.NotEqual(t, digest, SharedKeyDigest("salt", "client", "nonce", "other"))
+
+	ping, err := ParsePing(decode(t, pack([]interface{}{"PING", "client", bin("salt"), digest, "", ""})))
+	require.NoError(t, err)
+	assert.Equal(t, &Ping{Hostname: "client", SharedKeySalt: "salt", SharedKeyDigest: digest}, ping)
+
+	_, err = ParsePing(decode(t, pack([]interface{}{"app", 1, record})))
+	assert.ErrorIs(t, err, ErrInvalidMessage)
+
+	pong := decode(t, AppendPong(nil, false, "shared key mismatch", "server", ""))
+	assert.Equal(t, []interface{}{"PONG", false, "shared key mismatch", "server", ""}, pong)
+
+	assert.Equal(t, Map{{"ack", "chunk"}}, decode(t, AppendAck(nil, "chunk")))
+}
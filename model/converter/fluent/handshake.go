@@ -0,0 +1,85 @@
+package fluent
+
+import (
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+)
+
+// Ping is the PING message a client answers the HELO of the server with.
+type Ping struct {
+	Hostname      string
+	SharedKeySalt string
+	// SharedKeyDigest is the hex SHA-512 of the salt, the hostname, the nonce and the shared key
+	SharedKeyDigest string
+	Username        string
+	Password        string
+}
+
+// AppendHelo appends the HELO message that opens the handshake of a connection.
+// The server does not authenticate users, so the auth salt is empty.
+func AppendHelo(b []byte, nonce string) []byte {
+	b = appendArrayHeader(b, 2)
+	b = appendString(b, "HELO")
+	b = appendMapHeader(b, 3)
+	b = appendString(b, "nonce")
+	b = appendString(b, nonce)
+	b = appendString(b, "auth")
+	b = appendString(b, "")
+	b = appendString(b, "keepalive")
+	return appendBool(b, true)
+}
+
+// ParsePing reads a PING message from a value returned by Decoder.Decode.
+func ParsePing(v interface{}) (*Ping, error) {
+	arr, ok := v.([]interface{})
+	if !ok || len(arr) != 6 || text(arr[0]) != "PING" {
+		return nil, fmt.Errorf("%w: not a PING message", ErrInvalidMessage)
+	}
+	return &Ping{
+		Hostname:        text(arr[1]),
+		SharedKeySalt:   text(arr[2]),
+		SharedKeyDigest: text(arr[3]),
+		Username:        text(arr[4]),
+		Password:        text(arr[5]),
+	}, nil
+}
+
+// text returns a str or bin value as a string, handshake clients use either.
+func text(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	}
+	return ""
+}
+
+// SharedKeyDigest returns the digest proving the knowledge of sharedKey for a handshake.
+func SharedKeyDigest(salt, hostname, nonce, sharedKey string) string {
+	h := sha512.New()
+	h.Write([]byte(salt))
+	h.Write([]byte(hostname))
+	h.Write([]byte(nonce))
+	h.Write([]byte(sharedKey))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// AppendPong appends the PONG message that closes the handshake. The digest is
+// computed for the hostname of the server, so that the client can authenticate it.
+func AppendPong(b []byte, ok bool, reason, hostname, digest string) []byte {
+	b = appendArrayHeader(b, 5)
+	b = appendString(b, "PONG")
+	b = appendBool(b, ok)
+	b = appendString(b, reason)
+	b = appendString(b, hostname)
+	return appendString(b, digest)
+}
+
+// AppendAck appends the response acknowledging the chunk of a message.
+func AppendAck(b []byte, chunk string) []byte {
+	b = appendMapHeader(b, 1)
+	b = appendString(b, "ack")
+	return appendString(b, chunk)
+}
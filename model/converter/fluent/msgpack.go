@@ -0,0 +1,373 @@
+package fluent
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+	"unsafe"
+)
+
+// eventTimeExtType is the msgpack extension type of the Forward protocol EventTime
+const eventTimeExtType = 0
+
+// maxDepth bounds the nesting of arrays and maps, which are decoded recursively.
+const maxDepth = 100
+
+// the size in memory of an element of a decoded array and of an entry of a decoded map
+const (
+	elementSize = int(unsafe.Sizeof(interface{}(nil)))
+	entrySize   = int(unsafe.Sizeof(MapEntry{}))
+)
+
+// ErrTooLarge is returned for a message larger than the size allowed to the decoder.
+var ErrTooLarge = errors.New("forward message too large")
+
+// Map is a msgpack map, decoded with its entries in order.
+type Map []MapEntry
+
+// MapEntry is an entry of a Map. Keys that are not strings are formatted as text.
+type MapEntry struct {
+	Key   string
+	Value interface{}
+}
+
+// Get returns the value of key.
+func (m Map) Get(key string) (interface{}, bool) {
+	for _, entry := range m {
+		if entry.Key == key {
+			return entry.Value, true
+		}
+	}
+	return nil, false
+}
+
+// Ext is a msgpack extension value other than EventTime.
+type Ext struct {
+	Type int8
+	Data []byte
+}
+
+// decoder reads msgpack values into nil, bool, int64, uint64 (above math.MaxInt64), float64,
+// string, []byte, []interface{}, Map, time.Time (EventTime) and Ext. Every value read
+// since the last reset counts against the budget, arrays and maps for their size in
+// memory, so that a length prefix cannot make the decoder allocate more than maxSize
+// bytes. Arrays and maps nested deeper than maxDepth are refused with ErrInvalidMessage.
+type decoder struct {
+	r       *bufio.Reader
+	maxSize int
+	budget  int
+	depth   int
+}
+
+func newDecoder(r io.Reader, maxSize int) *decoder {
+	br, ok := r.(*bufio.Reader)
+	if !ok {
+		br = bufio.NewReader(r)
+	}
+	return &decoder{r: br, maxSize: maxSize, budget: maxSize}
+}
+
+func (d *decoder) reset() {
+	d.budget = d.maxSize
+	d.depth = 0
+}
+
+// enter accounts for a nested array or map; the returned function leaves it.
+func (d *decoder) enter() (func(), error) {
+	if d.depth >= maxDepth {
+		return nil, fmt.Errorf("%w: nested deeper than %d levels", ErrInvalidMessage, maxDepth)
+	}
+	d.depth++
+	return func() { d.depth-- }, nil
+}
+
+func (d *decoder) spend(n int) error {
+	if n < 0 || n > d.budget {
+		return ErrTooLarge
+	}
+	d.budget -= n
+	return nil
+}
+
+// spendElements charges the memory of n elements of size bytes each.
+func (d *decoder) spendElements(n, size int) error {
+	if n < 0 || n > d.budget/size {
+		return ErrTooLarge
+	}
+	return d.spend(n * size)
+}
+
+func (d *decoder) readByte() (byte, error) {
+	if err := d.spend(1); err != nil {
+		return 0, err
+	}
+	return d.r.ReadByte()
+}
+
+func (d *decoder) readN(n int) ([]byte, error) {
+	if err := d.spend(n); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(d.r, buf); err != nil {
+		return nil, unexpectedEOF(err)
+	}
+	return buf, nil
+}
+
+// readUint reads a big endian unsigned integer of size bytes.
+func (d *decoder) readUint(size int) (uint64, error) {
+	buf, err := d.readN(size)
+	if err != nil {
+		return 0, err
+	}
+	switch size {
+	case 1:
+		return uint64(buf[0]), nil
+	case 2:
+		return uint64(binary.BigEndian.Uint16(buf)), nil
+	case 4:
+		return uint64(binary.BigEndian.Uint32(buf)), nil
+	default:
+		return binary.BigEndian.Uint64(buf), nil
+	}
+}
+
+func (d *decoder) readLength(size int) (int, error) {
+	n, err := d.readUint(size)
+	if err != nil {
+		return 0, err
+	}
+	if n > math.MaxInt32 {
+		return 0, ErrTooLarge
+	}
+	return int(n), nil
+}
+
+// decode reads the next value. It returns io.EOF only when the stream ends between values.
+func (d *decoder) decode() (interface{}, error) {
+	b, err := d.readByte()
+	if err != nil {
+		return nil, err
+	}
+	v, err := d.decodeValue(b)
+	return v, unexpectedEOF(err)
+}
+
+func (d *decoder) decodeNested() (interface{}, error) {
+	b, err := d.readByte()
+	if err != nil {
+		return nil, unexpectedEOF(err)
+	}
+	return d.decodeValue(b)
+}
+
+func (d *decoder) decodeValue(b byte) (interface{}, error) {
+	switch {
+	case b <= 0x7f:
+		return int64(b), nil
+	case b >= 0xe0:
+		return int64(int8(b)), nil
+	case b >= 0xa0 && b <= 0xbf:
+		return d.decodeString(int(b & 0x1f))
+	case b >= 0x90 && b <= 0x9f:
+		return d.decodeArray(int(b & 0x0f))
+	case b >= 0x80 && b <= 0x8f:
+		return d.decodeMap(int(b & 0x0f))
+	}
+	switch b {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xc4, 0xc5, 0xc6:
+		n, err := d.readLength(1 << (b - 0xc4))
+		if err != nil {
+			return nil, err
+		}
+		return d.readN(n)
+	case 0xc7, 0xc8, 0xc9:
+		n, err := d.readLength(1 << (b - 0xc7))
+		if err != nil {
+			return nil, err
+		}
+		return d.decodeExt(n)
+	case 0xca:
+		bits, err := d.readUint(4)
+		return float64(math.Float32frombits(uint32(bits))), err
+	case 0xcb:
+		bits, err := d.readUint(8)
+		return math.Float64frombits(bits), err
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		n, err := d.readUint(1 << (b - 0xcc))
+		if err != nil {
+			return nil, err
+		}
+		if n > math.MaxInt64 {
+			return n, nil
+		}
+		return int64(n), nil
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		size := 1 << (b - 0xd0)
+		n, err := d.readUint(size)
+		if err != nil {
+			return nil, err
+		}
+		// sign extend from size bytes
+		shift := 64 - 8*size
+		return int64(n<<shift) >> shift, nil
+	case 0xd4, 0xd5, 0xd6, 0xd7, 0xd8:
+		return d.decodeExt(1 << (b - 0xd4))
+	case 0xd9, 0xda, 0xdb:
+		n, err := d.readLength(1 << (b - 0xd9))
+		if err != nil {
+			return nil, err
+		}
+		return d.decodeString(n)
+	case 0xdc, 0xdd:
+		n, err := d.readLength(2 << (b - 0xdc))
+		if err != nil {
+			return nil, err
+		}
+		return d.decodeArray(n)
+	case 0xde, 0xdf:
+		n, err := d.readLength(2 << (b - 0xde))
+		if err != nil {
+			return nil, err
+		}
+		return d.decodeMap(n)
+	}
+	return nil, fmt.Errorf("invalid msgpack type 0x%x", b)
+}
+
+func (d *decoder) decodeString(n int) (string, error) {
+	buf, err := d.readN(n)
+	return string(buf), err
+}
+
+func (d *decoder) decodeArray(n int) ([]interface{}, error) {
+	if err := d.spendElements(n, elementSize); err != nil {
+		return nil, err
+	}
+	leave, err := d.enter()
+	if err != nil {
+		return nil, err
+	}
+	defer leave()
+	res := make([]interface{}, n)
+	for i := range res {
+		v, err := d.decodeNested()
+		if err != nil {
+			return nil, err
+		}
+		res[i] = v
+	}
+	return res, nil
+}
+
+func (d *decoder) decodeMap(n int) (Map, error) {
+	if err := d.spendElements(n, entrySize); err != nil {
+		return nil, err
+	}
+	leave, err := d.enter()
+	if err != nil {
+		return nil, err
+	}
+	defer leave()
+	res := make(Map, n)
+	for i := range res {
+		k, err := d.decodeNested()
+		if err != nil {
+			return nil, err
+		}
+		v, err := d.decodeNested()
+		if err != nil {
+			return nil, err
+		}
+		res[i] = MapEntry{Key: keyString(k), Value: v}
+	}
+	return res, nil
+}
+
+func keyString(k interface{}) string {
+	switch k := k.(type) {
+	case string:
+		return k
+	case []byte:
+		return string(k)
+	}
+	return fmt.Sprint(k)
+}
+
+func (d *decoder) decodeExt(n int) (interface{}, error) {
+	typ, err := d.readByte()
+	if err != nil {
+		return nil, err
+	}
+	data, err := d.readN(n)
+	if err != nil {
+		return nil, err
+	}
+	if int8(typ) == eventTimeExtType && n == 8 {
+		sec := binary.BigEndian.Uint32(data[:4])
+		nsec := binary.BigEndian.Uint32(data[4:])
+		return time.Unix(int64(sec), int64(nsec)).UTC(), nil
+	}
+	return Ext{Type: int8(typ), Data: data}, nil
+}
+
+func unexpectedEOF(err error) error {
+	if errors.Is(err, io.EOF) {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// appendArrayHeader appends the header of an array of n elements.
+func appendArrayHeader(b []byte, n int) []byte {
+	switch {
+	case n <= 0x0f:
+		return append(b, 0x90|byte(n))
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, 0xdc), uint16(n))
+	}
+	return binary.BigEndian.AppendUint32(append(b, 0xdd), uint32(n))
+}
+
+// appendMapHeader appends the header of a map of n entries.
+func appendMapHeader(b []byte, n int) []byte {
+	switch {
+	case n <= 0x0f:
+		return append(b, 0x80|byte(n))
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, 0xde), uint16(n))
+	}
+	return binary.BigEndian.AppendUint32(append(b, 0xdf), uint32(n))
+}
+
+func appendString(b []byte, s string) []byte {
+	switch n := len(s); {
+	case n <= 0x1f:
+		b = append(b, 0xa0|byte(n))
+	case n <= math.MaxUint8:
+		b = append(b, 0xd9, byte(n))
+	case n <= math.MaxUint16:
+		b = binary.BigEndian.AppendUint16(append(b, 0xda), uint16(n))
+	default:
+		b = binary.BigEndian.AppendUint32(append(b, 0xdb), uint32(n))
+	}
+	return append(b, s...)
+}
+
+func appendBool(b []byte, v bool) []byte {
+	if v {
+		return append(b, 0xc3)
+	}
+	return append(b, 0xc2)
+}
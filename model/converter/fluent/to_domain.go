@@ -0,0 +1,145 @@
+package fluent
+
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+
+	"logger/model"
+	common "logger/model/proto/common/v1"
+)
+
+// bodyFields are the record fields holding the log line, by precedence. Fluent Bit
+// tail inputs use "log", most structured loggers "message" or "msg".
+var bodyFields = []string{"log", "message", "msg"}
+
+// ToDomainLogs converts the events of a message received at the given time to log
+// records. The tag becomes the service, the log line of each record its body and the
+// other fields its attributes. A record without a log line has its JSON as body.
+func ToDomainLogs(m *Message, received time.Time) []*model.LogRecord {
+	process := &model.Process{
+		ServiceName: m.Tag,
+		Attributes:  []model.KeyValue{},
+	}
+	logs := make([]*model.LogRecord, 0, len(m.Entries))
+	for _, entry := range m.Entries {
+		ts := entry.Time
+		if ts.IsZero() {
+			ts = received
+		}
+		body, attributes := splitRecord(entry.Record)
+		logs = append(logs, &model.LogRecord{
+			TimeUnixNano:         uint64(ts.UnixNano()),
+			ObservedTimeUnixNano: uint64(received.UnixNano()),
+			Body:                 body,
+			Attributes:           attributes,
+			Process:              process,
+		})
+	}
+	return logs
+}
+
+func splitRecord(record Map) (string, []model.KeyValue) {
+	bodyKey, body := "", ""
+	for _, field := range bodyFields {
+		v, _ := record.Get(field)
+		// older clients send strings as bin
+		if s, ok := v.(string); ok {
+			bodyKey, body = field, s
+		} else if b, ok := v.([]byte); ok {
+			bodyKey, body = field, string(b)
+		}
+		if bodyKey != "" {
+			break
+		}
+	}
+	if bodyKey == "" && len(record) > 0 {
+		var buf bytes.Buffer
+		appendJSON(&buf, record)
+		body = buf.String()
+	}
+	attributes := make([]model.KeyValue, 0, len(record))
+	for _, entry := range record {
+		if entry.Key == bodyKey {
+			continue
+		}
+		attributes = append(attributes, model.KeyValue{
+			Key:   entry.Key,
+			Value: toAnyValue(entry.Value),
+		})
+	}
+	return body, attributes
+}
+
+// toAnyValue converts a decoded msgpack value to an attribute value.
+func toAnyValue(v interface{}) *common.AnyValue {
+	switch v := v.(type) {
+	case string:
+		return &common.AnyValue{Value: &common.AnyValue_StringValue{StringValue: v}}
+	case bool:
+		return &common.AnyValue{Value: &common.AnyValue_BoolValue{BoolValue: v}}
+	case int64:
+		return &common.AnyValue{Value: &common.AnyValue_IntValue{IntValue: v}}
+	case uint64:
+		return &common.AnyValue{Value: &common.AnyValue_DoubleValue{DoubleValue: float64(v)}}
+	case float64:
+		return &common.AnyValue{Value: &common.AnyValue_DoubleValue{DoubleValue: v}}
+	case []byte:
+		return &common.AnyValue{Value: &common.AnyValue_BytesValue{BytesValue: v}}
+	case Ext:
+		return &common.AnyValue{Value: &common.AnyValue_BytesValue{BytesValue: v.Data}}
+	case time.Time:
+		return &common.AnyValue{Value: &common.AnyValue_StringValue{StringValue: v.Format(time.RFC3339Nano)}}
+	case []interface{}:
+		values := make([]*common.AnyValue, len(v))
+		for i, value := range v {
+			values[i] = toAnyValue(value)
+		}
+		return &common.AnyValue{Value: &common.AnyValue_ArrayValue{ArrayValue: &common.ArrayValue{Values: values}}}
+	case Map:
+		values := make([]*common.KeyValue, len(v))
+		for i, entry := range v {
+			values[i] = &common.KeyValue{Key: entry.Key, Value: toAnyValue(entry.Value)}
+		}
+		return &common.AnyValue{Value: &common.AnyValue_KvlistValue{KvlistValue: &common.KeyValueList{Values: values}}}
+	}
+	return &common.AnyValue{}
+}
+
+// appendJSON writes v as JSON, keeping the order of the map entries.
+func appendJSON(buf *bytes.Buffer, v interface{}) {
+	switch v := v.(type) {
+	case Map:
+		buf.WriteByte('{')
+		for i, entry := range v {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			appendJSON(buf, entry.Key)
+			buf.WriteByte(':')
+			appendJSON(buf, entry.Value)
+		}
+		buf.WriteByte('}')
+	case []interface{}:
+		buf.WriteByte('[')
+		for i, value := range v {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			appendJSON(buf, value)
+		}
+		buf.WriteByte(']')
+	case []byte:
+		appendJSON(buf, string(v))
+	case Ext:
+		appendJSON(buf, v.Data)
+	default:
+		// NaN and infinite floats have no JSON encoding
+		data, err := json.Marshal(v)
+		if err != nil {
+			buf.WriteString("null")
+			return
+		}
+		buf.Write(data)
+	}
+}
@@ -0,0 +1,59 @@
+package fluent
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"logger/model"
+	common "logger/model/proto/common/v1"
+)
+
+func TestToDomainLogs(t *testing.T) {
+	received := ts.Add(time.Second)
+	logs := ToDomainLogs(&Message{
+		Tag: "kube.var.log.containers.web",
+		Entries: []Entry{
+			{Time: ts, Record: Map{
+				{"stream", "stderr"},
+				{"log", "connection refused"},
+				{"kubernetes", Map{{"pod_name", "web-1"}}},
+				{"retries", int64(3)},
+			}},
+			{Record: Map{{"status", int64(200)}, {"path", "/"}, {"tags", []interface{}{"a", []byte("b")}}}},
+		},
+	}, received)
+	require.Len(t, logs, 2)
+
+	assert.Equal(t, &model.Process{ServiceName: "kube.var.log.containers.web", Attributes: []model.KeyValue{}}, logs[0].Process)
+	assert.Same(t, logs[0].Process, logs[1].Process)
+
+	assert.Equal(t, uint64(ts.UnixNano()), logs[0].TimeUnixNano)
+	assert.Equal(t, uint64(received.UnixNano()), logs[0].ObservedTimeUnixNano)
+	assert.Equal(t, "connection refused", logs[0].Body)
+	assert.Equal(t, []model.KeyValue{
+		{Key: "stream", Value: &common.AnyValue{Value: &common.AnyValue_StringValue{StringValue: "stderr"}}},
+		{Key: "kubernetes", Value: &common.AnyValue{Value: &common.AnyValue_KvlistValue{KvlistValue: &common.KeyValueList{
+			Values: []*common.KeyValue{{Key: "pod_name", Value: &common.AnyValue{Value: &common.AnyValue_StringValue{StringValue: "web-1"}}}},
+		}}}},
+		{Key: "retries", Value: &common.AnyValue{Value: &common.AnyValue_IntValue{IntValue: 3}}},
+	}, logs[0].Attributes)
+
+	// without a log line the record is the body
+	assert.Equal(t, uint64(received.UnixNano()), logs[1].TimeUnixNano)
+	assert.Equal(t, `{"status":200,"path":"/","tags":["a","b"]}`, logs[1].Body)
+	assert.Len(t, logs[1].Attributes, 3)
+}
+
+func TestToDomainLogsBodyPrecedence(t *testing.T) {
+	logs := ToDomainLogs(&Message{Entries: []Entry{
+		{Record: Map{{"msg", "third"}, {"message", "second"}, {"log", int64(1)}}},
+		{Record: Map{{"msg", []byte("bytes")}}},
+	}}, ts)
+	assert.Equal(t, "second", logs[0].Body)
+	assert.Len(t, logs[0].Attributes, 2)
+	assert.Equal(t, "bytes", logs[1].Body)
+	assert.Empty(t, logs[1].Attributes)
+}
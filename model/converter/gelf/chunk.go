@@ -0,0 +1,139 @@
+package gelf
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"time"
+	"unsafe"
+)
+
+const (
+	// chunkHeaderSize is the size of the magic bytes, message ID, sequence number and count of a chunk
+	chunkHeaderSize = 12
+	// maxChunks is the maximum sequence count of a chunked message
+	maxChunks = 128
+	// chunkSlotSize is the memory taken by the slot of a chunk in a pending message
+	chunkSlotSize = int(unsafe.Sizeof([]byte(nil)))
+)
+
+// chunkMagic starts the datagrams holding a chunk of a message
+var chunkMagic = []byte{0x1e, 0x0f}
+
+// IsChunk tells whether a datagram holds a chunk of a message.
+func IsChunk(datagram []byte) bool {
+	return bytes.HasPrefix(datagram, chunkMagic)
+}
+
+// Assembler reassembles the chunked messages of UDP datagrams. The messages that are
+// not complete within the timeout are dropped. It is safe for concurrent use.
+type Assembler struct {
+	timeout        time.Duration
+	maxSize        int
+	maxPendingSize int
+
+	mu      sync.Mutex
+	pending map[[8]byte]*chunkedMessage
+	// pendingSize is the memory held by the pending messages
+	pendingSize int
+}
+
+type chunkedMessage struct {
+	chunks   [][]byte
+	received int
+	size     int
+	deadline time.Time
+}
+
+// NewAssembler returns an Assembler dropping the messages that are incomplete after timeout
+// or larger than maxSize, and refusing the chunks that would make the incomplete messages
+// hold more than maxPendingSize bytes.
+func NewAssembler(timeout time.Duration, maxSize int, maxPendingSize int) *Assembler {
+	return &Assembler{
+		timeout:        timeout,
+		maxSize:        maxSize,
+		maxPendingSize: maxPendingSize,
+		pending:        make(map[[8]byte]*chunkedMessage),
+	}
+}
+
+// Add adds the chunk held by datagram, received at now. It returns the payload of the
+// message once all its chunks are added, nil before.
+func (a *Assembler) Add(datagram []byte, now time.Time) ([]byte, error) {
+	if len(datagram) < chunkHeaderSize || !IsChunk(datagram) {
+		return nil, fmt.Errorf("%w: truncated chunk", ErrInvalidMessage)
+	}
+	var id [8]byte
+	copy(id[:], datagram[2:10])
+	seq, count := int(datagram[10]), int(datagram[11])
+	if count == 0 || count > maxChunks || seq >= count {
+		return nil, fmt.Errorf("%w: chunk %d of %d", ErrInvalidMessage, seq, count)
+	}
+	data := datagram[chunkHeaderSize:]
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	m, ok := a.pending[id]
+	if ok && now.After(m.deadline) {
+		a.drop(id, m)
+		ok = false
+	}
+	if !ok {
+		if a.pendingSize+count*chunkSlotSize > a.maxPendingSize {
+			return nil, fmt.Errorf("%w: too many incomplete chunked messages", ErrTooLarge)
+		}
+		m = &chunkedMessage{
+			chunks:   make([][]byte, count),
+			deadline: now.Add(a.timeout),
+		}
+		a.pending[id] = m
+		a.pendingSize += count * chunkSlotSize
+	}
+	if len(m.chunks) != count {
+		a.drop(id, m)
+		return nil, fmt.Errorf("%w: inconsistent chunk count", ErrInvalidMessage)
+	}
+	if m.chunks[seq] != nil {
+		// a duplicate datagram
+		return nil, nil
+	}
+	if m.size+len(data) > a.maxSize {
+		a.drop(id, m)
+		return nil, ErrTooLarge
+	}
+	if a.pendingSize+len(data) > a.maxPendingSize {
+		// the message cannot complete, the memory it holds is released
+		a.drop(id, m)
+		return nil, fmt.Errorf("%w: too many incomplete chunked messages", ErrTooLarge)
+	}
+	// the datagram buffer is reused by the caller
+	m.chunks[seq] = append([]byte{}, data...)
+	m.size += len(data)
+	a.pendingSize += len(data)
+	m.received++
+	if m.received < count {
+		return nil, nil
+	}
+	a.drop(id, m)
+	return bytes.Join(m.chunks, nil), nil
+}
+
+// drop forgets the pending message id and releases the memory it holds.
+func (a *Assembler) drop(id [8]byte, m *chunkedMessage) {
+	delete(a.pending, id)
+	a.pendingSize -= len(m.chunks)*chunkSlotSize + m.size
+}
+
+// Expire drops the messages that are still incomplete at now, and returns how many.
+func (a *Assembler) Expire(now time.Time) int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	expired := 0
+	for id, m := range a.pending {
+		if now.After(m.deadline) {
+			a.drop(id, m)
+			expired++
+		}
+	}
+	return expired
+}
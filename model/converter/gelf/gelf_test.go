@@ -0,0 +1,213 @@
+package gelf
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"logger/model"
+	common "logger/model/proto/common/v1"
+	pbL "logger/model/proto/logs/v1"
+)
+
+const payload = `{"version":"1.1","host":"web-1","short_message":"boom","full_message":"boom\nat Main.java:12",
+	"timestamp":1710072000.123,"level":3,"facility":"app","file":"Main.java","line":12,
+	"_service":"checkout","_user_id":42,"_ratio":0.5,"_tags":["a"],"_id":"reserved","other":"ignored"}`
+
+var now = time.Date(2024, time.March, 10, 12, 0, 0, 0, time.UTC)
+
+func TestParse(t *testing.T) {
+	m, err := Parse([]byte(payload))
+	require.NoError(t, err)
+	assert.Equal(t, &Message{
+		Host:         "web-1",
+		ShortMessage: "boom",
+		FullMessage:  "boom\nat Main.java:12",
+		Timestamp:    1710072000.123,
+		Level:        3,
+		Facility:     "app",
+		File:         "Main.java",
+		Line:         12,
+		Fields: []Field{
+			{"service", "checkout"},
+			{"user_id", json.Number("42")},
+			{"ratio", json.Number("0.5")},
+			{"tags", `["a"]`},
+		},
+	}, m)
+}
+
+func TestParseDefaults(t *testing.T) {
+	m, err := Parse([]byte(`{"short_message":"hi","level":"6","line":"7"}`))
+	require.NoError(t, err)
+	assert.Equal(t, &Message{ShortMessage: "hi", Level: 6, Line: 7}, m)
+
+	m, err = Parse([]byte(`{"short_message":"hi"}`))
+	require.NoError(t, err)
+	assert.Equal(t, 1, m.Level)
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, data := range []string{
+		``,
+		`[]`,
+		`{"host":"web-1"}`,
+		`{"short_message":1}`,
+		`{"short_message":"hi","timestamp":"now"}`,
+		`{"short_message":"hi","level":"high"}`,
+		`{"short_message":"hi","level":1.5}`,
+		`{"short_message":"hi"`,
+	} {
+		_, err := Parse([]byte(data))
+		assert.ErrorIs(t, err, ErrInvalidMessage, data)
+	}
+}
+
+func TestDecompress(t *testing.T) {
+	var gz, zl bytes.Buffer
+	gw := gzip.NewWriter(&gz)
+	gw.Write([]byte(payload))
+	gw.Close()
+	zw := zlib.NewWriter(&zl)
+	zw.Write([]byte(payload))
+	zw.Close()
+
+	for name, data := range map[string][]byte{"gzip": gz.Bytes(), "zlib": zl.Bytes(), "none": []byte(payload)} {
+		res, err := Decompress(data, len(payload))
+		require.NoError(t, err, name)
+		assert.Equal(t, payload, string(res), name)
+
+		_, err = Decompress(data, len(payload)-1)
+		assert.ErrorIs(t, err, ErrTooLarge, name)
+	}
+
+	_, err := Decompress([]byte{0x1f, 0x8b, 0x00}, 100)
+	assert.ErrorIs(t, err, ErrInvalidMessage)
+}
+
+func chunk(id byte, seq, count int, data string) []byte {
+	return append([]byte{0x1e, 0x0f, id, 0, 0, 0, 0, 0, 0, 0, byte(seq), byte(count)}, data...)
+}
+
+func TestAssembler(t *testing.T) {
+	// room for the slots of two messages of 3 and 2 chunks and 20 bytes of data
+	a := NewAssembler(time.Second, 100, 5*chunkSlotSize+20)
+	res, err := a.Add(chunk(1, 1, 3, "lo, "), now)
+	require.NoError(t, err)
+	assert.Nil(t, res)
+	res, err = a.Add(chunk(2, 0, 2, "other"), now)
+	require.NoError(t, err)
+	assert.Nil(t, res)
+	res, err = a.Add(chunk(1, 0, 3, "hel"), now)
+	require.NoError(t, err)
+	assert.Nil(t, res)
+	// a duplicate chunk is ignored
+	res, err = a.Add(chunk(1, 0, 3, "hel"), now)
+	require.NoError(t, err)
+	assert.Nil(t, res)
+
+	// a third message does not fit
+	_, err = a.Add(chunk(3, 0, 2, "third"), now)
+	assert.ErrorIs(t, err, ErrTooLarge)
+	assert.Equal(t, 5*chunkSlotSize+12, a.pendingSize)
+
+	res, err = a.Add(chunk(1, 2, 3, "world"), now)
+	require.NoError(t, err)
+	assert.Equal(t, "hello, world", string(res))
+
+	assert.Equal(t, 0, a.Expire(now.Add(time.Second)))
+	assert.Equal(t, 1, a.Expire(now.Add(2*time.Second)))
+
+	// the remaining chunk of an expired message starts a new one
+	res, err = a.Add(chunk(2, 1, 2, "late"), now.Add(2*time.Second))
+	require.NoError(t, err)
+	assert.Nil(t, res)
+	assert.Equal(t, 2*chunkSlotSize+4, a.pendingSize)
+
+	// a chunk overflowing the pending bytes drops its message
+	_, err = a.Add(chunk(2, 0, 2, strings.Repeat("x", 3*chunkSlotSize+17)), now.Add(2*time.Second))
+	assert.ErrorIs(t, err, ErrTooLarge)
+	assert.Equal(t, 0, a.pendingSize)
+	assert.Empty(t, a.pending)
+}
+
+func TestAssemblerErrors(t *testing.T) {
+	a := NewAssembler(time.Second, 10, 1000)
+	for _, datagram := range [][]byte{
+		[]byte{0x1e, 0x0f, 1},
+		[]byte(`{"short_message":"hi"}`),
+		chunk(1, 0, 0, "x"),
+		chunk(1, 2, 2, "x"),
+		chunk(1, 0, 129, "x"),
+	} {
+		_, err := a.Add(datagram, now)
+		assert.ErrorIs(t, err, ErrInvalidMessage)
+	}
+
+	_, err := a.Add(chunk(1, 0, 2, "x"), now)
+	require.NoError(t, err)
+	_, err = a.Add(chunk(1, 1, 3, "x"), now)
+	assert.ErrorIs(t, err, ErrInvalidMessage)
+
+	_, err = a.Add(chunk(2, 0, 2, "0123456789"), now)
+	require.NoError(t, err)
+	_, err = a.Add(chunk(2, 1, 2, "0"), now)
+	assert.ErrorIs(t, err, ErrTooLarge)
+}
+
+func TestScanner(t *testing.T) {
+	scanner := NewScanner(strings.NewReader("{\"a\":1}\x00{\"b\":2}\n\x00\x00{\"c\":3}"), 16)
+	var messages []string
+	for scanner.Scan() {
+		messages = append(messages, scanner.Text())
+	}
+	require.NoError(t, scanner.Err())
+	assert.Equal(t, []string{`{"a":1}`, `{"b":2}`, `{"c":3}`}, messages)
+
+	scanner = NewScanner(strings.NewReader(strings.Repeat("x", 20)+"\x00"), 16)
+	assert.False(t, scanner.Scan())
+	assert.ErrorIs(t, scanner.Err(), bufio.ErrTooLong)
+}
+
+func TestToDomainLog(t *testing.T) {
+	m, err := Parse([]byte(payload))
+	require.NoError(t, err)
+	log := ToDomainLog(m, now)
+
+	assert.Equal(t, uint64(time.Date(2024, time.March, 10, 12, 0, 0, 123000000, time.UTC).UnixNano()), log.TimeUnixNano)
+	assert.Equal(t, uint64(now.UnixNano()), log.ObservedTimeUnixNano)
+	assert.Equal(t, pbL.SeverityNumber_SEVERITY_NUMBER_ERROR, log.SeverityNumber)
+	assert.Equal(t, "err", log.SeverityText)
+	assert.Equal(t, "boom\nat Main.java:12", log.Body)
+	assert.Equal(t, []model.KeyValue{
+		stringAttribute("gelf.short_message", "boom"),
+		stringAttribute("gelf.facility", "app"),
+		stringAttribute("code.filepath", "Main.java"),
+		{Key: "code.lineno", Value: &common.AnyValue{Value: &common.AnyValue_IntValue{IntValue: 12}}},
+		{Key: "user_id", Value: &common.AnyValue{Value: &common.AnyValue_IntValue{IntValue: 42}}},
+		{Key: "ratio", Value: &common.AnyValue{Value: &common.AnyValue_DoubleValue{DoubleValue: 0.5}}},
+		stringAttribute("tags", `["a"]`),
+	}, log.Attributes)
+	assert.Equal(t, &model.Process{
+		ServiceName: "checkout",
+		Attributes:  []model.KeyValue{stringAttribute("host.name", "web-1")},
+	}, log.Process)
+}
+
+func TestToDomainLogDefaults(t *testing.T) {
+	log := ToDomainLog(&Message{ShortMessage: "hi", Level: 1, Fields: []Field{{"tag", "nginx"}, {"pid", json.Number("7")}}}, now)
+	assert.Equal(t, uint64(now.UnixNano()), log.TimeUnixNano)
+	assert.Equal(t, pbL.SeverityNumber_SEVERITY_NUMBER_FATAL3, log.SeverityNumber)
+	assert.Equal(t, "hi", log.Body)
+	assert.Equal(t, "nginx", log.Process.ServiceName)
+	assert.Empty(t, log.Process.Attributes)
+	assert.Len(t, log.Attributes, 1)
+}
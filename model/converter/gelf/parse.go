@@ -0,0 +1,195 @@
+package gelf
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	// defaultLevel is the level of a message without one, ALERT per the GELF specification
+	defaultLevel = 1
+	// additionalFieldPrefix starts the name of the additional fields of a message
+	additionalFieldPrefix = "_"
+)
+
+var (
+	// ErrInvalidMessage is returned for data that is not a GELF message.
+	ErrInvalidMessage = errors.New("invalid GELF message")
+	// ErrTooLarge is returned for a message larger than the allowed size, once decompressed.
+	ErrTooLarge = errors.New("GELF message too large")
+)
+
+// Message is a GELF payload.
+type Message struct {
+	Host         string
+	ShortMessage string
+	FullMessage  string
+	// Timestamp is the UNIX time in seconds, with a decimal part, zero when absent
+	Timestamp float64
+	Level     int
+	Facility  string
+	File      string
+	Line      int64
+	// Fields are the additional fields, named without their leading underscore, in order
+	Fields []Field
+}
+
+// Field is an additional field of a message. Its value is a string, a json.Number,
+// a bool, or the JSON of a nested value, which GELF does not allow but some senders use.
+type Field struct {
+	Name  string
+	Value interface{}
+}
+
+// Decompress returns the payload of a message, decompressing it when it starts with
+// the magic bytes of gzip or zlib, refusing to produce more than limit bytes.
+func Decompress(data []byte, limit int) ([]byte, error) {
+	var r io.ReadCloser
+	var err error
+	switch {
+	case len(data) >= 2 && data[0] == 0x1f && data[1] == 0x8b:
+		r, err = gzip.NewReader(bytes.NewReader(data))
+	case len(data) >= 2 && data[0] == 0x78 && (uint16(data[0])<<8|uint16(data[1]))%31 == 0:
+		r, err = zlib.NewReader(bytes.NewReader(data))
+	default:
+		if len(data) > limit {
+			return nil, ErrTooLarge
+		}
+		return data, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidMessage, err)
+	}
+	defer r.Close()
+	// read one byte past the limit to tell a message of exactly limit bytes from a larger one
+	res, err := io.ReadAll(io.LimitReader(r, int64(limit)+1))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidMessage, err)
+	}
+	if len(res) > limit {
+		return nil, ErrTooLarge
+	}
+	return res, nil
+}
+
+// Parse reads an uncompressed GELF payload.
+func Parse(data []byte) (*Message, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	tok, err := dec.Token()
+	if err != nil || tok != json.Delim('{') {
+		return nil, fmt.Errorf("%w: not a JSON object", ErrInvalidMessage)
+	}
+	m := &Message{Level: defaultLevel}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidMessage, err)
+		}
+		key := tok.(string)
+		var value interface{}
+		if err := dec.Decode(&value); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidMessage, err)
+		}
+		if err := m.setField(key, value); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := dec.Token(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidMessage, err)
+	}
+	if m.ShortMessage == "" && m.FullMessage == "" {
+		return nil, fmt.Errorf("%w: missing short_message", ErrInvalidMessage)
+	}
+	return m, nil
+}
+
+func (m *Message) setField(key string, value interface{}) error {
+	switch key {
+	case "version":
+		return nil
+	case "host":
+		return setString(&m.Host, key, value)
+	case "short_message":
+		return setString(&m.ShortMessage, key, value)
+	case "full_message":
+		return setString(&m.FullMessage, key, value)
+	case "facility":
+		return setString(&m.Facility, key, value)
+	case "file":
+		return setString(&m.File, key, value)
+	case "timestamp":
+		n, ok := value.(json.Number)
+		if !ok {
+			return fmt.Errorf("%w: timestamp is not a number", ErrInvalidMessage)
+		}
+		ts, err := n.Float64()
+		if err != nil {
+			return fmt.Errorf("%w: timestamp: %v", ErrInvalidMessage, err)
+		}
+		m.Timestamp = ts
+	case "level":
+		level, err := intField(key, value)
+		if err != nil {
+			return err
+		}
+		m.Level = int(level)
+	case "line":
+		line, err := intField(key, value)
+		if err != nil {
+			return err
+		}
+		m.Line = line
+	default:
+		name, ok := strings.CutPrefix(key, additionalFieldPrefix)
+		// _id is reserved, fields without the prefix are not part of the specification
+		if !ok || name == "" || name == "id" {
+			return nil
+		}
+		switch value.(type) {
+		case string, json.Number, bool:
+		case nil:
+			return nil
+		default:
+			data, _ := json.Marshal(value)
+			value = string(data)
+		}
+		m.Fields = append(m.Fields, Field{Name: name, Value: value})
+	}
+	return nil
+}
+
+func setString(field *string, key string, value interface{}) error {
+	switch value := value.(type) {
+	case string:
+		*field = value
+	case nil:
+	default:
+		return fmt.Errorf("%w: %s is not a string", ErrInvalidMessage, key)
+	}
+	return nil
+}
+
+// intField reads an integer, which some senders quote.
+func intField(key string, value interface{}) (int64, error) {
+	var n json.Number
+	switch value := value.(type) {
+	case json.Number:
+		n = value
+	case string:
+		n = json.Number(value)
+	default:
+		return 0, fmt.Errorf("%w: %s is not a number", ErrInvalidMessage, key)
+	}
+	i, err := n.Int64()
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s: %v", ErrInvalidMessage, key, err)
+	}
+	return i, nil
+}
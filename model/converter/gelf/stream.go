@@ -0,0 +1,27 @@
+package gelf
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+)
+
+// NewScanner returns a scanner splitting a GELF TCP stream into its null-delimited
+// messages. Messages larger than maxSize fail the scan with bufio.ErrTooLong.
+func NewScanner(r io.Reader, maxSize int) *bufio.Scanner {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, min(maxSize, bufio.MaxScanTokenSize)), maxSize+1)
+	scanner.Split(splitNull)
+	return scanner
+}
+
+func splitNull(data []byte, atEOF bool) (int, []byte, error) {
+	if i := bytes.IndexByte(data, 0); i >= 0 {
+		// some senders end messages with a newline as well
+		return i + 1, bytes.TrimSpace(data[:i]), nil
+	}
+	if atEOF && len(data) > 0 {
+		return len(data), bytes.TrimSpace(data), nil
+	}
+	return 0, nil, nil
+}
@@ -0,0 +1,126 @@
+package gelf
+
+import (
+	"encoding/json"
+	"math"
+	"time"
+
+	"logger/model"
+	"logger/model/converter/syslog"
+	common "logger/model/proto/common/v1"
+)
+
+const (
+	hostNameAttribute     = "host.name"
+	shortMessageAttribute = "gelf.short_message"
+	facilityAttribute     = "gelf.facility"
+	fileAttribute         = "code.filepath"
+	lineAttribute         = "code.lineno"
+)
+
+// serviceFields are the additional fields naming the service, by precedence. "tag" is
+// set by the Docker GELF log driver.
+var serviceFields = []string{"service", "application", "tag"}
+
+// ToDomainLog converts a GELF message received at the given time to a log record. The level
+// is a syslog severity. The full message, when there is one, is the body and the short one
+// an attribute. The host and the additional field naming the service go to the process.
+func ToDomainLog(m *Message, received time.Time) *model.LogRecord {
+	ts := received
+	if m.Timestamp > 0 {
+		sec, frac := math.Modf(m.Timestamp)
+		// rounding to the microsecond drops the float noise of the decimal part
+		ts = time.Unix(int64(sec), int64(math.Round(frac*1e6))*1e3)
+	}
+	severityText, severityNumber := syslog.Severity(m.Level)
+
+	var attributes []model.KeyValue
+	body := m.ShortMessage
+	if m.FullMessage != "" {
+		body = m.FullMessage
+		if m.ShortMessage != "" {
+			attributes = append(attributes, stringAttribute(shortMessageAttribute, m.ShortMessage))
+		}
+	}
+	if m.Facility != "" {
+		attributes = append(attributes, stringAttribute(facilityAttribute, m.Facility))
+	}
+	if m.File != "" {
+		attributes = append(attributes, stringAttribute(fileAttribute, m.File))
+	}
+	if m.Line > 0 {
+		attributes = append(attributes, model.KeyValue{
+			Key:   lineAttribute,
+			Value: &common.AnyValue{Value: &common.AnyValue_IntValue{IntValue: m.Line}},
+		})
+	}
+
+	service := serviceField(m.Fields)
+	for _, field := range m.Fields {
+		if field.Name == service {
+			continue
+		}
+		attributes = append(attributes, model.KeyValue{Key: field.Name, Value: toAnyValue(field.Value)})
+	}
+
+	process := &model.Process{Attributes: []model.KeyValue{}}
+	if service != "" {
+		process.ServiceName = model.AnyValueString(toAnyValue(fieldValue(m.Fields, service)))
+	}
+	if m.Host != "" {
+		process.Attributes = append(process.Attributes, stringAttribute(hostNameAttribute, m.Host))
+	}
+	return &model.LogRecord{
+		TimeUnixNano:         uint64(ts.UnixNano()),
+		ObservedTimeUnixNano: uint64(received.UnixNano()),
+		SeverityNumber:       severityNumber,
+		SeverityText:         severityText,
+		Body:                 body,
+		Attributes:           attributes,
+		Process:              process,
+	}
+}
+
+// serviceField returns the name of the field naming the service, empty when there is none.
+func serviceField(fields []Field) string {
+	for _, name := range serviceFields {
+		if fieldValue(fields, name) != nil {
+			return name
+		}
+	}
+	return ""
+}
+
+func fieldValue(fields []Field, name string) interface{} {
+	for _, field := range fields {
+		if field.Name == name {
+			return field.Value
+		}
+	}
+	return nil
+}
+
+func toAnyValue(v interface{}) *common.AnyValue {
+	switch v := v.(type) {
+	case string:
+		return &common.AnyValue{Value: &common.AnyValue_StringValue{StringValue: v}}
+	case bool:
+		return &common.AnyValue{Value: &common.AnyValue_BoolValue{BoolValue: v}}
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return &common.AnyValue{Value: &common.AnyValue_IntValue{IntValue: i}}
+		}
+		if f, err := v.Float64(); err == nil {
+			return &common.AnyValue{Value: &common.AnyValue_DoubleValue{DoubleValue: f}}
+		}
+		return &common.AnyValue{Value: &common.AnyValue_StringValue{StringValue: v.String()}}
+	}
+	return &common.AnyValue{}
+}
+
+func stringAttribute(key, value string) model.KeyValue {
+	return model.KeyValue{
+		Key:   key,
+		Value: &common.AnyValue{Value: &common.AnyValue_StringValue{StringValue: value}},
+	}
+}
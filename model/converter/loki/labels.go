@@ -0,0 +1,58 @@
+package loki
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ParseLabels reads the labels of a stream written as a Prometheus selector,
+// e.g. {job="varlogs", filename="/var/log/syslog"}.
+func ParseLabels(s string) ([]Label, error) {
+	rest := strings.TrimSpace(s)
+	if !strings.HasPrefix(rest, "{") || !strings.HasSuffix(rest, "}") {
+		return nil, fmt.Errorf("%w: labels %q", ErrInvalidRequest, s)
+	}
+	rest = strings.TrimSpace(rest[1 : len(rest)-1])
+	var labels []Label
+	for rest != "" {
+		eq := strings.IndexByte(rest, '=')
+		if eq <= 0 {
+			return nil, fmt.Errorf("%w: labels %q", ErrInvalidRequest, s)
+		}
+		name := strings.TrimSpace(rest[:eq])
+		if !isLabelName(name) {
+			return nil, fmt.Errorf("%w: label name %q", ErrInvalidRequest, name)
+		}
+		rest = strings.TrimSpace(rest[eq+1:])
+		quoted, err := strconv.QuotedPrefix(rest)
+		if err != nil || quoted[0] != '"' {
+			return nil, fmt.Errorf("%w: value of label %s", ErrInvalidRequest, name)
+		}
+		value, _ := strconv.Unquote(quoted)
+		labels = append(labels, Label{Name: name, Value: value})
+
+		rest = strings.TrimSpace(rest[len(quoted):])
+		if rest == "" {
+			break
+		}
+		if rest[0] != ',' {
+			return nil, fmt.Errorf("%w: labels %q", ErrInvalidRequest, s)
+		}
+		rest = strings.TrimSpace(rest[1:])
+	}
+	return labels, nil
+}
+
+func isLabelName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, c := range name {
+		if c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || i > 0 && c >= '0' && c <= '9' {
+			continue
+		}
+		return false
+	}
+	return true
+}
@@ -0,0 +1,146 @@
+package loki
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"logger/model"
+)
+
+var now = time.Date(2024, time.March, 10, 12, 0, 0, 0, time.UTC)
+
+func appendMessage(b []byte, num protowire.Number, m []byte) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, m)
+}
+
+func appendString(b []byte, num protowire.Number, s string) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, s)
+}
+
+// pushRequestProto encodes a push request like Promtail does, before snappy compression.
+func pushRequestProto() []byte {
+	var ts []byte
+	ts = protowire.AppendTag(ts, timestampSeconds, protowire.VarintType)
+	ts = protowire.AppendVarint(ts, uint64(now.Unix()))
+	ts = protowire.AppendTag(ts, timestampNanos, protowire.VarintType)
+	ts = protowire.AppendVarint(ts, 500)
+
+	var metadata []byte
+	metadata = appendString(metadata, labelName, "trace_id")
+	metadata = appendString(metadata, labelValue, "abc")
+
+	var entry []byte
+	entry = appendMessage(entry, entryTimestamp, ts)
+	entry = appendString(entry, entryLine, "hello")
+	entry = appendMessage(entry, entryStructuredMetadata, metadata)
+
+	var stream []byte
+	stream = appendString(stream, streamLabels, `{job="varlogs", filename="/var/log/\"x\".log"}`)
+	stream = appendMessage(stream, streamEntries, entry)
+	stream = appendMessage(stream, streamEntries, appendString(nil, entryLine, "no timestamp"))
+	// the hash of the labels is ignored
+	stream = protowire.AppendTag(stream, 3, protowire.VarintType)
+	stream = protowire.AppendVarint(stream, 42)
+
+	return appendMessage(nil, pushRequestStreams, stream)
+}
+
+func TestUnmarshalProto(t *testing.T) {
+	req, err := UnmarshalProto(pushRequestProto())
+	require.NoError(t, err)
+	assert.Equal(t, &PushRequest{Streams: []Stream{{
+		Labels: []Label{{"job", "varlogs"}, {"filename", `/var/log/"x".log`}},
+		Entries: []Entry{
+			{Timestamp: now.Add(500), Line: "hello", StructuredMetadata: []Label{{"trace_id", "abc"}}},
+			{Line: "no timestamp"},
+		},
+	}}}, req)
+
+	_, err = UnmarshalProto([]byte{0x0a, 0x05, 0x01})
+	assert.ErrorIs(t, err, ErrInvalidRequest)
+	_, err = UnmarshalProto(appendMessage(nil, pushRequestStreams, appendString(nil, streamLabels, "job=x")))
+	assert.ErrorIs(t, err, ErrInvalidRequest)
+}
+
+func TestUnmarshalJSON(t *testing.T) {
+	req, err := UnmarshalJSON([]byte(`{"streams":[{"stream":{"service_name":"checkout","env":"prod"},
+		"values":[["1710072000000000500","hello",{"trace_id":"abc"}],["1710072001000000000","world"]]}]}`))
+	require.NoError(t, err)
+	assert.Equal(t, &PushRequest{Streams: []Stream{{
+		Labels: []Label{{"service_name", "checkout"}, {"env", "prod"}},
+		Entries: []Entry{
+			{Timestamp: now.Add(500), Line: "hello", StructuredMetadata: []Label{{"trace_id", "abc"}}},
+			{Timestamp: now.Add(time.Second), Line: "world"},
+		},
+	}}}, req)
+
+	for _, data := range []string{
+		`[]`,
+		`{"streams":[{"stream":[],"values":[]}]}`,
+		`{"streams":[{"stream":{"a":1},"values":[]}]}`,
+		`{"streams":[{"values":[["now","hello"]]}]}`,
+		`{"streams":[{"values":[["1710072000000000000"]]}]}`,
+		`{"streams":[{"values":[["1710072000000000000",1]]}]}`,
+	} {
+		_, err := UnmarshalJSON([]byte(data))
+		assert.ErrorIs(t, err, ErrInvalidRequest, data)
+	}
+}
+
+func TestParseLabels(t *testing.T) {
+	labels, err := ParseLabels(` { a="1",b_2 = "x,\"y\"" , } `)
+	require.NoError(t, err)
+	assert.Equal(t, []Label{{"a", "1"}, {"b_2", `x,"y"`}}, labels)
+
+	labels, err = ParseLabels(`{}`)
+	require.NoError(t, err)
+	assert.Empty(t, labels)
+
+	for _, s := range []string{``, `a="1"`, `{a}`, `{1a="1"}`, `{a=1}`, `{a='1'}`, `{a="1" b="2"}`, `{a="1}`} {
+		_, err := ParseLabels(s)
+		assert.ErrorIs(t, err, ErrInvalidRequest, s)
+	}
+}
+
+func TestToDomainLogs(t *testing.T) {
+	received := now.Add(time.Minute)
+	req := &PushRequest{Streams: []Stream{
+		{
+			Labels: []Label{{"job", "varlogs"}, {"service_name", "checkout"}, {"env", "prod"}},
+			Entries: []Entry{
+				{Timestamp: now, Line: "hello", StructuredMetadata: []Label{{"trace_id", "abc"}}},
+				{Line: "world"},
+			},
+		},
+		{
+			Labels:  []Label{{"job", "nginx"}},
+			Entries: []Entry{{Timestamp: now, Line: "GET /"}},
+		},
+	}}
+	logs := ToDomainLogs(req, []string{"service_name", "job"}, received)
+	require.Len(t, logs, 3)
+
+	assert.Equal(t, &model.LogRecord{
+		TimeUnixNano:         uint64(now.UnixNano()),
+		ObservedTimeUnixNano: uint64(received.UnixNano()),
+		Body:                 "hello",
+		Attributes:           []model.KeyValue{stringAttribute("trace_id", "abc")},
+		Process: &model.Process{
+			ServiceName: "checkout",
+			Attributes:  []model.KeyValue{stringAttribute("job", "varlogs"), stringAttribute("env", "prod")},
+		},
+	}, logs[0])
+	assert.Equal(t, uint64(received.UnixNano()), logs[1].TimeUnixNano)
+	assert.Same(t, logs[0].Process, logs[1].Process)
+	assert.Equal(t, &model.Process{ServiceName: "nginx", Attributes: []model.KeyValue{}}, logs[2].Process)
+
+	logs = ToDomainLogs(req, nil, received)
+	assert.Empty(t, logs[2].Process.ServiceName)
+	assert.Len(t, logs[2].Process.Attributes, 1)
+}
@@ -0,0 +1,283 @@
+package loki
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// ErrInvalidRequest is returned for data that is not a push request.
+var ErrInvalidRequest = errors.New("invalid Loki push request")
+
+// PushRequest is the payload of the Loki push API.
+type PushRequest struct {
+	Streams []Stream
+}
+
+// Stream is a set of entries sharing the same labels.
+type Stream struct {
+	Labels  []Label
+	Entries []Entry
+}
+
+// Entry is a log line of a stream.
+type Entry struct {
+	Timestamp time.Time
+	Line      string
+	// StructuredMetadata are the labels attached to the entry only
+	StructuredMetadata []Label
+}
+
+// Label is a name and value pair, of a stream or of an entry.
+type Label struct {
+	Name  string
+	Value string
+}
+
+// Field numbers of the push.proto messages of Loki.
+const (
+	pushRequestStreams = 1
+
+	streamLabels  = 1
+	streamEntries = 2
+
+	entryTimestamp          = 1
+	entryLine               = 2
+	entryStructuredMetadata = 3
+
+	labelName  = 1
+	labelValue = 2
+
+	timestampSeconds = 1
+	timestampNanos   = 2
+)
+
+// UnmarshalProto reads a PushRequest encoded in protobuf, once snappy decompressed.
+func UnmarshalProto(data []byte) (*PushRequest, error) {
+	req := &PushRequest{}
+	err := walkFields(data, func(num protowire.Number, typ protowire.Type, v []byte) error {
+		if num != pushRequestStreams || typ != protowire.BytesType {
+			return nil
+		}
+		stream, err := unmarshalStream(v)
+		if err != nil {
+			return err
+		}
+		req.Streams = append(req.Streams, stream)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+func unmarshalStream(data []byte) (Stream, error) {
+	var stream Stream
+	err := walkFields(data, func(num protowire.Number, typ protowire.Type, v []byte) error {
+		if typ != protowire.BytesType {
+			return nil
+		}
+		switch num {
+		case streamLabels:
+			labels, err := ParseLabels(string(v))
+			if err != nil {
+				return err
+			}
+			stream.Labels = labels
+		case streamEntries:
+			entry, err := unmarshalEntry(v)
+			if err != nil {
+				return err
+			}
+			stream.Entries = append(stream.Entries, entry)
+		}
+		return nil
+	})
+	return stream, err
+}
+
+func unmarshalEntry(data []byte) (Entry, error) {
+	var entry Entry
+	err := walkFields(data, func(num protowire.Number, typ protowire.Type, v []byte) error {
+		if typ != protowire.BytesType {
+			return nil
+		}
+		switch num {
+		case entryTimestamp:
+			ts, err := unmarshalTimestamp(v)
+			if err != nil {
+				return err
+			}
+			entry.Timestamp = ts
+		case entryLine:
+			entry.Line = string(v)
+		case entryStructuredMetadata:
+			label, err := unmarshalLabel(v)
+			if err != nil {
+				return err
+			}
+			entry.StructuredMetadata = append(entry.StructuredMetadata, label)
+		}
+		return nil
+	})
+	return entry, err
+}
+
+func unmarshalLabel(data []byte) (Label, error) {
+	var label Label
+	err := walkFields(data, func(num protowire.Number, typ protowire.Type, v []byte) error {
+		if typ != protowire.BytesType {
+			return nil
+		}
+		switch num {
+		case labelName:
+			label.Name = string(v)
+		case labelValue:
+			label.Value = string(v)
+		}
+		return nil
+	})
+	return label, err
+}
+
+// unmarshalTimestamp reads a google.protobuf.Timestamp.
+func unmarshalTimestamp(data []byte) (time.Time, error) {
+	var seconds, nanos int64
+	err := walkFields(data, func(num protowire.Number, typ protowire.Type, v []byte) error {
+		if typ != protowire.VarintType {
+			return nil
+		}
+		n, _ := protowire.ConsumeVarint(v)
+		switch num {
+		case timestampSeconds:
+			seconds = int64(n)
+		case timestampNanos:
+			nanos = int64(int32(n))
+		}
+		return nil
+	})
+	return time.Unix(seconds, nanos).UTC(), err
+}
+
+// walkFields calls fn with the number, type and value of each field of a message. The
+// value of a varint field is its encoding, the value of a length-delimited one its content.
+func walkFields(data []byte, fn func(num protowire.Number, typ protowire.Type, v []byte) error) error {
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return fmt.Errorf("%w: %v", ErrInvalidRequest, protowire.ParseError(n))
+		}
+		data = data[n:]
+		var v []byte
+		if typ == protowire.BytesType {
+			v, n = protowire.ConsumeBytes(data)
+		} else {
+			n = protowire.ConsumeFieldValue(num, typ, data)
+			if n >= 0 {
+				v = data[:n]
+			}
+		}
+		if n < 0 {
+			return fmt.Errorf("%w: %v", ErrInvalidRequest, protowire.ParseError(n))
+		}
+		data = data[n:]
+		if err := fn(num, typ, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// jsonPushRequest is the JSON form of a push request, where each value is an array of
+// the timestamp in nanoseconds as a string, the line and optionally the structured metadata.
+type jsonPushRequest struct {
+	Streams []struct {
+		Stream json.RawMessage     `json:"stream"`
+		Values [][]json.RawMessage `json:"values"`
+	} `json:"streams"`
+}
+
+// UnmarshalJSON reads a PushRequest encoded in JSON.
+func UnmarshalJSON(data []byte) (*PushRequest, error) {
+	var doc jsonPushRequest
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRequest, err)
+	}
+	req := &PushRequest{Streams: make([]Stream, 0, len(doc.Streams))}
+	for _, s := range doc.Streams {
+		labels, err := unmarshalJSONLabels(s.Stream)
+		if err != nil {
+			return nil, err
+		}
+		stream := Stream{Labels: labels, Entries: make([]Entry, 0, len(s.Values))}
+		for _, value := range s.Values {
+			entry, err := unmarshalJSONEntry(value)
+			if err != nil {
+				return nil, err
+			}
+			stream.Entries = append(stream.Entries, entry)
+		}
+		req.Streams = append(req.Streams, stream)
+	}
+	return req, nil
+}
+
+func unmarshalJSONEntry(value []json.RawMessage) (Entry, error) {
+	var entry Entry
+	if len(value) < 2 || len(value) > 3 {
+		return entry, fmt.Errorf("%w: an entry has %d values", ErrInvalidRequest, len(value))
+	}
+	var ts string
+	if err := json.Unmarshal(value[0], &ts); err != nil {
+		return entry, fmt.Errorf("%w: timestamp: %v", ErrInvalidRequest, err)
+	}
+	nanos, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return entry, fmt.Errorf("%w: timestamp: %v", ErrInvalidRequest, err)
+	}
+	entry.Timestamp = time.Unix(0, nanos).UTC()
+	if err := json.Unmarshal(value[1], &entry.Line); err != nil {
+		return entry, fmt.Errorf("%w: line: %v", ErrInvalidRequest, err)
+	}
+	if len(value) == 3 {
+		entry.StructuredMetadata, err = unmarshalJSONLabels(value[2])
+		if err != nil {
+			return entry, err
+		}
+	}
+	return entry, nil
+}
+
+// unmarshalJSONLabels reads a JSON object of string values, keeping the order of its keys.
+func unmarshalJSONLabels(data json.RawMessage) ([]Label, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		if tok == nil && err == nil {
+			// null
+			return nil, nil
+		}
+		return nil, fmt.Errorf("%w: labels are not a JSON object", ErrInvalidRequest)
+	}
+	var labels []Label
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidRequest, err)
+		}
+		var value string
+		if err := dec.Decode(&value); err != nil {
+			return nil, fmt.Errorf("%w: label %s: %v", ErrInvalidRequest, tok, err)
+		}
+		labels = append(labels, Label{Name: tok.(string), Value: value})
+	}
+	return labels, nil
+}
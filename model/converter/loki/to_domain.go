@@ -0,0 +1,71 @@
+package loki
+
+import (
+	"time"
+
+	"logger/model"
+	common "logger/model/proto/common/v1"
+)
+
+// ToDomainLogs converts the entries of a push request received at the given time to log
+// records. The labels of a stream go to the process of its records, but for the first of
+// serviceLabels the stream has, whose value names the service. The structured metadata
+// of an entry are the attributes of its record.
+func ToDomainLogs(req *PushRequest, serviceLabels []string, received time.Time) []*model.LogRecord {
+	var logs []*model.LogRecord
+	for _, stream := range req.Streams {
+		process := toProcess(stream.Labels, serviceLabels)
+		for _, entry := range stream.Entries {
+			ts := entry.Timestamp
+			if ts.IsZero() {
+				ts = received
+			}
+			attributes := make([]model.KeyValue, 0, len(entry.StructuredMetadata))
+			for _, label := range entry.StructuredMetadata {
+				attributes = append(attributes, stringAttribute(label.Name, label.Value))
+			}
+			logs = append(logs, &model.LogRecord{
+				TimeUnixNano:         uint64(ts.UnixNano()),
+				ObservedTimeUnixNano: uint64(received.UnixNano()),
+				Body:                 entry.Line,
+				Attributes:           attributes,
+				Process:              process,
+			})
+		}
+	}
+	return logs
+}
+
+func toProcess(labels []Label, serviceLabels []string) *model.Process {
+	process := &model.Process{Attributes: make([]model.KeyValue, 0, len(labels))}
+	service := ""
+	for _, name := range serviceLabels {
+		if value, ok := findLabel(labels, name); ok {
+			service, process.ServiceName = name, value
+			break
+		}
+	}
+	for _, label := range labels {
+		if label.Name == service {
+			continue
+		}
+		process.Attributes = append(process.Attributes, stringAttribute(label.Name, label.Value))
+	}
+	return process
+}
+
+func findLabel(labels []Label, name string) (string, bool) {
+	for _, label := range labels {
+		if label.Name == name {
+			return label.Value, true
+		}
+	}
+	return "", false
+}
+
+func stringAttribute(key, value string) model.KeyValue {
+	return model.KeyValue{
+		Key:   key,
+		Value: &common.AnyValue{Value: &common.AnyValue_StringValue{StringValue: value}},
+	}
+}
@@ -0,0 +1,100 @@
+package proto
+
+import (
+	"slices"
+
+	"logger/model"
+	common "logger/model/proto/common/v1"
+	pbL "logger/model/proto/logs/v1"
+	resource "logger/model/proto/resource/v1"
+
+	"google.golang.org/protobuf/proto"
+)
+
+const serviceNameAttribute = "service.name"
+
+// FromDomainLog converts a stored log record back to its OTLP representation.
+func FromDomainLog(log *model.LogRecord) *pbL.LogRecord {
+	return fromDomain{}.fromDomainLog(log)
+}
+
+// FromDomainLogs converts log records to OTLP, grouping consecutive records of the
+// same process under one ResourceLogs.
+func FromDomainLogs(logs []*model.LogRecord) []*pbL.ResourceLogs {
+	return fromDomain{}.fromDomainLogs(logs)
+}
+
+// FromDomainProcess converts a process to the OTLP resource it was read from,
+// restoring the service.name attribute.
+func FromDomainProcess(process *model.Process) *resource.Resource {
+	return fromDomain{}.fromDomainProcess(process)
+}
+
+type fromDomain struct{}
+
+func (f fromDomain) fromDomainLogs(logs []*model.LogRecord) []*pbL.ResourceLogs {
+	var res []*pbL.ResourceLogs
+	var current *pbL.ResourceLogs
+	for _, log := range logs {
+		r := f.fromDomainProcess(log.Process)
+		if current == nil || !proto.Equal(current.Resource, r) {
+			current = &pbL.ResourceLogs{
+				Resource:  r,
+				ScopeLogs: []*pbL.ScopeLogs{{}},
+			}
+			res = append(res, current)
+		}
+		current.ScopeLogs[0].LogRecords = append(current.ScopeLogs[0].LogRecords, f.fromDomainLog(log))
+	}
+	return res
+}
+
+func (f fromDomain) fromDomainLog(log *model.LogRecord) *pbL.LogRecord {
+	attributes := f.fromDomainAttributes(log.Attributes)
+	if !log.ID.IsZero() {
+		// the ID assigned by the collector replaces any log.record.uid sent by the client
+		attributes = slices.DeleteFunc(attributes, func(kv *common.KeyValue) bool {
+			return kv.Key == model.LogIDAttribute
+		})
+		attributes = append(attributes, &common.KeyValue{
+			Key:   model.LogIDAttribute,
+			Value: &common.AnyValue{Value: &common.AnyValue_StringValue{StringValue: log.ID.String()}},
+		})
+	}
+	return &pbL.LogRecord{
+		TimeUnixNano:           log.TimeUnixNano,
+		ObservedTimeUnixNano:   log.ObservedTimeUnixNano,
+		SeverityNumber:         log.SeverityNumber,
+		SeverityText:           log.SeverityText,
+		Body:                   &common.AnyValue{Value: &common.AnyValue_StringValue{StringValue: log.Body}},
+		Attributes:             attributes,
+		DroppedAttributesCount: log.DroppedAttributesCount,
+		Flags:                  log.Flags,
+		TraceId:                log.TraceId,
+		SpanId:                 log.SpanId,
+	}
+}
+
+func (f fromDomain) fromDomainProcess(process *model.Process) *resource.Resource {
+	if process == nil {
+		return &resource.Resource{}
+	}
+	attributes := make([]*common.KeyValue, 0, len(process.Attributes)+1)
+	attributes = append(attributes, &common.KeyValue{
+		Key:   serviceNameAttribute,
+		Value: &common.AnyValue{Value: &common.AnyValue_StringValue{StringValue: process.ServiceName}},
+	})
+	attributes = append(attributes, f.fromDomainAttributes(process.Attributes)...)
+	return &resource.Resource{Attributes: attributes}
+}
+
+func (f fromDomain) fromDomainAttributes(attributes []model.KeyValue) []*common.KeyValue {
+	res := make([]*common.KeyValue, len(attributes))
+	for i, v := range attributes {
+		res[i] = &common.KeyValue{
+			Key:   v.Key,
+			Value: v.Value,
+		}
+	}
+	return res
+}
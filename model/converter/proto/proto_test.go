@@ -0,0 +1,42 @@
+package proto
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"logger/model"
+	common "logger/model/proto/common/v1"
+	pbL "logger/model/proto/logs/v1"
+)
+
+func stringValue(s string) *common.AnyValue {
+	return &common.AnyValue{Value: &common.AnyValue_StringValue{StringValue: s}}
+}
+
+func TestLogIDAttribute(t *testing.T) {
+	sent := model.NewLogID(time.Now()).String()
+	log := ToDomainLog(&pbL.LogRecord{
+		Body: stringValue("hello"),
+		Attributes: []*common.KeyValue{
+			{Key: model.LogIDAttribute, Value: stringValue(sent)},
+			{Key: "user", Value: stringValue("alice")},
+		},
+	}, &model.Process{ServiceName: "web"})
+
+	// the ID sent by the client is kept as a plain attribute, never as the ID of the record
+	assert.True(t, log.ID.IsZero())
+	require.Len(t, log.Attributes, 2)
+	assert.Equal(t, model.LogIDAttribute, log.Attributes[0].Key)
+	assert.Equal(t, sent, log.Attributes[0].Value.GetStringValue())
+	assert.Equal(t, "user", log.Attributes[1].Key)
+
+	log.ID = model.NewLogID(time.Now())
+	attributes := FromDomainLog(log).Attributes
+	require.Len(t, attributes, 2)
+	assert.Equal(t, "user", attributes[0].Key)
+	assert.Equal(t, model.LogIDAttribute, attributes[1].Key)
+	assert.Equal(t, log.ID.String(), attributes[1].Value.GetStringValue())
+}
@@ -45,4 +45,4 @@ func (t toDomain)toDomainAtrributes(attributes []*common.KeyValue)[]model.KeyVal
 		}	
 	}
 	return res
-}
\ No newline at end of file
+}
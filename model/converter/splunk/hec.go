@@ -0,0 +1,197 @@
+package splunk
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrInvalidEvent is returned for data that is not a HEC event.
+	ErrInvalidEvent = errors.New("invalid data format")
+	// ErrMissingEvent is returned for an event without an event field.
+	ErrMissingEvent = errors.New("event field is required")
+	// ErrBlankEvent is returned for an event whose event field is empty.
+	ErrBlankEvent = errors.New("event field cannot be blank")
+)
+
+// EventError is the error of the event at Index in a request.
+type EventError struct {
+	Index int
+	Err   error
+}
+
+func (e *EventError) Error() string {
+	return fmt.Sprintf("event %d: %v", e.Index, e.Err)
+}
+
+func (e *EventError) Unwrap() error {
+	return e.Err
+}
+
+// Metadata are the fields describing where an event comes from. For the raw endpoint,
+// they are set by the query string of the request.
+type Metadata struct {
+	Host       string
+	Source     string
+	SourceType string
+	Index      string
+}
+
+// Event is an event of the HEC event or raw endpoint.
+type Event struct {
+	Metadata
+	// Time is the time of the event, zero when the sender did not set it
+	Time time.Time
+	// Event is the log line of the event, the JSON of the event field when it is not a string
+	Event string
+	// Fields are the indexed fields of the event, by order of their names
+	Fields []Field
+}
+
+// Field is an indexed field of an event. Its value is a string, a json.Number, a bool or
+// a []interface{} of these.
+type Field struct {
+	Name  string
+	Value interface{}
+}
+
+// jsonEvent is an event as sent to the event endpoint.
+type jsonEvent struct {
+	Time       json.Number                `json:"time"`
+	Host       string                     `json:"host"`
+	Source     string                     `json:"source"`
+	SourceType string                     `json:"sourcetype"`
+	Index      string                     `json:"index"`
+	Event      json.RawMessage            `json:"event"`
+	Fields     map[string]json.RawMessage `json:"fields"`
+}
+
+// ParseEvents reads the JSON events of a request to the event endpoint, which are
+// concatenated, with or without whitespace between them. The query string metadata of
+// defaults apply to the events that do not set theirs.
+func ParseEvents(data []byte, defaults Metadata) ([]Event, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var events []Event
+	for i := 0; ; i++ {
+		var je jsonEvent
+		err := dec.Decode(&je)
+		if err == io.EOF {
+			return events, nil
+		}
+		if err != nil {
+			return nil, &EventError{Index: i, Err: fmt.Errorf("%w: %v", ErrInvalidEvent, err)}
+		}
+		event, err := toEvent(&je, defaults)
+		if err != nil {
+			return nil, &EventError{Index: i, Err: err}
+		}
+		events = append(events, event)
+	}
+}
+
+func toEvent(je *jsonEvent, defaults Metadata) (Event, error) {
+	event := Event{Metadata: Metadata{
+		Host:       firstNonEmpty(je.Host, defaults.Host),
+		Source:     firstNonEmpty(je.Source, defaults.Source),
+		SourceType: firstNonEmpty(je.SourceType, defaults.SourceType),
+		Index:      firstNonEmpty(je.Index, defaults.Index),
+	}}
+	if je.Time != "" {
+		seconds, err := je.Time.Float64()
+		if err != nil {
+			return event, fmt.Errorf("%w: time: %v", ErrInvalidEvent, err)
+		}
+		sec, frac := math.Modf(seconds)
+		// rounding to the microsecond drops the float noise of the decimal part
+		event.Time = time.Unix(int64(sec), int64(math.Round(frac*1e6))*1e3)
+	}
+
+	if len(je.Event) == 0 || string(je.Event) == "null" {
+		return event, ErrMissingEvent
+	}
+	var line string
+	if err := json.Unmarshal(je.Event, &line); err == nil {
+		event.Event = line
+	} else {
+		var compact bytes.Buffer
+		if err := json.Compact(&compact, je.Event); err != nil {
+			return event, fmt.Errorf("%w: event: %v", ErrInvalidEvent, err)
+		}
+		event.Event = compact.String()
+	}
+	if strings.TrimSpace(event.Event) == "" {
+		return event, ErrBlankEvent
+	}
+
+	for _, name := range sortedKeys(je.Fields) {
+		value, err := fieldValue(je.Fields[name])
+		if err != nil {
+			return event, fmt.Errorf("%w: field %s: %v", ErrInvalidEvent, name, err)
+		}
+		event.Fields = append(event.Fields, Field{Name: name, Value: value})
+	}
+	return event, nil
+}
+
+// fieldValue reads an indexed field, which is a scalar or an array of scalars.
+func fieldValue(data json.RawMessage) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	switch v := v.(type) {
+	case string, json.Number, bool:
+		return v, nil
+	case []interface{}:
+		for _, item := range v {
+			switch item.(type) {
+			case string, json.Number, bool:
+			default:
+				return nil, errors.New("not an array of scalars")
+			}
+		}
+		return v, nil
+	}
+	return nil, errors.New("not a scalar or an array")
+}
+
+// ParseRaw reads the events of a request to the raw endpoint, one per line.
+func ParseRaw(data []byte, metadata Metadata) []Event {
+	var events []Event
+	for _, line := range bytes.Split(data, []byte{'\n'}) {
+		line = bytes.TrimRight(line, "\r")
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		events = append(events, Event{Metadata: metadata, Event: string(line)})
+	}
+	return events
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func sortedKeys(m map[string]json.RawMessage) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,110 @@
+package splunk
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"logger/model"
+	common "logger/model/proto/common/v1"
+)
+
+var now = time.Date(2024, time.March, 10, 12, 0, 0, 0, time.UTC)
+
+func TestParseEvents(t *testing.T) {
+	events, err := ParseEvents([]byte(`{"time":1710072000.123,"host":"web-1","source":"/var/log/app.log","event":"hello",
+		"fields":{"region":"eu","codes":["a",1],"ok":true}}{"time":"1710072001","event":{"message":"world", "n": 1}}
+		{"event":"defaults","sourcetype":"json"}`), Metadata{SourceType: "syslog", Index: "main"})
+	require.NoError(t, err)
+	assert.Equal(t, []Event{
+		{
+			Metadata: Metadata{Host: "web-1", Source: "/var/log/app.log", SourceType: "syslog", Index: "main"},
+			Time:     now.Add(123 * time.Millisecond).Local(),
+			Event:    "hello",
+			Fields: []Field{
+				{"codes", []interface{}{"a", json.Number("1")}},
+				{"ok", true},
+				{"region", "eu"},
+			},
+		},
+		{
+			Metadata: Metadata{SourceType: "syslog", Index: "main"},
+			Time:     now.Add(time.Second).Local(),
+			Event:    `{"message":"world","n":1}`,
+		},
+		{
+			Metadata: Metadata{SourceType: "json", Index: "main"},
+			Event:    "defaults",
+		},
+	}, events)
+}
+
+func TestParseEventsErrors(t *testing.T) {
+	tests := []struct {
+		data  string
+		index int
+		err   error
+	}{
+		{`{"event":"a"} not json`, 1, ErrInvalidEvent},
+		{`{"event":"a"}{"time":"now","event":"b"}`, 1, ErrInvalidEvent},
+		{`{"host":"web-1"}`, 0, ErrMissingEvent},
+		{`{"event":null}`, 0, ErrMissingEvent},
+		{`{"event":"  "}`, 0, ErrBlankEvent},
+		{`{"event":"a","fields":{"nested":{"a":1}}}`, 0, ErrInvalidEvent},
+		{`{"event":"a","fields":{"nested":[[1]]}}`, 0, ErrInvalidEvent},
+		{`[]`, 0, ErrInvalidEvent},
+	}
+	for _, test := range tests {
+		_, err := ParseEvents([]byte(test.data), Metadata{})
+		assert.ErrorIs(t, err, test.err, test.data)
+		var eventErr *EventError
+		require.ErrorAs(t, err, &eventErr, test.data)
+		assert.Equal(t, test.index, eventErr.Index, test.data)
+	}
+
+	events, err := ParseEvents([]byte(" \n"), Metadata{})
+	require.NoError(t, err)
+	assert.Empty(t, events)
+}
+
+func TestParseRaw(t *testing.T) {
+	metadata := Metadata{Host: "web-1", Source: "app"}
+	assert.Equal(t, []Event{
+		{Metadata: metadata, Event: "first line"},
+		{Metadata: metadata, Event: "  second line"},
+	}, ParseRaw([]byte("first line\r\n\n  second line\n \n"), metadata))
+}
+
+func TestToDomainLog(t *testing.T) {
+	e := &Event{
+		Metadata: Metadata{Host: "web-1", Source: "/var/log/app.log", SourceType: "app", Index: "main"},
+		Time:     now,
+		Event:    "hello",
+		Fields:   []Field{{"region", "eu"}, {"count", json.Number("2")}},
+	}
+	received := now.Add(time.Minute)
+	assert.Equal(t, &model.LogRecord{
+		TimeUnixNano:         uint64(now.UnixNano()),
+		ObservedTimeUnixNano: uint64(received.UnixNano()),
+		Body:                 "hello",
+		Attributes: []model.KeyValue{
+			{Key: "com.splunk.source", Value: toAnyValue("/var/log/app.log")},
+			{Key: "com.splunk.sourcetype", Value: toAnyValue("app")},
+			{Key: "com.splunk.index", Value: toAnyValue("main")},
+			{Key: "region", Value: toAnyValue("eu")},
+			{Key: "count", Value: &common.AnyValue{Value: &common.AnyValue_IntValue{IntValue: 2}}},
+		},
+		Process: &model.Process{
+			ServiceName: "checkout",
+			Attributes:  []model.KeyValue{{Key: "host.name", Value: toAnyValue("web-1")}},
+		},
+	}, ToDomainLog(e, "checkout", received))
+
+	record := ToDomainLog(&Event{Event: "hello"}, "", received)
+	assert.Equal(t, uint64(received.UnixNano()), record.TimeUnixNano)
+	assert.Empty(t, record.Attributes)
+	assert.Empty(t, record.Process.Attributes)
+}
@@ -0,0 +1,75 @@
+package splunk
+
+import (
+	"encoding/json"
+	"time"
+
+	"logger/model"
+	common "logger/model/proto/common/v1"
+)
+
+const (
+	hostNameAttribute   = "host.name"
+	sourceAttribute     = "com.splunk.source"
+	sourceTypeAttribute = "com.splunk.sourcetype"
+	indexAttribute      = "com.splunk.index"
+)
+
+// ToDomainLog converts an event received at the given time to a log record of the given
+// service, which HEC does not carry but the token of the request names. The host goes to
+// the process, the source, sourcetype, index and indexed fields to the attributes.
+func ToDomainLog(e *Event, service string, received time.Time) *model.LogRecord {
+	ts := e.Time
+	if ts.IsZero() {
+		ts = received
+	}
+	var attributes []model.KeyValue
+	for _, metadata := range []struct{ key, value string }{
+		{sourceAttribute, e.Source},
+		{sourceTypeAttribute, e.SourceType},
+		{indexAttribute, e.Index},
+	} {
+		if metadata.value != "" {
+			attributes = append(attributes, model.KeyValue{Key: metadata.key, Value: toAnyValue(metadata.value)})
+		}
+	}
+	for _, field := range e.Fields {
+		attributes = append(attributes, model.KeyValue{Key: field.Name, Value: toAnyValue(field.Value)})
+	}
+
+	process := &model.Process{ServiceName: service, Attributes: []model.KeyValue{}}
+	if e.Host != "" {
+		process.Attributes = append(process.Attributes, model.KeyValue{Key: hostNameAttribute, Value: toAnyValue(e.Host)})
+	}
+	return &model.LogRecord{
+		TimeUnixNano:         uint64(ts.UnixNano()),
+		ObservedTimeUnixNano: uint64(received.UnixNano()),
+		Body:                 e.Event,
+		Attributes:           attributes,
+		Process:              process,
+	}
+}
+
+func toAnyValue(v interface{}) *common.AnyValue {
+	switch v := v.(type) {
+	case string:
+		return &common.AnyValue{Value: &common.AnyValue_StringValue{StringValue: v}}
+	case bool:
+		return &common.AnyValue{Value: &common.AnyValue_BoolValue{BoolValue: v}}
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return &common.AnyValue{Value: &common.AnyValue_IntValue{IntValue: i}}
+		}
+		if f, err := v.Float64(); err == nil {
+			return &common.AnyValue{Value: &common.AnyValue_DoubleValue{DoubleValue: f}}
+		}
+		return &common.AnyValue{Value: &common.AnyValue_StringValue{StringValue: v.String()}}
+	case []interface{}:
+		values := make([]*common.AnyValue, len(v))
+		for i, value := range v {
+			values[i] = toAnyValue(value)
+		}
+		return &common.AnyValue{Value: &common.AnyValue_ArrayValue{ArrayValue: &common.ArrayValue{Values: values}}}
+	}
+	return &common.AnyValue{}
+}
@@ -0,0 +1,69 @@
+package syslog
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// maxFrameLengthDigits bounds the MSG-LEN of an octet-counted frame, see RFC 6587
+const maxFrameLengthDigits = 10
+
+// NewScanner returns a scanner splitting a syslog stream into messages. Each message is
+// framed either by octet counting or by a trailing LF, as told by its first byte (RFC 6587).
+// Messages larger than maxSize fail the scan with bufio.ErrTooLong.
+func NewScanner(r io.Reader, maxSize int) *bufio.Scanner {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, min(maxSize, bufio.MaxScanTokenSize)), maxSize+maxFrameLengthDigits+1)
+	scanner.Split(splitFrames(maxSize))
+	return scanner
+}
+
+func splitFrames(maxSize int) bufio.SplitFunc {
+	return func(data []byte, atEOF bool) (int, []byte, error) {
+		if len(data) == 0 {
+			return 0, nil, nil
+		}
+		if data[0] >= '1' && data[0] <= '9' {
+			return splitOctetCounted(data, atEOF, maxSize)
+		}
+		if i := bytes.IndexByte(data, '\n'); i >= 0 {
+			return i + 1, bytes.TrimRight(data[:i], "\r"), nil
+		}
+		if atEOF {
+			return len(data), data, nil
+		}
+		return 0, nil, nil
+	}
+}
+
+// splitOctetCounted reads a "MSG-LEN SP SYSLOG-MSG" frame.
+func splitOctetCounted(data []byte, atEOF bool, maxSize int) (int, []byte, error) {
+	sp := bytes.IndexByte(data, ' ')
+	if sp < 0 {
+		if len(data) > maxFrameLengthDigits {
+			return 0, nil, fmt.Errorf("%w: malformed frame length", ErrInvalidMessage)
+		}
+		if atEOF {
+			return 0, nil, io.ErrUnexpectedEOF
+		}
+		return 0, nil, nil
+	}
+	length, err := strconv.Atoi(string(data[:sp]))
+	if err != nil || sp > maxFrameLengthDigits {
+		return 0, nil, fmt.Errorf("%w: malformed frame length %q", ErrInvalidMessage, data[:sp])
+	}
+	if length > maxSize {
+		return 0, nil, bufio.ErrTooLong
+	}
+	end := sp + 1 + length
+	if len(data) < end {
+		if atEOF {
+			return 0, nil, io.ErrUnexpectedEOF
+		}
+		return 0, nil, nil
+	}
+	return end, data[sp+1 : end], nil
+}
@@ -0,0 +1,298 @@
+package syslog
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// nilValue stands for an absent field in RFC 5424
+	nilValue = "-"
+	// maxPriority is the priority of the local7 facility at debug severity
+	maxPriority = 191
+	// maxTagLength is the maximum length of an RFC 3164 TAG
+	maxTagLength = 32
+	// bsdTimestampLayout is the RFC 3164 timestamp, without a year
+	bsdTimestampLayout = "Jan _2 15:04:05"
+)
+
+// utf8BOM may start the MSG of an RFC 5424 message
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// ErrInvalidMessage is returned for data that is not a syslog message.
+var ErrInvalidMessage = errors.New("invalid syslog message")
+
+// Message is a syslog message, as read from RFC 5424 or RFC 3164.
+type Message struct {
+	Facility int
+	Severity int
+	// Version is the RFC 5424 protocol version, zero for RFC 3164 messages
+	Version int
+	// Timestamp is zero when the message has none
+	Timestamp time.Time
+	Hostname  string
+	AppName   string
+	ProcID    string
+	MsgID     string
+	// StructuredData holds the SD-ELEMENTs of an RFC 5424 message, in order
+	StructuredData []SDElement
+	Message        string
+}
+
+// SDElement is an RFC 5424 structured data element.
+type SDElement struct {
+	ID     string
+	Params []SDParam
+}
+
+// SDParam is a parameter of a structured data element.
+type SDParam struct {
+	Name  string
+	Value string
+}
+
+// Parse reads an RFC 5424 or RFC 3164 message. now is the reception time, RFC 3164
+// timestamps take its year and location since they carry neither.
+func Parse(data []byte, now time.Time) (*Message, error) {
+	data = bytes.TrimRight(data, "\r\n")
+	pri, rest, err := parsePriority(data)
+	if err != nil {
+		return nil, err
+	}
+	m := &Message{
+		Facility: pri / 8,
+		Severity: pri % 8,
+	}
+	if version, after, ok := parseVersion(rest); ok {
+		m.Version = version
+		if err := parseRFC5424(m, after); err != nil {
+			return nil, err
+		}
+		return m, nil
+	}
+	parseRFC3164(m, rest, now)
+	return m, nil
+}
+
+func parsePriority(data []byte) (int, []byte, error) {
+	if len(data) == 0 || data[0] != '<' {
+		return 0, nil, fmt.Errorf("%w: missing priority", ErrInvalidMessage)
+	}
+	end := bytes.IndexByte(data, '>')
+	if end < 2 || end > 4 {
+		return 0, nil, fmt.Errorf("%w: malformed priority", ErrInvalidMessage)
+	}
+	pri, err := strconv.Atoi(string(data[1:end]))
+	if err != nil || pri < 0 || pri > maxPriority {
+		return 0, nil, fmt.Errorf("%w: priority %q out of range", ErrInvalidMessage, data[1:end])
+	}
+	return pri, data[end+1:], nil
+}
+
+// parseVersion reads the VERSION of an RFC 5424 header, which RFC 3164 messages lack.
+func parseVersion(data []byte) (int, []byte, bool) {
+	i := 0
+	for i < len(data) && i < 3 && data[i] >= '0' && data[i] <= '9' {
+		i++
+	}
+	if i == 0 || i >= len(data) || data[i] != ' ' || data[0] == '0' {
+		return 0, nil, false
+	}
+	version, _ := strconv.Atoi(string(data[:i]))
+	return version, data[i+1:], true
+}
+
+func parseRFC5424(m *Message, data []byte) error {
+	fields := make([]string, 5)
+	for i := range fields {
+		var field []byte
+		field, data = nextField(data)
+		if len(field) == 0 {
+			return fmt.Errorf("%w: truncated header", ErrInvalidMessage)
+		}
+		fields[i] = string(field)
+	}
+	if fields[0] != nilValue {
+		ts, err := time.Parse(time.RFC3339Nano, fields[0])
+		if err != nil {
+			return fmt.Errorf("%w: timestamp %q: %v", ErrInvalidMessage, fields[0], err)
+		}
+		m.Timestamp = ts
+	}
+	m.Hostname = nilToEmpty(fields[1])
+	m.AppName = nilToEmpty(fields[2])
+	m.ProcID = nilToEmpty(fields[3])
+	m.MsgID = nilToEmpty(fields[4])
+
+	sd, rest, err := parseStructuredData(data)
+	if err != nil {
+		return err
+	}
+	m.StructuredData = sd
+	if len(rest) > 0 {
+		if rest[0] != ' ' {
+			return fmt.Errorf("%w: missing space before message", ErrInvalidMessage)
+		}
+		m.Message = string(bytes.TrimPrefix(rest[1:], utf8BOM))
+	}
+	return nil
+}
+
+// nextField returns the field of data up to the next space, and what follows that space.
+func nextField(data []byte) ([]byte, []byte) {
+	if i := bytes.IndexByte(data, ' '); i >= 0 {
+		return data[:i], data[i+1:]
+	}
+	return data, nil
+}
+
+func nilToEmpty(field string) string {
+	if field == nilValue {
+		return ""
+	}
+	return field
+}
+
+func parseStructuredData(data []byte) ([]SDElement, []byte, error) {
+	if len(data) == 0 {
+		return nil, nil, fmt.Errorf("%w: missing structured data", ErrInvalidMessage)
+	}
+	if data[0] == '-' {
+		return nil, data[1:], nil
+	}
+	var elements []SDElement
+	for len(data) > 0 && data[0] == '[' {
+		var element SDElement
+		var err error
+		element, data, err = parseSDElement(data[1:])
+		if err != nil {
+			return nil, nil, err
+		}
+		elements = append(elements, element)
+	}
+	if elements == nil {
+		return nil, nil, fmt.Errorf("%w: malformed structured data", ErrInvalidMessage)
+	}
+	return elements, data, nil
+}
+
+// parseSDElement reads an SD-ELEMENT after its opening bracket.
+func parseSDElement(data []byte) (SDElement, []byte, error) {
+	var element SDElement
+	end := bytes.IndexAny(data, " ]")
+	if end <= 0 {
+		return element, nil, fmt.Errorf("%w: malformed structured data element", ErrInvalidMessage)
+	}
+	element.ID = string(data[:end])
+	data = data[end:]
+	for len(data) > 0 && data[0] == ' ' {
+		data = data[1:]
+		eq := bytes.IndexByte(data, '=')
+		if eq <= 0 || eq+1 >= len(data) || data[eq+1] != '"' {
+			return element, nil, fmt.Errorf("%w: malformed parameter in %s", ErrInvalidMessage, element.ID)
+		}
+		name := string(data[:eq])
+		value, rest, err := parseParamValue(data[eq+2:])
+		if err != nil {
+			return element, nil, fmt.Errorf("%w in %s", err, element.ID)
+		}
+		element.Params = append(element.Params, SDParam{Name: name, Value: value})
+		data = rest
+	}
+	if len(data) == 0 || data[0] != ']' {
+		return element, nil, fmt.Errorf("%w: unterminated structured data element %s", ErrInvalidMessage, element.ID)
+	}
+	return element, data[1:], nil
+}
+
+// parseParamValue reads a PARAM-VALUE after its opening quote, unescaping '"', '\' and ']'.
+func parseParamValue(data []byte) (string, []byte, error) {
+	var value strings.Builder
+	for i := 0; i < len(data); i++ {
+		switch c := data[i]; c {
+		case '"':
+			return value.String(), data[i+1:], nil
+		case '\\':
+			if i+1 < len(data) && (data[i+1] == '"' || data[i+1] == '\\' || data[i+1] == ']') {
+				i++
+				c = data[i]
+			}
+			value.WriteByte(c)
+		default:
+			value.WriteByte(c)
+		}
+	}
+	return "", nil, fmt.Errorf("%w: unterminated parameter value", ErrInvalidMessage)
+}
+
+// parseRFC3164 reads the HEADER and MSG of a BSD syslog message. As the format is loose,
+// a message without a valid timestamp is read as a bare MSG, per RFC 3164 section 4.3.2.
+func parseRFC3164(m *Message, data []byte, now time.Time) {
+	ts, rest, ok := parseBSDTimestamp(data, now)
+	if !ok {
+		m.Message = string(data)
+		return
+	}
+	m.Timestamp = ts
+	host, content := nextField(rest)
+	if isTag(host) {
+		// the sender left out the hostname
+		content = rest
+	} else {
+		m.Hostname = string(host)
+	}
+	m.AppName, m.ProcID, m.Message = parseTag(content)
+}
+
+// parseBSDTimestamp reads an RFC 3164 timestamp, or an RFC 3339 one as sent by some daemons.
+func parseBSDTimestamp(data []byte, now time.Time) (time.Time, []byte, bool) {
+	if len(data) > len(bsdTimestampLayout) && data[len(bsdTimestampLayout)] == ' ' {
+		ts, err := time.ParseInLocation(bsdTimestampLayout, string(data[:len(bsdTimestampLayout)]), now.Location())
+		if err == nil {
+			ts = time.Date(now.Year(), ts.Month(), ts.Day(), ts.Hour(), ts.Minute(), ts.Second(), 0, now.Location())
+			// a message from late December received in January belongs to the previous year
+			if ts.After(now.Add(24 * time.Hour)) {
+				ts = ts.AddDate(-1, 0, 0)
+			}
+			return ts, data[len(bsdTimestampLayout)+1:], true
+		}
+	}
+	field, rest := nextField(data)
+	if ts, err := time.Parse(time.RFC3339Nano, string(field)); err == nil {
+		return ts, rest, true
+	}
+	return time.Time{}, nil, false
+}
+
+// isTag tells whether a HEADER field is a TAG, terminated by ':' or a '[pid]'.
+func isTag(field []byte) bool {
+	return bytes.HasSuffix(field, []byte(":")) || bytes.IndexByte(field, '[') > 0
+}
+
+// parseTag splits the CONTENT of an RFC 3164 message into its TAG, PID and the message
+// that follows. The content is left whole when it does not start with a TAG.
+func parseTag(content []byte) (string, string, string) {
+	end := bytes.IndexAny(content, "[: ")
+	if end <= 0 || end > maxTagLength {
+		return "", "", string(content)
+	}
+	tag := string(content[:end])
+	rest := content[end:]
+	var pid string
+	if rest[0] == '[' {
+		pidEnd := bytes.IndexByte(rest, ']')
+		if pidEnd < 0 {
+			return "", "", string(content)
+		}
+		pid = string(rest[1:pidEnd])
+		rest = rest[pidEnd+1:]
+	}
+	if len(rest) == 0 || rest[0] != ':' {
+		return "", "", string(content)
+	}
+	return tag, pid, string(bytes.TrimPrefix(rest[1:], []byte(" ")))
+}
@@ -0,0 +1,154 @@
+package syslog
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var now = time.Date(2024, time.March, 10, 12, 0, 0, 0, time.UTC)
+
+func TestParseRFC5424(t *testing.T) {
+	m, err := Parse([]byte(`<165>1 2003-10-11T22:14:15.003Z mymachine.example.com evntslog 1234 ID47 [exampleSDID@32473 iut="3" eventSource="App\"lication\]"][meta seq="1"] `+"\xEF\xBB\xBF"+`An application event`+"\n"), now)
+	require.NoError(t, err)
+	assert.Equal(t, &Message{
+		Facility:  20,
+		Severity:  5,
+		Version:   1,
+		Timestamp: time.Date(2003, time.October, 11, 22, 14, 15, 3000000, time.UTC),
+		Hostname:  "mymachine.example.com",
+		AppName:   "evntslog",
+		ProcID:    "1234",
+		MsgID:     "ID47",
+		StructuredData: []SDElement{
+			{ID: "exampleSDID@32473", Params: []SDParam{{"iut", "3"}, {"eventSource", `App"lication]`}}},
+			{ID: "meta", Params: []SDParam{{"seq", "1"}}},
+		},
+		Message: "An application event",
+	}, m)
+}
+
+func TestParseRFC5424NilValues(t *testing.T) {
+	m, err := Parse([]byte(`<34>1 - - - - - -`), now)
+	require.NoError(t, err)
+	assert.Equal(t, &Message{Facility: 4, Severity: 2, Version: 1}, m)
+}
+
+func TestParseRFC3164(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		expected *Message
+	}{
+		{
+			name: "full header",
+			data: `<34>Oct 11 22:14:15 mymachine su[230]: 'su root' failed for lonvick`,
+			expected: &Message{
+				Facility: 4, Severity: 2,
+				Timestamp: time.Date(2023, time.October, 11, 22, 14, 15, 0, time.UTC),
+				Hostname:  "mymachine", AppName: "su", ProcID: "230",
+				Message: "'su root' failed for lonvick",
+			},
+		},
+		{
+			name: "no hostname",
+			data: `<13>Mar  9 08:01:02 cron: job done`,
+			expected: &Message{
+				Facility: 1, Severity: 5,
+				Timestamp: time.Date(2024, time.March, 9, 8, 1, 2, 0, time.UTC),
+				AppName:   "cron",
+				Message:   "job done",
+			},
+		},
+		{
+			name: "no tag",
+			data: `<13>Mar  9 08:01:02 router link down on eth0`,
+			expected: &Message{
+				Facility: 1, Severity: 5,
+				Timestamp: time.Date(2024, time.March, 9, 8, 1, 2, 0, time.UTC),
+				Hostname:  "router",
+				Message:   "link down on eth0",
+			},
+		},
+		{
+			name: "RFC 3339 timestamp",
+			data: `<30>2024-03-09T08:01:02+01:00 host sshd[42]: accepted`,
+			expected: &Message{
+				Facility: 3, Severity: 6,
+				Timestamp: time.Date(2024, time.March, 9, 8, 1, 2, 0, time.FixedZone("", 3600)),
+				Hostname:  "host", AppName: "sshd", ProcID: "42",
+				Message: "accepted",
+			},
+		},
+		{
+			name:     "no timestamp",
+			data:     `<14>just a message`,
+			expected: &Message{Facility: 1, Severity: 6, Message: "just a message"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			m, err := Parse([]byte(test.data), now)
+			require.NoError(t, err)
+			assert.True(t, test.expected.Timestamp.Equal(m.Timestamp), "timestamp %v", m.Timestamp)
+			test.expected.Timestamp = m.Timestamp
+			assert.Equal(t, test.expected, m)
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, data := range []string{
+		``,
+		`no priority`,
+		`<192>1 - - - - - -`,
+		`<1a>msg`,
+		`<34>1 - host`,
+		`<34>1 yesterday - - - - -`,
+		`<34>1 - - - - - [id`,
+		`<34>1 - - - - - [id a=b]`,
+		`<34>1 - - - - - [id a="b]`,
+		`<34>1 - - - - - x`,
+		`<34>1 - - - - - -msg`,
+	} {
+		_, err := Parse([]byte(data), now)
+		assert.ErrorIs(t, err, ErrInvalidMessage, data)
+	}
+}
+
+func TestScanner(t *testing.T) {
+	stream := "<13>1 - - - - - - one\n" +
+		"22 <13>1 - - - - - - two\n" +
+		"<13>1 - - - - - - three\r\n" +
+		"<13>1 - - - - - - four"
+	scanner := NewScanner(strings.NewReader(stream), 1024)
+	var messages []string
+	for scanner.Scan() {
+		messages = append(messages, scanner.Text())
+	}
+	require.NoError(t, scanner.Err())
+	assert.Equal(t, []string{
+		"<13>1 - - - - - - one",
+		"<13>1 - - - - - - two\n",
+		"<13>1 - - - - - - three",
+		"<13>1 - - - - - - four",
+	}, messages)
+}
+
+func TestScannerErrors(t *testing.T) {
+	scanner := NewScanner(strings.NewReader("2000 <13>1 - - - - - - msg"), 1024)
+	assert.False(t, scanner.Scan())
+	assert.ErrorIs(t, scanner.Err(), bufio.ErrTooLong)
+
+	scanner = NewScanner(strings.NewReader("30 <13>1 - - -"), 1024)
+	assert.False(t, scanner.Scan())
+	assert.Error(t, scanner.Err())
+
+	scanner = NewScanner(strings.NewReader("12345678901234 <13>"), 1024)
+	assert.False(t, scanner.Scan())
+	assert.ErrorIs(t, scanner.Err(), ErrInvalidMessage)
+}
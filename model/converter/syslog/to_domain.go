@@ -0,0 +1,117 @@
+package syslog
+
+import (
+	"strconv"
+	"time"
+
+	"logger/model"
+	common "logger/model/proto/common/v1"
+	pbL "logger/model/proto/logs/v1"
+)
+
+const (
+	hostNameAttribute   = "host.name"
+	processPIDAttribute = "process.pid"
+	facilityAttribute   = "syslog.facility"
+	versionAttribute    = "syslog.version"
+	msgIDAttribute      = "syslog.msgid"
+	// structuredDataPrefix prefixes the attributes read from structured data, as <prefix><SD-ID>.<PARAM-NAME>
+	structuredDataPrefix = "syslog.sd."
+)
+
+// facilities are the keywords of the syslog facilities, by code
+var facilities = []string{
+	"kern", "user", "mail", "daemon", "auth", "syslog", "lpr", "news",
+	"uucp", "cron", "authpriv", "ftp", "ntp", "security", "console", "solaris-cron",
+	"local0", "local1", "local2", "local3", "local4", "local5", "local6", "local7",
+}
+
+// severities are the keywords and OpenTelemetry severity numbers of the syslog severities, by code
+var severities = []struct {
+	text   string
+	number pbL.SeverityNumber
+}{
+	{"emerg", pbL.SeverityNumber_SEVERITY_NUMBER_FATAL4},
+	{"alert", pbL.SeverityNumber_SEVERITY_NUMBER_FATAL3},
+	{"crit", pbL.SeverityNumber_SEVERITY_NUMBER_FATAL},
+	{"err", pbL.SeverityNumber_SEVERITY_NUMBER_ERROR},
+	{"warning", pbL.SeverityNumber_SEVERITY_NUMBER_WARN},
+	{"notice", pbL.SeverityNumber_SEVERITY_NUMBER_INFO2},
+	{"info", pbL.SeverityNumber_SEVERITY_NUMBER_INFO},
+	{"debug", pbL.SeverityNumber_SEVERITY_NUMBER_DEBUG},
+}
+
+// ToDomainLog converts a syslog message received at the given time to a log record. The
+// app-name becomes the service of the process, the hostname and procid its attributes.
+func ToDomainLog(m *Message, received time.Time) *model.LogRecord {
+	ts := m.Timestamp
+	if ts.IsZero() {
+		ts = received
+	}
+	severityText, severityNumber := Severity(m.Severity)
+	return &model.LogRecord{
+		TimeUnixNano:         uint64(ts.UnixNano()),
+		ObservedTimeUnixNano: uint64(received.UnixNano()),
+		SeverityNumber:       severityNumber,
+		SeverityText:         severityText,
+		Body:                 m.Message,
+		Attributes:           toDomainAttributes(m),
+		Process:              toDomainProcess(m),
+	}
+}
+
+// Severity returns the keyword and OpenTelemetry severity number of a syslog severity,
+// or nothing for a code out of the 0 to 7 range.
+func Severity(code int) (string, pbL.SeverityNumber) {
+	if code < 0 || code >= len(severities) {
+		return "", pbL.SeverityNumber_SEVERITY_NUMBER_UNSPECIFIED
+	}
+	return severities[code].text, severities[code].number
+}
+
+func toDomainAttributes(m *Message) []model.KeyValue {
+	attributes := []model.KeyValue{stringAttribute(facilityAttribute, facilities[m.Facility])}
+	if m.Version > 0 {
+		attributes = append(attributes, model.KeyValue{
+			Key:   versionAttribute,
+			Value: &common.AnyValue{Value: &common.AnyValue_IntValue{IntValue: int64(m.Version)}},
+		})
+	}
+	if m.MsgID != "" {
+		attributes = append(attributes, stringAttribute(msgIDAttribute, m.MsgID))
+	}
+	for _, element := range m.StructuredData {
+		for _, param := range element.Params {
+			attributes = append(attributes, stringAttribute(structuredDataPrefix+element.ID+"."+param.Name, param.Value))
+		}
+	}
+	return attributes
+}
+
+func toDomainProcess(m *Message) *model.Process {
+	process := &model.Process{
+		ServiceName: m.AppName,
+		Attributes:  []model.KeyValue{},
+	}
+	if m.Hostname != "" {
+		process.Attributes = append(process.Attributes, stringAttribute(hostNameAttribute, m.Hostname))
+	}
+	if m.ProcID != "" {
+		if pid, err := strconv.ParseInt(m.ProcID, 10, 64); err == nil {
+			process.Attributes = append(process.Attributes, model.KeyValue{
+				Key:   processPIDAttribute,
+				Value: &common.AnyValue{Value: &common.AnyValue_IntValue{IntValue: pid}},
+			})
+		} else {
+			process.Attributes = append(process.Attributes, stringAttribute(processPIDAttribute, m.ProcID))
+		}
+	}
+	return process
+}
+
+func stringAttribute(key, value string) model.KeyValue {
+	return model.KeyValue{
+		Key:   key,
+		Value: &common.AnyValue{Value: &common.AnyValue_StringValue{StringValue: value}},
+	}
+}
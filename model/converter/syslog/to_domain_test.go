@@ -0,0 +1,57 @@
+package syslog
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"logger/model"
+	common "logger/model/proto/common/v1"
+	pbL "logger/model/proto/logs/v1"
+)
+
+func TestToDomainLog(t *testing.T) {
+	ts := time.Date(2003, time.October, 11, 22, 14, 15, 0, time.UTC)
+	log := ToDomainLog(&Message{
+		Facility:  20,
+		Severity:  5,
+		Version:   1,
+		Timestamp: ts,
+		Hostname:  "mymachine",
+		AppName:   "evntslog",
+		ProcID:    "1234",
+		MsgID:     "ID47",
+		StructuredData: []SDElement{
+			{ID: "origin", Params: []SDParam{{"ip", "192.0.2.1"}}},
+		},
+		Message: "An application event",
+	}, now)
+
+	assert.Equal(t, uint64(ts.UnixNano()), log.TimeUnixNano)
+	assert.Equal(t, uint64(now.UnixNano()), log.ObservedTimeUnixNano)
+	assert.Equal(t, pbL.SeverityNumber_SEVERITY_NUMBER_INFO2, log.SeverityNumber)
+	assert.Equal(t, "notice", log.SeverityText)
+	assert.Equal(t, "An application event", log.Body)
+	assert.Equal(t, []model.KeyValue{
+		stringAttribute("syslog.facility", "local4"),
+		{Key: "syslog.version", Value: &common.AnyValue{Value: &common.AnyValue_IntValue{IntValue: 1}}},
+		stringAttribute("syslog.msgid", "ID47"),
+		stringAttribute("syslog.sd.origin.ip", "192.0.2.1"),
+	}, log.Attributes)
+	assert.Equal(t, &model.Process{
+		ServiceName: "evntslog",
+		Attributes: []model.KeyValue{
+			stringAttribute("host.name", "mymachine"),
+			{Key: "process.pid", Value: &common.AnyValue{Value: &common.AnyValue_IntValue{IntValue: 1234}}},
+		},
+	}, log.Process)
+}
+
+func TestToDomainLogDefaults(t *testing.T) {
+	log := ToDomainLog(&Message{Facility: 0, Severity: 0, ProcID: "worker-1"}, now)
+	assert.Equal(t, uint64(now.UnixNano()), log.TimeUnixNano)
+	assert.Equal(t, pbL.SeverityNumber_SEVERITY_NUMBER_FATAL4, log.SeverityNumber)
+	assert.Equal(t, []model.KeyValue{stringAttribute("syslog.facility", "kern")}, log.Attributes)
+	assert.Equal(t, []model.KeyValue{stringAttribute("process.pid", "worker-1")}, log.Process.Attributes)
+}
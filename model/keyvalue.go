@@ -0,0 +1,57 @@
+package model
+
+import (
+	"encoding/hex"
+	"strconv"
+
+	common "logger/model/proto/common/v1"
+
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// AnyValueString renders a value as text: scalars as their literal, bytes in hex
+// and arrays or maps as JSON.
+func AnyValueString(v *common.AnyValue) string {
+	switch value := v.GetValue().(type) {
+	case nil:
+		return ""
+	case *common.AnyValue_StringValue:
+		return value.StringValue
+	case *common.AnyValue_BoolValue:
+		return strconv.FormatBool(value.BoolValue)
+	case *common.AnyValue_IntValue:
+		return strconv.FormatInt(value.IntValue, 10)
+	case *common.AnyValue_DoubleValue:
+		return strconv.FormatFloat(value.DoubleValue, 'g', -1, 64)
+	case *common.AnyValue_BytesValue:
+		return hex.EncodeToString(value.BytesValue)
+	default:
+		data, err := protojson.Marshal(v)
+		if err != nil {
+			return ""
+		}
+		return string(data)
+	}
+}
+
+// AnyValueType returns the name of the type of a value, one of the *_TYPE constants,
+// "array" or "map", or "" when the value is empty.
+func AnyValueType(v *common.AnyValue) string {
+	switch v.GetValue().(type) {
+	case *common.AnyValue_StringValue:
+		return STRING_TYPE
+	case *common.AnyValue_BoolValue:
+		return BOOL_TYPE
+	case *common.AnyValue_IntValue:
+		return INT64_TYPE
+	case *common.AnyValue_DoubleValue:
+		return FLOAT64_TYPE
+	case *common.AnyValue_BytesValue:
+		return BINARY_TYPE
+	case *common.AnyValue_ArrayValue:
+		return "array"
+	case *common.AnyValue_KvlistValue:
+		return "map"
+	}
+	return ""
+}
@@ -0,0 +1,150 @@
+package model
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// LogIDAttribute is the OTLP attribute carrying the ID of a log record, as in the
+// log.record.uid semantic convention.
+const LogIDAttribute = "log.record.uid"
+
+// crockford is the base32 alphabet of ULIDs, without I, L, O and U.
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// logIDLen is the length of the text form of a LogID.
+const logIDLen = 26
+
+// LogID identifies a log record. It is a ULID: a 48 bits timestamp in milliseconds
+// followed by 80 random bits, so that IDs sort by the time of their record.
+type LogID [16]byte
+
+// ErrInvalidLogID is returned when parsing a malformed LogID.
+var ErrInvalidLogID = errors.New("invalid log id, it must be a 26 characters ULID")
+
+var crockfordIndex = func() [256]byte {
+	var index [256]byte
+	for i := range index {
+		index[i] = 0xFF
+	}
+	for i := 0; i < len(crockford); i++ {
+		index[crockford[i]] = byte(i)
+		// decoding is case-insensitive
+		index[crockford[i]|0x20] = byte(i)
+	}
+	return index
+}()
+
+// NewLogID returns a LogID for a record written at t with random low bits.
+func NewLogID(t time.Time) LogID {
+	var id LogID
+	ms := uint64(t.UnixMilli())
+	id[0] = byte(ms >> 40)
+	id[1] = byte(ms >> 32)
+	binary.BigEndian.PutUint32(id[2:6], uint32(ms))
+	if _, err := rand.Read(id[6:]); err != nil {
+		panic(fmt.Sprintf("cannot read random bytes: %v", err))
+	}
+	return id
+}
+
+// NewLogIDFor returns a LogID for log, timestamped with the time of the record, or
+// its observed time when the record has none.
+func NewLogIDFor(log *LogRecord) LogID {
+	ts := log.TimeUnixNano
+	if ts == 0 {
+		ts = log.ObservedTimeUnixNano
+	}
+	if ts == 0 {
+		return NewLogID(time.Now())
+	}
+	return NewLogID(time.Unix(0, int64(ts)))
+}
+
+// ParseLogID parses the text form of a LogID.
+func ParseLogID(s string) (LogID, error) {
+	var id LogID
+	if len(s) != logIDLen {
+		return id, ErrInvalidLogID
+	}
+	// the 26 characters encode 130 bits, the first one only holds the 3 high bits
+	if crockfordIndex[s[0]] > 7 {
+		return id, ErrInvalidLogID
+	}
+	var acc uint64
+	bits := 0
+	n := 0
+	for i := 0; i < logIDLen; i++ {
+		v := crockfordIndex[s[i]]
+		if v == 0xFF {
+			return id, ErrInvalidLogID
+		}
+		acc = acc<<5 | uint64(v)
+		bits += 5
+		if i == 0 {
+			// drop the 2 padding bits of the first character
+			bits -= 2
+		}
+		for bits >= 8 {
+			bits -= 8
+			id[n] = byte(acc >> bits)
+			n++
+		}
+	}
+	return id, nil
+}
+
+// String returns the 26 characters text form of id.
+func (id LogID) String() string {
+	var buf [logIDLen]byte
+	// 128 bits are written as 130, with 2 padding bits in front
+	var acc uint64
+	bits := 2
+	n := 0
+	for _, b := range id {
+		acc = acc<<8 | uint64(b)
+		bits += 8
+		for bits >= 5 {
+			bits -= 5
+			buf[n] = crockford[(acc>>bits)&0x1F]
+			n++
+		}
+	}
+	return string(buf[:])
+}
+
+// IsZero reports whether id is unset.
+func (id LogID) IsZero() bool {
+	return id == LogID{}
+}
+
+// Time returns the instant encoded in id, to the millisecond.
+func (id LogID) Time() time.Time {
+	ms := uint64(id[0])<<40 | uint64(id[1])<<32 | uint64(binary.BigEndian.Uint32(id[2:6]))
+	return time.UnixMilli(int64(ms))
+}
+
+// MarshalText implements encoding.TextMarshaler; an unset id is written empty.
+func (id LogID) MarshalText() ([]byte, error) {
+	if id.IsZero() {
+		return []byte{}, nil
+	}
+	return []byte(id.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (id *LogID) UnmarshalText(data []byte) error {
+	if len(data) == 0 {
+		*id = LogID{}
+		return nil
+	}
+	parsed, err := ParseLogID(string(data))
+	if err != nil {
+		return err
+	}
+	*id = parsed
+	return nil
+}
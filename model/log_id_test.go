@@ -0,0 +1,61 @@
+package model
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogIDString(t *testing.T) {
+	// example of the ULID specification
+	id, err := ParseLogID("01ARYZ6S41TSV4RRFFQ69G5FAV")
+	require.NoError(t, err)
+	assert.Equal(t, "01ARYZ6S41TSV4RRFFQ69G5FAV", id.String())
+	assert.Equal(t, int64(1469918176385), id.Time().UnixMilli())
+
+	lower, err := ParseLogID(strings.ToLower("01ARYZ6S41TSV4RRFFQ69G5FAV"))
+	require.NoError(t, err)
+	assert.Equal(t, id, lower)
+
+	max := LogID{0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF}
+	assert.Equal(t, "7ZZZZZZZZZZZZZZZZZZZZZZZZZ", max.String())
+}
+
+func TestParseLogIDErrors(t *testing.T) {
+	for _, s := range []string{"", "01ARZ3NDEKTSV4RRFFQ69G5FA", "81ARZ3NDEKTSV4RRFFQ69G5FAV", "01ARZ3NDEKTSV4RRFFQ69G5FAU"} {
+		_, err := ParseLogID(s)
+		assert.ErrorIs(t, err, ErrInvalidLogID, s)
+	}
+}
+
+func TestNewLogIDSortsByTime(t *testing.T) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	var ids []string
+	for i := 0; i < 10; i++ {
+		id := NewLogID(base.Add(time.Duration(i) * time.Millisecond))
+		assert.Equal(t, base.Add(time.Duration(i)*time.Millisecond), id.Time().UTC())
+		ids = append(ids, id.String())
+	}
+	assert.True(t, sort.StringsAreSorted(ids))
+	assert.NotEqual(t, NewLogID(base), NewLogID(base))
+}
+
+func TestLogIDJSON(t *testing.T) {
+	log := LogRecord{ID: NewLogIDFor(&LogRecord{TimeUnixNano: 1700000000000000000})}
+	data, err := json.Marshal(log)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"id":"`+log.ID.String()+`"`)
+
+	var decoded LogRecord
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, log.ID, decoded.ID)
+
+	data, err = json.Marshal(LogRecord{})
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"id":""`)
+}
@@ -58,6 +58,8 @@ func (k *KeyValue) ToKeyValueDomain(v *common.KeyValue) {
 // }
 
 type LogRecord struct {
+	// ID identifies the record, it is assigned by the collector when the record is received.
+	ID                   LogID               `json:"id"`
 	TimeUnixNano         uint64              `protobuf:"fixed64,1,opt,name=time_unix_nano,json=timeUnixNano,proto3" json:"time_unix_nano,omitempty"`
 	ObservedTimeUnixNano uint64              `protobuf:"fixed64,11,opt,name=observed_time_unix_nano,json=observedTimeUnixNano,proto3" json:"observed_time_unix_nano,omitempty"`
 	SeverityNumber       logs.SeverityNumber `protobuf:"varint,2,opt,name=severity_number,json=severityNumber,proto3,enum=opentelemetry.proto.logs.v1.SeverityNumber" json:"severity_number,omitempty"`
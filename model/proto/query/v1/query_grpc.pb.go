@@ -0,0 +1,287 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.4.0
+// - protoc             (unknown)
+// source: query.proto
+
+package v1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.62.0 or later.
+const _ = grpc.SupportPackageIsVersion8
+
+const (
+	QueryService_FindLogs_FullMethodName       = "/logger.api_v1.QueryService/FindLogs"
+	QueryService_GetServices_FullMethodName    = "/logger.api_v1.QueryService/GetServices"
+	QueryService_GetOperations_FullMethodName  = "/logger.api_v1.QueryService/GetOperations"
+	QueryService_GetLogsByTrace_FullMethodName = "/logger.api_v1.QueryService/GetLogsByTrace"
+)
+
+// QueryServiceClient is the client API for QueryService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// QueryService reads logs from the storage of the query service.
+type QueryServiceClient interface {
+	// FindLogs streams the logs matching a query in chunks.
+	FindLogs(ctx context.Context, in *FindLogsRequest, opts ...grpc.CallOption) (QueryService_FindLogsClient, error)
+	GetServices(ctx context.Context, in *GetServicesRequest, opts ...grpc.CallOption) (*GetServicesResponse, error)
+	GetOperations(ctx context.Context, in *GetOperationsRequest, opts ...grpc.CallOption) (*GetOperationsResponse, error)
+	// GetLogsByTrace streams the logs correlated with a trace, oldest first.
+	GetLogsByTrace(ctx context.Context, in *GetLogsByTraceRequest, opts ...grpc.CallOption) (QueryService_GetLogsByTraceClient, error)
+}
+
+type queryServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewQueryServiceClient(cc grpc.ClientConnInterface) QueryServiceClient {
+	return &queryServiceClient{cc}
+}
+
+func (c *queryServiceClient) FindLogs(ctx context.Context, in *FindLogsRequest, opts ...grpc.CallOption) (QueryService_FindLogsClient, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &QueryService_ServiceDesc.Streams[0], QueryService_FindLogs_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &queryServiceFindLogsClient{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type QueryService_FindLogsClient interface {
+	Recv() (*LogsResponseChunk, error)
+	grpc.ClientStream
+}
+
+type queryServiceFindLogsClient struct {
+	grpc.ClientStream
+}
+
+func (x *queryServiceFindLogsClient) Recv() (*LogsResponseChunk, error) {
+	m := new(LogsResponseChunk)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *queryServiceClient) GetServices(ctx context.Context, in *GetServicesRequest, opts ...grpc.CallOption) (*GetServicesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetServicesResponse)
+	err := c.cc.Invoke(ctx, QueryService_GetServices_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *queryServiceClient) GetOperations(ctx context.Context, in *GetOperationsRequest, opts ...grpc.CallOption) (*GetOperationsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetOperationsResponse)
+	err := c.cc.Invoke(ctx, QueryService_GetOperations_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *queryServiceClient) GetLogsByTrace(ctx context.Context, in *GetLogsByTraceRequest, opts ...grpc.CallOption) (QueryService_GetLogsByTraceClient, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &QueryService_ServiceDesc.Streams[1], QueryService_GetLogsByTrace_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &queryServiceGetLogsByTraceClient{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type QueryService_GetLogsByTraceClient interface {
+	Recv() (*LogsResponseChunk, error)
+	grpc.ClientStream
+}
+
+type queryServiceGetLogsByTraceClient struct {
+	grpc.ClientStream
+}
+
+func (x *queryServiceGetLogsByTraceClient) Recv() (*LogsResponseChunk, error) {
+	m := new(LogsResponseChunk)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// QueryServiceServer is the server API for QueryService service.
+// All implementations must embed UnimplementedQueryServiceServer
+// for forward compatibility
+//
+// QueryService reads logs from the storage of the query service.
+type QueryServiceServer interface {
+	// FindLogs streams the logs matching a query in chunks.
+	FindLogs(*FindLogsRequest, QueryService_FindLogsServer) error
+	GetServices(context.Context, *GetServicesRequest) (*GetServicesResponse, error)
+	GetOperations(context.Context, *GetOperationsRequest) (*GetOperationsResponse, error)
+	// GetLogsByTrace streams the logs correlated with a trace, oldest first.
+	GetLogsByTrace(*GetLogsByTraceRequest, QueryService_GetLogsByTraceServer) error
+	mustEmbedUnimplementedQueryServiceServer()
+}
+
+// UnimplementedQueryServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedQueryServiceServer struct {
+}
+
+func (UnimplementedQueryServiceServer) FindLogs(*FindLogsRequest, QueryService_FindLogsServer) error {
+	return status.Errorf(codes.Unimplemented, "method FindLogs not implemented")
+}
+func (UnimplementedQueryServiceServer) GetServices(context.Context, *GetServicesRequest) (*GetServicesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetServices not implemented")
+}
+func (UnimplementedQueryServiceServer) GetOperations(context.Context, *GetOperationsRequest) (*GetOperationsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetOperations not implemented")
+}
+func (UnimplementedQueryServiceServer) GetLogsByTrace(*GetLogsByTraceRequest, QueryService_GetLogsByTraceServer) error {
+	return status.Errorf(codes.Unimplemented, "method GetLogsByTrace not implemented")
+}
+func (UnimplementedQueryServiceServer) mustEmbedUnimplementedQueryServiceServer() {}
+
+// UnsafeQueryServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to QueryServiceServer will
+// result in compilation errors.
+type UnsafeQueryServiceServer interface {
+	mustEmbedUnimplementedQueryServiceServer()
+}
+
+func RegisterQueryServiceServer(s grpc.ServiceRegistrar, srv QueryServiceServer) {
+	s.RegisterService(&QueryService_ServiceDesc, srv)
+}
+
+func _QueryService_FindLogs_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(FindLogsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(QueryServiceServer).FindLogs(m, &queryServiceFindLogsServer{ServerStream: stream})
+}
+
+type QueryService_FindLogsServer interface {
+	Send(*LogsResponseChunk) error
+	grpc.ServerStream
+}
+
+type queryServiceFindLogsServer struct {
+	grpc.ServerStream
+}
+
+func (x *queryServiceFindLogsServer) Send(m *LogsResponseChunk) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _QueryService_GetServices_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetServicesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(QueryServiceServer).GetServices(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: QueryService_GetServices_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(QueryServiceServer).GetServices(ctx, req.(*GetServicesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _QueryService_GetOperations_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetOperationsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(QueryServiceServer).GetOperations(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: QueryService_GetOperations_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(QueryServiceServer).GetOperations(ctx, req.(*GetOperationsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _QueryService_GetLogsByTrace_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(GetLogsByTraceRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(QueryServiceServer).GetLogsByTrace(m, &queryServiceGetLogsByTraceServer{ServerStream: stream})
+}
+
+type QueryService_GetLogsByTraceServer interface {
+	Send(*LogsResponseChunk) error
+	grpc.ServerStream
+}
+
+type queryServiceGetLogsByTraceServer struct {
+	grpc.ServerStream
+}
+
+func (x *queryServiceGetLogsByTraceServer) Send(m *LogsResponseChunk) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// QueryService_ServiceDesc is the grpc.ServiceDesc for QueryService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var QueryService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "logger.api_v1.QueryService",
+	HandlerType: (*QueryServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetServices",
+			Handler:    _QueryService_GetServices_Handler,
+		},
+		{
+			MethodName: "GetOperations",
+			Handler:    _QueryService_GetOperations_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "FindLogs",
+			Handler:       _QueryService_FindLogs_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "GetLogsByTrace",
+			Handler:       _QueryService_GetLogsByTrace_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "query.proto",
+}
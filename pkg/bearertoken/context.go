@@ -0,0 +1,23 @@
+// Package bearertoken carries the bearer token of an API call in its context.Context,
+// so that storage backends can forward the credentials of the caller.
+package bearertoken
+
+import "context"
+
+type contextKeyType int
+
+const contextKey = contextKeyType(iota)
+
+// ContextWithBearerToken returns a copy of ctx carrying token.
+func ContextWithBearerToken(ctx context.Context, token string) context.Context {
+	if token == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, contextKey, token)
+}
+
+// GetBearerToken returns the bearer token carried by ctx, if any.
+func GetBearerToken(ctx context.Context) (string, bool) {
+	token, ok := ctx.Value(contextKey).(string)
+	return token, ok
+}
@@ -0,0 +1,65 @@
+package bearertoken
+
+import (
+	"context"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type tokenServerStream struct {
+	grpc.ServerStream
+	context context.Context
+}
+
+// Context returns the context carrying the bearer token.
+func (s *tokenServerStream) Context() context.Context {
+	return s.context
+}
+
+// tokenFromMetadata returns the token held by header in the incoming metadata of ctx.
+func tokenFromMetadata(ctx context.Context, header string) (string, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return "", nil
+	}
+	values := md.Get(strings.ToLower(header))
+	if len(values) > 1 {
+		return "", status.Errorf(codes.PermissionDenied, "extra %s header", header)
+	}
+	if len(values) == 0 {
+		return "", nil
+	}
+	return TokenFromHeader(header, values[0]), nil
+}
+
+// NewUnaryServerInterceptor moves the token held by header in the request metadata to the context.
+func NewUnaryServerInterceptor(header string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		token, err := tokenFromMetadata(ctx, header)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ContextWithBearerToken(ctx, token), req)
+	}
+}
+
+// NewStreamServerInterceptor moves the token held by header in the stream metadata to the context.
+func NewStreamServerInterceptor(header string) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		token, err := tokenFromMetadata(ss.Context(), header)
+		if err != nil {
+			return err
+		}
+		if token == "" {
+			return handler(srv, ss)
+		}
+		return handler(srv, &tokenServerStream{
+			ServerStream: ss,
+			context:      ContextWithBearerToken(ss.Context(), token),
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package bearertoken
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+type mockServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *mockServerStream) Context() context.Context {
+	return s.ctx
+}
+
+func TestServerInterceptors(t *testing.T) {
+	tests := []struct {
+		name   string
+		md     metadata.MD
+		token  string
+		errMsg string
+	}{
+		{name: "no token", md: metadata.MD{}},
+		{name: "bearer token", md: metadata.Pairs("authorization", "Bearer abc"), token: "abc"},
+		{
+			name:   "extra header",
+			md:     metadata.Pairs("authorization", "Bearer abc", "authorization", "Bearer def"),
+			errMsg: "rpc error: code = PermissionDenied desc = extra Authorization header",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := metadata.NewIncomingContext(context.Background(), test.md)
+			check := func(ctx context.Context) {
+				token, _ := GetBearerToken(ctx)
+				assert.Equal(t, test.token, token)
+			}
+
+			unary := NewUnaryServerInterceptor(DefaultHeader)
+			_, err := unary(ctx, nil, &grpc.UnaryServerInfo{}, func(ctx context.Context, req interface{}) (interface{}, error) {
+				check(ctx)
+				return nil, nil
+			})
+			stream := NewStreamServerInterceptor(DefaultHeader)
+			streamErr := stream(nil, &mockServerStream{ctx: ctx}, &grpc.StreamServerInfo{}, func(srv interface{}, ss grpc.ServerStream) error {
+				check(ss.Context())
+				return nil
+			})
+			if test.errMsg == "" {
+				require.NoError(t, err)
+				require.NoError(t, streamErr)
+			} else {
+				require.EqualError(t, err, test.errMsg)
+				require.EqualError(t, streamErr, test.errMsg)
+			}
+		})
+	}
+}
@@ -0,0 +1,21 @@
+package bearertoken
+
+import "strings"
+
+// DefaultHeader is the header the token is read from when no other is configured.
+const DefaultHeader = "Authorization"
+
+const bearerPrefix = "bearer "
+
+// TokenFromHeader extracts the token from the value of header. The Authorization header
+// must use the Bearer scheme, any other header is expected to hold the bare token.
+func TokenFromHeader(header, value string) string {
+	value = strings.TrimSpace(value)
+	if !strings.EqualFold(header, DefaultHeader) {
+		return value
+	}
+	if len(value) < len(bearerPrefix) || !strings.EqualFold(value[:len(bearerPrefix)], bearerPrefix) {
+		return ""
+	}
+	return strings.TrimSpace(value[len(bearerPrefix):])
+}
@@ -0,0 +1,37 @@
+package bearertoken
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenFromHeader(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		value  string
+		token  string
+	}{
+		{name: "bearer scheme", header: "Authorization", value: "Bearer abc", token: "abc"},
+		{name: "case insensitive scheme", header: "authorization", value: "bearer  abc ", token: "abc"},
+		{name: "other scheme", header: "Authorization", value: "Basic abc", token: ""},
+		{name: "empty", header: "Authorization", value: "", token: ""},
+		{name: "custom header", header: "X-Access-Token", value: "abc", token: "abc"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.token, TokenFromHeader(test.header, test.value))
+		})
+	}
+}
+
+func TestContextWithBearerToken(t *testing.T) {
+	_, ok := GetBearerToken(ContextWithBearerToken(context.Background(), ""))
+	assert.False(t, ok)
+
+	token, ok := GetBearerToken(ContextWithBearerToken(context.Background(), "abc"))
+	assert.True(t, ok)
+	assert.Equal(t, "abc", token)
+}
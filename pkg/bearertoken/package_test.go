@@ -0,0 +1,11 @@
+package bearertoken
+
+import (
+	"testing"
+
+	"logger/pkg/testutils"
+)
+
+func TestMain(m *testing.M) {
+	testutils.VerifyGoLeaks(m)
+}
@@ -0,0 +1,72 @@
+package corscfg
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/savsgio/atreugo/v11"
+)
+
+const corsAllowedMethods = "GET, POST, PUT, DELETE, OPTIONS"
+
+// Middleware answers CORS preflight requests and allows the configured origins.
+// Without any configured origin every origin is allowed.
+func Middleware(options Options) atreugo.Middleware {
+	origins := nonEmpty(options.AllowedOrigins)
+	allowedHeaders := strings.Join(nonEmpty(options.AllowedHeaders), ", ")
+	return func(c *atreugo.RequestCtx) error {
+		origin := string(c.Request.Header.Peek("Origin"))
+		if origin == "" {
+			return c.Next()
+		}
+		switch {
+		case len(origins) == 0:
+			c.Response.Header.Set("Access-Control-Allow-Origin", "*")
+		case originAllowed(origins, origin):
+			c.Response.Header.Set("Access-Control-Allow-Origin", origin)
+			c.Response.Header.Add("Vary", "Origin")
+		default:
+			return c.Next()
+		}
+		if !c.IsOptions() || len(c.Request.Header.Peek("Access-Control-Request-Method")) == 0 {
+			return c.Next()
+		}
+		c.Response.Header.Set("Access-Control-Allow-Methods", corsAllowedMethods)
+		headers := allowedHeaders
+		if headers == "" {
+			headers = string(c.Request.Header.Peek("Access-Control-Request-Headers"))
+		}
+		if headers != "" {
+			c.Response.Header.Set("Access-Control-Allow-Headers", headers)
+		}
+		c.SetStatusCode(http.StatusNoContent)
+		return nil
+	}
+}
+
+// originAllowed matches origin against the allowed origins, which may contain a single "*" wildcard.
+func originAllowed(allowed []string, origin string) bool {
+	origin = strings.ToLower(origin)
+	for _, pattern := range allowed {
+		pattern = strings.ToLower(pattern)
+		if pattern == "*" || pattern == origin {
+			return true
+		}
+		if prefix, suffix, ok := strings.Cut(pattern, "*"); ok &&
+			len(origin) >= len(prefix)+len(suffix) &&
+			strings.HasPrefix(origin, prefix) && strings.HasSuffix(origin, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+func nonEmpty(values []string) []string {
+	res := make([]string, 0, len(values))
+	for _, v := range values {
+		if v != "" {
+			res = append(res, v)
+		}
+	}
+	return res
+}
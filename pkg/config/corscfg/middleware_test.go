@@ -0,0 +1,18 @@
+package corscfg
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOriginAllowed(t *testing.T) {
+	allowed := []string{"https://grafana.example.com", "http://*.domain.com"}
+	assert.True(t, originAllowed(allowed, "https://grafana.example.com"))
+	assert.True(t, originAllowed(allowed, "HTTPS://Grafana.example.com"))
+	assert.True(t, originAllowed(allowed, "http://ui.domain.com"))
+	assert.False(t, originAllowed(allowed, "http://domain.com"))
+	assert.False(t, originAllowed(allowed, "https://ui.domain.com"))
+	assert.False(t, originAllowed(allowed, "https://evil.com"))
+	assert.True(t, originAllowed([]string{"*"}, "https://evil.com"))
+}
@@ -0,0 +1,98 @@
+// Package jtracer builds the OpenTelemetry tracer provider the services trace themselves with.
+package jtracer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+const (
+	// ExporterOTLPHTTP sends the spans over OTLP/HTTP.
+	ExporterOTLPHTTP = "otlp-http"
+	// ExporterStdout prints the spans on the standard output.
+	ExporterStdout = "stdout"
+)
+
+// Options configures where the spans are exported to.
+type Options struct {
+	// Exporter is one of otlp-http or stdout.
+	Exporter string
+	// Endpoint is the host:port of the OTLP/HTTP receiver. When empty, the standard
+	// OTEL_EXPORTER_OTLP_ENDPOINT and OTEL_EXPORTER_OTLP_TRACES_ENDPOINT variables apply.
+	Endpoint string
+	// Insecure disables TLS towards the OTLP receiver.
+	Insecure bool
+}
+
+// JTracer holds the tracer provider of a service.
+type JTracer struct {
+	OTEL   trace.TracerProvider
+	closer func(ctx context.Context) error
+}
+
+var once sync.Once
+
+// New returns a JTracer exporting the spans of serviceName as configured by options.
+// The first tracer created also becomes the global tracer provider and propagator.
+func New(serviceName string, options Options) (*JTracer, error) {
+	ctx := context.Background()
+	exporter, err := newExporter(ctx, options)
+	if err != nil {
+		return nil, err
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceNameKey.String(serviceName),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create tracer resource: %w", err)
+	}
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	once.Do(func() {
+		otel.SetTracerProvider(tp)
+		otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+			propagation.TraceContext{},
+			propagation.Baggage{},
+		))
+	})
+	return &JTracer{OTEL: tp, closer: tp.Shutdown}, nil
+}
+
+func newExporter(ctx context.Context, options Options) (sdktrace.SpanExporter, error) {
+	switch options.Exporter {
+	case ExporterOTLPHTTP, "":
+		return newOTLPExporter(options), nil
+	case ExporterStdout:
+		return stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q, expected %s or %s",
+			options.Exporter, ExporterOTLPHTTP, ExporterStdout)
+	}
+}
+
+// NoOp returns a JTracer that records nothing.
+func NoOp() *JTracer {
+	return &JTracer{OTEL: noop.NewTracerProvider()}
+}
+
+// Close flushes the pending spans and stops the exporter.
+func (jt *JTracer) Close(ctx context.Context) error {
+	if jt.closer == nil {
+		return nil
+	}
+	return jt.closer(ctx)
+}
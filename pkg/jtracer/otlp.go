@@ -0,0 +1,255 @@
+package jtracer
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/protobuf/proto"
+
+	commonv1 "logger/model/proto/common/v1"
+	resourcev1 "logger/model/proto/resource/v1"
+	tracev1 "logger/model/proto/trace/v1"
+	api_v1 "logger/model/proto/v1"
+)
+
+const (
+	defaultOTLPEndpoint = "localhost:4318"
+	otlpTracesPath      = "/v1/traces"
+	otlpExportTimeout   = 10 * time.Second
+)
+
+// otlpExporter sends the spans to an OTLP/HTTP receiver. The exporter of the OpenTelemetry
+// SDK is not used because its copy of the OTLP messages conflicts with the one of the model.
+type otlpExporter struct {
+	url    string
+	client *http.Client
+
+	mu       sync.Mutex
+	shutdown bool
+}
+
+func newOTLPExporter(options Options) *otlpExporter {
+	return &otlpExporter{
+		url:    otlpTracesURL(options),
+		client: &http.Client{Timeout: otlpExportTimeout},
+	}
+}
+
+// otlpTracesURL returns the URL the spans are posted to: the configured endpoint,
+// else the one of the standard environment variables, else the local receiver.
+func otlpTracesURL(options Options) string {
+	scheme := "https://"
+	if options.Insecure {
+		scheme = "http://"
+	}
+	if options.Endpoint != "" {
+		return scheme + options.Endpoint + otlpTracesPath
+	}
+	if url := os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"); url != "" {
+		return url
+	}
+	if url := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"); url != "" {
+		return strings.TrimSuffix(url, "/") + otlpTracesPath
+	}
+	return scheme + defaultOTLPEndpoint + otlpTracesPath
+}
+
+// ExportSpans implements sdktrace.SpanExporter#ExportSpans
+func (e *otlpExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	e.mu.Lock()
+	shutdown := e.shutdown
+	e.mu.Unlock()
+	if shutdown || len(spans) == 0 {
+		return nil
+	}
+	body, err := proto.Marshal(&api_v1.ExportTraceServiceRequest{ResourceSpans: toResourceSpans(spans)})
+	if err != nil {
+		return fmt.Errorf("failed to marshal spans: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to export spans: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("failed to export spans: %s answered %s", e.url, resp.Status)
+	}
+	return nil
+}
+
+// Shutdown implements sdktrace.SpanExporter#Shutdown
+func (e *otlpExporter) Shutdown(ctx context.Context) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.shutdown = true
+	e.client.CloseIdleConnections()
+	return nil
+}
+
+// toResourceSpans groups spans by resource and instrumentation scope.
+func toResourceSpans(spans []sdktrace.ReadOnlySpan) []*tracev1.ResourceSpans {
+	var res []*tracev1.ResourceSpans
+	resources := make(map[attribute.Distinct]*tracev1.ResourceSpans)
+	scopes := make(map[attribute.Distinct]map[instrumentation.Scope]*tracev1.ScopeSpans)
+	for _, span := range spans {
+		key := span.Resource().Equivalent()
+		rs, ok := resources[key]
+		if !ok {
+			rs = &tracev1.ResourceSpans{Resource: toResource(span.Resource()), SchemaUrl: span.Resource().SchemaURL()}
+			resources[key] = rs
+			scopes[key] = make(map[instrumentation.Scope]*tracev1.ScopeSpans)
+			res = append(res, rs)
+		}
+		scope := span.InstrumentationScope()
+		ss, ok := scopes[key][scope]
+		if !ok {
+			ss = &tracev1.ScopeSpans{
+				Scope:     &commonv1.InstrumentationScope{Name: scope.Name, Version: scope.Version},
+				SchemaUrl: scope.SchemaURL,
+			}
+			scopes[key][scope] = ss
+			rs.ScopeSpans = append(rs.ScopeSpans, ss)
+		}
+		ss.Spans = append(ss.Spans, toSpan(span))
+	}
+	return res
+}
+
+func toResource(r *resource.Resource) *resourcev1.Resource {
+	if r == nil {
+		return &resourcev1.Resource{}
+	}
+	return &resourcev1.Resource{Attributes: toKeyValues(r.Attributes())}
+}
+
+func toSpan(span sdktrace.ReadOnlySpan) *tracev1.Span {
+	sc := span.SpanContext()
+	traceID, spanID := sc.TraceID(), sc.SpanID()
+	s := &tracev1.Span{
+		TraceId:                traceID[:],
+		SpanId:                 spanID[:],
+		TraceState:             sc.TraceState().String(),
+		Flags:                  uint32(sc.TraceFlags()),
+		Name:                   span.Name(),
+		Kind:                   toSpanKind(span.SpanKind()),
+		StartTimeUnixNano:      uint64(span.StartTime().UnixNano()),
+		EndTimeUnixNano:        uint64(span.EndTime().UnixNano()),
+		Attributes:             toKeyValues(span.Attributes()),
+		DroppedAttributesCount: uint32(span.DroppedAttributes()),
+		DroppedEventsCount:     uint32(span.DroppedEvents()),
+		DroppedLinksCount:      uint32(span.DroppedLinks()),
+		Status:                 toStatus(span.Status()),
+	}
+	if parent := span.Parent(); parent.SpanID().IsValid() {
+		parentID := parent.SpanID()
+		s.ParentSpanId = parentID[:]
+	}
+	for _, event := range span.Events() {
+		s.Events = append(s.Events, &tracev1.Span_Event{
+			TimeUnixNano:           uint64(event.Time.UnixNano()),
+			Name:                   event.Name,
+			Attributes:             toKeyValues(event.Attributes),
+			DroppedAttributesCount: uint32(event.DroppedAttributeCount),
+		})
+	}
+	for _, link := range span.Links() {
+		linkTraceID, linkSpanID := link.SpanContext.TraceID(), link.SpanContext.SpanID()
+		s.Links = append(s.Links, &tracev1.Span_Link{
+			TraceId:                linkTraceID[:],
+			SpanId:                 linkSpanID[:],
+			TraceState:             link.SpanContext.TraceState().String(),
+			Attributes:             toKeyValues(link.Attributes),
+			DroppedAttributesCount: uint32(link.DroppedAttributeCount),
+			Flags:                  uint32(link.SpanContext.TraceFlags()),
+		})
+	}
+	return s
+}
+
+// toSpanKind maps the span kinds, which the API numbers as OTLP does.
+func toSpanKind(kind trace.SpanKind) tracev1.Span_SpanKind {
+	if kind < trace.SpanKindUnspecified || kind > trace.SpanKindConsumer {
+		return tracev1.Span_SPAN_KIND_UNSPECIFIED
+	}
+	return tracev1.Span_SpanKind(kind)
+}
+
+func toStatus(status sdktrace.Status) *tracev1.Status {
+	switch status.Code {
+	case codes.Error:
+		return &tracev1.Status{Code: tracev1.Status_STATUS_CODE_ERROR, Message: status.Description}
+	case codes.Ok:
+		return &tracev1.Status{Code: tracev1.Status_STATUS_CODE_OK}
+	}
+	return &tracev1.Status{}
+}
+
+func toKeyValues(attrs []attribute.KeyValue) []*commonv1.KeyValue {
+	if len(attrs) == 0 {
+		return nil
+	}
+	res := make([]*commonv1.KeyValue, 0, len(attrs))
+	for _, kv := range attrs {
+		res = append(res, &commonv1.KeyValue{Key: string(kv.Key), Value: toAnyValue(kv.Value)})
+	}
+	return res
+}
+
+func toAnyValue(v attribute.Value) *commonv1.AnyValue {
+	switch v.Type() {
+	case attribute.BOOL:
+		return &commonv1.AnyValue{Value: &commonv1.AnyValue_BoolValue{BoolValue: v.AsBool()}}
+	case attribute.INT64:
+		return &commonv1.AnyValue{Value: &commonv1.AnyValue_IntValue{IntValue: v.AsInt64()}}
+	case attribute.FLOAT64:
+		return &commonv1.AnyValue{Value: &commonv1.AnyValue_DoubleValue{DoubleValue: v.AsFloat64()}}
+	case attribute.BOOLSLICE:
+		var values []*commonv1.AnyValue
+		for _, b := range v.AsBoolSlice() {
+			values = append(values, &commonv1.AnyValue{Value: &commonv1.AnyValue_BoolValue{BoolValue: b}})
+		}
+		return arrayValue(values)
+	case attribute.INT64SLICE:
+		var values []*commonv1.AnyValue
+		for _, i := range v.AsInt64Slice() {
+			values = append(values, &commonv1.AnyValue{Value: &commonv1.AnyValue_IntValue{IntValue: i}})
+		}
+		return arrayValue(values)
+	case attribute.FLOAT64SLICE:
+		var values []*commonv1.AnyValue
+		for _, f := range v.AsFloat64Slice() {
+			values = append(values, &commonv1.AnyValue{Value: &commonv1.AnyValue_DoubleValue{DoubleValue: f}})
+		}
+		return arrayValue(values)
+	case attribute.STRINGSLICE:
+		var values []*commonv1.AnyValue
+		for _, s := range v.AsStringSlice() {
+			values = append(values, &commonv1.AnyValue{Value: &commonv1.AnyValue_StringValue{StringValue: s}})
+		}
+		return arrayValue(values)
+	}
+	return &commonv1.AnyValue{Value: &commonv1.AnyValue_StringValue{StringValue: v.Emit()}}
+}
+
+func arrayValue(values []*commonv1.AnyValue) *commonv1.AnyValue {
+	return &commonv1.AnyValue{Value: &commonv1.AnyValue_ArrayValue{ArrayValue: &commonv1.ArrayValue{Values: values}}}
+}
@@ -0,0 +1,68 @@
+package jtracer
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"google.golang.org/protobuf/proto"
+
+	tracev1 "logger/model/proto/trace/v1"
+	api_v1 "logger/model/proto/v1"
+)
+
+func TestOTLPExporter(t *testing.T) {
+	received := make(chan *api_v1.ExportTraceServiceRequest, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, otlpTracesPath, r.URL.Path)
+		assert.Equal(t, "application/x-protobuf", r.Header.Get("Content-Type"))
+		body, err := io.ReadAll(r.Body)
+		assert.NoError(t, err)
+		req := &api_v1.ExportTraceServiceRequest{}
+		assert.NoError(t, proto.Unmarshal(body, req))
+		received <- req
+	}))
+	defer server.Close()
+
+	exporter := newOTLPExporter(Options{Endpoint: strings.TrimPrefix(server.URL, "http://"), Insecure: true})
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	ctx, parent := tp.Tracer("test").Start(context.Background(), "parent")
+	_, child := tp.Tracer("test").Start(ctx, "child")
+	child.SetAttributes(attribute.Int("results", 3))
+	child.SetStatus(codes.Error, "boom")
+	child.End()
+
+	req := <-received
+	require.Len(t, req.ResourceSpans, 1)
+	require.Len(t, req.ResourceSpans[0].ScopeSpans, 1)
+	assert.Equal(t, "test", req.ResourceSpans[0].ScopeSpans[0].Scope.Name)
+	spans := req.ResourceSpans[0].ScopeSpans[0].Spans
+	require.Len(t, spans, 1)
+	span := spans[0]
+	assert.Equal(t, "child", span.Name)
+	parentID := parent.SpanContext().SpanID()
+	assert.Equal(t, parentID[:], span.ParentSpanId)
+	assert.Equal(t, tracev1.Status_STATUS_CODE_ERROR, span.Status.Code)
+	assert.Equal(t, "boom", span.Status.Message)
+	require.Len(t, span.Attributes, 1)
+	assert.Equal(t, "results", span.Attributes[0].Key)
+	assert.Equal(t, int64(3), span.Attributes[0].Value.GetIntValue())
+
+	parent.End()
+	<-received
+	require.NoError(t, tp.Shutdown(context.Background()))
+}
+
+func TestOTLPTracesURL(t *testing.T) {
+	assert.Equal(t, "https://collector:4318/v1/traces", otlpTracesURL(Options{Endpoint: "collector:4318"}))
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "http://collector:4318/")
+	assert.Equal(t, "http://collector:4318/v1/traces", otlpTracesURL(Options{}))
+}
@@ -34,10 +34,20 @@ func IgnoreOpenCensusWorkerLeak() goleak.Option {
 	return goleak.IgnoreTopFunction("go.opencensus.io/stats/view.(*worker).start")
 }
 
+// IgnoreFastHTTPLeak ignores the goroutines of fasthttp that outlive a server shutdown:
+// the package wide date updater and the worker pool cleaner, which exits on its next tick.
+func IgnoreFastHTTPLeak() []goleak.Option {
+	return []goleak.Option{
+		goleak.IgnoreAnyFunction("github.com/valyala/fasthttp.updateServerDate.func1"),
+		goleak.IgnoreAnyFunction("github.com/valyala/fasthttp.(*workerPool).Start.func2"),
+	}
+}
+
 // VerifyGoLeaks verifies that unit tests do not leak any goroutines.
 // It should be called in TestMain.
-func VerifyGoLeaks(m *testing.M) {
-	goleak.VerifyTestMain(m, IgnoreGlogFlushDaemonLeak(), IgnoreOpenCensusWorkerLeak())
+func VerifyGoLeaks(m *testing.M, options ...goleak.Option) {
+	options = append(options, IgnoreGlogFlushDaemonLeak(), IgnoreOpenCensusWorkerLeak())
+	goleak.VerifyTestMain(m, options...)
 }
 
 // VerifyGoLeaksOnce verifies that a given unit test does not leak any goroutines.
@@ -17,6 +17,8 @@ import (
 	ls "logger/storage/logstore"
 
 	cLogStore "logger/plugin/storage/cassandra/logstore"
+	cSavedStore "logger/plugin/storage/cassandra/savedstore"
+	"logger/storage/savedstore"
 )
 
 const (
@@ -26,6 +28,7 @@ const (
 
 var ( // interface comformance checks
 	_ storage.FactoryBase = (*Factory)(nil)
+	_ storage.SavedSearchStoreFactory = (*Factory)(nil)
 	// _ storage.Purger               = (*Factory)(nil)
 	// _ storage.ArchiveFactory       = (*Factory)(nil)
 	// _ storage.SamplingStoreFactory = (*Factory)(nil)
@@ -108,6 +111,16 @@ func (f *Factory) CreateLogWriter() (ls.Writer, error) {
 		f.primarySession, f.Options.SpanStoreWriteCacheTTL, f.primaryMetricsFactory, f.logger, options...), nil
 }
 
+// CreateSavedSearchStore implements storage.SavedSearchStoreFactory, failing when the
+// keyspace has no saved_searches table.
+func (f *Factory) CreateSavedSearchStore() (savedstore.Store, error) {
+	store := cSavedStore.NewStore(f.primarySession)
+	if err := store.CheckTable(); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
 func (f *Factory) AddFlags(flagSet *flag.FlagSet) {
 }
 
@@ -80,9 +80,19 @@ func (c converter) fromDomain(log *model.LogRecord) *LogRecord {
 		ServiceName:            process.ServiceName,
 		ServiceAttributes:      process.Attributes,
 		OperationName:          c.getMethodNameFromAttr(log.Attributes),
+		LogID:                  c.toDBLogID(log.ID),
 	}
 }
 
+// toDBLogID returns an empty, not null, blob for a record without an ID, as log_id is a
+// clustering column of the logs tables.
+func (c converter) toDBLogID(id model.LogID) []byte {
+	if id.IsZero() {
+		return []byte{}
+	}
+	return id[:]
+}
+
 func (c converter) toDomain(log *LogRecord) (*model.LogRecord, error) {
 	attributes, err := c.fromDBAttrinutes(log.Attributes)
 	if err != nil {
@@ -98,6 +108,7 @@ func (c converter) toDomain(log *LogRecord) (*model.LogRecord, error) {
 		return nil, err
 	}
 	span := &model.LogRecord{
+		ID:                     c.fromDBLogID(log.LogID),
 		TimeUnixNano:           log.TimeUnixNano,
 		ObservedTimeUnixNano:   log.ObservedTimeUnixNano,
 		SeverityNumber:         logs.SeverityNumber(log.SeverityNumber),
@@ -113,6 +124,15 @@ func (c converter) toDomain(log *LogRecord) (*model.LogRecord, error) {
 	return span, nil
 }
 
+// fromDBLogID returns the zero LogID for records stored without an ID.
+func (c converter) fromDBLogID(id []byte) model.LogID {
+	var res model.LogID
+	if len(id) == len(res) {
+		copy(res[:], id)
+	}
+	return res
+}
+
 func (c converter) fromDBAttrinutes(attributes []KeyValue) ([]model.KeyValue, error) {
 	retMe := make([]model.KeyValue, len(attributes))
 	for i, attr := range attributes {
@@ -45,6 +45,8 @@ type LogRecord struct {
 	ServiceName            string
 	OperationName          string
 	ServiceAttributes      []KeyValue
+	// LogID is the model.LogID of the record, empty for records stored before IDs were assigned
+	LogID []byte
 
 	// Process                Process
 }
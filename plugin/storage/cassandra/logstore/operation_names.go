@@ -16,6 +16,7 @@
 package logstore
 
 import (
+	"context"
 	"fmt"
 	"time"
 
@@ -51,6 +52,7 @@ type tableMeta struct {
 	queryStmt        string
 	createWriteQuery func(query cassandra.Query, service, opName string) cassandra.Query
 	getOperations    func(
+		ctx context.Context,
 		s *OperationNamesStorage,
 		query logstore.OperationQueryParameters,
 	) ([]logstore.Operation, error)
@@ -151,9 +153,10 @@ func (s *OperationNamesStorage) Write(operation dbmodel.Operation) error {
 
 // GetOperations returns all operations for a specific service traced by Jaeger
 func (s *OperationNamesStorage) GetOperations(
+	ctx context.Context,
 	query logstore.OperationQueryParameters,
 ) ([]logstore.Operation, error) {
-	return s.table.getOperations(s, query)
+	return s.table.getOperations(ctx, s, query)
 }
 
 func tableExist(session cassandra.Session, tableName string) bool {
@@ -163,10 +166,11 @@ func tableExist(session cassandra.Session, tableName string) bool {
 }
 
 func getOperationsV1(
+	ctx context.Context,
 	s *OperationNamesStorage,
 	query logstore.OperationQueryParameters,
 ) ([]logstore.Operation, error) {
-	iter := s.session.Query(s.table.queryStmt, query.ServiceName).Iter()
+	iter := s.session.Query(s.table.queryStmt, query.ServiceName).WithContext(ctx).Iter()
 
 	var operation string
 	var operations []logstore.Operation
@@ -184,6 +188,7 @@ func getOperationsV1(
 }
 
 func getOperationsV2(
+	ctx context.Context,
 	s *OperationNamesStorage,
 	query logstore.OperationQueryParameters,
 ) ([]logstore.Operation, error) {
@@ -195,7 +200,7 @@ func getOperationsV2(
 		// Get operations for given spanKind
 		// casQuery = s.session.Query(s.table.queryByKindStmt, query.ServiceName, query.SpanKind)
 	// }
-	iter := casQuery.Iter()
+	iter := casQuery.WithContext(ctx).Iter()
 
 	var operationName string
 	var spanKind string
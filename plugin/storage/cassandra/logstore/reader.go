@@ -1,11 +1,14 @@
 package logstore
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
 	"logger/pkg/cassandra"
 	"logger/plugin/storage/cassandra/logstore/dbmodel"
+	"sort"
+	"strings"
 
 	"logger/model"
 	"logger/storage/logstore"
@@ -15,9 +18,34 @@ import (
 
 // attributes
 const (
-	queryLogs = `SELECT severity_number,body, start_time, observed_time_unix_nano,service_name,operation_name,service_attributes,attributes
-	FROM logs where service_name = ? and operation_name = ?  AND start_time > ? AND start_time < ? LIMIT ?`
-	defaultNumTraces = 100
+	queryLogs = `SELECT severity_number,body, start_time, observed_time_unix_nano,service_name,operation_name,service_attributes,attributes,trace_id,span_id,log_id
+	FROM logs where service_name = ? and operation_name = ?  AND start_time > ? AND start_time < ? ORDER BY start_time DESC LIMIT ?`
+	queryAllLogs = `SELECT severity_number,body, start_time, observed_time_unix_nano,service_name,operation_name,service_attributes,attributes,trace_id,span_id,log_id
+	FROM logs where service_name = ? and operation_name = ?  AND start_time > ? AND start_time < ? ORDER BY start_time DESC`
+	queryLogsAsc = `SELECT severity_number,body, start_time, observed_time_unix_nano,service_name,operation_name,service_attributes,attributes,trace_id,span_id,log_id
+	FROM logs where service_name = ? and operation_name = ?  AND start_time > ? AND start_time < ? ORDER BY start_time ASC LIMIT ?`
+	queryAllLogsAsc = `SELECT severity_number,body, start_time, observed_time_unix_nano,service_name,operation_name,service_attributes,attributes,trace_id,span_id,log_id
+	FROM logs where service_name = ? and operation_name = ?  AND start_time > ? AND start_time < ? ORDER BY start_time ASC`
+	queryLogsBefore = `SELECT severity_number,body, start_time, observed_time_unix_nano,service_name,operation_name,service_attributes,attributes,trace_id,span_id,log_id
+	FROM logs where service_name = ? and operation_name = ? AND start_time < ? ORDER BY start_time DESC LIMIT ?`
+	queryLogsFrom = `SELECT severity_number,body, start_time, observed_time_unix_nano,service_name,operation_name,service_attributes,attributes,trace_id,span_id,log_id
+	FROM logs where service_name = ? and operation_name = ? AND start_time >= ? ORDER BY start_time ASC LIMIT ?`
+	queryLogsByTrace = `SELECT severity_number,body, start_time, observed_time_unix_nano,service_name,operation_name,service_attributes,attributes,trace_id,span_id,log_id
+	FROM logs_by_trace where trace_id = ?`
+	queryLogByID = `SELECT severity_number,body, start_time, observed_time_unix_nano,service_name,operation_name,service_attributes,attributes,trace_id,span_id,log_id
+	FROM logs_by_id where log_id = ?`
+	defaultNumTraces   = 100
+	defaultContextSize = 10
+	// maxContextScan bounds the rows read from a partition when the context is filtered by host or trace
+	maxContextScan    = 10000
+	hostNameAttribute = "host.name"
+	// streamPageSize is the number of rows fetched per round trip while streaming
+	streamPageSize = 1000
+	// names of the tables reported by explain
+	logsTable           = "logs"
+	logsByTraceTable    = "logs_by_trace"
+	logsByIDTable       = "logs_by_id"
+	operationNamesTable = "operation_names"
 	// queryLogs = `SELECT severity_number,body, start_time, observed_time_unix_nano, attributes, process
 	// FROM logs`
 )
@@ -26,9 +54,6 @@ var (
 	// ErrServiceNameNotSet occurs when attempting to query with an empty service name
 	ErrServiceNameNotSet = errors.New("service Name must be set")
 
-	// ErrOperationNameNotSet occurs when attempting to query with an empty service name
-	ErrOperationNameNotSet = errors.New("operation Name must be set")
-
 	// ErrStartTimeMinGreaterThanMax occurs when start time min is above start time max
 	ErrStartTimeMinGreaterThanMax = errors.New("start Time Minimum is above Maximum")
 
@@ -40,11 +65,23 @@ var (
 
 	// ErrStartAndEndTimeNotSet occurs when start time and end time are not set
 	ErrStartAndEndTimeNotSet = errors.New("start and End Time must be set")
+
+	// ErrTimestampNotSet occurs when a context query has no anchor timestamp
+	ErrTimestampNotSet = errors.New("timestamp must be set")
+
+	// ErrTraceIDNotSet occurs when a trace query has no trace id
+	ErrTraceIDNotSet = errors.New("trace id must be set")
+
+	// ErrLogIDNotSet occurs when a log is looked up without an id
+	ErrLogIDNotSet = errors.New("log id must be set")
+
+	// errStopScan is returned by a scan callback to stop reading without an error
+	errStopScan = errors.New("stop scan")
 )
 
-type serviceNamesReader func() ([]string, error)
+type serviceNamesReader func(ctx context.Context) ([]string, error)
 
-type operationNamesReader func(query logstore.OperationQueryParameters) ([]logstore.Operation, error)
+type operationNamesReader func(ctx context.Context, query logstore.OperationQueryParameters) ([]logstore.Operation, error)
 
 type LogReader struct {
 	session              cassandra.Session
@@ -68,66 +105,397 @@ func NewLogReader(
 }
 
 func (l *LogReader) GetServices(ctx context.Context) ([]string, error) {
-	return l.serviceNamesReader()
+	return l.serviceNamesReader(ctx)
 }
 
 func (l *LogReader) GetOperations(ctx context.Context, p logstore.OperationQueryParameters) ([]logstore.Operation, error) {
-	return l.operationNamesReader(p)
+	return l.operationNamesReader(ctx, p)
 }
 
-
+// GetLogs returns at most p.NumTraces logs, even with p.ShouldFetchAll set.
 func (l *LogReader) GetLogs(ctx context.Context, p logstore.LogQueryParameters) ([]*model.LogRecord, error) {
-
-	return l.getLogs(ctx, p)
+	p.ShouldFetchAll = false
+	res := make([]*model.LogRecord, 0)
+	err := l.StreamLogs(ctx, p, func(log *model.LogRecord) error {
+		res = append(res, log)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
 }
 
-func (l *LogReader) getLogs(ctx context.Context, p logstore.LogQueryParameters) ([]*model.LogRecord, error) {
+// StreamLogs reads the logs matching p page by page and hands them to fn without
+// holding the result set in memory. With p.ShouldFetchAll set, NumTraces is ignored.
+// The partitions of the operations read are merged as they are scanned, so that the
+// logs come out sorted in p.Order across operations.
+func (l *LogReader) StreamLogs(ctx context.Context, p logstore.LogQueryParameters, fn func(*model.LogRecord) error) error {
 	if err := validateQuery(&p); err != nil {
-		return nil, err
+		return err
 	}
 	if p.NumTraces == 0 {
 		p.NumTraces = defaultNumTraces
 	}
-	// query := l.buildQuery(p)
-	q := l.session.Query(queryLogs,
+	stats := logstore.QueryStatsFromContext(ctx)
+	operations, err := l.operations(ctx, p.ServiceName, p.OperationName)
+	if err != nil {
+		return err
+	}
+	stats.AddPartitions(len(operations))
+	defer stats.StartStage("cassandra.scan")()
+	iterators := make([]logstore.LogIterator, 0, len(operations))
+	defer func() {
+		for _, it := range iterators {
+			it.(*logIterator).Close()
+		}
+	}()
+	for _, operation := range operations {
+		iterators = append(iterators, newLogIterator(ctx, l.logsQuery(ctx, p, operation)))
+	}
+	count := 0
+	err = logstore.MergeLogs(p.Order, iterators, func(log *model.LogRecord) error {
+		if err := fn(log); err != nil {
+			return err
+		}
+		stats.AddReturned(1)
+		count++
+		if !p.ShouldFetchAll && count >= p.NumTraces {
+			return errStopScan
+		}
+		return nil
+	})
+	if errors.Is(err, errStopScan) {
+		return nil
+	}
+	return err
+}
+
+// logsQuery returns the query reading the logs of an operation matching p, in p.Order.
+// Each partition is read up to the limit of the whole query, the merge keeps the first ones.
+func (l *LogReader) logsQuery(ctx context.Context, p logstore.LogQueryParameters, operation string) cassandra.Query {
+	args := []interface{}{
 		p.ServiceName,
-		p.OperationName,
+		operation,
 		model.TimeAsEpochMicroseconds(p.StartTimeMin),
 		model.TimeAsEpochMicroseconds(p.StartTimeMax),
-		p.NumTraces,
-	)
-	i := q.Iter()
-	var timeUnixNano, observedTimeUnixNano uint64
-	var severityNumber uint32
-	var body, serviceName, methodName string
-	var attributes, serviceAttributes []dbmodel.KeyValue
-	res := make([]*model.LogRecord, 0)
-	for i.Scan(&severityNumber, &body, &timeUnixNano, &observedTimeUnixNano, &serviceName, &methodName, &serviceAttributes, &attributes) {
-		dbLog := dbmodel.LogRecord{
-			SeverityNumber:       severityNumber,
-			Body:                 body,
-			TimeUnixNano:         timeUnixNano,
-			ObservedTimeUnixNano: observedTimeUnixNano,
-			ServiceName:          serviceName,
-			OperationName:        methodName,
-			ServiceAttributes:    serviceAttributes,
-			Attributes:           attributes,
-		}
-		logModel, err := dbmodel.ToDomain(&dbLog)
+	}
+	var query string
+	switch {
+	case p.ShouldFetchAll && p.Order.Ascending():
+		query = queryAllLogsAsc
+	case p.ShouldFetchAll:
+		query = queryAllLogs
+	case p.Order.Ascending():
+		query, args = queryLogsAsc, append(args, p.NumTraces)
+	default:
+		query, args = queryLogs, append(args, p.NumTraces)
+	}
+	if stats := logstore.QueryStatsFromContext(ctx); stats != nil {
+		stats.AddPlan(planStep(logsTable, query, args...))
+	}
+	return l.session.Query(query, args...).PageSize(streamPageSize).WithContext(ctx)
+}
+
+// operations returns the operation to read, or every operation of the service when it is empty.
+func (l *LogReader) operations(ctx context.Context, service, operation string) ([]string, error) {
+	if operation != "" {
+		return []string{operation}, nil
+	}
+	stats := logstore.QueryStatsFromContext(ctx)
+	stats.UseIndex(operationNamesTable)
+	defer stats.StartStage("cassandra.operations")()
+	ops, err := l.operationNamesReader(ctx, logstore.OperationQueryParameters{ServiceName: service})
+	if err != nil {
+		return nil, err
+	}
+	res := make([]string, 0, len(ops))
+	for _, op := range ops {
+		res = append(res, op.Name)
+	}
+	return res, nil
+}
+
+// GetLog reads a log by its ID from the logs_by_id table. The record is not counted as
+// returned: the lookup locates the anchor of a context query, whose records are counted.
+func (l *LogReader) GetLog(ctx context.Context, id model.LogID) (*model.LogRecord, error) {
+	if id.IsZero() {
+		return nil, ErrLogIDNotSet
+	}
+	stats := logstore.QueryStatsFromContext(ctx)
+	stats.UseIndex(logsByIDTable)
+	stats.AddPartitions(1)
+	if stats != nil {
+		stats.AddPlan(planStep(logsByIDTable, queryLogByID, id.String()))
+	}
+	defer stats.StartStage("cassandra.scan")()
+	var res *model.LogRecord
+	err := l.scanLogs(ctx, l.session.Query(queryLogByID, id[:]).WithContext(ctx), func(log *model.LogRecord) error {
+		res = log
+		return errStopScan
+	})
+	if err != nil {
+		return nil, err
+	}
+	if res == nil {
+		return nil, logstore.ErrLogNotFound
+	}
+	return res, nil
+}
+
+// StreamLogsByTrace reads the logs of a trace, oldest first, from the logs_by_trace table
+// page by page, and hands them to fn without holding the trace in memory.
+func (l *LogReader) StreamLogsByTrace(ctx context.Context, traceID []byte, fn func(*model.LogRecord) error) error {
+	if len(traceID) == 0 {
+		return ErrTraceIDNotSet
+	}
+	stats := logstore.QueryStatsFromContext(ctx)
+	stats.UseIndex(logsByTraceTable)
+	stats.AddPartitions(1)
+	if stats != nil {
+		stats.AddPlan(planStep(logsByTraceTable, queryLogsByTrace, fmt.Sprintf("%x", traceID)))
+	}
+	defer stats.StartStage("cassandra.scan")()
+	returned := 0
+	defer func() { stats.AddReturned(returned) }()
+	return l.scanLogs(ctx, l.session.Query(queryLogsByTrace, traceID).PageSize(streamPageSize).WithContext(ctx), func(log *model.LogRecord) error {
+		if err := fn(log); err != nil {
+			return err
+		}
+		returned++
+		return nil
+	})
+}
+
+// GetLogContext reads the neighbors of p.Timestamp from every partition of the service
+// (or only the one of p.OperationName) and merges them into a single timeline.
+func (l *LogReader) GetLogContext(ctx context.Context, p logstore.LogContextParameters) (*logstore.LogContext, error) {
+	if err := validateContextQuery(&p); err != nil {
+		return nil, err
+	}
+	if p.Before == 0 {
+		p.Before = defaultContextSize
+	}
+	if p.After == 0 {
+		p.After = defaultContextSize
+	}
+	stats := logstore.QueryStatsFromContext(ctx)
+	operations, err := l.operations(ctx, p.ServiceName, p.OperationName)
+	if err != nil {
+		return nil, err
+	}
+	stats.AddPartitions(len(operations))
+	defer stats.StartStage("cassandra.scan")()
+	keep := contextFilter(&p)
+	// without a post-filter every row read is a match, so the limit can be pushed to Cassandra
+	beforeLimit, afterLimit := p.Before, p.After+1
+	if keep != nil {
+		beforeLimit, afterLimit = maxContextScan, maxContextScan
+	}
+	ts := model.TimeAsEpochMicroseconds(p.Timestamp)
+	var before, from []*model.LogRecord
+	for _, operation := range operations {
+		if stats != nil {
+			stats.AddPlan(planStep(logsTable, queryLogsBefore, p.ServiceName, operation, ts, beforeLimit))
+			stats.AddPlan(planStep(logsTable, queryLogsFrom, p.ServiceName, operation, ts, afterLimit))
+		}
+		b, err := l.readLogs(ctx, l.session.Query(queryLogsBefore, p.ServiceName, operation, ts, beforeLimit).WithContext(ctx), p.Before, keep)
 		if err != nil {
-			fmt.Println(err)
 			return nil, err
 		}
-		res = append(res, logModel)
+		before = append(before, b...)
+		a, err := l.readLogs(ctx, l.session.Query(queryLogsFrom, p.ServiceName, operation, ts, afterLimit).WithContext(ctx), afterLimit, keep)
+		if err != nil {
+			return nil, err
+		}
+		from = append(from, a...)
+	}
+	sortByTime(before)
+	sortByTime(from)
+	if len(before) > p.Before {
+		before = before[len(before)-p.Before:]
+	}
+	res := &logstore.LogContext{Before: before}
+	for _, log := range from {
+		if log.TimeUnixNano == ts {
+			res.Anchor = append(res.Anchor, log)
+		} else if len(res.After) < p.After {
+			res.After = append(res.After, log)
+		}
 	}
+	stats.AddReturned(len(res.Before) + len(res.Anchor) + len(res.After))
+	return res, nil
+}
 
-	err := i.Close()
+// readLogs scans the rows returned by q, converts them to the domain model and keeps
+// at most limit of the records accepted by keep. A nil keep accepts every record.
+func (l *LogReader) readLogs(ctx context.Context, q cassandra.Query, limit int, keep func(*model.LogRecord) bool) ([]*model.LogRecord, error) {
+	res := make([]*model.LogRecord, 0)
+	if limit <= 0 {
+		return res, nil
+	}
+	rejected := 0
+	defer func() { logstore.QueryStatsFromContext(ctx).AddRejected(rejected) }()
+	err := l.scanLogs(ctx, q, func(log *model.LogRecord) error {
+		if keep == nil || keep(log) {
+			res = append(res, log)
+		} else {
+			rejected++
+		}
+		if len(res) >= limit {
+			return errStopScan
+		}
+		return nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("error reading logs from storage: %w", err)
+		return nil, err
 	}
 	return res, nil
 }
 
+// scanLogs converts the rows returned by q one at a time and hands them to fn.
+// Scanning stops at the first error returned by fn; errStopScan stops it without an error.
+func (l *LogReader) scanLogs(ctx context.Context, q cassandra.Query, fn func(*model.LogRecord) error) error {
+	it := newLogIterator(ctx, q)
+	for log, ok := it.Next(); ok; log, ok = it.Next() {
+		if err := fn(log); err != nil {
+			it.Close()
+			if errors.Is(err, errStopScan) {
+				return nil
+			}
+			return err
+		}
+	}
+	return it.Err()
+}
+
+// logIterator converts the rows returned by a query to the domain model one at a time.
+// The rows scanned are recorded in the stats of the context of the query when it is closed.
+type logIterator struct {
+	iter    cassandra.Iterator
+	stats   *logstore.QueryStats
+	scanned int
+	err     error
+	closed  bool
+}
+
+func newLogIterator(ctx context.Context, q cassandra.Query) *logIterator {
+	return &logIterator{iter: q.Iter(), stats: logstore.QueryStatsFromContext(ctx)}
+}
+
+// Next implements logstore.LogIterator#Next
+func (it *logIterator) Next() (*model.LogRecord, bool) {
+	if it.closed {
+		return nil, false
+	}
+	var timeUnixNano, observedTimeUnixNano uint64
+	var severityNumber uint32
+	var body, serviceName, methodName string
+	var attributes, serviceAttributes []dbmodel.KeyValue
+	// gocql reuses the buffer of a non-nil []byte destination, so they are fresh for every row
+	var traceID, spanID, logID []byte
+	if !it.iter.Scan(&severityNumber, &body, &timeUnixNano, &observedTimeUnixNano, &serviceName, &methodName, &serviceAttributes, &attributes, &traceID, &spanID, &logID) {
+		it.Close()
+		return nil, false
+	}
+	it.scanned++
+	logModel, err := dbmodel.ToDomain(&dbmodel.LogRecord{
+		SeverityNumber:       severityNumber,
+		Body:                 body,
+		TimeUnixNano:         timeUnixNano,
+		ObservedTimeUnixNano: observedTimeUnixNano,
+		ServiceName:          serviceName,
+		OperationName:        methodName,
+		ServiceAttributes:    serviceAttributes,
+		Attributes:           attributes,
+		TraceId:              traceID,
+		SpanId:               spanID,
+		LogID:                logID,
+	})
+	if err != nil {
+		it.Close()
+		it.err = err
+		return nil, false
+	}
+	return logModel, true
+}
+
+// Err implements logstore.LogIterator#Err
+func (it *logIterator) Err() error {
+	return it.err
+}
+
+// Close releases the query; the error of the query, if any, is kept for Err.
+func (it *logIterator) Close() {
+	if it.closed {
+		return
+	}
+	it.closed = true
+	it.stats.AddScanned(it.scanned)
+	if err := it.iter.Close(); err != nil {
+		it.err = fmt.Errorf("error reading logs from storage: %w", err)
+	}
+}
+
+// planStep describes a read of table for explain, with the arguments bound to the
+// placeholders of query.
+func planStep(table, query string, args ...interface{}) string {
+	var b strings.Builder
+	b.WriteString(table)
+	b.WriteString(": ")
+	stmt := strings.Join(strings.Fields(query), " ")
+	if i := strings.Index(stmt, " where "); i >= 0 {
+		stmt = stmt[i+len(" where "):]
+	}
+	for _, arg := range args {
+		i := strings.IndexByte(stmt, '?')
+		if i < 0 {
+			break
+		}
+		b.WriteString(stmt[:i])
+		if s, ok := arg.(string); ok {
+			b.WriteString(fmt.Sprintf("%q", s))
+		} else {
+			b.WriteString(fmt.Sprint(arg))
+		}
+		stmt = stmt[i+1:]
+	}
+	b.WriteString(stmt)
+	return b.String()
+}
+
+func contextFilter(p *logstore.LogContextParameters) func(*model.LogRecord) bool {
+	if p.Host == "" && len(p.TraceID) == 0 {
+		return nil
+	}
+	return func(log *model.LogRecord) bool {
+		if len(p.TraceID) != 0 && !bytes.Equal(log.TraceId, p.TraceID) {
+			return false
+		}
+		if p.Host != "" && hostName(log) != p.Host {
+			return false
+		}
+		return true
+	}
+}
+
+func hostName(log *model.LogRecord) string {
+	if log.Process == nil {
+		return ""
+	}
+	for _, attr := range log.Process.Attributes {
+		if attr.Key == hostNameAttribute {
+			return attr.Value.GetStringValue()
+		}
+	}
+	return ""
+}
+
+func sortByTime(logs []*model.LogRecord) {
+	sort.Slice(logs, func(i, j int) bool {
+		return logstore.CompareLogs(logs[i], logs[j]) < 0
+	})
+}
 
 func validateQuery(p *logstore.LogQueryParameters) error {
 	if p == nil {
@@ -136,9 +504,6 @@ func validateQuery(p *logstore.LogQueryParameters) error {
 	if p.ServiceName == "" {
 		return ErrServiceNameNotSet
 	}
-	if p.OperationName == "" {
-		return ErrOperationNameNotSet
-	}
 	if p.StartTimeMin.IsZero() || p.StartTimeMax.IsZero() {
 		return ErrStartAndEndTimeNotSet
 	}
@@ -148,6 +513,19 @@ func validateQuery(p *logstore.LogQueryParameters) error {
 	return nil
 }
 
+func validateContextQuery(p *logstore.LogContextParameters) error {
+	if p == nil {
+		return ErrMalformedRequestObject
+	}
+	if p.ServiceName == "" {
+		return ErrServiceNameNotSet
+	}
+	if p.Timestamp.IsZero() {
+		return ErrTimestampNotSet
+	}
+	return nil
+}
+
 // func (l *LogReader) buildQuery( p logstore.LogQueryParameters) string {
 // 	var partitionQuery string
 // 	// if p.ShouldFetchAll {
@@ -159,8 +537,8 @@ func validateQuery(p *logstore.LogQueryParameters) error {
 // 	// 	severityNumberQuery = fmt.Sprintf("and severity_number = %d",p.SeverityNumber)
 // 	// }
 // 	query := fmt.Sprintf(`SELECT severity_number,body, start_time, observed_time_unix_nano,service_name,
-// 	operation_name,service_attributes,attributes FROM logs where %s 
+// 	operation_name,service_attributes,attributes FROM logs where %s
 // 	start_time > ? AND start_time < ? limit ?`,partitionQuery)
 // 	fmt.Println("QUERY BUILDER",query)
 // 	return query
-// }
\ No newline at end of file
+// }
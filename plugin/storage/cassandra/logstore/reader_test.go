@@ -0,0 +1,289 @@
+package logstore
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"logger/model"
+	"logger/pkg/cassandra"
+	"logger/plugin/storage/cassandra/logstore/dbmodel"
+	"logger/storage/logstore"
+)
+
+// fakeSession answers the queries with the rows returned by rows for their statement and
+// arguments, and records the queries it was given.
+type fakeSession struct {
+	rows    func(stmt string, args []interface{}) [][]interface{}
+	err     error
+	queries []*fakeQuery
+}
+
+func (s *fakeSession) Query(stmt string, values ...interface{}) cassandra.Query {
+	q := &fakeQuery{session: s, stmt: stmt, args: values}
+	s.queries = append(s.queries, q)
+	return q
+}
+
+func (s *fakeSession) Close() {}
+
+type fakeQuery struct {
+	session *fakeSession
+	stmt    string
+	args    []interface{}
+	ctx     context.Context
+}
+
+func (q *fakeQuery) Exec() error                                       { return nil }
+func (q *fakeQuery) String() string                                    { return q.stmt }
+func (q *fakeQuery) ScanCAS(...interface{}) (bool, error)              { return false, nil }
+func (q *fakeQuery) Bind(v ...interface{}) cassandra.Query             { q.args = v; return q }
+func (q *fakeQuery) Consistency(cassandra.Consistency) cassandra.Query { return q }
+func (q *fakeQuery) PageSize(int) cassandra.Query                      { return q }
+
+func (q *fakeQuery) WithContext(ctx context.Context) cassandra.Query {
+	q.ctx = ctx
+	return q
+}
+
+func (q *fakeQuery) Iter() cassandra.Iterator {
+	var rows [][]interface{}
+	if q.session.rows != nil {
+		rows = q.session.rows(q.stmt, q.args)
+	}
+	return &fakeIterator{rows: rows, err: q.session.err}
+}
+
+type fakeIterator struct {
+	rows [][]interface{}
+	err  error
+}
+
+func (it *fakeIterator) Scan(dest ...interface{}) bool {
+	if len(it.rows) == 0 {
+		return false
+	}
+	for i, value := range it.rows[0] {
+		if value != nil {
+			reflect.ValueOf(dest[i]).Elem().Set(reflect.ValueOf(value))
+		}
+	}
+	it.rows = it.rows[1:]
+	return true
+}
+
+func (it *fakeIterator) Close() error {
+	return it.err
+}
+
+var anchor = time.Date(2024, time.March, 10, 12, 0, 0, 0, time.UTC)
+
+// row returns the columns read by the log queries for a record of operation at anchor+offset.
+func row(offset time.Duration, operation, host string, traceID []byte) []interface{} {
+	var serviceAttributes []dbmodel.KeyValue
+	if host != "" {
+		serviceAttributes = []dbmodel.KeyValue{{Key: hostNameAttribute, ValueType: model.STRING_TYPE, ValueString: host}}
+	}
+	return []interface{}{
+		uint32(9), operation + " " + offset.String(), uint64(anchor.Add(offset).UnixNano()), uint64(anchor.Add(offset).UnixNano()),
+		"svc", operation, serviceAttributes, []dbmodel.KeyValue(nil), traceID, []byte(nil), []byte(nil),
+	}
+}
+
+func newTestReader(session *fakeSession, operations ...string) *LogReader {
+	return &LogReader{
+		session: session,
+		logger:  zap.NewNop(),
+		operationNamesReader: func(context.Context, logstore.OperationQueryParameters) ([]logstore.Operation, error) {
+			res := make([]logstore.Operation, len(operations))
+			for i, op := range operations {
+				res[i] = logstore.Operation{Name: op}
+			}
+			return res, nil
+		},
+	}
+}
+
+func bodies(logs []*model.LogRecord) []string {
+	res := make([]string, len(logs))
+	for i, log := range logs {
+		res[i] = log.Body
+	}
+	return res
+}
+
+func TestGetLogContext(t *testing.T) {
+	session := &fakeSession{rows: func(stmt string, args []interface{}) [][]interface{} {
+		op := args[1].(string)
+		if stmt == queryLogsBefore {
+			return [][]interface{}{row(-time.Second, op, "", nil), row(-3*time.Second, op, "", nil)}
+		}
+		return [][]interface{}{row(0, op, "", nil), row(2*time.Second, op, "", nil)}
+	}}
+	r := newTestReader(session, "a", "b")
+
+	res, err := r.GetLogContext(context.Background(), logstore.LogContextParameters{ServiceName: "svc", Timestamp: anchor, Before: 3, After: 1})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"b -3s", "a -1s", "b -1s"}, bodies(res.Before))
+	assert.Equal(t, []string{"a 0s", "b 0s"}, bodies(res.Anchor))
+	assert.Equal(t, []string{"a 2s"}, bodies(res.After))
+
+	require.Len(t, session.queries, 4)
+	for _, q := range session.queries {
+		assert.NotNil(t, q.ctx)
+	}
+	// without a filter, the limits are pushed to the storage
+	assert.Equal(t, []interface{}{"svc", "a", model.TimeAsEpochMicroseconds(anchor), 3}, session.queries[0].args)
+	assert.Equal(t, []interface{}{"svc", "a", model.TimeAsEpochMicroseconds(anchor), 2}, session.queries[1].args)
+}
+
+func TestGetLogContextFilters(t *testing.T) {
+	traceID := []byte{1, 2}
+	session := &fakeSession{rows: func(stmt string, args []interface{}) [][]interface{} {
+		if stmt == queryLogsBefore {
+			return [][]interface{}{row(-time.Second, "a", "web-2", traceID), row(-2*time.Second, "a", "web-1", nil), row(-3*time.Second, "a", "web-1", traceID)}
+		}
+		return [][]interface{}{row(0, "a", "web-1", traceID), row(time.Second, "a", "web-1", traceID)}
+	}}
+	r := newTestReader(session)
+
+	res, err := r.GetLogContext(context.Background(), logstore.LogContextParameters{
+		ServiceName: "svc", OperationName: "a", Timestamp: anchor, Host: "web-1", TraceID: traceID,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a -3s"}, bodies(res.Before))
+	assert.Equal(t, []string{"a 0s"}, bodies(res.Anchor))
+	assert.Equal(t, []string{"a 1s"}, bodies(res.After))
+	// with a filter, enough rows are read to find the matches
+	require.Len(t, session.queries, 2)
+	assert.Equal(t, maxContextScan, session.queries[0].args[3])
+}
+
+func TestGetLogContextErrors(t *testing.T) {
+	r := newTestReader(&fakeSession{err: errors.New("unavailable")}, "a")
+	_, err := r.GetLogContext(context.Background(), logstore.LogContextParameters{Timestamp: anchor})
+	assert.ErrorIs(t, err, ErrServiceNameNotSet)
+	_, err = r.GetLogContext(context.Background(), logstore.LogContextParameters{ServiceName: "svc"})
+	assert.ErrorIs(t, err, ErrTimestampNotSet)
+	_, err = r.GetLogContext(context.Background(), logstore.LogContextParameters{ServiceName: "svc", Timestamp: anchor})
+	assert.ErrorContains(t, err, "unavailable")
+}
+
+func TestStreamLogsByTrace(t *testing.T) {
+	traceID := []byte{1, 2}
+	session := &fakeSession{rows: func(stmt string, args []interface{}) [][]interface{} {
+		require.Equal(t, queryLogsByTrace, stmt)
+		require.Equal(t, traceID, args[0])
+		return [][]interface{}{row(0, "a", "", traceID), row(time.Second, "b", "", traceID), row(2*time.Second, "c", "", traceID)}
+	}}
+	r := newTestReader(session)
+
+	var logs []*model.LogRecord
+	err := r.StreamLogsByTrace(context.Background(), traceID, func(log *model.LogRecord) error {
+		logs = append(logs, log)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a 0s", "b 1s", "c 2s"}, bodies(logs))
+	assert.Equal(t, traceID, logs[0].TraceId)
+
+	// the error of the callback stops the scan
+	stop := errors.New("stop")
+	logs = nil
+	err = r.StreamLogsByTrace(context.Background(), traceID, func(log *model.LogRecord) error {
+		logs = append(logs, log)
+		return stop
+	})
+	assert.ErrorIs(t, err, stop)
+	assert.Len(t, logs, 1)
+
+	err = r.StreamLogsByTrace(context.Background(), nil, nil)
+	assert.ErrorIs(t, err, ErrTraceIDNotSet)
+}
+
+func TestGetLog(t *testing.T) {
+	id := model.NewLogID(anchor)
+	found := true
+	session := &fakeSession{rows: func(stmt string, args []interface{}) [][]interface{} {
+		require.Equal(t, queryLogByID, stmt)
+		if !found {
+			return nil
+		}
+		columns := row(0, "a", "", nil)
+		columns[10] = id[:]
+		return [][]interface{}{columns}
+	}}
+	r := newTestReader(session)
+
+	log, err := r.GetLog(context.Background(), id)
+	require.NoError(t, err)
+	assert.Equal(t, id, log.ID)
+	assert.Equal(t, "svc", log.Process.ServiceName)
+
+	found = false
+	_, err = r.GetLog(context.Background(), id)
+	assert.ErrorIs(t, err, logstore.ErrLogNotFound)
+
+	_, err = r.GetLog(context.Background(), model.LogID{})
+	assert.ErrorIs(t, err, ErrLogIDNotSet)
+}
+
+func TestGetServicesAndOperations(t *testing.T) {
+	session := &fakeSession{rows: func(stmt string, args []interface{}) [][]interface{} {
+		if stmt == queryServiceNames {
+			return [][]interface{}{{"svc"}}
+		}
+		return [][]interface{}{{"", "op"}}
+	}}
+	r := NewLogReader(session, zap.NewNop())
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	services, err := r.GetServices(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"svc"}, services)
+	operations, err := r.GetOperations(ctx, logstore.OperationQueryParameters{ServiceName: "svc"})
+	require.NoError(t, err)
+	assert.Equal(t, []logstore.Operation{{Name: "op"}}, operations)
+
+	// the queries are interrupted with the request
+	last := len(session.queries) - 1
+	assert.Equal(t, ctx, session.queries[last-1].ctx)
+	assert.Equal(t, ctx, session.queries[last].ctx)
+}
+
+func TestGetLogContextByIDStats(t *testing.T) {
+	id := model.NewLogID(anchor)
+	session := &fakeSession{rows: func(stmt string, args []interface{}) [][]interface{} {
+		switch stmt {
+		case queryLogByID:
+			return [][]interface{}{row(0, "a", "", nil)}
+		case queryLogsBefore:
+			return [][]interface{}{row(-time.Second, args[1].(string), "", nil)}
+		default:
+			return [][]interface{}{row(0, args[1].(string), "", nil)}
+		}
+	}}
+	r := newTestReader(session, "a", "b")
+	stats := logstore.NewQueryStats()
+	ctx := logstore.WithQueryStats(context.Background(), stats)
+
+	// the query service locates the anchor by ID, then reads its context
+	log, err := r.GetLog(ctx, id)
+	require.NoError(t, err)
+	res, err := r.GetLogContext(ctx, logstore.LogContextParameters{ServiceName: "svc", Timestamp: time.Unix(0, int64(log.TimeUnixNano))})
+	require.NoError(t, err)
+
+	explain := stats.Explain()
+	// the logs_by_id partition and one logs partition per operation
+	assert.Equal(t, 3, explain.PartitionsTouched)
+	// the anchor is counted once, as part of the context
+	assert.Equal(t, len(res.Before)+len(res.Anchor)+len(res.After), explain.RowsReturned)
+	assert.Equal(t, 4, explain.RowsReturned)
+}
@@ -16,6 +16,7 @@
 package logstore
 
 import (
+	"context"
 	"fmt"
 	"time"
 
@@ -95,8 +96,8 @@ func checkWriteCache(key string, c cache.Cache, writeCacheTTL time.Duration) boo
 }
 
 // GetServices returns all services traced by Jaeger
-func (s *ServiceNamesStorage) GetServices() ([]string, error) {
-	iter := s.session.Query(s.QueryStmt).Iter()
+func (s *ServiceNamesStorage) GetServices(ctx context.Context) ([]string, error) {
+	iter := s.session.Query(s.QueryStmt).WithContext(ctx).Iter()
 
 	var service string
 	var services []string
@@ -20,8 +20,18 @@ import (
 const (
 	insertLog = `
 		INSERT
-		INTO logs(start_time,severity_number,body,observed_time_unix_nano,service_name,operation_name,service_attributes,attributes)
-		VALUES (?, ?, ?,?,?,?,?,?)`
+		INTO logs(start_time,severity_number,body,observed_time_unix_nano,service_name,operation_name,service_attributes,attributes,trace_id,span_id,log_id)
+		VALUES (?, ?, ?,?,?,?,?,?,?,?,?)`
+
+	insertLogByTrace = `
+		INSERT
+		INTO logs_by_trace(trace_id,start_time,severity_number,body,observed_time_unix_nano,service_name,operation_name,service_attributes,attributes,span_id,log_id)
+		VALUES (?, ?, ?,?,?,?,?,?,?,?,?)`
+
+	insertLogByID = `
+		INSERT
+		INTO logs_by_id(log_id,start_time,severity_number,body,observed_time_unix_nano,service_name,operation_name,service_attributes,attributes,trace_id,span_id)
+		VALUES (?, ?, ?,?,?,?,?,?,?,?,?)`
 
 	serviceNameIndex = `
 		INSERT
@@ -58,8 +68,8 @@ const (
 )
 
 type (
-	storageMode        uint8
-	serviceNamesWriter func(serviceName string) error
+	storageMode          uint8
+	serviceNamesWriter   func(serviceName string) error
 	operationNamesWriter func(operation dbmodel.Operation) error
 )
 
@@ -69,15 +79,17 @@ type spanWriterMetrics struct {
 	serviceNameIndex      *casMetrics.Table
 	serviceOperationIndex *casMetrics.Table
 	durationIndex         *casMetrics.Table
+	logsByTrace           *casMetrics.Table
+	logsByID              *casMetrics.Table
 }
 
 // LogWriter handles all writes to Cassandra for the Jaeger data model
 type LogWriter struct {
-	session            cassandra.Session
-	serviceNamesWriter serviceNamesWriter
+	session              cassandra.Session
+	serviceNamesWriter   serviceNamesWriter
 	operationNamesWriter operationNamesWriter
-	writerMetrics spanWriterMetrics
-	logger        *zap.Logger
+	writerMetrics        spanWriterMetrics
+	logger               *zap.Logger
 	// tagIndexSkipped      metrics.Counter
 	// tagFilter            dbmodel.TagFilter
 	storageMode storageMode
@@ -97,8 +109,8 @@ func NewLogWriter(
 	// tagIndexSkipped := metricsFactory.Counter(metrics.Options{Name: "tag_index_skipped", Tags: nil})
 	opts := applyOptions(options...)
 	return &LogWriter{
-		session:            session,
-		serviceNamesWriter: serviceNamesStorage.Write,
+		session:              session,
+		serviceNamesWriter:   serviceNamesStorage.Write,
 		operationNamesWriter: operationNamesStorage.Write,
 		writerMetrics: spanWriterMetrics{
 			traces:                casMetrics.NewTable(metricsFactory, "traces"),
@@ -106,6 +118,8 @@ func NewLogWriter(
 			serviceNameIndex:      casMetrics.NewTable(metricsFactory, "service_name_index"),
 			serviceOperationIndex: casMetrics.NewTable(metricsFactory, "service_operation_index"),
 			durationIndex:         casMetrics.NewTable(metricsFactory, "duration_index"),
+			logsByTrace:           casMetrics.NewTable(metricsFactory, "logs_by_trace"),
+			logsByID:              casMetrics.NewTable(metricsFactory, "logs_by_id"),
 		},
 		logger: logger,
 		// tagIndexSkipped: tagIndexSkipped,
@@ -161,6 +175,9 @@ func (s *LogWriter) writeSpan(log *model.LogRecord, ds *dbmodel.LogRecord) error
 		ds.OperationName,
 		ds.ServiceAttributes,
 		ds.Attributes,
+		ds.TraceId,
+		ds.SpanId,
+		ds.LogID,
 		// log.Process,
 	)
 	// mainQuery := s.session.Query(
@@ -202,6 +219,18 @@ func (s *LogWriter) writeIndexes(span *model.LogRecord, ds *dbmodel.LogRecord) e
 		return s.logError(ds, err, "Failed to insert service name and operation name", s.logger)
 	}
 
+	if len(ds.TraceId) > 0 {
+		if err := s.indexByTrace(ds); err != nil {
+			return s.logError(ds, err, "Failed to index log by trace", s.logger)
+		}
+	}
+
+	if len(ds.LogID) > 0 {
+		if err := s.indexByID(ds); err != nil {
+			return s.logError(ds, err, "Failed to index log by id", s.logger)
+		}
+	}
+
 	// if s.indexFilter(ds, dbmodel.ServiceIndex) {
 	// 	if err := s.indexByService(ds); err != nil {
 	// 		return s.logError(ds, err, "Failed to index service name", s.logger)
@@ -230,6 +259,42 @@ func (s *LogWriter) writeIndexes(span *model.LogRecord, ds *dbmodel.LogRecord) e
 	return nil
 }
 
+func (s *LogWriter) indexByTrace(ds *dbmodel.LogRecord) error {
+	q := s.session.Query(
+		insertLogByTrace,
+		ds.TraceId,
+		ds.TimeUnixNano,
+		ds.SeverityNumber,
+		ds.Body,
+		ds.ObservedTimeUnixNano,
+		ds.ServiceName,
+		ds.OperationName,
+		ds.ServiceAttributes,
+		ds.Attributes,
+		ds.SpanId,
+		ds.LogID,
+	)
+	return s.writerMetrics.logsByTrace.Exec(q, s.logger)
+}
+
+func (s *LogWriter) indexByID(ds *dbmodel.LogRecord) error {
+	q := s.session.Query(
+		insertLogByID,
+		ds.LogID,
+		ds.TimeUnixNano,
+		ds.SeverityNumber,
+		ds.Body,
+		ds.ObservedTimeUnixNano,
+		ds.ServiceName,
+		ds.OperationName,
+		ds.ServiceAttributes,
+		ds.Attributes,
+		ds.TraceId,
+		ds.SpanId,
+	)
+	return s.writerMetrics.logsByID.Exec(q, s.logger)
+}
+
 func (s *LogWriter) indexByTags(span *model.LogRecord, ds *dbmodel.LogRecord) error {
 	// for _, v := range dbmodel.GetAllUniqueTags(span, s.tagFilter) {
 	// 	// we should introduce retries or just ignore failures imo, retrying each individual tag insertion might be better
@@ -0,0 +1,166 @@
+// Package savedstore stores the saved searches in Cassandra, in the saved_searches
+// table partitioned by tenant.
+package savedstore
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"logger/pkg/cassandra"
+	"logger/pkg/tenancy"
+	"logger/storage/savedstore"
+)
+
+const (
+	insertSavedSearch = `INSERT INTO saved_searches(tenant, id, name, owner, description, query, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+	updateSavedSearch = `UPDATE saved_searches SET name = ?, owner = ?, description = ?, query = ?, created_at = ?, updated_at = ?
+		WHERE tenant = ? AND id = ? IF EXISTS`
+	deleteSavedSearch  = `DELETE FROM saved_searches WHERE tenant = ? AND id = ? IF EXISTS`
+	querySavedSearch   = `SELECT id, name, owner, description, query, created_at, updated_at FROM saved_searches WHERE tenant = ? AND id = ?`
+	querySavedSearches = `SELECT id, name, owner, description, query, created_at, updated_at FROM saved_searches WHERE tenant = ?`
+	checkSavedSearches = `SELECT id FROM saved_searches LIMIT 1`
+)
+
+var _ savedstore.Store = (*Store)(nil)
+
+// Store implements savedstore.Store on Cassandra. The query of a search is stored as JSON,
+// so that new query parameters do not require a schema change.
+type Store struct {
+	session cassandra.Session
+}
+
+// NewStore returns a Store using session.
+func NewStore(session cassandra.Session) *Store {
+	return &Store{session: session}
+}
+
+// CheckTable returns an error when the saved_searches table cannot be read, as in a keyspace
+// created before the v005 schema.
+func (s *Store) CheckTable() error {
+	if err := s.session.Query(checkSavedSearches).Exec(); err != nil {
+		return fmt.Errorf("cannot read the saved_searches table, upgrade the keyspace to the v005 schema "+
+			"with plugin/storage/cassandra/schema/migration/v004tov005.sh: %w", err)
+	}
+	return nil
+}
+
+// Create implements savedstore.Store#Create
+func (s *Store) Create(ctx context.Context, search *savedstore.SavedSearch) error {
+	query, err := json.Marshal(search.Query)
+	if err != nil {
+		return err
+	}
+	err = s.session.Query(insertSavedSearch,
+		tenancy.GetTenant(ctx),
+		search.ID,
+		search.Name,
+		search.Owner,
+		search.Description,
+		string(query),
+		search.CreatedAt.UnixNano(),
+		search.UpdatedAt.UnixNano(),
+	).WithContext(ctx).Exec()
+	if err != nil {
+		return fmt.Errorf("failed to insert saved search: %w", err)
+	}
+	return nil
+}
+
+// Get implements savedstore.Store#Get
+func (s *Store) Get(ctx context.Context, id string) (*savedstore.SavedSearch, error) {
+	var res *savedstore.SavedSearch
+	err := s.scan(s.session.Query(querySavedSearch, tenancy.GetTenant(ctx), id).WithContext(ctx), func(search *savedstore.SavedSearch) {
+		res = search
+	})
+	if err != nil {
+		return nil, err
+	}
+	if res == nil {
+		return nil, savedstore.ErrNotFound
+	}
+	return res, nil
+}
+
+// List implements savedstore.Store#List
+func (s *Store) List(ctx context.Context) ([]*savedstore.SavedSearch, error) {
+	res := make([]*savedstore.SavedSearch, 0)
+	err := s.scan(s.session.Query(querySavedSearches, tenancy.GetTenant(ctx)).WithContext(ctx), func(search *savedstore.SavedSearch) {
+		res = append(res, search)
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Name != res[j].Name {
+			return res[i].Name < res[j].Name
+		}
+		return res[i].ID < res[j].ID
+	})
+	return res, nil
+}
+
+// Update implements savedstore.Store#Update
+func (s *Store) Update(ctx context.Context, search *savedstore.SavedSearch) error {
+	query, err := json.Marshal(search.Query)
+	if err != nil {
+		return err
+	}
+	applied, err := s.session.Query(updateSavedSearch,
+		search.Name,
+		search.Owner,
+		search.Description,
+		string(query),
+		search.CreatedAt.UnixNano(),
+		search.UpdatedAt.UnixNano(),
+		tenancy.GetTenant(ctx),
+		search.ID,
+	).WithContext(ctx).ScanCAS()
+	if err != nil {
+		return fmt.Errorf("failed to update saved search: %w", err)
+	}
+	if !applied {
+		return savedstore.ErrNotFound
+	}
+	return nil
+}
+
+// Delete implements savedstore.Store#Delete
+func (s *Store) Delete(ctx context.Context, id string) error {
+	applied, err := s.session.Query(deleteSavedSearch, tenancy.GetTenant(ctx), id).WithContext(ctx).ScanCAS()
+	if err != nil {
+		return fmt.Errorf("failed to delete saved search: %w", err)
+	}
+	if !applied {
+		return savedstore.ErrNotFound
+	}
+	return nil
+}
+
+func (s *Store) scan(q cassandra.Query, fn func(*savedstore.SavedSearch)) error {
+	iter := q.Iter()
+	var id, name, owner, description, query string
+	var createdAt, updatedAt int64
+	for iter.Scan(&id, &name, &owner, &description, &query, &createdAt, &updatedAt) {
+		search := &savedstore.SavedSearch{
+			ID:          id,
+			Name:        name,
+			Owner:       owner,
+			Description: description,
+			CreatedAt:   time.Unix(0, createdAt).UTC(),
+			UpdatedAt:   time.Unix(0, updatedAt).UTC(),
+		}
+		if err := json.Unmarshal([]byte(query), &search.Query); err != nil {
+			iter.Close()
+			return fmt.Errorf("cannot parse the query of saved search %s: %w", id, err)
+		}
+		fn(search)
+	}
+	if err := iter.Close(); err != nil {
+		return fmt.Errorf("error reading saved searches from storage: %w", err)
+	}
+	return nil
+}
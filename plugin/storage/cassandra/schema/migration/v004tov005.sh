@@ -0,0 +1,172 @@
+#!/usr/bin/env bash
+
+# Recreate the logs table with the trace and log id columns and log_id as its last clustering
+# column, and create the logs_by_trace, logs_by_id and saved_searches tables of the v005 schema.
+# The rows of the logs table are exported to logs.csv and imported back with an empty log_id.
+# Sample usage: KEYSPACE=jaeger_v1 CQL_CMD='cqlsh host 9042 -u test_user -p test_password --request-timeout=3000' bash
+# ./v004tov005.sh
+
+set -euo pipefail
+
+function usage {
+    >&2 echo "Error: $1"
+    >&2 echo ""
+    >&2 echo "Usage: KEYSPACE={keyspace} CQL_CMD={cql_cmd} $0"
+    >&2 echo ""
+    >&2 echo "The following parameters can be set via environment:"
+    >&2 echo "  KEYSPACE           - keyspace"
+    >&2 echo "  CQL_CMD            - cqlsh host port -u user -p password"
+    >&2 echo ""
+    exit 1
+}
+
+confirm() {
+    read -r -p "${1:-Continue? [y/N]} " response
+    case "$response" in
+        [yY][eE][sS]|[yY])
+            true
+            ;;
+        *)
+            exit 1
+            ;;
+    esac
+}
+
+if [[ ${KEYSPACE:-} == "" ]]; then
+   usage "missing KEYSPACE parameter"
+fi
+
+if [[ ${KEYSPACE} =~ [^a-zA-Z0-9_] ]]; then
+    usage "invalid characters in KEYSPACE=$KEYSPACE parameter, please use letters, digits or underscores"
+fi
+
+keyspace=${KEYSPACE}
+cqlsh_cmd=${CQL_CMD:-}
+
+if [[ ${cqlsh_cmd} == "" ]]; then
+   cqlsh_cmd=cqlsh
+fi
+
+echo "Using cql command: $cqlsh_cmd"
+
+# the new log tables expire and compact like the logs table they copy
+ttl=$(${cqlsh_cmd} -e "select default_time_to_live from system_schema.tables WHERE keyspace_name='$keyspace' AND table_name='logs';"|head -4|tail -1|tr -d ' ')
+compaction_window_size=$(${cqlsh_cmd} -e "select compaction['compaction_window_size'] from system_schema.tables WHERE keyspace_name='$keyspace' AND table_name='logs';"|head -4|tail -1|tr -d ' ')
+compaction_window_unit=$(${cqlsh_cmd} -e "select compaction['compaction_window_unit'] from system_schema.tables WHERE keyspace_name='$keyspace' AND table_name='logs';"|head -4|tail -1|tr -d ' ')
+
+row_count=$(${cqlsh_cmd} -e "select count(*) from $keyspace.logs;"|head -4|tail -1| tr -d ' ')
+
+echo "About to copy $row_count rows of $keyspace.logs to logs.csv, recreate it with trace_id, span_id and log_id and create logs_by_trace and logs_by_id with ttl: $ttl, compaction window: $compaction_window_size $compaction_window_unit"
+
+confirm
+
+columns="severity_number, body, start_time, observed_time_unix_nano, attributes, service_name, operation_name, service_attributes"
+
+${cqlsh_cmd} -e "COPY $keyspace.logs ($columns) to 'logs.csv';"
+
+if [[ ! -f logs.csv ]]; then
+    echo "Could not find logs.csv. Backup from cassandra was probably not successful"
+    exit 1
+fi
+
+echo "Generating data for the new logs table..."
+# a body may span several lines, a record ends on a line that closes all its quotes
+awk '{ record = record $0; quotes += gsub(/"/, "&") } quotes % 2 == 0 { print record ",0x"; record = ""; quotes = 0; next } { record = record "\n" }' logs.csv > logs_v005.csv
+
+echo "Before recreating it, do you want to delete the table: $keyspace.logs? logs.csv is kept as a backup"
+confirm
+${cqlsh_cmd} -e "DROP TABLE IF EXISTS $keyspace.logs;"
+
+${cqlsh_cmd} -e "CREATE TABLE IF NOT EXISTS $keyspace.logs (
+    severity_number           int,
+    body text,
+    start_time      bigint,
+    observed_time_unix_nano        bigint,
+    attributes            list<frozen<attribute>>,
+    service_name text,
+    operation_name text,
+    service_attributes list<frozen<attribute>>,
+    trace_id blob,
+    span_id blob,
+    log_id blob,
+    PRIMARY KEY ((service_name,operation_name),start_time,severity_number,log_id)
+) WITH CLUSTERING ORDER BY (start_time DESC)
+    AND compaction = {
+        'compaction_window_size': '$compaction_window_size',
+        'compaction_window_unit': '$compaction_window_unit',
+        'class': 'org.apache.cassandra.db.compaction.TimeWindowCompactionStrategy'
+    }
+    AND default_time_to_live = $ttl
+    AND speculative_retry = 'NONE'
+    AND gc_grace_seconds = 10800;"
+
+echo "Import data to new table: $keyspace.logs from logs_v005.csv"
+
+${cqlsh_cmd} -e "COPY $keyspace.logs ($columns, log_id) FROM 'logs_v005.csv';"
+
+${cqlsh_cmd} -e "CREATE TABLE IF NOT EXISTS $keyspace.logs_by_trace (
+    trace_id blob,
+    start_time      bigint,
+    severity_number           int,
+    body text,
+    observed_time_unix_nano        bigint,
+    attributes            list<frozen<attribute>>,
+    service_name text,
+    operation_name text,
+    service_attributes list<frozen<attribute>>,
+    span_id blob,
+    log_id blob,
+    PRIMARY KEY ((trace_id),start_time,service_name,operation_name,severity_number,log_id)
+) WITH CLUSTERING ORDER BY (start_time ASC)
+    AND compaction = {
+        'compaction_window_size': '$compaction_window_size',
+        'compaction_window_unit': '$compaction_window_unit',
+        'class': 'org.apache.cassandra.db.compaction.TimeWindowCompactionStrategy'
+    }
+    AND default_time_to_live = $ttl
+    AND speculative_retry = 'NONE'
+    AND gc_grace_seconds = 10800;"
+
+${cqlsh_cmd} -e "CREATE TABLE IF NOT EXISTS $keyspace.logs_by_id (
+    log_id blob,
+    start_time      bigint,
+    severity_number           int,
+    body text,
+    observed_time_unix_nano        bigint,
+    attributes            list<frozen<attribute>>,
+    service_name text,
+    operation_name text,
+    service_attributes list<frozen<attribute>>,
+    trace_id blob,
+    span_id blob,
+    PRIMARY KEY (log_id)
+)
+    WITH compaction = {
+        'compaction_window_size': '$compaction_window_size',
+        'compaction_window_unit': '$compaction_window_unit',
+        'class': 'org.apache.cassandra.db.compaction.TimeWindowCompactionStrategy'
+    }
+    AND default_time_to_live = $ttl
+    AND speculative_retry = 'NONE'
+    AND gc_grace_seconds = 10800;"
+
+${cqlsh_cmd} -e "CREATE TABLE IF NOT EXISTS $keyspace.saved_searches (
+    tenant          text,
+    id              text,
+    name            text,
+    owner           text,
+    description     text,
+    query           text,
+    created_at      bigint,
+    updated_at      bigint,
+    PRIMARY KEY ((tenant), id)
+)
+    WITH compaction = {
+        'min_threshold': '4',
+        'max_threshold': '32',
+        'class': 'org.apache.cassandra.db.compaction.SizeTieredCompactionStrategy'
+    }
+    AND speculative_retry = 'NONE'
+    AND gc_grace_seconds = 10800;"
+
+echo "Keyspace $keyspace is migrated to v005. The logs written before the migration have no trace or log id, so they are not found by trace or by id, and their time to live restarted at the import."
@@ -14,6 +14,7 @@ import (
 	"logger/plugin/storage/cassandra"
 	"logger/storage/logstore"
 	ls "logger/storage/logstore"
+	"logger/storage/savedstore"
 )
 
 const (
@@ -31,6 +32,7 @@ var AllStorageTypes = []string{
 
 var ( // interface comformance checks
 	_ storage.FactoryBase = (*Factory)(nil)
+	_ storage.SavedSearchStoreFactory = (*Factory)(nil)
 	// _ storage.ArchiveFactory = (*Factory)(nil)
 	_ io.Closer           = (*Factory)(nil)
 	_ plugin.Configurable = (*Factory)(nil)
@@ -144,6 +146,20 @@ func (f *Factory) CreateLogWriter() (ls.Writer, error) {
 	// }), nil
 }
 
+// CreateSavedSearchStore implements storage.SavedSearchStoreFactory, storing the saved
+// searches in the backend the logs are read from.
+func (f *Factory) CreateSavedSearchStore() (savedstore.Store, error) {
+	factory, ok := f.factories[f.LogReaderType]
+	if !ok {
+		return nil, fmt.Errorf("no %s backend registered for saved search store", f.LogReaderType)
+	}
+	ssFactory, ok := factory.(storage.SavedSearchStoreFactory)
+	if !ok {
+		return nil, fmt.Errorf("%s backend cannot store saved searches", f.LogReaderType)
+	}
+	return ssFactory.CreateSavedSearchStore()
+}
+
 func (f *Factory) AddFlags(flagSet *flag.FlagSet) {
 }
 
@@ -0,0 +1,95 @@
+// Package memory provides in-memory implementations of the stores, which do not
+// survive a restart and are not shared between instances.
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"logger/pkg/tenancy"
+	"logger/storage/savedstore"
+)
+
+var _ savedstore.Store = (*SavedSearchStore)(nil)
+
+// SavedSearchStore keeps the saved searches of every tenant in memory.
+type SavedSearchStore struct {
+	mu       sync.RWMutex
+	searches map[string]map[string]savedstore.SavedSearch
+}
+
+// NewSavedSearchStore returns an empty SavedSearchStore.
+func NewSavedSearchStore() *SavedSearchStore {
+	return &SavedSearchStore{
+		searches: make(map[string]map[string]savedstore.SavedSearch),
+	}
+}
+
+// Create implements savedstore.Store#Create
+func (s *SavedSearchStore) Create(ctx context.Context, search *savedstore.SavedSearch) error {
+	tenant := tenancy.GetTenant(ctx)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	searches, ok := s.searches[tenant]
+	if !ok {
+		searches = make(map[string]savedstore.SavedSearch)
+		s.searches[tenant] = searches
+	}
+	searches[search.ID] = *search
+	return nil
+}
+
+// Get implements savedstore.Store#Get
+func (s *SavedSearchStore) Get(ctx context.Context, id string) (*savedstore.SavedSearch, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	search, ok := s.searches[tenancy.GetTenant(ctx)][id]
+	if !ok {
+		return nil, savedstore.ErrNotFound
+	}
+	return &search, nil
+}
+
+// List implements savedstore.Store#List
+func (s *SavedSearchStore) List(ctx context.Context) ([]*savedstore.SavedSearch, error) {
+	s.mu.RLock()
+	searches := s.searches[tenancy.GetTenant(ctx)]
+	res := make([]*savedstore.SavedSearch, 0, len(searches))
+	for _, search := range searches {
+		search := search
+		res = append(res, &search)
+	}
+	s.mu.RUnlock()
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Name != res[j].Name {
+			return res[i].Name < res[j].Name
+		}
+		return res[i].ID < res[j].ID
+	})
+	return res, nil
+}
+
+// Update implements savedstore.Store#Update
+func (s *SavedSearchStore) Update(ctx context.Context, search *savedstore.SavedSearch) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	searches := s.searches[tenancy.GetTenant(ctx)]
+	if _, ok := searches[search.ID]; !ok {
+		return savedstore.ErrNotFound
+	}
+	searches[search.ID] = *search
+	return nil
+}
+
+// Delete implements savedstore.Store#Delete
+func (s *SavedSearchStore) Delete(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	searches := s.searches[tenancy.GetTenant(ctx)]
+	if _, ok := searches[id]; !ok {
+		return savedstore.ErrNotFound
+	}
+	delete(searches, id)
+	return nil
+}
@@ -0,0 +1,60 @@
+package memory
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"logger/pkg/tenancy"
+	"logger/storage/savedstore"
+)
+
+func TestSavedSearchStore(t *testing.T) {
+	store := NewSavedSearchStore()
+	ctx := tenancy.WithTenant(context.Background(), "acme")
+
+	require.NoError(t, store.Create(ctx, &savedstore.SavedSearch{ID: "2", Name: "b"}))
+	require.NoError(t, store.Create(ctx, &savedstore.SavedSearch{ID: "1", Name: "a"}))
+
+	search, err := store.Get(ctx, "1")
+	require.NoError(t, err)
+	assert.Equal(t, "a", search.Name)
+
+	searches, err := store.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, searches, 2)
+	assert.Equal(t, "1", searches[0].ID)
+	assert.Equal(t, "2", searches[1].ID)
+
+	require.NoError(t, store.Update(ctx, &savedstore.SavedSearch{ID: "1", Name: "c"}))
+	search, err = store.Get(ctx, "1")
+	require.NoError(t, err)
+	assert.Equal(t, "c", search.Name)
+
+	require.NoError(t, store.Delete(ctx, "1"))
+	_, err = store.Get(ctx, "1")
+	assert.ErrorIs(t, err, savedstore.ErrNotFound)
+	assert.ErrorIs(t, store.Delete(ctx, "1"), savedstore.ErrNotFound)
+	assert.ErrorIs(t, store.Update(ctx, &savedstore.SavedSearch{ID: "1"}), savedstore.ErrNotFound)
+}
+
+func TestSavedSearchStoreTenants(t *testing.T) {
+	store := NewSavedSearchStore()
+	acme := tenancy.WithTenant(context.Background(), "acme")
+	other := tenancy.WithTenant(context.Background(), "other")
+
+	require.NoError(t, store.Create(acme, &savedstore.SavedSearch{ID: "1", Name: "a"}))
+
+	_, err := store.Get(other, "1")
+	assert.ErrorIs(t, err, savedstore.ErrNotFound)
+	searches, err := store.List(other)
+	require.NoError(t, err)
+	assert.Empty(t, searches)
+	assert.ErrorIs(t, store.Update(other, &savedstore.SavedSearch{ID: "1"}), savedstore.ErrNotFound)
+	assert.ErrorIs(t, store.Delete(other, "1"), savedstore.ErrNotFound)
+
+	_, err = store.Get(acme, "1")
+	assert.NoError(t, err)
+}
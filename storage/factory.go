@@ -4,6 +4,7 @@ import (
 	"logger/pkg/metrics"
 	"go.uber.org/zap"
 	"logger/storage/logstore"
+	"logger/storage/savedstore"
 )
 
 type FactoryBase interface {
@@ -11,4 +12,9 @@ type FactoryBase interface {
 	Close() error	
 	CreateLogReader()(logstore.Reader,error)
 	CreateLogWriter()(logstore.Writer,error)
-}
\ No newline at end of file
+}
+
+// SavedSearchStoreFactory is implemented by the factories of the backends able to persist saved searches.
+type SavedSearchStoreFactory interface {
+	CreateSavedSearchStore() (savedstore.Store, error)
+}
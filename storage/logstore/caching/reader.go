@@ -0,0 +1,221 @@
+// Package caching provides a logstore.Reader decorator caching the results of the
+// queries repeated on every page load of the UI and every refresh of a dashboard.
+package caching
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"logger/model"
+	"logger/pkg/bearertoken"
+	"logger/pkg/cache"
+	"logger/pkg/metrics"
+	"logger/pkg/tenancy"
+	"logger/storage/logstore"
+)
+
+// Options control what the Reader caches and for how long.
+type Options struct {
+	// NamesTTL is how long the service and operation names are cached, zero disables it.
+	NamesTTL time.Duration
+	// LogsTTL is how long the results of GetLogs over a closed time range are cached, zero disables it.
+	LogsTTL time.Duration
+	// MaxEntries bounds the number of entries of each cache.
+	MaxEntries int
+	// MaxEntryLogs is the largest number of logs a cached result of GetLogs may hold, so
+	// that the memory held by the cache stays bounded; zero caches results of any size.
+	MaxEntryLogs int
+}
+
+// Enabled tells whether anything is cached at all.
+func (o Options) Enabled() bool {
+	return o.MaxEntries > 0 && (o.NamesTTL > 0 || o.LogsTTL > 0)
+}
+
+type cacheMetrics struct {
+	Hits   metrics.Counter `metric:"requests" tags:"result=hit"`
+	Misses metrics.Counter `metric:"requests" tags:"result=miss"`
+}
+
+func (m *cacheMetrics) emit(hit bool) {
+	if hit {
+		m.Hits.Inc(1)
+	} else {
+		m.Misses.Inc(1)
+	}
+}
+
+// Reader wraps a logstore.Reader and caches the names of services and operations,
+// the logs of queries whose time range is over and the logs read by ID.
+type Reader struct {
+	reader       logstore.Reader
+	timeNow      func() time.Time
+	maxEntryLogs int
+
+	services   *cache.LRU
+	operations *cache.LRU
+	logs       *cache.LRU
+
+	servicesMetrics   *cacheMetrics
+	operationsMetrics *cacheMetrics
+	logsMetrics       *cacheMetrics
+}
+
+// NewReader returns a Reader caching the results of reader as configured by options.
+func NewReader(reader logstore.Reader, options Options, metricsFactory metrics.Factory) *Reader {
+	r := &Reader{
+		reader:            reader,
+		timeNow:           time.Now,
+		maxEntryLogs:      options.MaxEntryLogs,
+		servicesMetrics:   buildCacheMetrics("services", metricsFactory),
+		operationsMetrics: buildCacheMetrics("operations", metricsFactory),
+		logsMetrics:       buildCacheMetrics("logs", metricsFactory),
+	}
+	if options.MaxEntries > 0 && options.NamesTTL > 0 {
+		r.services = cache.NewLRUWithOptions(options.MaxEntries, &cache.Options{TTL: options.NamesTTL})
+		r.operations = cache.NewLRUWithOptions(options.MaxEntries, &cache.Options{TTL: options.NamesTTL})
+	}
+	if options.MaxEntries > 0 && options.LogsTTL > 0 {
+		r.logs = cache.NewLRUWithOptions(options.MaxEntries, &cache.Options{TTL: options.LogsTTL})
+	}
+	return r
+}
+
+func buildCacheMetrics(kind string, metricsFactory metrics.Factory) *cacheMetrics {
+	m := &cacheMetrics{}
+	scoped := metricsFactory.Namespace(metrics.NSOptions{Name: "cache", Tags: map[string]string{"kind": kind}})
+	metrics.Init(m, scoped, nil)
+	return m
+}
+
+// callerKey identifies who a result was read for, since the tenant and the
+// bearer token of the caller may restrict what the storage returns.
+func callerKey(ctx context.Context) string {
+	token, _ := bearertoken.GetBearerToken(ctx)
+	return fmt.Sprintf("%q|%q", tenancy.GetTenant(ctx), token)
+}
+
+// GetServices implements logstore.Reader#GetServices
+func (r *Reader) GetServices(ctx context.Context) ([]string, error) {
+	if r.services == nil {
+		return r.reader.GetServices(ctx)
+	}
+	key := callerKey(ctx)
+	if cached, ok := r.services.Get(key).([]string); ok {
+		r.servicesMetrics.emit(true)
+		return append([]string(nil), cached...), nil
+	}
+	r.servicesMetrics.emit(false)
+	services, err := r.reader.GetServices(ctx)
+	if err != nil {
+		return nil, err
+	}
+	r.services.Put(key, append([]string(nil), services...))
+	return services, nil
+}
+
+// GetOperations implements logstore.Reader#GetOperations
+func (r *Reader) GetOperations(ctx context.Context, p logstore.OperationQueryParameters) ([]logstore.Operation, error) {
+	if r.operations == nil {
+		return r.reader.GetOperations(ctx, p)
+	}
+	key := callerKey(ctx) + "|" + fmt.Sprintf("%q", p.ServiceName)
+	if cached, ok := r.operations.Get(key).([]logstore.Operation); ok {
+		r.operationsMetrics.emit(true)
+		return append([]logstore.Operation(nil), cached...), nil
+	}
+	r.operationsMetrics.emit(false)
+	operations, err := r.reader.GetOperations(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	r.operations.Put(key, append([]logstore.Operation(nil), operations...))
+	return operations, nil
+}
+
+// GetLogs implements logstore.Reader#GetLogs. Only the queries whose time range is
+// closed and over are cached, the result of any other may still change, and only
+// their results holding at most MaxEntryLogs logs.
+func (r *Reader) GetLogs(ctx context.Context, p logstore.LogQueryParameters) ([]*model.LogRecord, error) {
+	if r.logs == nil || !r.closedRange(p) {
+		return r.reader.GetLogs(ctx, p)
+	}
+	key := callerKey(ctx) + "|" + logsKey(p)
+	stats := logstore.QueryStatsFromContext(ctx)
+	done := stats.StartStage("cache.lookup")
+	cached, ok := r.logs.Get(key).([]*model.LogRecord)
+	done()
+	if ok {
+		r.logsMetrics.emit(true)
+		stats.SetCache(logstore.CacheHit)
+		stats.AddReturned(len(cached))
+		return append([]*model.LogRecord(nil), cached...), nil
+	}
+	r.logsMetrics.emit(false)
+	stats.SetCache(logstore.CacheMiss)
+	logs, err := r.reader.GetLogs(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	if r.maxEntryLogs == 0 || len(logs) <= r.maxEntryLogs {
+		r.logs.Put(key, append([]*model.LogRecord(nil), logs...))
+	}
+	return logs, nil
+}
+
+func (r *Reader) closedRange(p logstore.LogQueryParameters) bool {
+	return !p.StartTimeMin.IsZero() && !p.StartTimeMax.IsZero() && p.StartTimeMax.Before(r.timeNow())
+}
+
+func logsKey(p logstore.LogQueryParameters) string {
+	return strings.Join([]string{
+		fmt.Sprintf("%q", p.ServiceName),
+		fmt.Sprintf("%q", p.OperationName),
+		fmt.Sprint(p.StartTimeMin.UnixNano()),
+		fmt.Sprint(p.StartTimeMax.UnixNano()),
+		fmt.Sprint(p.NumTraces),
+		fmt.Sprint(p.SeverityNumber),
+		fmt.Sprint(p.ShouldFetchAll),
+		fmt.Sprint(p.Order.Ascending()),
+	}, "|")
+}
+
+// GetLogContext implements logstore.Reader#GetLogContext
+func (r *Reader) GetLogContext(ctx context.Context, p logstore.LogContextParameters) (*logstore.LogContext, error) {
+	return r.reader.GetLogContext(ctx, p)
+}
+
+// StreamLogs implements logstore.Reader#StreamLogs
+func (r *Reader) StreamLogs(ctx context.Context, p logstore.LogQueryParameters, fn func(*model.LogRecord) error) error {
+	return r.reader.StreamLogs(ctx, p, fn)
+}
+
+// StreamLogsByTrace implements logstore.Reader#StreamLogsByTrace
+func (r *Reader) StreamLogsByTrace(ctx context.Context, traceID []byte, fn func(*model.LogRecord) error) error {
+	return r.reader.StreamLogsByTrace(ctx, traceID, fn)
+}
+
+// GetLog implements logstore.Reader#GetLog. A stored log never changes, so it is cached
+// with the results of GetLogs.
+func (r *Reader) GetLog(ctx context.Context, id model.LogID) (*model.LogRecord, error) {
+	if r.logs == nil {
+		return r.reader.GetLog(ctx, id)
+	}
+	key := callerKey(ctx) + "|id=" + id.String()
+	stats := logstore.QueryStatsFromContext(ctx)
+	if cached, ok := r.logs.Get(key).(*model.LogRecord); ok {
+		r.logsMetrics.emit(true)
+		stats.SetCache(logstore.CacheHit)
+		return cached, nil
+	}
+	r.logsMetrics.emit(false)
+	stats.SetCache(logstore.CacheMiss)
+	log, err := r.reader.GetLog(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	r.logs.Put(key, log)
+	return log, nil
+}
@@ -0,0 +1,137 @@
+package caching
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"logger/internal/metricstest"
+	"logger/model"
+	"logger/pkg/tenancy"
+	"logger/storage/logstore"
+)
+
+type countingReader struct {
+	logstore.Reader
+	services   int
+	operations int
+	logs       int
+	// results is the number of logs returned by GetLogs, one when zero
+	results int
+}
+
+func (r *countingReader) GetServices(context.Context) ([]string, error) {
+	r.services++
+	return []string{"svc"}, nil
+}
+
+func (r *countingReader) GetOperations(context.Context, logstore.OperationQueryParameters) ([]logstore.Operation, error) {
+	r.operations++
+	return []logstore.Operation{{Name: "op"}}, nil
+}
+
+func (r *countingReader) GetLogs(context.Context, logstore.LogQueryParameters) ([]*model.LogRecord, error) {
+	r.logs++
+	logs := make([]*model.LogRecord, max(r.results, 1))
+	for i := range logs {
+		logs[i] = &model.LogRecord{Body: "hello"}
+	}
+	return logs, nil
+}
+
+func TestReaderCachesNames(t *testing.T) {
+	mf := metricstest.NewFactory(0)
+	defer mf.Stop()
+	underlying := &countingReader{}
+	r := NewReader(underlying, Options{NamesTTL: time.Minute, MaxEntries: 10}, mf)
+
+	for i := 0; i < 3; i++ {
+		services, err := r.GetServices(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, []string{"svc"}, services)
+		_, err = r.GetOperations(context.Background(), logstore.OperationQueryParameters{ServiceName: "svc"})
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 1, underlying.services)
+	assert.Equal(t, 1, underlying.operations)
+
+	// another tenant does not see the names read for the first one
+	_, err := r.GetServices(tenancy.WithTenant(context.Background(), "acme"))
+	require.NoError(t, err)
+	assert.Equal(t, 2, underlying.services)
+
+	mf.AssertCounterMetrics(t,
+		metricstest.ExpectedMetric{Name: "cache.requests", Tags: map[string]string{"kind": "services", "result": "hit"}, Value: 2},
+		metricstest.ExpectedMetric{Name: "cache.requests", Tags: map[string]string{"kind": "services", "result": "miss"}, Value: 2},
+	)
+}
+
+func TestReaderCachesClosedRangesOnly(t *testing.T) {
+	mf := metricstest.NewFactory(0)
+	defer mf.Stop()
+	underlying := &countingReader{}
+	r := NewReader(underlying, Options{LogsTTL: time.Minute, MaxEntries: 10}, mf)
+	now := time.Now()
+	r.timeNow = func() time.Time { return now }
+
+	closed := logstore.LogQueryParameters{ServiceName: "svc", StartTimeMin: now.Add(-time.Hour), StartTimeMax: now.Add(-time.Minute)}
+	open := logstore.LogQueryParameters{ServiceName: "svc", StartTimeMin: now.Add(-time.Hour), StartTimeMax: now.Add(time.Minute)}
+	for i := 0; i < 2; i++ {
+		_, err := r.GetLogs(context.Background(), closed)
+		require.NoError(t, err)
+		_, err = r.GetLogs(context.Background(), open)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 3, underlying.logs)
+
+	// names are not cached when only the logs are
+	_, err := r.GetServices(context.Background())
+	require.NoError(t, err)
+	_, err = r.GetServices(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 2, underlying.services)
+}
+
+func TestReaderSkipsLargeResults(t *testing.T) {
+	mf := metricstest.NewFactory(0)
+	defer mf.Stop()
+	underlying := &countingReader{results: 3}
+	r := NewReader(underlying, Options{LogsTTL: time.Minute, MaxEntries: 10, MaxEntryLogs: 2}, mf)
+	now := time.Now()
+	closed := logstore.LogQueryParameters{ServiceName: "svc", StartTimeMin: now.Add(-time.Hour), StartTimeMax: now.Add(-time.Minute)}
+	for i := 0; i < 2; i++ {
+		logs, err := r.GetLogs(context.Background(), closed)
+		require.NoError(t, err)
+		assert.Len(t, logs, 3)
+	}
+	assert.Equal(t, 2, underlying.logs)
+
+	underlying.results = 2
+	for i := 0; i < 2; i++ {
+		_, err := r.GetLogs(context.Background(), closed)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 3, underlying.logs)
+}
+
+func TestReaderExplainsCacheOutcome(t *testing.T) {
+	mf := metricstest.NewFactory(0)
+	defer mf.Stop()
+	r := NewReader(&countingReader{}, Options{LogsTTL: time.Minute, MaxEntries: 10}, mf)
+	now := time.Now()
+	closed := logstore.LogQueryParameters{ServiceName: "svc", StartTimeMin: now.Add(-time.Hour), StartTimeMax: now.Add(-time.Minute)}
+
+	miss := logstore.NewQueryStats()
+	_, err := r.GetLogs(logstore.WithQueryStats(context.Background(), miss), closed)
+	require.NoError(t, err)
+	assert.Equal(t, logstore.CacheMiss, miss.Explain().Cache)
+
+	hit := logstore.NewQueryStats()
+	_, err = r.GetLogs(logstore.WithQueryStats(context.Background(), hit), closed)
+	require.NoError(t, err)
+	assert.Equal(t, logstore.CacheHit, hit.Explain().Cache)
+	assert.Equal(t, 1, hit.Explain().RowsReturned)
+}
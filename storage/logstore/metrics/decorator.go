@@ -16,28 +16,30 @@
 package metrics
 
 import (
-	// "context"
+	"context"
 	"time"
 
-	// "logger/model"
+	"logger/model"
 	"logger/pkg/metrics"
 	"logger/storage/logstore"
 )
 
 // ReadMetricsDecorator wraps a logstore.Reader and collects metrics around each read operation.
 type ReadMetricsDecorator struct {
-	spanReader           logstore.Reader
-	findTracesMetrics    *queryMetrics
-	findTraceIDsMetrics  *queryMetrics
-	getTraceMetrics      *queryMetrics
-	getServicesMetrics   *queryMetrics
-	getOperationsMetrics *queryMetrics
+	logReader                logstore.Reader
+	getLogsMetrics           *queryMetrics
+	getServicesMetrics       *queryMetrics
+	getOperationsMetrics     *queryMetrics
+	getLogContextMetrics     *queryMetrics
+	streamLogsMetrics        *queryMetrics
+	streamLogsByTraceMetrics *queryMetrics
+	getLogMetrics            *queryMetrics
 }
 
 type queryMetrics struct {
 	Errors     metrics.Counter `metric:"requests" tags:"result=err"`
 	Successes  metrics.Counter `metric:"requests" tags:"result=ok"`
-	Responses  metrics.Timer   `metric:"responses"` // used as a histogram, not necessary for GetTrace
+	Responses  metrics.Timer   `metric:"responses"` // used as a histogram of the number of results
 	ErrLatency metrics.Timer   `metric:"latency" tags:"result=err"`
 	OKLatency  metrics.Timer   `metric:"latency" tags:"result=ok"`
 }
@@ -54,14 +56,16 @@ func (q *queryMetrics) emit(err error, latency time.Duration, responses int) {
 }
 
 // NewReadMetricsDecorator returns a new ReadMetricsDecorator.
-func NewReadMetricsDecorator(spanReader logstore.Reader, metricsFactory metrics.Factory) *ReadMetricsDecorator {
+func NewReadMetricsDecorator(logReader logstore.Reader, metricsFactory metrics.Factory) *ReadMetricsDecorator {
 	return &ReadMetricsDecorator{
-		spanReader:           spanReader,
-		findTracesMetrics:    buildQueryMetrics("find_traces", metricsFactory),
-		findTraceIDsMetrics:  buildQueryMetrics("find_trace_ids", metricsFactory),
-		getTraceMetrics:      buildQueryMetrics("get_trace", metricsFactory),
-		getServicesMetrics:   buildQueryMetrics("get_services", metricsFactory),
-		getOperationsMetrics: buildQueryMetrics("get_operations", metricsFactory),
+		logReader:                logReader,
+		getLogsMetrics:           buildQueryMetrics("get_logs", metricsFactory),
+		getServicesMetrics:       buildQueryMetrics("get_services", metricsFactory),
+		getOperationsMetrics:     buildQueryMetrics("get_operations", metricsFactory),
+		getLogContextMetrics:     buildQueryMetrics("get_log_context", metricsFactory),
+		streamLogsMetrics:        buildQueryMetrics("stream_logs", metricsFactory),
+		streamLogsByTraceMetrics: buildQueryMetrics("stream_logs_by_trace", metricsFactory),
+		getLogMetrics:            buildQueryMetrics("get_log", metricsFactory),
 	}
 }
 
@@ -72,45 +76,77 @@ func buildQueryMetrics(operation string, metricsFactory metrics.Factory) *queryM
 	return qMetrics
 }
 
-// FindTraces implements logstore.Reader#FindTraces
-// func (m *ReadMetricsDecorator) FindTraces(ctx context.Context, traceQuery *logstore.TraceQueryParameters) ([]*model.Trace, error) {
-// 	start := time.Now()
-// 	retMe, err := m.spanReader.FindTraces(ctx, traceQuery)
-// 	m.findTracesMetrics.emit(err, time.Since(start), len(retMe))
-// 	return retMe, err
-// }
+// GetLogs implements logstore.Reader#GetLogs
+func (m *ReadMetricsDecorator) GetLogs(ctx context.Context, query logstore.LogQueryParameters) ([]*model.LogRecord, error) {
+	start := time.Now()
+	retMe, err := m.logReader.GetLogs(ctx, query)
+	m.getLogsMetrics.emit(err, time.Since(start), len(retMe))
+	return retMe, err
+}
 
-// // FindTraceIDs implements logstore.Reader#FindTraceIDs
-// func (m *ReadMetricsDecorator) FindTraceIDs(ctx context.Context, traceQuery *logstore.TraceQueryParameters) ([]model.TraceID, error) {
-// 	start := time.Now()
-// 	retMe, err := m.spanReader.FindTraceIDs(ctx, traceQuery)
-// 	m.findTraceIDsMetrics.emit(err, time.Since(start), len(retMe))
-// 	return retMe, err
-// }
+// GetServices implements logstore.Reader#GetServices
+func (m *ReadMetricsDecorator) GetServices(ctx context.Context) ([]string, error) {
+	start := time.Now()
+	retMe, err := m.logReader.GetServices(ctx)
+	m.getServicesMetrics.emit(err, time.Since(start), len(retMe))
+	return retMe, err
+}
 
-// // GetTrace implements logstore.Reader#GetTrace
-// func (m *ReadMetricsDecorator) GetTrace(ctx context.Context, traceID model.TraceID) (*model.Trace, error) {
-// 	start := time.Now()
-// 	retMe, err := m.spanReader.GetTrace(ctx, traceID)
-// 	m.getTraceMetrics.emit(err, time.Since(start), 1)
-// 	return retMe, err
-// }
+// GetOperations implements logstore.Reader#GetOperations
+func (m *ReadMetricsDecorator) GetOperations(
+	ctx context.Context,
+	query logstore.OperationQueryParameters,
+) ([]logstore.Operation, error) {
+	start := time.Now()
+	retMe, err := m.logReader.GetOperations(ctx, query)
+	m.getOperationsMetrics.emit(err, time.Since(start), len(retMe))
+	return retMe, err
+}
 
-// // GetServices implements logstore.Reader#GetServices
-// func (m *ReadMetricsDecorator) GetServices(ctx context.Context) ([]string, error) {
-// 	start := time.Now()
-// 	retMe, err := m.spanReader.GetServices(ctx)
-// 	m.getServicesMetrics.emit(err, time.Since(start), len(retMe))
-// 	return retMe, err
-// }
+// GetLogContext implements logstore.Reader#GetLogContext
+func (m *ReadMetricsDecorator) GetLogContext(ctx context.Context, query logstore.LogContextParameters) (*logstore.LogContext, error) {
+	start := time.Now()
+	retMe, err := m.logReader.GetLogContext(ctx, query)
+	responses := 0
+	if retMe != nil {
+		responses = len(retMe.Before) + len(retMe.Anchor) + len(retMe.After)
+	}
+	m.getLogContextMetrics.emit(err, time.Since(start), responses)
+	return retMe, err
+}
 
-// // GetOperations implements logstore.Reader#GetOperations
-// func (m *ReadMetricsDecorator) GetOperations(
-// 	ctx context.Context,
-// 	query logstore.OperationQueryParameters,
-// ) ([]logstore.Operation, error) {
-// 	start := time.Now()
-// 	retMe, err := m.spanReader.GetOperations(ctx, query)
-// 	m.getOperationsMetrics.emit(err, time.Since(start), len(retMe))
-// 	return retMe, err
-// }
+// StreamLogs implements logstore.Reader#StreamLogs
+func (m *ReadMetricsDecorator) StreamLogs(ctx context.Context, query logstore.LogQueryParameters, fn func(*model.LogRecord) error) error {
+	start := time.Now()
+	responses := 0
+	err := m.logReader.StreamLogs(ctx, query, func(log *model.LogRecord) error {
+		responses++
+		return fn(log)
+	})
+	m.streamLogsMetrics.emit(err, time.Since(start), responses)
+	return err
+}
+
+// StreamLogsByTrace implements logstore.Reader#StreamLogsByTrace
+func (m *ReadMetricsDecorator) StreamLogsByTrace(ctx context.Context, traceID []byte, fn func(*model.LogRecord) error) error {
+	start := time.Now()
+	responses := 0
+	err := m.logReader.StreamLogsByTrace(ctx, traceID, func(log *model.LogRecord) error {
+		responses++
+		return fn(log)
+	})
+	m.streamLogsByTraceMetrics.emit(err, time.Since(start), responses)
+	return err
+}
+
+// GetLog implements logstore.Reader#GetLog
+func (m *ReadMetricsDecorator) GetLog(ctx context.Context, id model.LogID) (*model.LogRecord, error) {
+	start := time.Now()
+	retMe, err := m.logReader.GetLog(ctx, id)
+	responses := 0
+	if retMe != nil {
+		responses = 1
+	}
+	m.getLogMetrics.emit(err, time.Since(start), responses)
+	return retMe, err
+}
@@ -15,131 +15,84 @@
 
 package metrics_test
 
-// import (
-// 	"context"
-// 	"errors"
-// 	"testing"
-
-// 	"github.com/stretchr/testify/assert"
-
-// 	"github.com/jaegertracing/jaeger/internal/metricstest"
-// 	"github.com/jaegertracing/jaeger/model"
-// 	"github.com/jaegertracing/jaeger/storage/spanstore"
-// 	. "github.com/jaegertracing/jaeger/storage/spanstore/metrics"
-// 	"github.com/jaegertracing/jaeger/storage/spanstore/mocks"
-// )
-
-// func TestSuccessfulUnderlyingCalls(t *testing.T) {
-// 	mf := metricstest.NewFactory(0)
-
-// 	mockReader := mocks.Reader{}
-// 	mrs := NewReadMetricsDecorator(&mockReader, mf)
-// 	mockReader.On("GetServices", context.Background()).Return([]string{}, nil)
-// 	mrs.GetServices(context.Background())
-// 	operationQuery := spanstore.OperationQueryParameters{ServiceName: "something"}
-// 	mockReader.On("GetOperations", context.Background(), operationQuery).
-// 		Return([]spanstore.Operation{}, nil)
-// 	mrs.GetOperations(context.Background(), operationQuery)
-// 	mockReader.On("GetTrace", context.Background(), model.TraceID{}).Return(&model.Trace{}, nil)
-// 	mrs.GetTrace(context.Background(), model.TraceID{})
-// 	mockReader.On("FindTraces", context.Background(), &spanstore.TraceQueryParameters{}).
-// 		Return([]*model.Trace{}, nil)
-// 	mrs.FindTraces(context.Background(), &spanstore.TraceQueryParameters{})
-// 	mockReader.On("FindTraceIDs", context.Background(), &spanstore.TraceQueryParameters{}).
-// 		Return([]model.TraceID{}, nil)
-// 	mrs.FindTraceIDs(context.Background(), &spanstore.TraceQueryParameters{})
-// 	counters, gauges := mf.Snapshot()
-// 	expecteds := map[string]int64{
-// 		"requests|operation=get_operations|result=ok":  1,
-// 		"requests|operation=get_operations|result=err": 0,
-// 		"requests|operation=get_trace|result=ok":       1,
-// 		"requests|operation=get_trace|result=err":      0,
-// 		"requests|operation=find_traces|result=ok":     1,
-// 		"requests|operation=find_traces|result=err":    0,
-// 		"requests|operation=find_trace_ids|result=ok":  1,
-// 		"requests|operation=find_trace_ids|result=err": 0,
-// 		"requests|operation=get_services|result=ok":    1,
-// 		"requests|operation=get_services|result=err":   0,
-// 	}
-
-// 	existingKeys := []string{
-// 		"latency|operation=get_operations|result=ok.P50",
-// 		"responses|operation=get_trace.P50",
-// 		"latency|operation=find_traces|result=ok.P50", // this is not exhaustive
-// 	}
-// 	nonExistentKeys := []string{
-// 		"latency|operation=get_operations|result=err.P50",
-// 	}
-
-// 	checkExpectedExistingAndNonExistentCounters(t, counters, expecteds, gauges, existingKeys, nonExistentKeys)
-// }
-
-// func checkExpectedExistingAndNonExistentCounters(t *testing.T,
-// 	actualCounters,
-// 	expectedCounters,
-// 	actualGauges map[string]int64,
-// 	existingKeys,
-// 	nonExistentKeys []string,
-// ) {
-// 	for k, v := range expectedCounters {
-// 		assert.EqualValues(t, v, actualCounters[k], k)
-// 	}
-
-// 	for _, k := range existingKeys {
-// 		_, ok := actualGauges[k]
-// 		assert.True(t, ok)
-// 	}
-
-// 	for _, k := range nonExistentKeys {
-// 		_, ok := actualGauges[k]
-// 		assert.False(t, ok)
-// 	}
-// }
-
-// func TestFailingUnderlyingCalls(t *testing.T) {
-// 	mf := metricstest.NewFactory(0)
-
-// 	mockReader := mocks.Reader{}
-// 	mrs := NewReadMetricsDecorator(&mockReader, mf)
-// 	mockReader.On("GetServices", context.Background()).
-// 		Return(nil, errors.New("Failure"))
-// 	mrs.GetServices(context.Background())
-// 	operationQuery := spanstore.OperationQueryParameters{ServiceName: "something"}
-// 	mockReader.On("GetOperations", context.Background(), operationQuery).
-// 		Return(nil, errors.New("Failure"))
-// 	mrs.GetOperations(context.Background(), operationQuery)
-// 	mockReader.On("GetTrace", context.Background(), model.TraceID{}).
-// 		Return(nil, errors.New("Failure"))
-// 	mrs.GetTrace(context.Background(), model.TraceID{})
-// 	mockReader.On("FindTraces", context.Background(), &spanstore.TraceQueryParameters{}).
-// 		Return(nil, errors.New("Failure"))
-// 	mrs.FindTraces(context.Background(), &spanstore.TraceQueryParameters{})
-// 	mockReader.On("FindTraceIDs", context.Background(), &spanstore.TraceQueryParameters{}).
-// 		Return(nil, errors.New("Failure"))
-// 	mrs.FindTraceIDs(context.Background(), &spanstore.TraceQueryParameters{})
-// 	counters, gauges := mf.Snapshot()
-// 	expecteds := map[string]int64{
-// 		"requests|operation=get_operations|result=ok":  0,
-// 		"requests|operation=get_operations|result=err": 1,
-// 		"requests|operation=get_trace|result=ok":       0,
-// 		"requests|operation=get_trace|result=err":      1,
-// 		"requests|operation=find_traces|result=ok":     0,
-// 		"requests|operation=find_traces|result=err":    1,
-// 		"requests|operation=find_trace_ids|result=ok":  0,
-// 		"requests|operation=find_trace_ids|result=err": 1,
-// 		"requests|operation=get_services|result=ok":    0,
-// 		"requests|operation=get_services|result=err":   1,
-// 	}
-
-// 	existingKeys := []string{
-// 		"latency|operation=get_operations|result=err.P50",
-// 	}
-
-// 	nonExistentKeys := []string{
-// 		"latency|operation=get_operations|result=ok.P50",
-// 		"responses|operation=get_trace.P50",
-// 		"latency|operation=query|result=ok.P50", // this is not exhaustive
-// 	}
-
-// 	checkExpectedExistingAndNonExistentCounters(t, counters, expecteds, gauges, existingKeys, nonExistentKeys)
-// }
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"logger/internal/metricstest"
+	"logger/model"
+	"logger/storage/logstore"
+	. "logger/storage/logstore/metrics"
+)
+
+type stubReader struct {
+	logstore.Reader
+	err error
+}
+
+func (r *stubReader) GetLogs(context.Context, logstore.LogQueryParameters) ([]*model.LogRecord, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	return []*model.LogRecord{{}, {}}, nil
+}
+
+func (r *stubReader) GetServices(context.Context) ([]string, error) {
+	return []string{"svc"}, r.err
+}
+
+func (r *stubReader) GetOperations(context.Context, logstore.OperationQueryParameters) ([]logstore.Operation, error) {
+	return nil, r.err
+}
+
+func callReader(reader logstore.Reader) {
+	reader.GetLogs(context.Background(), logstore.LogQueryParameters{})
+	reader.GetServices(context.Background())
+	reader.GetOperations(context.Background(), logstore.OperationQueryParameters{ServiceName: "svc"})
+}
+
+func TestSuccessfulUnderlyingCalls(t *testing.T) {
+	mf := metricstest.NewFactory(0)
+	defer mf.Stop()
+
+	callReader(NewReadMetricsDecorator(&stubReader{}, mf))
+	counters, gauges := mf.Snapshot()
+	expecteds := map[string]int64{
+		"requests|operation=get_logs|result=ok":        1,
+		"requests|operation=get_logs|result=err":       0,
+		"requests|operation=get_services|result=ok":    1,
+		"requests|operation=get_services|result=err":   0,
+		"requests|operation=get_operations|result=ok":  1,
+		"requests|operation=get_operations|result=err": 0,
+	}
+	for k, v := range expecteds {
+		assert.EqualValues(t, v, counters[k], k)
+	}
+	assert.Contains(t, gauges, "latency|operation=get_logs|result=ok.P50")
+	assert.Contains(t, gauges, "responses|operation=get_logs.P50")
+	assert.NotContains(t, gauges, "latency|operation=get_logs|result=err.P50")
+}
+
+func TestFailingUnderlyingCalls(t *testing.T) {
+	mf := metricstest.NewFactory(0)
+	defer mf.Stop()
+
+	callReader(NewReadMetricsDecorator(&stubReader{err: errors.New("failure")}, mf))
+	counters, gauges := mf.Snapshot()
+	expecteds := map[string]int64{
+		"requests|operation=get_logs|result=ok":        0,
+		"requests|operation=get_logs|result=err":       1,
+		"requests|operation=get_services|result=ok":    0,
+		"requests|operation=get_services|result=err":   1,
+		"requests|operation=get_operations|result=ok":  0,
+		"requests|operation=get_operations|result=err": 1,
+	}
+	for k, v := range expecteds {
+		assert.EqualValues(t, v, counters[k], k)
+	}
+	assert.Contains(t, gauges, "latency|operation=get_operations|result=err.P50")
+	assert.NotContains(t, gauges, "responses|operation=get_logs.P50")
+}
@@ -0,0 +1,186 @@
+package logstore
+
+import (
+	"bytes"
+	"container/heap"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"logger/model"
+)
+
+// Order is the order logs are returned in by a query.
+type Order string
+
+const (
+	// OrderDesc returns the newest logs first. It is the default.
+	OrderDesc Order = "desc"
+	// OrderAsc returns the oldest logs first.
+	OrderAsc Order = "asc"
+)
+
+// ParseOrder parses "asc" or "desc", case-insensitively; an empty value is OrderDesc.
+func ParseOrder(value string) (Order, error) {
+	switch Order(strings.ToLower(value)) {
+	case "", OrderDesc:
+		return OrderDesc, nil
+	case OrderAsc:
+		return OrderAsc, nil
+	}
+	return "", fmt.Errorf("invalid order %q, it must be %q or %q", value, OrderAsc, OrderDesc)
+}
+
+// UnmarshalJSON implements json.Unmarshaler, refusing unknown orders.
+func (o *Order) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	order, err := ParseOrder(s)
+	if err != nil {
+		return err
+	}
+	*o = order
+	return nil
+}
+
+// Ascending reports whether o returns the oldest logs first.
+func (o Order) Ascending() bool {
+	return o == OrderAsc
+}
+
+// Less reports whether a comes before b in the order o. OrderDesc is the exact
+// reverse of OrderAsc, ties included.
+func (o Order) Less(a, b *model.LogRecord) bool {
+	if o.Ascending() {
+		return CompareLogs(a, b) < 0
+	}
+	return CompareLogs(a, b) > 0
+}
+
+// CompareLogs orders logs oldest first. Logs written at the same instant are ordered
+// by service, then by severity, highest first, as in the clustering order of the
+// Cassandra logs table read ascending, then by their content and last by their ID, so
+// that the order of a merge does not depend on the order its sources answered in.
+func CompareLogs(a, b *model.LogRecord) int {
+	switch {
+	case a.TimeUnixNano < b.TimeUnixNano:
+		return -1
+	case a.TimeUnixNano > b.TimeUnixNano:
+		return 1
+	}
+	if c := strings.Compare(serviceName(a), serviceName(b)); c != 0 {
+		return c
+	}
+	switch {
+	case a.SeverityNumber > b.SeverityNumber:
+		return -1
+	case a.SeverityNumber < b.SeverityNumber:
+		return 1
+	}
+	if c := strings.Compare(a.Body, b.Body); c != 0 {
+		return c
+	}
+	switch {
+	case a.ObservedTimeUnixNano < b.ObservedTimeUnixNano:
+		return -1
+	case a.ObservedTimeUnixNano > b.ObservedTimeUnixNano:
+		return 1
+	}
+	if c := bytes.Compare(a.TraceId, b.TraceId); c != 0 {
+		return c
+	}
+	if c := bytes.Compare(a.SpanId, b.SpanId); c != 0 {
+		return c
+	}
+	return bytes.Compare(a.ID[:], b.ID[:])
+}
+
+func serviceName(log *model.LogRecord) string {
+	if log.Process == nil {
+		return ""
+	}
+	return log.Process.ServiceName
+}
+
+// LogIterator hands out the logs of a source one at a time. Next returns false once
+// the source is exhausted or has failed, which Err then tells.
+type LogIterator interface {
+	Next() (*model.LogRecord, bool)
+	Err() error
+}
+
+// MergeLogs merges sources, each already sorted in order, into a single sequence sorted
+// in order and hands it to fn, reading every source only as far as needed. It stops at
+// the first error returned by fn or by a source.
+func MergeLogs(order Order, sources []LogIterator, fn func(*model.LogRecord) error) error {
+	h := &mergeHeap{order: order}
+	for _, it := range sources {
+		if err := h.pushNext(it); err != nil {
+			return err
+		}
+	}
+	for h.Len() > 0 {
+		head := h.heads[0]
+		if err := fn(head.log); err != nil {
+			return err
+		}
+		heap.Pop(h)
+		if err := h.pushNext(head.it); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SliceIterator returns a LogIterator over logs.
+func SliceIterator(logs []*model.LogRecord) LogIterator {
+	return &sliceIterator{logs: logs}
+}
+
+type sliceIterator struct {
+	logs []*model.LogRecord
+}
+
+func (it *sliceIterator) Next() (*model.LogRecord, bool) {
+	if len(it.logs) == 0 {
+		return nil, false
+	}
+	log := it.logs[0]
+	it.logs = it.logs[1:]
+	return log, true
+}
+
+func (it *sliceIterator) Err() error {
+	return nil
+}
+
+type mergeHead struct {
+	log *model.LogRecord
+	it  LogIterator
+}
+
+// mergeHeap holds the next log of every source that is not exhausted.
+type mergeHeap struct {
+	order Order
+	heads []mergeHead
+}
+
+func (h *mergeHeap) pushNext(it LogIterator) error {
+	if log, ok := it.Next(); ok {
+		heap.Push(h, mergeHead{log: log, it: it})
+		return nil
+	}
+	return it.Err()
+}
+
+func (h *mergeHeap) Len() int           { return len(h.heads) }
+func (h *mergeHeap) Less(i, j int) bool { return h.order.Less(h.heads[i].log, h.heads[j].log) }
+func (h *mergeHeap) Swap(i, j int)      { h.heads[i], h.heads[j] = h.heads[j], h.heads[i] }
+func (h *mergeHeap) Push(x interface{}) { h.heads = append(h.heads, x.(mergeHead)) }
+func (h *mergeHeap) Pop() interface{} {
+	head := h.heads[len(h.heads)-1]
+	h.heads = h.heads[:len(h.heads)-1]
+	return head
+}
@@ -0,0 +1,94 @@
+package logstore
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"logger/model"
+	logs "logger/model/proto/logs/v1"
+)
+
+func log(ts uint64, service string, severity int32, body string) *model.LogRecord {
+	return &model.LogRecord{
+		TimeUnixNano:   ts,
+		SeverityNumber: logs.SeverityNumber(severity),
+		Body:           body,
+		Process:        &model.Process{ServiceName: service},
+	}
+}
+
+func bodies(logs []*model.LogRecord) []string {
+	res := make([]string, len(logs))
+	for i, l := range logs {
+		res[i] = l.Body
+	}
+	return res
+}
+
+func merge(t *testing.T, order Order, sources ...[]*model.LogRecord) []*model.LogRecord {
+	iterators := make([]LogIterator, len(sources))
+	for i, source := range sources {
+		iterators[i] = SliceIterator(source)
+	}
+	var res []*model.LogRecord
+	require.NoError(t, MergeLogs(order, iterators, func(l *model.LogRecord) error {
+		res = append(res, l)
+		return nil
+	}))
+	return res
+}
+
+func TestMergeLogs(t *testing.T) {
+	// each partition is sorted as Cassandra returns it read ascending
+	get := []*model.LogRecord{log(1, "api", 9, "get 1"), log(3, "api", 17, "get 3 error"), log(3, "api", 9, "get 3 info")}
+	post := []*model.LogRecord{log(2, "api", 9, "post 2"), log(3, "api", 9, "post 3"), log(4, "api", 9, "post 4")}
+
+	asc := merge(t, OrderAsc, get, post)
+	assert.Equal(t, []string{"get 1", "post 2", "get 3 error", "get 3 info", "post 3", "post 4"}, bodies(asc))
+
+	reverse := func(logs []*model.LogRecord) []*model.LogRecord {
+		res := make([]*model.LogRecord, len(logs))
+		for i, l := range logs {
+			res[len(logs)-1-i] = l
+		}
+		return res
+	}
+	// the order does not depend on the order of the sources, and desc is the reverse of asc
+	desc := merge(t, OrderDesc, reverse(post), reverse(get))
+	assert.Equal(t, reverse(asc), desc)
+}
+
+func TestCompareLogs(t *testing.T) {
+	a, b := log(1, "api", 9, "retry"), log(1, "api", 9, "retry")
+	assert.Equal(t, 0, CompareLogs(a, b))
+
+	// identical records are told apart by their ID
+	a.ID, b.ID = model.LogID{1}, model.LogID{2}
+	assert.Equal(t, -1, CompareLogs(a, b))
+	assert.Equal(t, 1, CompareLogs(b, a))
+	assert.True(t, OrderDesc.Less(b, a))
+}
+
+func TestMergeLogsStops(t *testing.T) {
+	errStop := errors.New("stop")
+	n := 0
+	err := MergeLogs(OrderAsc, []LogIterator{SliceIterator([]*model.LogRecord{log(1, "a", 9, "1"), log(2, "a", 9, "2")})}, func(*model.LogRecord) error {
+		n++
+		return errStop
+	})
+	assert.Equal(t, errStop, err)
+	assert.Equal(t, 1, n)
+}
+
+func TestOrderJSON(t *testing.T) {
+	var p LogQueryParameters
+	require.NoError(t, json.Unmarshal([]byte(`{"order":"ASC"}`), &p))
+	assert.Equal(t, OrderAsc, p.Order)
+	require.NoError(t, json.Unmarshal([]byte(`{"order":""}`), &p))
+	assert.Equal(t, OrderDesc, p.Order)
+	assert.Error(t, json.Unmarshal([]byte(`{"order":"newest"}`), &p))
+}
@@ -2,18 +2,33 @@ package logstore
 
 import (
 	"context"
+	"errors"
 	"logger/model"
 	"time"
 )
 
+// ErrLogNotFound is returned by GetLog when no log has the requested ID.
+var ErrLogNotFound = errors.New("log not found")
+
 type Reader interface {
 	// GetServices(ctx context.Context) ([]string, error)
 	GetLogs(ctx context.Context, p LogQueryParameters) ([]*model.LogRecord, error)
 	GetServices(ctx context.Context) ([]string, error)
 	GetOperations(ctx context.Context, p OperationQueryParameters) ([]Operation, error)
+	// GetLogContext returns the records written immediately before and after a given instant.
+	GetLogContext(ctx context.Context, p LogContextParameters) (*LogContext, error)
+	// StreamLogs hands the logs matching p to fn one at a time, stopping at the first error fn returns.
+	StreamLogs(ctx context.Context, p LogQueryParameters, fn func(*model.LogRecord) error) error
+	// StreamLogsByTrace hands the logs correlated with a trace to fn one at a time, oldest
+	// first, stopping at the first error fn returns.
+	StreamLogsByTrace(ctx context.Context, traceID []byte, fn func(*model.LogRecord) error) error
+	// GetLog returns the log with the given ID, or ErrLogNotFound.
+	GetLog(ctx context.Context, id model.LogID) (*model.LogRecord, error)
 }
 
 // LogQueryParameters contains parameters of a log query.
+// An empty OperationName searches every operation of the service, and the logs of all
+// the partitions read are merged into a single sequence sorted in Order.
 type LogQueryParameters struct {
 	ServiceName   string `json:"service_name"`
 	OperationName string `json:"operation_name"`
@@ -22,7 +37,33 @@ type LogQueryParameters struct {
 	StartTimeMax   time.Time `json:"start_time_max"`
 	NumTraces      int       `json:"num_traces"`
 	SeverityNumber int       `json:"severity_number"`
-	ShouldFetchAll bool    `json:"should_fetch_all"`
+	ShouldFetchAll bool      `json:"should_fetch_all"`
+	// Order is the order of the results, OrderDesc when empty.
+	Order Order `json:"order"`
+}
+
+// LogContextParameters contains parameters of a query for the records surrounding an instant.
+// An empty OperationName searches every operation of the service.
+type LogContextParameters struct {
+	// ID anchors the context on a stored record, in place of ServiceName and Timestamp.
+	ID            model.LogID `json:"id"`
+	ServiceName   string    `json:"service_name"`
+	OperationName string    `json:"operation_name"`
+	Timestamp     time.Time `json:"timestamp"`
+	Before        int       `json:"before"`
+	After         int       `json:"after"`
+	// Host restricts the neighbors to records emitted by the same host.name.
+	Host string `json:"host"`
+	// TraceID restricts the neighbors to records of the same trace.
+	TraceID []byte `json:"trace_id"`
+}
+
+// LogContext holds the records surrounding an instant, each slice ordered oldest first.
+// Anchor contains the records written exactly at the requested instant.
+type LogContext struct {
+	Before []*model.LogRecord `json:"before"`
+	Anchor []*model.LogRecord `json:"anchor"`
+	After  []*model.LogRecord `json:"after"`
 }
 
 // OperationQueryParameters contains parameters of query operations, empty spanKind means get operations for all kinds of span.
@@ -0,0 +1,146 @@
+package logstore
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// statsKeyType is a custom type for the key "query-stats", following context.Context convention
+type statsKeyType string
+
+const statsKey = statsKeyType("query-stats")
+
+// Cache outcomes recorded by a caching reader.
+const (
+	CacheHit  = "hit"
+	CacheMiss = "miss"
+)
+
+// QueryStats collects how a query is executed. It travels through the context, see
+// WithQueryStats, and every method is a no-op on a nil collector so that the readers
+// can record unconditionally.
+type QueryStats struct {
+	mu      sync.Mutex
+	explain QueryExplain
+}
+
+// QueryExplain is the storage plan and execution statistics of a query.
+type QueryExplain struct {
+	// Plan lists the storage reads of the query, in the order they were issued.
+	Plan []string `json:"plan"`
+	// PartitionsTouched is the number of storage partitions read. The readers never read
+	// the bucketed index tables, so no bucket count is reported.
+	PartitionsTouched int `json:"partitions_touched"`
+	// RowsScanned is the number of rows read from the storage.
+	RowsScanned int `json:"rows_scanned"`
+	// RowsReturned is the number of records the storage layer returned as the result of the query.
+	RowsReturned int `json:"rows_returned"`
+	// RowsRejected is the number of rows read but dropped by a filter applied after the read.
+	RowsRejected int `json:"rows_rejected"`
+	// Cache is CacheHit or CacheMiss when a cache was consulted, empty otherwise.
+	Cache string `json:"cache,omitempty"`
+	// Indexes lists the index tables read.
+	Indexes []string `json:"indexes"`
+	// Stages is the latency of each stage of the query, in the order they ended.
+	Stages []StageLatency `json:"stages"`
+}
+
+// StageLatency is the time spent in a stage of a query.
+type StageLatency struct {
+	Name     string        `json:"name"`
+	Duration time.Duration `json:"duration_ns"`
+}
+
+// NewQueryStats returns an empty collector.
+func NewQueryStats() *QueryStats {
+	return &QueryStats{}
+}
+
+// WithQueryStats returns a context carrying stats.
+func WithQueryStats(ctx context.Context, stats *QueryStats) context.Context {
+	return context.WithValue(ctx, statsKey, stats)
+}
+
+// QueryStatsFromContext returns the collector carried by ctx, or nil.
+func QueryStatsFromContext(ctx context.Context) *QueryStats {
+	stats, _ := ctx.Value(statsKey).(*QueryStats)
+	return stats
+}
+
+func (s *QueryStats) update(fn func(e *QueryExplain)) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	fn(&s.explain)
+	s.mu.Unlock()
+}
+
+// AddPlan records a storage read.
+func (s *QueryStats) AddPlan(step string) {
+	s.update(func(e *QueryExplain) { e.Plan = append(e.Plan, step) })
+}
+
+// AddPartitions records n partitions read.
+func (s *QueryStats) AddPartitions(n int) {
+	s.update(func(e *QueryExplain) { e.PartitionsTouched += n })
+}
+
+// AddScanned records n rows read from the storage.
+func (s *QueryStats) AddScanned(n int) {
+	s.update(func(e *QueryExplain) { e.RowsScanned += n })
+}
+
+// AddReturned records n records returned.
+func (s *QueryStats) AddReturned(n int) {
+	s.update(func(e *QueryExplain) { e.RowsReturned += n })
+}
+
+// AddRejected records n rows dropped by a post-filter.
+func (s *QueryStats) AddRejected(n int) {
+	s.update(func(e *QueryExplain) { e.RowsRejected += n })
+}
+
+// SetCache records whether the result came from a cache, see CacheHit and CacheMiss.
+func (s *QueryStats) SetCache(outcome string) {
+	s.update(func(e *QueryExplain) { e.Cache = outcome })
+}
+
+// UseIndex records a read of the index table name.
+func (s *QueryStats) UseIndex(name string) {
+	s.update(func(e *QueryExplain) {
+		for _, index := range e.Indexes {
+			if index == name {
+				return
+			}
+		}
+		e.Indexes = append(e.Indexes, name)
+	})
+}
+
+// StartStage starts timing the stage name; the returned function ends it.
+func (s *QueryStats) StartStage(name string) func() {
+	if s == nil {
+		return func() {}
+	}
+	start := time.Now()
+	return func() {
+		d := time.Since(start)
+		s.update(func(e *QueryExplain) { e.Stages = append(e.Stages, StageLatency{Name: name, Duration: d}) })
+	}
+}
+
+// Explain returns a copy of what was collected so far.
+func (s *QueryStats) Explain() *QueryExplain {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	res := s.explain
+	res.Plan = append(make([]string, 0, len(res.Plan)), res.Plan...)
+	res.Indexes = append(make([]string, 0, len(res.Indexes)), res.Indexes...)
+	res.Stages = append(make([]StageLatency, 0, len(res.Stages)), res.Stages...)
+	return &res
+}
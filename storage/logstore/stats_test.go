@@ -0,0 +1,58 @@
+package logstore
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryStats(t *testing.T) {
+	stats := NewQueryStats()
+	ctx := WithQueryStats(context.Background(), stats)
+	require.Same(t, stats, QueryStatsFromContext(ctx))
+
+	stats.AddPlan("logs(api, GET)")
+	stats.AddPartitions(2)
+	stats.AddScanned(10)
+	stats.AddReturned(4)
+	stats.AddRejected(6)
+	stats.SetCache(CacheMiss)
+	stats.UseIndex("operation_names")
+	stats.UseIndex("operation_names")
+	stats.StartStage("storage")()
+
+	explain := stats.Explain()
+	assert.Equal(t, []string{"logs(api, GET)"}, explain.Plan)
+	assert.Equal(t, 2, explain.PartitionsTouched)
+	assert.Equal(t, 10, explain.RowsScanned)
+	assert.Equal(t, 4, explain.RowsReturned)
+	assert.Equal(t, 6, explain.RowsRejected)
+	assert.Equal(t, CacheMiss, explain.Cache)
+	assert.Equal(t, []string{"operation_names"}, explain.Indexes)
+	require.Len(t, explain.Stages, 1)
+	assert.Equal(t, "storage", explain.Stages[0].Name)
+
+	// the explain is a copy
+	explain.Plan[0] = "changed"
+	assert.Equal(t, "logs(api, GET)", stats.Explain().Plan[0])
+}
+
+func TestQueryStatsNil(t *testing.T) {
+	stats := QueryStatsFromContext(context.Background())
+	require.Nil(t, stats)
+	stats.AddPlan("logs")
+	stats.AddScanned(1)
+	stats.UseIndex("logs_by_id")
+	stats.StartStage("storage")()
+	assert.Nil(t, stats.Explain())
+}
+
+func TestQueryExplainJSON(t *testing.T) {
+	data, err := json.Marshal(NewQueryStats().Explain())
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"plan":[],"partitions_touched":0,"rows_scanned":0,
+		"rows_returned":0,"rows_rejected":0,"indexes":[],"stages":[]}`, string(data))
+}
@@ -0,0 +1,127 @@
+// Package tracing provides a logstore.Reader decorator recording a span around each read operation.
+package tracing
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"logger/model"
+	"logger/storage/logstore"
+)
+
+// ReadTracingDecorator wraps a logstore.Reader and records a span around each read operation.
+type ReadTracingDecorator struct {
+	logReader logstore.Reader
+	tracer    trace.Tracer
+}
+
+// NewReadTracingDecorator returns a new ReadTracingDecorator.
+func NewReadTracingDecorator(logReader logstore.Reader, tracerProvider trace.TracerProvider) *ReadTracingDecorator {
+	return &ReadTracingDecorator{
+		logReader: logReader,
+		tracer:    tracerProvider.Tracer("logger/storage/logstore"),
+	}
+}
+
+func (d *ReadTracingDecorator) start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return d.tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+}
+
+func end(span trace.Span, err error, results int) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	} else {
+		span.SetAttributes(attribute.Int("results", results))
+	}
+	span.End()
+}
+
+func queryAttributes(query logstore.LogQueryParameters) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.String("service_name", query.ServiceName),
+		attribute.String("operation_name", query.OperationName),
+		attribute.String("start_time_min", query.StartTimeMin.String()),
+		attribute.String("start_time_max", query.StartTimeMax.String()),
+		attribute.Int("num_traces", query.NumTraces),
+	}
+}
+
+// GetLogs implements logstore.Reader#GetLogs
+func (d *ReadTracingDecorator) GetLogs(ctx context.Context, query logstore.LogQueryParameters) ([]*model.LogRecord, error) {
+	ctx, span := d.start(ctx, "GetLogs", queryAttributes(query)...)
+	retMe, err := d.logReader.GetLogs(ctx, query)
+	end(span, err, len(retMe))
+	return retMe, err
+}
+
+// GetServices implements logstore.Reader#GetServices
+func (d *ReadTracingDecorator) GetServices(ctx context.Context) ([]string, error) {
+	ctx, span := d.start(ctx, "GetServices")
+	retMe, err := d.logReader.GetServices(ctx)
+	end(span, err, len(retMe))
+	return retMe, err
+}
+
+// GetOperations implements logstore.Reader#GetOperations
+func (d *ReadTracingDecorator) GetOperations(ctx context.Context, query logstore.OperationQueryParameters) ([]logstore.Operation, error) {
+	ctx, span := d.start(ctx, "GetOperations", attribute.String("service_name", query.ServiceName))
+	retMe, err := d.logReader.GetOperations(ctx, query)
+	end(span, err, len(retMe))
+	return retMe, err
+}
+
+// GetLogContext implements logstore.Reader#GetLogContext
+func (d *ReadTracingDecorator) GetLogContext(ctx context.Context, query logstore.LogContextParameters) (*logstore.LogContext, error) {
+	ctx, span := d.start(ctx, "GetLogContext",
+		attribute.String("service_name", query.ServiceName),
+		attribute.String("operation_name", query.OperationName),
+		attribute.String("timestamp", query.Timestamp.String()),
+	)
+	retMe, err := d.logReader.GetLogContext(ctx, query)
+	results := 0
+	if retMe != nil {
+		results = len(retMe.Before) + len(retMe.Anchor) + len(retMe.After)
+	}
+	end(span, err, results)
+	return retMe, err
+}
+
+// StreamLogs implements logstore.Reader#StreamLogs
+func (d *ReadTracingDecorator) StreamLogs(ctx context.Context, query logstore.LogQueryParameters, fn func(*model.LogRecord) error) error {
+	ctx, span := d.start(ctx, "StreamLogs", queryAttributes(query)...)
+	results := 0
+	err := d.logReader.StreamLogs(ctx, query, func(log *model.LogRecord) error {
+		results++
+		return fn(log)
+	})
+	end(span, err, results)
+	return err
+}
+
+// StreamLogsByTrace implements logstore.Reader#StreamLogsByTrace
+func (d *ReadTracingDecorator) StreamLogsByTrace(ctx context.Context, traceID []byte, fn func(*model.LogRecord) error) error {
+	ctx, span := d.start(ctx, "StreamLogsByTrace")
+	results := 0
+	err := d.logReader.StreamLogsByTrace(ctx, traceID, func(log *model.LogRecord) error {
+		results++
+		return fn(log)
+	})
+	end(span, err, results)
+	return err
+}
+
+// GetLog implements logstore.Reader#GetLog
+func (d *ReadTracingDecorator) GetLog(ctx context.Context, id model.LogID) (*model.LogRecord, error) {
+	ctx, span := d.start(ctx, "GetLog", attribute.String("log_id", id.String()))
+	retMe, err := d.logReader.GetLog(ctx, id)
+	responses := 0
+	if retMe != nil {
+		responses = 1
+	}
+	end(span, err, responses)
+	return retMe, err
+}
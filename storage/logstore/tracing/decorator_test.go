@@ -0,0 +1,127 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"logger/model"
+	"logger/storage/logstore"
+)
+
+type stubReader struct {
+	logstore.Reader
+	ctx context.Context
+	err error
+}
+
+func (r *stubReader) GetLogs(ctx context.Context, _ logstore.LogQueryParameters) ([]*model.LogRecord, error) {
+	r.ctx = ctx
+	return []*model.LogRecord{{}, {}}, r.err
+}
+
+func (r *stubReader) StreamLogs(ctx context.Context, _ logstore.LogQueryParameters, fn func(*model.LogRecord) error) error {
+	r.ctx = ctx
+	for i := 0; i < 3; i++ {
+		if err := fn(&model.LogRecord{}); err != nil {
+			return err
+		}
+	}
+	return r.err
+}
+
+func (r *stubReader) GetLog(ctx context.Context, _ model.LogID) (*model.LogRecord, error) {
+	r.ctx = ctx
+	if r.err != nil {
+		return nil, r.err
+	}
+	return &model.LogRecord{}, nil
+}
+
+func newDecorator(reader logstore.Reader) (*ReadTracingDecorator, *tracetest.InMemoryExporter) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	return NewReadTracingDecorator(reader, tp), exporter
+}
+
+func attributes(span tracetest.SpanStub) map[attribute.Key]attribute.Value {
+	res := make(map[attribute.Key]attribute.Value, len(span.Attributes))
+	for _, kv := range span.Attributes {
+		res[kv.Key] = kv.Value
+	}
+	return res
+}
+
+func TestGetLogs(t *testing.T) {
+	reader := &stubReader{}
+	d, exporter := newDecorator(reader)
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	logs, err := d.GetLogs(context.Background(), logstore.LogQueryParameters{
+		ServiceName:   "api",
+		OperationName: "GET",
+		StartTimeMin:  start,
+		StartTimeMax:  start.Add(time.Hour),
+		NumTraces:     20,
+	})
+	require.NoError(t, err)
+	assert.Len(t, logs, 2)
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 1)
+	span := spans[0]
+	assert.Equal(t, "GetLogs", span.Name)
+	assert.Equal(t, trace.SpanKindClient, span.SpanKind)
+	assert.Equal(t, codes.Unset, span.Status.Code)
+	attrs := attributes(span)
+	assert.Equal(t, "api", attrs["service_name"].AsString())
+	assert.Equal(t, "GET", attrs["operation_name"].AsString())
+	assert.Equal(t, start.String(), attrs["start_time_min"].AsString())
+	assert.Equal(t, int64(20), attrs["num_traces"].AsInt64())
+	assert.Equal(t, int64(2), attrs["results"].AsInt64())
+	// the reader runs within the span
+	assert.Equal(t, span.SpanContext.SpanID(), trace.SpanContextFromContext(reader.ctx).SpanID())
+}
+
+func TestStreamLogs(t *testing.T) {
+	d, exporter := newDecorator(&stubReader{})
+	n := 0
+	require.NoError(t, d.StreamLogs(context.Background(), logstore.LogQueryParameters{ServiceName: "api"}, func(*model.LogRecord) error {
+		n++
+		return nil
+	}))
+	assert.Equal(t, 3, n)
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "StreamLogs", spans[0].Name)
+	assert.Equal(t, int64(3), attributes(spans[0])["results"].AsInt64())
+}
+
+func TestGetLogError(t *testing.T) {
+	errBoom := errors.New("boom")
+	d, exporter := newDecorator(&stubReader{err: errBoom})
+	id := model.NewLogID(time.Now())
+	_, err := d.GetLog(context.Background(), id)
+	assert.Equal(t, errBoom, err)
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 1)
+	span := spans[0]
+	assert.Equal(t, "GetLog", span.Name)
+	assert.Equal(t, codes.Error, span.Status.Code)
+	assert.Equal(t, "boom", span.Status.Description)
+	attrs := attributes(span)
+	assert.Equal(t, id.String(), attrs["log_id"].AsString())
+	assert.NotContains(t, attrs, attribute.Key("results"))
+	require.Len(t, span.Events, 1)
+	assert.Equal(t, "exception", span.Events[0].Name)
+}
@@ -0,0 +1,133 @@
+// Package savedstore defines the storage of saved searches: named log queries over a
+// time range relative to the moment they are run, which can be shared as permalinks.
+package savedstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"logger/storage/logstore"
+)
+
+// ErrNotFound is returned when no saved search of the tenant has the requested ID.
+var ErrNotFound = errors.New("saved search not found")
+
+// Store persists saved searches. Every method is scoped to the tenant of the context,
+// a tenant never sees the searches of another.
+type Store interface {
+	// Create stores a new search; its ID is set by the caller.
+	Create(ctx context.Context, search *SavedSearch) error
+	// Get returns a search or ErrNotFound.
+	Get(ctx context.Context, id string) (*SavedSearch, error)
+	// List returns every search of the tenant, ordered by name.
+	List(ctx context.Context) ([]*SavedSearch, error)
+	// Update replaces a search or returns ErrNotFound.
+	Update(ctx context.Context, search *SavedSearch) error
+	// Delete removes a search or returns ErrNotFound.
+	Delete(ctx context.Context, id string) error
+}
+
+// SavedSearch is a named log query.
+type SavedSearch struct {
+	ID          string    `json:"id"`
+	Name        string    `json:"name"`
+	Owner       string    `json:"owner"`
+	Description string    `json:"description"`
+	Query       Query     `json:"query"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// Query holds the parameters of a saved search, see logstore.LogQueryParameters.
+type Query struct {
+	ServiceName    string         `json:"service_name"`
+	OperationName  string         `json:"operation_name"`
+	NumTraces      int            `json:"num_traces"`
+	SeverityNumber int            `json:"severity_number"`
+	Order          logstore.Order `json:"order"`
+	Range          Range          `json:"range"`
+}
+
+// Range is a time range relative to the moment a search is run. From and To are
+// "now" or "now-" followed by a duration such as "now-15m"; an empty To is "now".
+type Range struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+const now = "now"
+
+// Validate checks that the search can be run.
+func (s *SavedSearch) Validate() error {
+	if strings.TrimSpace(s.Name) == "" {
+		return errors.New("name must be set")
+	}
+	if s.Query.ServiceName == "" {
+		return errors.New("query.service_name must be set")
+	}
+	if s.Query.NumTraces < 0 {
+		return errors.New("query.num_traces must not be negative")
+	}
+	_, _, err := s.Query.Range.Resolve(time.Now())
+	return err
+}
+
+// LogQuery returns the log query of q run at t.
+func (q Query) LogQuery(t time.Time) (logstore.LogQueryParameters, error) {
+	start, end, err := q.Range.Resolve(t)
+	if err != nil {
+		return logstore.LogQueryParameters{}, err
+	}
+	return logstore.LogQueryParameters{
+		ServiceName:    q.ServiceName,
+		OperationName:  q.OperationName,
+		StartTimeMin:   start,
+		StartTimeMax:   end,
+		NumTraces:      q.NumTraces,
+		SeverityNumber: q.SeverityNumber,
+		Order:          q.Order,
+	}, nil
+}
+
+// Resolve returns the absolute bounds of r when run at t.
+func (r Range) Resolve(t time.Time) (time.Time, time.Time, error) {
+	if r.From == "" {
+		return time.Time{}, time.Time{}, errors.New("range.from must be set")
+	}
+	start, err := ParseRelativeTime(r.From, t)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("cannot parse range.from: %w", err)
+	}
+	end := t
+	if r.To != "" {
+		if end, err = ParseRelativeTime(r.To, t); err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("cannot parse range.to: %w", err)
+		}
+	}
+	if !start.Before(end) {
+		return time.Time{}, time.Time{}, errors.New("range.from must be before range.to")
+	}
+	return start, end, nil
+}
+
+// ParseRelativeTime parses "now" or "now-<duration>" relative to t.
+func ParseRelativeTime(value string, t time.Time) (time.Time, error) {
+	if value == now {
+		return t, nil
+	}
+	offset, ok := strings.CutPrefix(value, now+"-")
+	if !ok {
+		return time.Time{}, fmt.Errorf("%q is not \"now\" nor \"now-<duration>\"", value)
+	}
+	d, err := time.ParseDuration(offset)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if d < 0 {
+		return time.Time{}, fmt.Errorf("%q points to the future", value)
+	}
+	return t.Add(-d), nil
+}
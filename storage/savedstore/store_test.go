@@ -0,0 +1,90 @@
+package savedstore
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"logger/storage/logstore"
+)
+
+func TestParseRelativeTime(t *testing.T) {
+	at := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	for value, expected := range map[string]time.Time{
+		"now":       at,
+		"now-15m":   at.Add(-15 * time.Minute),
+		"now-1h30m": at.Add(-90 * time.Minute),
+	} {
+		res, err := ParseRelativeTime(value, at)
+		require.NoError(t, err, value)
+		assert.Equal(t, expected, res, value)
+	}
+	for _, value := range []string{"", "today", "now+1h", "now-1x", "now--1h"} {
+		_, err := ParseRelativeTime(value, at)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestRangeResolve(t *testing.T) {
+	at := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	start, end, err := Range{From: "now-1h"}.Resolve(at)
+	require.NoError(t, err)
+	assert.Equal(t, at.Add(-time.Hour), start)
+	assert.Equal(t, at, end)
+
+	start, end, err = Range{From: "now-2h", To: "now-1h"}.Resolve(at)
+	require.NoError(t, err)
+	assert.Equal(t, at.Add(-2*time.Hour), start)
+	assert.Equal(t, at.Add(-time.Hour), end)
+
+	for _, r := range []Range{{}, {From: "now"}, {From: "now-1h", To: "now-2h"}, {From: "yesterday"}} {
+		_, _, err := r.Resolve(at)
+		assert.Error(t, err, r)
+	}
+}
+
+func TestQueryLogQuery(t *testing.T) {
+	at := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	q := Query{
+		ServiceName:    "api",
+		OperationName:  "GET",
+		NumTraces:      20,
+		SeverityNumber: 17,
+		Order:          logstore.OrderAsc,
+		Range:          Range{From: "now-5m"},
+	}
+	res, err := q.LogQuery(at)
+	require.NoError(t, err)
+	assert.Equal(t, logstore.LogQueryParameters{
+		ServiceName:    "api",
+		OperationName:  "GET",
+		StartTimeMin:   at.Add(-5 * time.Minute),
+		StartTimeMax:   at,
+		NumTraces:      20,
+		SeverityNumber: 17,
+		Order:          logstore.OrderAsc,
+	}, res)
+}
+
+func TestSavedSearchValidate(t *testing.T) {
+	valid := SavedSearch{Name: "errors", Query: Query{ServiceName: "api", Range: Range{From: "now-1h"}}}
+	assert.NoError(t, valid.Validate())
+
+	noName := valid
+	noName.Name = " "
+	assert.Error(t, noName.Validate())
+
+	noService := valid
+	noService.Query.ServiceName = ""
+	assert.Error(t, noService.Validate())
+
+	negative := valid
+	negative.Query.NumTraces = -1
+	assert.Error(t, negative.Validate())
+
+	noRange := valid
+	noRange.Query.Range = Range{}
+	assert.Error(t, noRange.Validate())
+}